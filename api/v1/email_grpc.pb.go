@@ -0,0 +1,151 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: v1/email.proto
+
+package api
+
+import (
+	context "context"
+	v1 "github.com/usercoredev/proto/api/v1"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	EmailChangeService_ConfirmEmailChange_FullMethodName = "/usercore.v1.EmailChangeService/ConfirmEmailChange"
+	EmailChangeService_RevertEmailChange_FullMethodName  = "/usercore.v1.EmailChangeService/RevertEmailChange"
+)
+
+// EmailChangeServiceClient is the client API for EmailChangeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EmailChangeServiceClient interface {
+	// Confirms a change requested with UserService.ChangeEmail, with the new email and its code or the link token
+	ConfirmEmailChange(ctx context.Context, in *ConfirmEmailChangeRequest, opts ...grpc.CallOption) (*v1.DefaultResponse, error)
+	// Cancels a pending change or restores the previous email, with the token sent to the previous email
+	RevertEmailChange(ctx context.Context, in *RevertEmailChangeRequest, opts ...grpc.CallOption) (*v1.DefaultResponse, error)
+}
+
+type emailChangeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEmailChangeServiceClient(cc grpc.ClientConnInterface) EmailChangeServiceClient {
+	return &emailChangeServiceClient{cc}
+}
+
+func (c *emailChangeServiceClient) ConfirmEmailChange(ctx context.Context, in *ConfirmEmailChangeRequest, opts ...grpc.CallOption) (*v1.DefaultResponse, error) {
+	out := new(v1.DefaultResponse)
+	err := c.cc.Invoke(ctx, EmailChangeService_ConfirmEmailChange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailChangeServiceClient) RevertEmailChange(ctx context.Context, in *RevertEmailChangeRequest, opts ...grpc.CallOption) (*v1.DefaultResponse, error) {
+	out := new(v1.DefaultResponse)
+	err := c.cc.Invoke(ctx, EmailChangeService_RevertEmailChange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EmailChangeServiceServer is the server API for EmailChangeService service.
+// All implementations must embed UnimplementedEmailChangeServiceServer
+// for forward compatibility
+type EmailChangeServiceServer interface {
+	// Confirms a change requested with UserService.ChangeEmail, with the new email and its code or the link token
+	ConfirmEmailChange(context.Context, *ConfirmEmailChangeRequest) (*v1.DefaultResponse, error)
+	// Cancels a pending change or restores the previous email, with the token sent to the previous email
+	RevertEmailChange(context.Context, *RevertEmailChangeRequest) (*v1.DefaultResponse, error)
+	mustEmbedUnimplementedEmailChangeServiceServer()
+}
+
+// UnimplementedEmailChangeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedEmailChangeServiceServer struct {
+}
+
+func (UnimplementedEmailChangeServiceServer) ConfirmEmailChange(context.Context, *ConfirmEmailChangeRequest) (*v1.DefaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmailChange not implemented")
+}
+func (UnimplementedEmailChangeServiceServer) RevertEmailChange(context.Context, *RevertEmailChangeRequest) (*v1.DefaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertEmailChange not implemented")
+}
+func (UnimplementedEmailChangeServiceServer) mustEmbedUnimplementedEmailChangeServiceServer() {}
+
+// UnsafeEmailChangeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EmailChangeServiceServer will
+// result in compilation errors.
+type UnsafeEmailChangeServiceServer interface {
+	mustEmbedUnimplementedEmailChangeServiceServer()
+}
+
+func RegisterEmailChangeServiceServer(s grpc.ServiceRegistrar, srv EmailChangeServiceServer) {
+	s.RegisterService(&EmailChangeService_ServiceDesc, srv)
+}
+
+func _EmailChangeService_ConfirmEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmEmailChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailChangeServiceServer).ConfirmEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmailChangeService_ConfirmEmailChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailChangeServiceServer).ConfirmEmailChange(ctx, req.(*ConfirmEmailChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailChangeService_RevertEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevertEmailChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailChangeServiceServer).RevertEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EmailChangeService_RevertEmailChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailChangeServiceServer).RevertEmailChange(ctx, req.(*RevertEmailChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EmailChangeService_ServiceDesc is the grpc.ServiceDesc for EmailChangeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EmailChangeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.EmailChangeService",
+	HandlerType: (*EmailChangeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ConfirmEmailChange",
+			Handler:    _EmailChangeService_ConfirmEmailChange_Handler,
+		},
+		{
+			MethodName: "RevertEmailChange",
+			Handler:    _EmailChangeService_RevertEmailChange_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/email.proto",
+}
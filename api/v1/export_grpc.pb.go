@@ -0,0 +1,238 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: v1/export.proto
+
+package api
+
+import (
+	context "context"
+	httpbody "google.golang.org/genproto/googleapis/api/httpbody"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	DataExportService_RequestDataExport_FullMethodName = "/usercore.v1.DataExportService/RequestDataExport"
+	DataExportService_GetDataExport_FullMethodName     = "/usercore.v1.DataExportService/GetDataExport"
+)
+
+// DataExportServiceClient is the client API for DataExportService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DataExportServiceClient interface {
+	RequestDataExport(ctx context.Context, in *RequestDataExportRequest, opts ...grpc.CallOption) (*RequestDataExportResponse, error)
+	GetDataExport(ctx context.Context, in *GetDataExportRequest, opts ...grpc.CallOption) (*DataExport, error)
+}
+
+type dataExportServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataExportServiceClient(cc grpc.ClientConnInterface) DataExportServiceClient {
+	return &dataExportServiceClient{cc}
+}
+
+func (c *dataExportServiceClient) RequestDataExport(ctx context.Context, in *RequestDataExportRequest, opts ...grpc.CallOption) (*RequestDataExportResponse, error) {
+	out := new(RequestDataExportResponse)
+	err := c.cc.Invoke(ctx, DataExportService_RequestDataExport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataExportServiceClient) GetDataExport(ctx context.Context, in *GetDataExportRequest, opts ...grpc.CallOption) (*DataExport, error) {
+	out := new(DataExport)
+	err := c.cc.Invoke(ctx, DataExportService_GetDataExport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataExportServiceServer is the server API for DataExportService service.
+// All implementations must embed UnimplementedDataExportServiceServer
+// for forward compatibility
+type DataExportServiceServer interface {
+	RequestDataExport(context.Context, *RequestDataExportRequest) (*RequestDataExportResponse, error)
+	GetDataExport(context.Context, *GetDataExportRequest) (*DataExport, error)
+	mustEmbedUnimplementedDataExportServiceServer()
+}
+
+// UnimplementedDataExportServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDataExportServiceServer struct {
+}
+
+func (UnimplementedDataExportServiceServer) RequestDataExport(context.Context, *RequestDataExportRequest) (*RequestDataExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestDataExport not implemented")
+}
+func (UnimplementedDataExportServiceServer) GetDataExport(context.Context, *GetDataExportRequest) (*DataExport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDataExport not implemented")
+}
+func (UnimplementedDataExportServiceServer) mustEmbedUnimplementedDataExportServiceServer() {}
+
+// UnsafeDataExportServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataExportServiceServer will
+// result in compilation errors.
+type UnsafeDataExportServiceServer interface {
+	mustEmbedUnimplementedDataExportServiceServer()
+}
+
+func RegisterDataExportServiceServer(s grpc.ServiceRegistrar, srv DataExportServiceServer) {
+	s.RegisterService(&DataExportService_ServiceDesc, srv)
+}
+
+func _DataExportService_RequestDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestDataExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataExportServiceServer).RequestDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataExportService_RequestDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataExportServiceServer).RequestDataExport(ctx, req.(*RequestDataExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataExportService_GetDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDataExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataExportServiceServer).GetDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataExportService_GetDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataExportServiceServer).GetDataExport(ctx, req.(*GetDataExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DataExportService_ServiceDesc is the grpc.ServiceDesc for DataExportService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DataExportService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.DataExportService",
+	HandlerType: (*DataExportServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RequestDataExport",
+			Handler:    _DataExportService_RequestDataExport_Handler,
+		},
+		{
+			MethodName: "GetDataExport",
+			Handler:    _DataExportService_GetDataExport_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/export.proto",
+}
+
+const (
+	DataExportDownloadService_DownloadDataExport_FullMethodName = "/usercore.v1.DataExportDownloadService/DownloadDataExport"
+)
+
+// DataExportDownloadServiceClient is the client API for DataExportDownloadService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DataExportDownloadServiceClient interface {
+	DownloadDataExport(ctx context.Context, in *DownloadDataExportRequest, opts ...grpc.CallOption) (*httpbody.HttpBody, error)
+}
+
+type dataExportDownloadServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataExportDownloadServiceClient(cc grpc.ClientConnInterface) DataExportDownloadServiceClient {
+	return &dataExportDownloadServiceClient{cc}
+}
+
+func (c *dataExportDownloadServiceClient) DownloadDataExport(ctx context.Context, in *DownloadDataExportRequest, opts ...grpc.CallOption) (*httpbody.HttpBody, error) {
+	out := new(httpbody.HttpBody)
+	err := c.cc.Invoke(ctx, DataExportDownloadService_DownloadDataExport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataExportDownloadServiceServer is the server API for DataExportDownloadService service.
+// All implementations must embed UnimplementedDataExportDownloadServiceServer
+// for forward compatibility
+type DataExportDownloadServiceServer interface {
+	DownloadDataExport(context.Context, *DownloadDataExportRequest) (*httpbody.HttpBody, error)
+	mustEmbedUnimplementedDataExportDownloadServiceServer()
+}
+
+// UnimplementedDataExportDownloadServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDataExportDownloadServiceServer struct {
+}
+
+func (UnimplementedDataExportDownloadServiceServer) DownloadDataExport(context.Context, *DownloadDataExportRequest) (*httpbody.HttpBody, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DownloadDataExport not implemented")
+}
+func (UnimplementedDataExportDownloadServiceServer) mustEmbedUnimplementedDataExportDownloadServiceServer() {
+}
+
+// UnsafeDataExportDownloadServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataExportDownloadServiceServer will
+// result in compilation errors.
+type UnsafeDataExportDownloadServiceServer interface {
+	mustEmbedUnimplementedDataExportDownloadServiceServer()
+}
+
+func RegisterDataExportDownloadServiceServer(s grpc.ServiceRegistrar, srv DataExportDownloadServiceServer) {
+	s.RegisterService(&DataExportDownloadService_ServiceDesc, srv)
+}
+
+func _DataExportDownloadService_DownloadDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DownloadDataExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataExportDownloadServiceServer).DownloadDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataExportDownloadService_DownloadDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataExportDownloadServiceServer).DownloadDataExport(ctx, req.(*DownloadDataExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DataExportDownloadService_ServiceDesc is the grpc.ServiceDesc for DataExportDownloadService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DataExportDownloadService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.DataExportDownloadService",
+	HandlerType: (*DataExportDownloadServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "DownloadDataExport",
+			Handler:    _DataExportDownloadService_DownloadDataExport_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/export.proto",
+}
@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: v1/import.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	UserImportService_ImportUsers_FullMethodName = "/usercore.v1.UserImportService/ImportUsers"
+)
+
+// UserImportServiceClient is the client API for UserImportService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserImportServiceClient interface {
+	ImportUsers(ctx context.Context, in *ImportUsersRequest, opts ...grpc.CallOption) (*ImportUsersResponse, error)
+}
+
+type userImportServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserImportServiceClient(cc grpc.ClientConnInterface) UserImportServiceClient {
+	return &userImportServiceClient{cc}
+}
+
+func (c *userImportServiceClient) ImportUsers(ctx context.Context, in *ImportUsersRequest, opts ...grpc.CallOption) (*ImportUsersResponse, error) {
+	out := new(ImportUsersResponse)
+	err := c.cc.Invoke(ctx, UserImportService_ImportUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserImportServiceServer is the server API for UserImportService service.
+// All implementations must embed UnimplementedUserImportServiceServer
+// for forward compatibility
+type UserImportServiceServer interface {
+	ImportUsers(context.Context, *ImportUsersRequest) (*ImportUsersResponse, error)
+	mustEmbedUnimplementedUserImportServiceServer()
+}
+
+// UnimplementedUserImportServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserImportServiceServer struct {
+}
+
+func (UnimplementedUserImportServiceServer) ImportUsers(context.Context, *ImportUsersRequest) (*ImportUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportUsers not implemented")
+}
+func (UnimplementedUserImportServiceServer) mustEmbedUnimplementedUserImportServiceServer() {}
+
+// UnsafeUserImportServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserImportServiceServer will
+// result in compilation errors.
+type UnsafeUserImportServiceServer interface {
+	mustEmbedUnimplementedUserImportServiceServer()
+}
+
+func RegisterUserImportServiceServer(s grpc.ServiceRegistrar, srv UserImportServiceServer) {
+	s.RegisterService(&UserImportService_ServiceDesc, srv)
+}
+
+func _UserImportService_ImportUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserImportServiceServer).ImportUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserImportService_ImportUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserImportServiceServer).ImportUsers(ctx, req.(*ImportUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserImportService_ServiceDesc is the grpc.ServiceDesc for UserImportService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserImportService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.UserImportService",
+	HandlerType: (*UserImportServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ImportUsers",
+			Handler:    _UserImportService_ImportUsers_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/import.proto",
+}
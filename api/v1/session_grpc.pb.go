@@ -0,0 +1,151 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: v1/session.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SessionRevocationService_RevokeOtherSessions_FullMethodName = "/usercore.v1.SessionRevocationService/RevokeOtherSessions"
+	SessionRevocationService_RevokeUserSessions_FullMethodName  = "/usercore.v1.SessionRevocationService/RevokeUserSessions"
+)
+
+// SessionRevocationServiceClient is the client API for SessionRevocationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SessionRevocationServiceClient interface {
+	// Signs the user out everywhere except the session of the access token
+	RevokeOtherSessions(ctx context.Context, in *RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error)
+	// Admin: signs a user out of all sessions
+	RevokeUserSessions(ctx context.Context, in *RevokeUserSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error)
+}
+
+type sessionRevocationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSessionRevocationServiceClient(cc grpc.ClientConnInterface) SessionRevocationServiceClient {
+	return &sessionRevocationServiceClient{cc}
+}
+
+func (c *sessionRevocationServiceClient) RevokeOtherSessions(ctx context.Context, in *RevokeOtherSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error) {
+	out := new(RevokeSessionsResponse)
+	err := c.cc.Invoke(ctx, SessionRevocationService_RevokeOtherSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sessionRevocationServiceClient) RevokeUserSessions(ctx context.Context, in *RevokeUserSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error) {
+	out := new(RevokeSessionsResponse)
+	err := c.cc.Invoke(ctx, SessionRevocationService_RevokeUserSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SessionRevocationServiceServer is the server API for SessionRevocationService service.
+// All implementations must embed UnimplementedSessionRevocationServiceServer
+// for forward compatibility
+type SessionRevocationServiceServer interface {
+	// Signs the user out everywhere except the session of the access token
+	RevokeOtherSessions(context.Context, *RevokeOtherSessionsRequest) (*RevokeSessionsResponse, error)
+	// Admin: signs a user out of all sessions
+	RevokeUserSessions(context.Context, *RevokeUserSessionsRequest) (*RevokeSessionsResponse, error)
+	mustEmbedUnimplementedSessionRevocationServiceServer()
+}
+
+// UnimplementedSessionRevocationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSessionRevocationServiceServer struct {
+}
+
+func (UnimplementedSessionRevocationServiceServer) RevokeOtherSessions(context.Context, *RevokeOtherSessionsRequest) (*RevokeSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeOtherSessions not implemented")
+}
+func (UnimplementedSessionRevocationServiceServer) RevokeUserSessions(context.Context, *RevokeUserSessionsRequest) (*RevokeSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeUserSessions not implemented")
+}
+func (UnimplementedSessionRevocationServiceServer) mustEmbedUnimplementedSessionRevocationServiceServer() {
+}
+
+// UnsafeSessionRevocationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SessionRevocationServiceServer will
+// result in compilation errors.
+type UnsafeSessionRevocationServiceServer interface {
+	mustEmbedUnimplementedSessionRevocationServiceServer()
+}
+
+func RegisterSessionRevocationServiceServer(s grpc.ServiceRegistrar, srv SessionRevocationServiceServer) {
+	s.RegisterService(&SessionRevocationService_ServiceDesc, srv)
+}
+
+func _SessionRevocationService_RevokeOtherSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeOtherSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionRevocationServiceServer).RevokeOtherSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SessionRevocationService_RevokeOtherSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionRevocationServiceServer).RevokeOtherSessions(ctx, req.(*RevokeOtherSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SessionRevocationService_RevokeUserSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeUserSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SessionRevocationServiceServer).RevokeUserSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SessionRevocationService_RevokeUserSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SessionRevocationServiceServer).RevokeUserSessions(ctx, req.(*RevokeUserSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SessionRevocationService_ServiceDesc is the grpc.ServiceDesc for SessionRevocationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SessionRevocationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.SessionRevocationService",
+	HandlerType: (*SessionRevocationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RevokeOtherSessions",
+			Handler:    _SessionRevocationService_RevokeOtherSessions_Handler,
+		},
+		{
+			MethodName: "RevokeUserSessions",
+			Handler:    _SessionRevocationService_RevokeUserSessions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/session.proto",
+}
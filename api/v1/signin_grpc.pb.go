@@ -0,0 +1,115 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: v1/signin.proto
+
+package api
+
+import (
+	context "context"
+	v1 "github.com/usercoredev/proto/api/v1"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SignInConfirmationService_ConfirmSignIn_FullMethodName = "/usercore.v1.SignInConfirmationService/ConfirmSignIn"
+)
+
+// SignInConfirmationServiceClient is the client API for SignInConfirmationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SignInConfirmationServiceClient interface {
+	// Confirms a held back sign-in with the email and its code or the link token, and returns the tokens of the new
+	// session. It must be called by the client that signed in.
+	ConfirmSignIn(ctx context.Context, in *ConfirmSignInRequest, opts ...grpc.CallOption) (*v1.AuthenticationResponse, error)
+}
+
+type signInConfirmationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSignInConfirmationServiceClient(cc grpc.ClientConnInterface) SignInConfirmationServiceClient {
+	return &signInConfirmationServiceClient{cc}
+}
+
+func (c *signInConfirmationServiceClient) ConfirmSignIn(ctx context.Context, in *ConfirmSignInRequest, opts ...grpc.CallOption) (*v1.AuthenticationResponse, error) {
+	out := new(v1.AuthenticationResponse)
+	err := c.cc.Invoke(ctx, SignInConfirmationService_ConfirmSignIn_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignInConfirmationServiceServer is the server API for SignInConfirmationService service.
+// All implementations must embed UnimplementedSignInConfirmationServiceServer
+// for forward compatibility
+type SignInConfirmationServiceServer interface {
+	// Confirms a held back sign-in with the email and its code or the link token, and returns the tokens of the new
+	// session. It must be called by the client that signed in.
+	ConfirmSignIn(context.Context, *ConfirmSignInRequest) (*v1.AuthenticationResponse, error)
+	mustEmbedUnimplementedSignInConfirmationServiceServer()
+}
+
+// UnimplementedSignInConfirmationServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSignInConfirmationServiceServer struct {
+}
+
+func (UnimplementedSignInConfirmationServiceServer) ConfirmSignIn(context.Context, *ConfirmSignInRequest) (*v1.AuthenticationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmSignIn not implemented")
+}
+func (UnimplementedSignInConfirmationServiceServer) mustEmbedUnimplementedSignInConfirmationServiceServer() {
+}
+
+// UnsafeSignInConfirmationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SignInConfirmationServiceServer will
+// result in compilation errors.
+type UnsafeSignInConfirmationServiceServer interface {
+	mustEmbedUnimplementedSignInConfirmationServiceServer()
+}
+
+func RegisterSignInConfirmationServiceServer(s grpc.ServiceRegistrar, srv SignInConfirmationServiceServer) {
+	s.RegisterService(&SignInConfirmationService_ServiceDesc, srv)
+}
+
+func _SignInConfirmationService_ConfirmSignIn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmSignInRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignInConfirmationServiceServer).ConfirmSignIn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SignInConfirmationService_ConfirmSignIn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignInConfirmationServiceServer).ConfirmSignIn(ctx, req.(*ConfirmSignInRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SignInConfirmationService_ServiceDesc is the grpc.ServiceDesc for SignInConfirmationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SignInConfirmationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "usercore.v1.SignInConfirmationService",
+	HandlerType: (*SignInConfirmationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ConfirmSignIn",
+			Handler:    _SignInConfirmationService_ConfirmSignIn_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v1/signin.proto",
+}
@@ -0,0 +1,731 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/cipher"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/config"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/password"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// minKeyBits is the smallest RSA key generate-keys accepts
+const minKeyBits = 2048
+
+// cliClient is the client the admin commands act as, their audit events carry its ID
+var cliClient = &client.Item{ID: "admin-cli", Name: "Admin CLI"}
+
+// commandContext returns the context of an admin command, carrying the admin CLI client for the audit events
+func commandContext() context.Context {
+	return context.WithValue(context.Background(), client.Key, cliClient)
+}
+
+// recordCommand records the audit event of a change made by an admin command, with the admin CLI as the actor
+func (a *Application) recordCommand(ctx context.Context, action string, userID uuid.UUID, metadata map[string]string) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["actor"] = cliClient.ID
+	audit.RecordOrLog(ctx, a.logger, audit.Event{
+		UserID:   &userID,
+		Action:   action,
+		Outcome:  database.AuditOutcomeSuccess,
+		Metadata: metadata,
+	})
+}
+
+// configureCommandNotifications sets up the security notifications of a command. They are only queued, the server
+// sends them, so no mailer is needed.
+func (a *Application) configureCommandNotifications() {
+	a.ConfigureNotifications()
+}
+
+// writeOutput writes value as JSON with --json, and the human readable form written by text otherwise
+func writeOutput(out io.Writer, asJSON bool, value interface{}, text func(w io.Writer)) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	text(writer)
+	return writer.Flush()
+}
+
+// connectForCommand connects to the database for a subcommand with the given migration mode. The logs and query
+// errors go to stderr, so they don't mix with the output.
+func (a *Application) connectForCommand(migrationMode string) {
+	if a.loggerSettings.Output == nil {
+		a.loggerSettings.Output = os.Stderr
+	}
+	a.ConfigureLogger()
+	a.databaseOptions.EnableMigration = migrationMode
+	a.ConnectToDatabase()
+	database.DB.Logger = gormlogger.New(log.New(os.Stderr, "", log.LstdFlags), gormlogger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  gormlogger.Warn,
+		IgnoreRecordNotFoundError: true,
+	})
+}
+
+// findUser returns the user with the given email or ID
+func findUser(reference string) (*database.User, error) {
+	if reference == "" {
+		return nil, errors.New("--user is required")
+	}
+	var user *database.User
+	var err error
+	if id, parseErr := uuid.Parse(reference); parseErr == nil {
+		user, err = database.GetUserByID(id, false)
+	} else {
+		user, err = database.GetUserByEmail(reference)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %s not found", reference)
+	}
+	return user, err
+}
+
+// readPassword reads the password from a file, - for stdin. Without a file a random password is generated and
+// returned with generated set.
+func readPassword(file string) (plain string, generated bool, err error) {
+	if file == "" {
+		plain, err = generatePassword(24)
+		return plain, true, err
+	}
+	var content []byte
+	if file == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	plain = strings.TrimRight(string(content), "\r\n")
+	if plain == "" {
+		return "", false, errors.New("the password file is empty")
+	}
+	return plain, false, nil
+}
+
+// generatePassword returns a random password with upper and lower case letters, digits and symbols
+func generatePassword(length int) (string, error) {
+	classes := []string{"ABCDEFGHJKLMNPQRSTUVWXYZ", "abcdefghijkmnopqrstuvwxyz", "23456789", "!#$%&*+-=?@^_"}
+	all := strings.Join(classes, "")
+	pick := func(set string) (byte, error) {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return 0, err
+		}
+		return set[index.Int64()], nil
+	}
+	result := make([]byte, length)
+	for i := range result {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+		char, err := pick(set)
+		if err != nil {
+			return "", err
+		}
+		result[i] = char
+	}
+	// shuffle, so the guaranteed classes are not always first
+	for i := len(result) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		result[i], result[j.Int64()] = result[j.Int64()], result[i]
+	}
+	return string(result), nil
+}
+
+// checkPassword applies the password policy and the breach screening to a password set from the command line
+func (a *Application) checkPassword(plain string, passwordCtx password.Context) error {
+	violations := a.passwordPolicy.Check(plain, passwordCtx)
+	breached, err := a.passwordPolicy.CheckBreached(context.Background(), plain)
+	if err != nil {
+		return err
+	}
+	if breached != nil {
+		violations = append(violations, *breached)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	reasons := make([]string, len(violations))
+	for i, violation := range violations {
+		reasons[i] = violation.Reason
+	}
+	return fmt.Errorf("the password does not follow the password policy: %s", strings.Join(reasons, ", "))
+}
+
+type userOutput struct {
+	ID            string   `json:"id"`
+	Email         string   `json:"email"`
+	Name          string   `json:"name"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	Password      string   `json:"password,omitempty"`
+	Revoked       *int     `json:"revoked_sessions,omitempty"`
+}
+
+func newUserOutput(user *database.User) (userOutput, error) {
+	roles, err := database.GetUserRoles(user.ID)
+	if err != nil {
+		return userOutput{}, err
+	}
+	output := userOutput{
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerified,
+		Roles:         []string{},
+	}
+	for _, role := range roles {
+		output.Roles = append(output.Roles, role.Key)
+	}
+	return output, nil
+}
+
+func (o userOutput) write(w io.Writer) {
+	fmt.Fprintf(w, "ID\t%s\n", o.ID)
+	fmt.Fprintf(w, "Email\t%s\n", o.Email)
+	fmt.Fprintf(w, "Name\t%s\n", o.Name)
+	fmt.Fprintf(w, "Email verified\t%t\n", o.EmailVerified)
+	fmt.Fprintf(w, "Roles\t%s\n", strings.Join(o.Roles, ", "))
+	if o.Revoked != nil {
+		fmt.Fprintf(w, "Revoked sessions\t%d\n", *o.Revoked)
+	}
+	if o.Password != "" {
+		fmt.Fprintf(w, "Generated password\t%s\n", o.Password)
+	}
+}
+
+// ensureAdminRole returns the admin role, created on first use
+func ensureAdminRole() (*database.Role, error) {
+	return database.EnsureRole(database.AdminRoleKey, "Admin", "Access to the admin RPCs")
+}
+
+// createUser creates a user with a verified or unverified email, and with --admin the admin role in the same
+// transaction
+func (a *Application) createUser(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	email := flags.String("email", "", "email of the user")
+	name := flags.String("name", "", "name of the user")
+	passwordFile := flags.String("password-file", "", "file with the password, - for stdin, a random password is generated when empty")
+	admin := flags.Bool("admin", false, "assign the admin role")
+	verified := flags.Bool("verified", false, "mark the email as verified")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *name == "" {
+		return errors.New("--email and --name are required")
+	}
+	plain, generated, err := readPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+
+	a.connectForCommand(database.MigrationModeCheck)
+	a.ConfigurePasswordHashing()
+	a.LoadBreachedPasswords()
+	if err = a.checkPassword(plain, password.Context{Name: *name, Email: *email}); err != nil {
+		return err
+	}
+	if _, err = database.GetUserByEmail(*email); err == nil {
+		return fmt.Errorf("a user with the email %s already exists", *email)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var roles []*database.Role
+	if *admin {
+		role, err := ensureAdminRole()
+		if err != nil {
+			return err
+		}
+		roles = append(roles, role)
+	}
+	user := database.User{Name: *name, Email: *email, EmailVerified: *verified}
+	if err = user.SetPassword(plain); err != nil {
+		return err
+	}
+	if err = user.Create(a.passwordPolicy.HistoryDepth, roles...); err != nil {
+		return err
+	}
+	a.recordCommand(commandContext(), audit.ActionUserCreate, user.ID, map[string]string{"admin": strconv.FormatBool(*admin)})
+
+	output, err := newUserOutput(&user)
+	if err != nil {
+		return err
+	}
+	if generated {
+		output.Password = plain
+	}
+	return writeOutput(out, *asJSON, output, output.write)
+}
+
+// resetPassword sets a new password for a user and signs them out everywhere. The user is notified like after
+// changing the password.
+func (a *Application) resetPassword(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	reference := flags.String("user", "", "email or ID of the user")
+	passwordFile := flags.String("password-file", "", "file with the password, - for stdin, a random password is generated when empty")
+	keepSessions := flags.Bool("keep-sessions", false, "do not revoke the sessions of the user")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	plain, generated, err := readPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+
+	a.connectForCommand(database.MigrationModeCheck)
+	a.ConfigurePasswordHashing()
+	a.LoadBreachedPasswords()
+	a.configureCommandNotifications()
+	user, err := findUser(*reference)
+	if err != nil {
+		return err
+	}
+	if err = a.checkPassword(plain, password.Context{Name: user.Name, Email: user.Email}); err != nil {
+		return err
+	}
+	if err = user.SetPassword(plain); err != nil {
+		return err
+	}
+	if err = user.SavePassword(a.passwordPolicy.HistoryDepth); err != nil {
+		return err
+	}
+	revoked := 0
+	if !*keepSessions {
+		sessions, err := database.RevokeSessions(user.ID, 0)
+		if err != nil {
+			return err
+		}
+		revoked = len(sessions)
+	}
+	ctx := commandContext()
+	a.recordCommand(ctx, audit.ActionPasswordSet, user.ID, map[string]string{"revoked_sessions": strconv.Itoa(revoked)})
+	a.notifier.Notify(ctx, user.ID, notify.EventPasswordChanged, notify.Data{})
+
+	output, err := newUserOutput(user)
+	if err != nil {
+		return err
+	}
+	output.Revoked = &revoked
+	if generated {
+		output.Password = plain
+	}
+	return writeOutput(out, *asJSON, output, output.write)
+}
+
+// assignRole gives a role to a user or takes it away with --remove. The admin role is created on first use, other
+// roles must exist.
+func (a *Application) assignRole(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("assign-role", flag.ContinueOnError)
+	reference := flags.String("user", "", "email or ID of the user")
+	key := flags.String("role", "", "key of the role")
+	remove := flags.Bool("remove", false, "take the role away")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		return errors.New("--role is required")
+	}
+
+	a.connectForCommand(database.MigrationModeCheck)
+	user, err := findUser(*reference)
+	if err != nil {
+		return err
+	}
+	var role *database.Role
+	if *key == database.AdminRoleKey && !*remove {
+		role, err = ensureAdminRole()
+	} else {
+		role, err = database.GetRoleByKey(*key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("role %s not found", *key)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	action := audit.ActionRoleAssign
+	if *remove {
+		action = audit.ActionRoleUnassign
+		err = database.UnassignRole(user, role)
+	} else {
+		err = database.AssignRole(user, role)
+	}
+	if err != nil {
+		return err
+	}
+	a.recordCommand(commandContext(), action, user.ID, map[string]string{"role": role.Key})
+
+	output, err := newUserOutput(user)
+	if err != nil {
+		return err
+	}
+	return writeOutput(out, *asJSON, output, output.write)
+}
+
+type sessionOutput struct {
+	ID         uint64     `json:"id"`
+	ClientID   string     `json:"client_id"`
+	ClientName string     `json:"client_name"`
+	IP         string     `json:"ip"`
+	DeviceType string     `json:"device_type"`
+	Browser    string     `json:"browser"`
+	OS         string     `json:"os"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// listSessions lists the sessions of a user, newest first
+func (a *Application) listSessions(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("list-sessions", flag.ContinueOnError)
+	reference := flags.String("user", "", "email or ID of the user")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	a.connectForCommand(database.MigrationModeCheck)
+	user, err := findUser(*reference)
+	if err != nil {
+		return err
+	}
+	sessions, err := database.GetSessionsByUserId(user.ID)
+	if err != nil {
+		return err
+	}
+	output := make([]sessionOutput, len(sessions))
+	for i, session := range sessions {
+		output[i] = sessionOutput{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			ClientName: session.ClientName,
+			IP:         session.IP,
+			DeviceType: session.DeviceType,
+			Browser:    session.Browser,
+			OS:         session.OS,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+		}
+	}
+	return writeOutput(out, *asJSON, output, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tCLIENT\tIP\tDEVICE\tBROWSER\tOS\tCREATED AT\tLAST SEEN AT\tEXPIRES AT")
+		for _, session := range output {
+			lastSeenAt := "-"
+			if session.LastSeenAt != nil {
+				lastSeenAt = session.LastSeenAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", session.ID, session.ClientName, session.IP,
+				session.DeviceType, session.Browser, session.OS, session.CreatedAt.UTC().Format(time.RFC3339), lastSeenAt,
+				session.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+	})
+}
+
+// revokeSessions signs a user out of one session with --session, of all sessions otherwise. The user is notified like
+// after a revocation by an admin through the API.
+func (a *Application) revokeSessions(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("revoke-sessions", flag.ContinueOnError)
+	reference := flags.String("user", "", "email or ID of the user")
+	sessionID := flags.Uint64("session", 0, "ID of the session, all sessions of the user when 0")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	a.connectForCommand(database.MigrationModeCheck)
+	a.configureCommandNotifications()
+	user, err := findUser(*reference)
+	if err != nil {
+		return err
+	}
+	var revoked []database.Session
+	action := audit.ActionSessionRevokeUser
+	if *sessionID != 0 {
+		session, err := database.GetSessionById(*sessionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !session.SessionBelongsToUser(user.ID)) {
+			return fmt.Errorf("session %d of user %s not found", *sessionID, user.Email)
+		}
+		if err != nil {
+			return err
+		}
+		if err = session.Delete(); err != nil {
+			return err
+		}
+		revoked = []database.Session{*session}
+		action = audit.ActionSessionDelete
+	} else if revoked, err = database.RevokeSessions(user.ID, 0); err != nil {
+		return err
+	}
+	if len(revoked) > 0 {
+		ctx := commandContext()
+		metadata := map[string]string{"revoked_sessions": strconv.Itoa(len(revoked))}
+		if *sessionID != 0 {
+			metadata["session_id"] = strconv.FormatUint(*sessionID, 10)
+		}
+		a.recordCommand(ctx, action, user.ID, metadata)
+		a.notifier.Notify(ctx, user.ID, notify.EventSessionRevokedByAdmin, notify.RevokedSessions(revoked))
+	}
+
+	output := map[string]interface{}{"user_id": user.ID.String(), "revoked": len(revoked)}
+	return writeOutput(out, *asJSON, output, func(w io.Writer) {
+		fmt.Fprintf(w, "Revoked %d session(s) of %s\n", len(revoked), user.Email)
+	})
+}
+
+// addClient adds a client to the client file. The server reads the clients at startup.
+func (a *Application) addClient(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("add-client", flag.ContinueOnError)
+	id := flags.String("id", "", "ID the client sends, a random UUID when empty")
+	name := flags.String("name", "", "name of the client")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+	if *id == "" {
+		*id = uuid.NewString()
+	}
+	if err := a.clientSettings.LoadClients(); err != nil {
+		return err
+	}
+	if a.clientSettings.GetClient(*id) != nil {
+		return fmt.Errorf("client %s already exists", *id)
+	}
+	item := client.Item{ID: *id, Name: *name}
+	a.clientSettings.Clients = append(a.clientSettings.Clients, item)
+	if err := a.clientSettings.SaveClients(); err != nil {
+		return err
+	}
+	return writeOutput(out, *asJSON, item, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%s\n", item.ID)
+		fmt.Fprintf(w, "Name\t%s\n", item.Name)
+		fmt.Fprintf(w, "Added to %s, restart the server to use it\n", a.clientSettings.ClientFilePath)
+	})
+}
+
+// removeClient removes a client from the client file. The sessions of the client stay until they expire.
+func (a *Application) removeClient(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("remove-client", flag.ContinueOnError)
+	id := flags.String("id", "", "ID of the client")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := a.clientSettings.LoadClients(); err != nil {
+		return err
+	}
+	removed := a.clientSettings.GetClient(*id)
+	if removed == nil {
+		return fmt.Errorf("client %s not found", *id)
+	}
+	var clients []client.Item
+	for _, item := range a.clientSettings.Clients {
+		if item.ID != *id {
+			clients = append(clients, item)
+		}
+	}
+	a.clientSettings.Clients = clients
+	if a.clientSettings.Clients == nil {
+		a.clientSettings.Clients = []client.Item{}
+	}
+	if err := a.clientSettings.SaveClients(); err != nil {
+		return err
+	}
+	return writeOutput(out, *asJSON, removed, func(w io.Writer) {
+		fmt.Fprintf(w, "Removed client %s (%s) from %s, restart the server to apply\n", removed.ID, removed.Name, a.clientSettings.ClientFilePath)
+	})
+}
+
+// generateKeys writes a new JWT signing key pair to the configured key paths
+func (a *Application) generateKeys(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("generate-keys", flag.ContinueOnError)
+	privatePath := flags.String("private", a.tokenSettings.PrivateKeyPath, "file of the private key")
+	publicPath := flags.String("public", a.tokenSettings.PublicKeyPath, "file of the public key")
+	bits := flags.Int("bits", minKeyBits, "size of the RSA key")
+	force := flags.Bool("force", false, "overwrite existing keys, tokens signed with them stop working")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *bits < minKeyBits {
+		return fmt.Errorf("--bits must be at least %d", minKeyBits)
+	}
+	if !*force {
+		for _, path := range []string{*privatePath, *publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use --force to replace it", path)
+			}
+		}
+	}
+	privateKey, publicKey, err := cipher.GenerateKeyPair(*bits)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(*privatePath, privateKey, 0o600); err != nil {
+		return err
+	}
+	if err = os.WriteFile(*publicPath, publicKey, 0o644); err != nil {
+		return err
+	}
+
+	output := map[string]interface{}{"private_key_path": *privatePath, "public_key_path": *publicPath, "bits": *bits}
+	return writeOutput(out, *asJSON, output, func(w io.Writer) {
+		fmt.Fprintf(w, "Private key\t%s\n", *privatePath)
+		fmt.Fprintf(w, "Public key\t%s\n", *publicPath)
+	})
+}
+
+type configCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// runCheck runs a setup step of the server, the steps panic on invalid configuration
+func runCheck(name string, step func()) (check configCheck) {
+	check.Name = name
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			check.Error = fmt.Sprint(recovered)
+		}
+	}()
+	step()
+	check.OK = true
+	return check
+}
+
+// checkConfig runs the setup steps of the server without starting it, and connects to the database and the cache
+func (a *Application) checkConfig(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	a.ConfigureLogger()
+	checks := []configCheck{
+		runCheck("signing keys", func() {
+			a.ConfigureToken()
+			if !cipher.PrivateKey(a.tokenSettings.PrivateKeyPath).PublicKey.Equal(cipher.PublicKey(a.tokenSettings.PublicKeyPath)) {
+				panic("the public key does not belong to the private key")
+			}
+		}),
+		runCheck("clients", a.LoadClients),
+		runCheck("rate limits", a.LoadRateLimits),
+		runCheck("trusted proxies", a.ConfigureClientIP),
+		runCheck("session policy", a.ConfigureSessionPolicy),
+		runCheck("password hashing", a.ConfigurePasswordHashing),
+		runCheck("breached passwords", a.LoadBreachedPasswords),
+		runCheck("user import", func() { a.firebaseParameters() }),
+		runCheck("mailer", a.ConfigureMailer),
+		runCheck("notifications", a.ConfigureNotifications),
+		runCheck("risk", a.ConfigureRisk),
+		runCheck("database", func() {
+			a.databaseOptions.EnableMigration = database.MigrationModeCheck
+			a.ConnectToDatabase()
+		}),
+	}
+	if a.cacheOptions.Enabled {
+		checks = append(checks, runCheck("cache", a.SetupCache))
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if !check.OK {
+			failed++
+		}
+	}
+	err := writeOutput(out, *asJSON, checks, func(w io.Writer) {
+		for _, check := range checks {
+			result := "ok"
+			if !check.OK {
+				result = "FAILED: " + check.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\n", check.Name, result)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// configCommand prints the loaded configuration, with the secrets masked by --redacted, or validates it. The config
+// command runs even when the configuration is invalid, so the problems can be inspected.
+func (a *Application) configCommand(args []string, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		return errors.New("usage: config print [--redacted] [--format yaml|toml] | validate")
+	}
+	flags := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	redacted := flags.Bool("redacted", false, "mask the secrets")
+	format := flags.String("format", "yaml", "yaml or toml")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if args[0] == "print" {
+		cfg := a.config
+		if *redacted {
+			cfg = cfg.Redacted()
+		}
+		return cfg.Encode(out, *format)
+	}
+	err := a.config.Validate()
+	var problems config.Errors
+	errors.As(err, &problems)
+	output := map[string]interface{}{"valid": err == nil, "errors": problems}
+	if writeErr := writeOutput(out, *asJSON, output, func(w io.Writer) {
+		if err == nil {
+			fmt.Fprintln(w, "configuration is valid")
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(w, problem)
+		}
+	}); writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%d configuration problems found", len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/config"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/notify"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPassword = "Correct-Horse-Battery-42"
+
+// newTestApplication returns an application with a migrated sqlite database and an empty client file in a temporary
+// directory
+func newTestApplication(t *testing.T) *Application {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Database.Engine = "sqlite"
+	cfg.Database.FilePath = filepath.Join(dir, "usercore.db")
+	cfg.Clients.FilePath = filepath.Join(dir, "clients.json")
+	if err := os.WriteFile(cfg.Clients.FilePath, []byte("[]\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write the client file: %v", err)
+	}
+	a := Create(cfg)
+	a.loggerSettings.Output = io.Discard
+	previous := database.DB
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		database.DB = previous
+	})
+	if err := a.migrate([]string{"up"}, io.Discard); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return &a
+}
+
+// writePasswordFile writes the test password to a file and returns its path
+func writePasswordFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(testPassword+"\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write the password file: %v", err)
+	}
+	return path
+}
+
+// runJSON runs a command with --json and decodes its output into value
+func runJSON(t *testing.T, command func([]string, io.Writer) error, args []string, value interface{}) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := command(append(args, "--json"), &out); err != nil {
+		t.Fatalf("Expected the command to succeed, got %v", err)
+	}
+	if err := json.Unmarshal(out.Bytes(), value); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out.String(), err)
+	}
+}
+
+// commandEvents returns the audit events recorded by the admin commands with the action
+func commandEvents(t *testing.T, action string) []database.AuditEvent {
+	t.Helper()
+	var events []database.AuditEvent
+	if err := database.DB.Where("action = ? AND client_id = ?", action, cliClient.ID).Find(&events).Error; err != nil {
+		t.Fatalf("Failed to get the audit events: %v", err)
+	}
+	return events
+}
+
+func TestCreateUser(t *testing.T) {
+	a := newTestApplication(t)
+
+	var output userOutput
+	runJSON(t, a.createUser, []string{"--email", "user@example.com", "--name", "User", "--verified"}, &output)
+	if output.Email != "user@example.com" || !output.EmailVerified {
+		t.Errorf("Expected a verified user@example.com, got %+v", output)
+	}
+	if len(output.Roles) != 0 {
+		t.Errorf("Expected no roles, got %v", output.Roles)
+	}
+	if output.Password == "" {
+		t.Error("Expected the generated password in the output")
+	}
+	user, err := database.GetUserByEmail("user@example.com")
+	if err != nil {
+		t.Fatalf("Expected the user to be created, got %v", err)
+	}
+	if !user.ComparePassword(output.Password) {
+		t.Error("Expected the generated password to be set")
+	}
+	if events := commandEvents(t, audit.ActionUserCreate); len(events) != 1 || events[0].UserID == nil || *events[0].UserID != user.ID {
+		t.Errorf("Expected a user.create audit event for the user, got %+v", events)
+	}
+
+	if err := a.createUser([]string{"--email", "user@example.com", "--name", "User"}, io.Discard); err == nil {
+		t.Error("Expected an error for an existing email")
+	}
+}
+
+func TestCreateAdminUser(t *testing.T) {
+	a := newTestApplication(t)
+
+	var output userOutput
+	runJSON(t, a.createUser, []string{"--email", "admin@example.com", "--name", "Admin", "--admin", "--password-file", writePasswordFile(t)}, &output)
+	if len(output.Roles) != 1 || output.Roles[0] != database.AdminRoleKey {
+		t.Errorf("Expected the admin role, got %v", output.Roles)
+	}
+	if output.Password != "" {
+		t.Error("Expected no password in the output when it is read from a file")
+	}
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	a := newTestApplication(t)
+	runJSON(t, a.createUser, []string{"--email", "user@example.com", "--name", "User"}, &userOutput{})
+	user, err := database.GetUserByEmail("user@example.com")
+	if err != nil {
+		t.Fatalf("Failed to get the user: %v", err)
+	}
+	for _, refreshToken := range []string{"first", "second"} {
+		session := database.Session{UserID: user.ID, RefreshToken: refreshToken, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := database.DB.Create(&session).Error; err != nil {
+			t.Fatalf("Failed to create a session: %v", err)
+		}
+	}
+
+	var output userOutput
+	runJSON(t, a.resetPassword, []string{"--user", "user@example.com", "--password-file", writePasswordFile(t)}, &output)
+	if output.Revoked == nil || *output.Revoked != 2 {
+		t.Errorf("Expected 2 revoked sessions, got %v", output.Revoked)
+	}
+	sessions, err := database.GetSessionsByUserId(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get the sessions: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected the sessions to be revoked, %d are left", len(sessions))
+	}
+	user, err = database.GetUserByID(user.ID, false)
+	if err != nil {
+		t.Fatalf("Failed to get the user: %v", err)
+	}
+	if !user.ComparePassword(testPassword) {
+		t.Error("Expected the password from the file to be set")
+	}
+	events := commandEvents(t, audit.ActionPasswordSet)
+	if len(events) != 1 {
+		t.Fatalf("Expected a user.password_set audit event, got %d", len(events))
+	}
+	if metadata := events[0].GetMetadata(); metadata["revoked_sessions"] != "2" || metadata["actor"] != cliClient.ID {
+		t.Errorf("Expected the revoked sessions and the admin CLI actor in the metadata, got %v", metadata)
+	}
+	var notifications []database.Notification
+	if err = database.DB.Where("user_id = ?", user.ID).Find(&notifications).Error; err != nil {
+		t.Fatalf("Failed to get the notifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Event != notify.EventPasswordChanged {
+		t.Errorf("Expected a queued password_changed notification, got %+v", notifications)
+	}
+}
+
+func TestAssignAndRemoveRole(t *testing.T) {
+	a := newTestApplication(t)
+	runJSON(t, a.createUser, []string{"--email", "user@example.com", "--name", "User"}, &userOutput{})
+
+	var output userOutput
+	runJSON(t, a.assignRole, []string{"--user", "user@example.com", "--role", database.AdminRoleKey}, &output)
+	if len(output.Roles) != 1 || output.Roles[0] != database.AdminRoleKey {
+		t.Errorf("Expected the admin role, got %v", output.Roles)
+	}
+	runJSON(t, a.assignRole, []string{"--user", "user@example.com", "--role", database.AdminRoleKey, "--remove"}, &output)
+	if len(output.Roles) != 0 {
+		t.Errorf("Expected no roles after --remove, got %v", output.Roles)
+	}
+	if len(commandEvents(t, audit.ActionRoleAssign)) != 1 || len(commandEvents(t, audit.ActionRoleUnassign)) != 1 {
+		t.Error("Expected a role.assign and a role.unassign audit event")
+	}
+
+	if err := a.assignRole([]string{"--user", "user@example.com", "--role", "missing"}, io.Discard); err == nil {
+		t.Error("Expected an error for a missing role")
+	}
+}
+
+func TestAddAndRemoveClient(t *testing.T) {
+	a := newTestApplication(t)
+
+	var added client.Item
+	runJSON(t, a.addClient, []string{"--name", "Web", "--id", "web"}, &added)
+	if added.ID != "web" || added.Name != "Web" {
+		t.Errorf("Expected the web client, got %+v", added)
+	}
+	settings := client.Settings{ClientFilePath: a.clientSettings.ClientFilePath}
+	if err := settings.LoadClients(); err != nil {
+		t.Fatalf("Failed to load the client file: %v", err)
+	}
+	if settings.GetClient("web") == nil {
+		t.Fatalf("Expected the client in the client file, got %+v", settings.Clients)
+	}
+	if err := a.addClient([]string{"--name", "Web", "--id", "web"}, io.Discard); err == nil {
+		t.Error("Expected an error for an existing client")
+	}
+
+	var removed client.Item
+	runJSON(t, a.removeClient, []string{"--id", "web"}, &removed)
+	if removed.ID != "web" {
+		t.Errorf("Expected the web client to be removed, got %+v", removed)
+	}
+	content, err := os.ReadFile(a.clientSettings.ClientFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read the client file: %v", err)
+	}
+	if string(content) != "[]\n" {
+		t.Errorf("Expected an empty client file, got %q", content)
+	}
+}
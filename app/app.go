@@ -5,31 +5,75 @@ import (
 	"errors"
 	"fmt"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"github.com/talut/dotenv"
 	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
 	"github.com/usercoredev/usercore/app/services"
+	"github.com/usercoredev/usercore/internal/audit"
 	"github.com/usercoredev/usercore/internal/cache"
 	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/config"
 	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/dateutil"
 	"github.com/usercoredev/usercore/internal/errorutil"
+	"github.com/usercoredev/usercore/internal/export"
+	"github.com/usercoredev/usercore/internal/lockout"
+	"github.com/usercoredev/usercore/internal/logger"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/password"
+	"github.com/usercoredev/usercore/internal/ratelimit"
+	"github.com/usercoredev/usercore/internal/risk"
+	"github.com/usercoredev/usercore/internal/scheduler"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
 	"github.com/usercoredev/usercore/internal/token"
+	"github.com/usercoredev/usercore/internal/webhook"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"time"
 )
 
 type Application struct {
+	config          *config.Config
 	clientSettings  client.Settings
+	clientIP        clientip.Settings
 	grpcServer      Server
 	httpServer      Server
 	tokenSettings   token.Settings
 	databaseOptions database.Database
 	cacheOptions    cache.Settings
+	loggerSettings  logger.Settings
+	webhookSettings webhook.Settings
+	accountPurge    config.Account
+	schedulerConfig scheduler.Settings
+	jobs            config.Jobs
+	exportSettings  export.Settings
+	lockoutSettings lockout.Settings
+	rateLimits      ratelimit.Settings
+	passwordPolicy  password.Policy
+	breachSettings  password.BreachSettings
+	hashSettings    password.HashSettings
+	firebaseScrypt  config.Firebase
+	mailSettings    mailer.Settings
+	passwordReset   services.PasswordResetSettings
+	emailChange     services.EmailChangeSettings
+	notifySettings  notify.Settings
+	pushSettings    notify.PushSettings
+	riskSettings    risk.Settings
+	signInConfirm   services.SignInConfirmationSettings
+	sessionPolicy   sessionpolicy.Settings
+	dateSettings    dateutil.Settings
+	mailer          mailer.Sender
+	notifier        *notify.Notifier
+	risk            *risk.Engine
+	scheduler       *scheduler.Scheduler
+	logger          *slog.Logger
 }
 
 type Server struct {
@@ -37,53 +81,234 @@ type Server struct {
 	Port string
 }
 
-func Create() Application {
+// Create maps the configuration onto the settings of every component, it is expected to be validated
+func Create(cfg *config.Config) Application {
 	return Application{
+		config: cfg,
 		tokenSettings: token.Settings{
-			Scheme:             dotenv.GetString("TOKEN_SCHEME", "Bearer"),
-			Issuer:             dotenv.MustGetString("APP_NAME"),
-			Audience:           dotenv.MustGetString("JWT_AUDIENCE"),
-			PrivateKeyPath:     dotenv.MustGetString("PRIVATE_KEY_PATH"),
-			PublicKeyPath:      dotenv.MustGetString("PUBLIC_KEY_PATH"),
-			AccessTokenExpire:  dotenv.GetDuration("ACCESS_TOKEN_EXPIRE", 1*time.Hour),
-			RefreshTokenExpire: dotenv.GetDuration("REFRESH_TOKEN_EXPIRE", 24*time.Hour),
+			Scheme:             cfg.Token.Scheme,
+			Issuer:             cfg.App.Name,
+			Audience:           cfg.Token.Audience,
+			PrivateKeyPath:     cfg.Token.PrivateKeyPath,
+			PublicKeyPath:      cfg.Token.PublicKeyPath,
+			AccessTokenExpire:  cfg.Token.AccessTokenExpire,
+			RefreshTokenExpire: cfg.Token.RefreshTokenExpire,
+			SessionCacheTTL:    cfg.Token.SessionCacheTTL,
+			ActivityInterval:   cfg.Token.SessionActivityInterval,
+		},
+		clientIP: clientip.Settings{
+			TrustedProxies: cfg.App.TrustedProxies,
 		},
 		grpcServer: Server{
-			Host: dotenv.GetString("GRPC_SERVER_HOST", ""),
-			Port: dotenv.MustGetString("GRPC_SERVER_PORT"),
+			Host: cfg.Server.GRPCHost,
+			Port: cfg.Server.GRPCPort,
 		},
 		httpServer: Server{
-			Host: dotenv.GetString("HTTP_SERVER_HOST", ""),
-			Port: dotenv.MustGetString("HTTP_SERVER_PORT"),
+			Host: cfg.Server.HTTPHost,
+			Port: cfg.Server.HTTPPort,
 		},
 		databaseOptions: database.Database{
-			Host:            dotenv.GetString("DB_HOST", ""),
-			Port:            dotenv.GetString("DB_PORT", ""),
-			User:            dotenv.GetString("DB_USER", ""),
-			Password:        dotenv.GetString("DB_PASSWORD", ""),
-			PasswordFile:    dotenv.GetString("DB_PASSWORD_FILE", ""),
-			Database:        dotenv.GetString("DB_NAME", ""),
-			DatabaseFile:    dotenv.GetString("DB_FILE_PATH", ""),
-			Engine:          dotenv.GetString("DB_ENGINE", ""),
-			Charset:         dotenv.GetString("DB_CHARSET", ""),
-			Certificate:     dotenv.GetString("DB_CERTIFICATE_FILE", ""),
-			EnableMigration: dotenv.GetString("DB_MIGRATE", ""),
+			Host:            cfg.Database.Host,
+			Port:            cfg.Database.Port,
+			User:            cfg.Database.User,
+			Password:        cfg.Database.Password,
+			Database:        cfg.Database.Name,
+			DatabaseFile:    cfg.Database.FilePath,
+			Engine:          cfg.Database.Engine,
+			Charset:         cfg.Database.Charset,
+			Certificate:     cfg.Database.CertificateFile,
+			EnableMigration: cfg.Database.Migrate,
+			CodeHashKey:     cfg.App.CodeHashKey,
 		},
 		cacheOptions: cache.Settings{
-			Enabled:                    dotenv.GetBool("CACHE_ENABLED", false),
-			Host:                       dotenv.MustGetString("CACHE_HOST"),
-			Port:                       dotenv.MustGetString("CACHE_PORT"),
-			Password:                   dotenv.GetString("CACHE_PASSWORD", ""),
-			PasswordFile:               dotenv.GetString("CACHE_PASSWORD_FILE", ""),
-			EncryptionKey:              dotenv.GetString("CACHE_ENCRYPTION_KEY", ""),
-			UserCacheExpiration:        dotenv.GetDuration("USER_CACHE_EXPIRATION", 48*time.Hour),
-			UserProfileCacheExpiration: dotenv.GetDuration("USER_PROFILE_CACHE_EXPIRATION", 48*time.Hour),
-			UserCachePrefix:            dotenv.GetString("USER_CACHE_PREFIX", "user"),
-			UserProfileCachePrefix:     dotenv.GetString("USER_PROFILE_CACHE_PREFIX", "profile"),
+			Enabled:                    cfg.Cache.Enabled,
+			Host:                       cfg.Cache.Host,
+			Port:                       cfg.Cache.Port,
+			Password:                   cfg.Cache.Password,
+			EncryptionKey:              cfg.Cache.EncryptionKey,
+			UserCacheExpiration:        cfg.Cache.UserExpiration,
+			UserProfileCacheExpiration: cfg.Cache.UserProfileExpiration,
+			UserCachePrefix:            cfg.Cache.UserPrefix,
+			UserProfileCachePrefix:     cfg.Cache.UserProfilePrefix,
 		},
 		clientSettings: client.Settings{
-			ClientFilePath: dotenv.MustGetString("CLIENTS_FILE_PATH"),
+			ClientFilePath: cfg.Clients.FilePath,
+		},
+		loggerSettings: logger.Settings{
+			Level:  cfg.Log.Level,
+			Format: cfg.Log.Format,
+		},
+		webhookSettings: webhook.Settings{
+			Enabled:        cfg.Webhook.Enabled,
+			PollInterval:   cfg.Webhook.PollInterval,
+			Timeout:        cfg.Webhook.Timeout,
+			MaxAttempts:    cfg.Webhook.MaxAttempts,
+			InitialBackoff: cfg.Webhook.RetryBackoff,
+			MaxBackoff:     cfg.Webhook.MaxBackoff,
+		},
+		accountPurge: cfg.Account,
+		schedulerConfig: scheduler.Settings{
+			Enabled:      cfg.Scheduler.Enabled,
+			PollInterval: cfg.Scheduler.PollInterval,
+			LeaseTTL:     cfg.Scheduler.LeaseTTL,
+		},
+		jobs: cfg.Scheduler.Jobs,
+		exportSettings: export.Settings{
+			PollInterval: cfg.DataExport.PollInterval,
+			DownloadTTL:  cfg.DataExport.DownloadTTL,
+			Timeout:      cfg.DataExport.Timeout,
+		},
+		lockoutSettings: lockout.Settings{
+			Enabled:          cfg.Lockout.Enabled,
+			MaxEmailFailures: cfg.Lockout.MaxEmailFailures,
+			MaxIPFailures:    cfg.Lockout.MaxIPFailures,
+			DelayAfter:       cfg.Lockout.DelayAfter,
+			BaseDelay:        cfg.Lockout.BaseDelay,
+			MaxDelay:         cfg.Lockout.MaxDelay,
+			Duration:         cfg.Lockout.Duration,
+			Window:           cfg.Lockout.Window,
+		},
+		rateLimits: ratelimit.Settings{
+			Enabled:        cfg.RateLimit.Enabled,
+			PolicyFilePath: cfg.RateLimit.FilePath,
+		},
+		passwordPolicy: password.Policy{
+			MinLength:        cfg.Password.MinLength,
+			MaxLength:        cfg.Password.MaxLength,
+			RequireUppercase: cfg.Password.RequireUppercase,
+			RequireLowercase: cfg.Password.RequireLowercase,
+			RequireDigit:     cfg.Password.RequireDigit,
+			RequireSymbol:    cfg.Password.RequireSymbol,
+			CheckBlocklist:   cfg.Password.Blocklist,
+			MinScore:         cfg.Password.MinScore,
+			HistoryDepth:     cfg.Password.HistoryDepth,
+			MinAge:           cfg.Password.MinAge,
+		},
+		breachSettings: password.BreachSettings{
+			DataPath:     cfg.Password.Breach.DataPath,
+			RangeURL:     cfg.Password.Breach.RangeURL,
+			RangeTimeout: cfg.Password.Breach.RangeTimeout,
+			ServeRange:   cfg.Password.Breach.ServeRange,
+		},
+		hashSettings: password.HashSettings{
+			Algorithm:         cfg.Password.Hash.Algorithm,
+			Argon2Memory:      uint32(cfg.Password.Hash.Argon2Memory),
+			Argon2Iterations:  uint32(cfg.Password.Hash.Argon2Iterations),
+			Argon2Parallelism: uint8(cfg.Password.Hash.Argon2Parallelism),
+			BcryptCost:        cfg.Password.Hash.BcryptCost,
+			ScryptLogN:        uint8(cfg.Password.Hash.ScryptLogN),
+			ScryptR:           cfg.Password.Hash.ScryptR,
+			ScryptP:           cfg.Password.Hash.ScryptP,
+			FirebaseSignerKey: cfg.Import.Firebase.SignerKey,
+		},
+		firebaseScrypt: cfg.Import.Firebase,
+		mailSettings: mailer.Settings{
+			Driver:      cfg.Mail.Driver,
+			Host:        cfg.Mail.Host,
+			Port:        cfg.Mail.Port,
+			Username:    cfg.Mail.Username,
+			Password:    cfg.Mail.Password,
+			From:        cfg.Mail.From,
+			ImplicitTLS: cfg.Mail.ImplicitTLS,
+			Timeout:     cfg.Mail.Timeout,
+		},
+		passwordReset: services.PasswordResetSettings{
+			TTL:         cfg.Password.Reset.TTL,
+			MaxAttempts: cfg.Password.Reset.MaxAttempts,
+			Interval:    cfg.Password.Reset.Interval,
+			URL:         cfg.Password.Reset.URL,
+		},
+		notifySettings: notify.Settings{
+			Enabled:       cfg.Notify.Enabled,
+			DefaultLocale: cfg.Notify.DefaultLocale,
+			Timeout:       cfg.Notify.Timeout,
+			PollInterval:  cfg.Notify.PollInterval,
+			MaxAttempts:   cfg.Notify.MaxAttempts,
+		},
+		pushSettings: notify.PushSettings{
+			Driver:       cfg.Notify.Push.Driver,
+			GatewayURL:   cfg.Notify.Push.GatewayURL,
+			GatewayToken: cfg.Notify.Push.GatewayToken,
+			Timeout:      cfg.Notify.Push.Timeout,
+		},
+		emailChange: services.EmailChangeSettings{
+			TTL:         cfg.EmailChange.TTL,
+			MaxAttempts: cfg.EmailChange.MaxAttempts,
+			Interval:    cfg.EmailChange.Interval,
+			RevertTTL:   cfg.EmailChange.RevertTTL,
+			ConfirmURL:  cfg.EmailChange.ConfirmURL,
+			RevertURL:   cfg.EmailChange.RevertURL,
 		},
+		riskSettings: risk.Settings{
+			Enabled:        cfg.Risk.Enabled,
+			GeoIPDatabases: cfg.Risk.GeoIPDatabases,
+			MaxTravelSpeed: float64(cfg.Risk.MaxTravelSpeed),
+			History:        cfg.Risk.History,
+			Policy: client.RiskPolicy{
+				RequireMFA:               cfg.Risk.RequireMFAScore,
+				RequireEmailConfirmation: cfg.Risk.RequireEmailScore,
+				Block:                    cfg.Risk.BlockScore,
+			},
+		},
+		sessionPolicy: sessionpolicy.Settings{
+			MaxPerUser:             cfg.Sessions.MaxPerUser,
+			MaxPerClient:           cfg.Sessions.MaxPerClient,
+			MaxPerDeviceType:       cfg.Sessions.MaxPerDeviceType,
+			SingleSessionPerClient: cfg.Sessions.SingleSessionPerClient,
+			IdleTimeout:            cfg.Sessions.IdleTimeout,
+			MaxLifetime:            cfg.Sessions.MaxLifetime,
+		},
+		dateSettings: dateutil.Settings{
+			DateFormat:     cfg.App.DateFormat,
+			DateTimeFormat: cfg.App.DateTimeFormat,
+		},
+		signInConfirm: services.SignInConfirmationSettings{
+			TTL:         cfg.Risk.Confirmation.TTL,
+			MaxAttempts: cfg.Risk.Confirmation.MaxAttempts,
+			URL:         cfg.Risk.Confirmation.URL,
+		},
+	}
+}
+
+func (a *Application) ConfigureLogger() {
+	a.logger = a.loggerSettings.New()
+	slog.SetDefault(a.logger)
+}
+
+// ConfigureMailer sets up the delivery of emails, without MAIL_DRIVER no emails are sent
+func (a *Application) ConfigureMailer() {
+	sender, err := a.mailSettings.New(a.logger)
+	if err != nil {
+		panic(err)
+	}
+	a.mailer = sender
+	if sender == nil {
+		a.logger.Warn("No mailer configured, emails like password resets cannot be sent")
+	}
+}
+
+// ConfigureNotifications sets up the security notifications sent by email and push. The mailer must be configured
+// first.
+func (a *Application) ConfigureNotifications() {
+	pusher, err := a.pushSettings.New(a.logger)
+	if err != nil {
+		panic(err)
+	}
+	a.notifier, err = notify.New(a.notifySettings, a.mailer, pusher, a.logger)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// ConfigureRisk sets up the risk scoring of sign-ins and token refreshes, it is off unless RISK_ENABLED is set
+func (a *Application) ConfigureRisk() {
+	engine, err := a.riskSettings.New(a.logger)
+	if err != nil {
+		panic(err)
+	}
+	a.risk = engine
+	if engine != nil {
+		a.logger.Info("Risk-based sign-in enabled", "geoip_databases", a.riskSettings.GeoIPDatabases)
 	}
 }
 
@@ -93,16 +318,112 @@ func (a *Application) ConnectToDatabase() {
 	}
 }
 
+// ConfigureClientIP sets the proxies whose X-Forwarded-For entries are trusted when resolving the address of a caller
+func (a *Application) ConfigureClientIP() {
+	if err := a.clientIP.Setup(); err != nil {
+		panic(err)
+	}
+}
+
+// ConfigureToken loads the signing keys. Access tokens of deleted sessions, and of sessions that expired under the
+// session policy, are rejected.
 func (a *Application) ConfigureToken() {
+	a.tokenSettings.LoadSession = database.LoadTokenSession
+	a.tokenSettings.EndSession = func(ctx context.Context, sessionID uint64, session token.Session, now time.Time) error {
+		return services.EndExpiredSession(ctx, a.logger, sessionID, session, now)
+	}
+	a.tokenSettings.TouchSession = database.TouchSession
 	a.tokenSettings.Setup()
 }
 
+// ConfigureDates sets the layouts of the dates in requests and responses
+func (a *Application) ConfigureDates() {
+	a.dateSettings.Setup()
+}
+
+// ConfigureSessionPolicy sets the session limits and lifetimes of the clients without a policy of their own
+func (a *Application) ConfigureSessionPolicy() {
+	if err := a.sessionPolicy.Setup(); err != nil {
+		panic(err)
+	}
+}
+
 func (a *Application) LoadClients() {
 	if err := a.clientSettings.LoadClients(); err != nil {
 		panic(err)
 	}
 }
 
+func (a *Application) LoadRateLimits() {
+	if err := a.rateLimits.LoadPolicies(); err != nil {
+		panic(err)
+	}
+}
+
+// ConfigurePasswordHashing selects the algorithm for new password hashes, existing hashes are upgraded on sign-in
+func (a *Application) ConfigurePasswordHashing() {
+	if err := a.hashSettings.Setup(); err != nil {
+		panic(err)
+	}
+}
+
+// LoadBreachedPasswords enables screening new passwords against the local breach corpus or range endpoint
+func (a *Application) LoadBreachedPasswords() {
+	store, err := a.breachSettings.Store()
+	if err != nil {
+		panic(err)
+	}
+	a.passwordPolicy.Breaches = store
+	if store != nil {
+		a.logger.Info("Breached password screening enabled", "data_path", a.breachSettings.DataPath, "range_url", a.breachSettings.RangeURL)
+	}
+}
+
+// StartWebhookDispatcher delivers the outbox events to the client webhooks in the background. Clients must be loaded
+// first.
+func (a *Application) StartWebhookDispatcher() {
+	if !a.webhookSettings.Enabled {
+		return
+	}
+	dispatcher := webhook.NewDispatcher(a.webhookSettings, &a.clientSettings, a.logger)
+	go dispatcher.Run(context.Background())
+	a.logger.Info("Webhook dispatcher running", "poll_interval", a.webhookSettings.PollInterval.String())
+}
+
+// StartScheduler runs the cleanup jobs and the account purge in the background, on the one replica holding the
+// scheduler lease
+func (a *Application) StartScheduler() {
+	a.scheduler = scheduler.New(a.schedulerConfig, a.logger)
+	a.scheduler.Register(scheduler.SessionCleanup(a.jobs.SessionCleanup))
+	a.scheduler.Register(scheduler.PasswordResetCleanup(a.jobs.PasswordResetCleanup))
+	a.scheduler.Register(scheduler.VerificationCodeCleanup(a.jobs.VerificationCodeCleanup, a.jobs.VerificationCodeTTL))
+	a.scheduler.Register(scheduler.AccountPurge(a.accountPurge.PurgeInterval, a.accountPurge.DeletionGracePeriod))
+	a.scheduler.Register(scheduler.RunHistoryCleanup(a.jobs.RunHistoryCleanup, a.jobs.RunHistoryRetention))
+	a.scheduler.Register(scheduler.RiskDecisionCleanup(a.jobs.RiskDecisionCleanup, a.jobs.RiskDecisionRetention))
+	a.scheduler.Register(scheduler.LoginAttemptCleanup(a.jobs.LoginAttemptCleanup, a.jobs.LoginAttemptRetention))
+	a.scheduler.Register(scheduler.NotificationCleanup(a.jobs.NotificationCleanup, a.jobs.NotificationRetention))
+	if !a.schedulerConfig.Enabled {
+		a.logger.Warn("Scheduler disabled, another replica has to run the scheduled jobs")
+		return
+	}
+	go a.scheduler.Run(context.Background())
+	a.logger.Info("Scheduler running", "holder", a.scheduler.Holder())
+}
+
+// StartDataExportWorker builds the requested data exports in the background
+func (a *Application) StartDataExportWorker() {
+	worker := export.NewWorker(a.exportSettings, a.logger)
+	go worker.Run(context.Background())
+}
+
+// StartNotificationWorker sends the queued security notifications in the background
+func (a *Application) StartNotificationWorker() {
+	if a.notifier == nil {
+		return
+	}
+	go a.notifier.Run(context.Background())
+}
+
 func (a *Application) SetupCache() {
 	if err := a.cacheOptions.SetupCache(); err != nil {
 		panic(err)
@@ -125,25 +446,87 @@ func (a *Application) StartServer() {
 func (a *Application) startGRPCServer(lis net.Listener) {
 	s := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
+			logger.RequestIDInterceptor(),
+			logger.AccessLogInterceptor(a.logger),
 			client.ClientInterceptor(a.clientSettings),
+			a.rateLimitInterceptor(ratelimit.ByIP, ratelimit.ByClient),
 			a.tokenSettings.AuthInterceptor(),
+			a.rateLimitInterceptor(ratelimit.ByUser),
+			audit.Interceptor(a.logger),
 		),
 	)
 	a.registerGRPCServices(s)
 	go func() {
-		fmt.Println("GRPC Server running on: ", lis.Addr())
+		a.logger.Info("GRPC server running", "address", lis.Addr().String())
 		if err := s.Serve(lis); err != nil {
 			panic(errors.New(fmt.Sprintf("Code: %d, %s: %v", errorutil.ErrGRPCFailedToServe.Code, errorutil.ErrGRPCFailedToServe.Message, err)))
 		}
 	}()
 }
 
+// rateLimitInterceptor checks the rate limit policies keyed by one of keys
+func (a *Application) rateLimitInterceptor(keys ...string) grpc.UnaryServerInterceptor {
+	var primary ratelimit.Limiter
+	if redisClient := cache.Redis(); redisClient != nil {
+		primary = ratelimit.NewRedisLimiter(redisClient)
+	}
+	return ratelimit.Interceptor(a.rateLimits, primary, ratelimit.NewMemoryLimiter(), a.logger, keys...)
+}
+
+// firebaseParameters returns the configured Firebase hash parameters, invalid parameters stop the server
+func (a *Application) firebaseParameters() *password.FirebaseScryptParameters {
+	params, err := a.firebaseScrypt.Parameters()
+	if err != nil {
+		panic(err)
+	}
+	return params
+}
+
+// lockoutGuard keeps the sign-in failures in redis when the cache is enabled, with the database as fallback
+func (a *Application) lockoutGuard() *lockout.Guard {
+	var primary lockout.Store
+	if redisClient := cache.Redis(); redisClient != nil {
+		primary = lockout.NewRedisStore(redisClient, a.lockoutSettings.Window)
+	}
+	return lockout.NewGuard(a.lockoutSettings, primary, lockout.NewDatabaseStore(a.lockoutSettings.Window), a.logger)
+}
+
 func (a *Application) registerGRPCServices(server *grpc.Server) {
-	v1.RegisterAuthenticationServiceServer(server, &services.AuthenticationServer{})
-	v1.RegisterUserServiceServer(server, &services.UserServer{})
-	v1.RegisterSessionServiceServer(server, &services.SessionServer{})
-	v1.RegisterRoleServiceServer(server, &services.RoleServer{})
-	v1.RegisterPermissionServiceServer(server, &services.PermissionServer{})
+	v1.RegisterAuthenticationServiceServer(server, &services.AuthenticationServer{
+		Logger:                     a.logger,
+		Lockout:                    a.lockoutGuard(),
+		PasswordPolicy:             &a.passwordPolicy,
+		PasswordReset:              a.passwordReset,
+		Mailer:                     a.mailer,
+		Notifier:                   a.notifier,
+		Risk:                       a.risk,
+		SignInConfirmation:         a.signInConfirm,
+		OTPLength:                  a.config.App.OTPLength,
+		AccountDeletionGracePeriod: a.accountPurge.DeletionGracePeriod,
+	})
+	v1.RegisterUserServiceServer(server, &services.UserServer{
+		Logger:         a.logger,
+		PasswordPolicy: &a.passwordPolicy,
+		EmailChange:    a.emailChange,
+		Mailer:         a.mailer,
+		Notifier:       a.notifier,
+		OTPLength:      a.config.App.OTPLength,
+	})
+	v1.RegisterSessionServiceServer(server, &services.SessionServer{Logger: a.logger})
+	api.RegisterSessionRevocationServiceServer(server, &services.SessionRevocationServer{Logger: a.logger, Notifier: a.notifier})
+	v1.RegisterRoleServiceServer(server, &services.RoleServer{Logger: a.logger})
+	v1.RegisterPermissionServiceServer(server, &services.PermissionServer{Logger: a.logger})
+	api.RegisterAuditServiceServer(server, &services.AuditServer{Logger: a.logger})
+	api.RegisterWebhookServiceServer(server, &services.WebhookServer{Logger: a.logger})
+	api.RegisterAccountServiceServer(server, &services.AccountServer{Logger: a.logger})
+	api.RegisterEmailChangeServiceServer(server, &services.EmailChangeServer{Logger: a.logger, EmailChange: a.emailChange, Notifier: a.notifier})
+	api.RegisterNotificationServiceServer(server, &services.NotificationServer{Logger: a.logger})
+	api.RegisterDeviceServiceServer(server, &services.DeviceServer{Logger: a.logger})
+	api.RegisterJobServiceServer(server, &services.JobServer{Logger: a.logger, Scheduler: a.scheduler})
+	api.RegisterDataExportServiceServer(server, &services.DataExportServer{Logger: a.logger})
+	api.RegisterDataExportDownloadServiceServer(server, &services.DataExportDownloadServer{Logger: a.logger})
+	api.RegisterSignInConfirmationServiceServer(server, &services.SignInConfirmationServer{Logger: a.logger, SignInConfirmation: a.signInConfirm, Notifier: a.notifier})
+	api.RegisterUserImportServiceServer(server, &services.UserImportServer{Logger: a.logger, Firebase: a.firebaseParameters()})
 	reflection.Register(server)
 }
 
@@ -161,7 +544,17 @@ func (a *Application) startHTTPServer() error {
 	}
 	mux := runtime.NewServeMux(
 		runtime.WithMetadata(func(_ context.Context, req *http.Request) metadata.MD {
-			return metadata.Pairs(string(client.Key), req.Header.Get(string(client.Key)))
+			return metadata.Join(metadata.Pairs(
+				string(client.Key), req.Header.Get(string(client.Key)),
+				logger.RequestIDHeader, req.Header.Get(logger.RequestIDHeader),
+			), clientip.GatewayMetadata(req))
+		}),
+		runtime.WithIncomingHeaderMatcher(clientip.HeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+			if key == logger.RequestIDHeader || key == ratelimit.RetryAfterHeader {
+				return http.CanonicalHeaderKey(key), true
+			}
+			return runtime.MetadataHeaderPrefix + key, true
 		}),
 	)
 	a.registerHTTPServices(ctx, mux, conn)
@@ -170,7 +563,7 @@ func (a *Application) startHTTPServer() error {
 		Addr:    httpServerAddr,
 		Handler: mux,
 	}
-	fmt.Println("HTTP Server running on: ", httpServerAddr)
+	a.logger.Info("HTTP server running", "address", httpServerAddr)
 	if err = server.ListenAndServe(); err != nil {
 		return err
 	}
@@ -187,10 +580,55 @@ func (a *Application) registerHTTPServices(ctx context.Context, mux *runtime.Ser
 	if err := v1.RegisterSessionServiceHandler(ctx, mux, conn); err != nil {
 		log.Fatalln("Failed to register gateway:", err)
 	}
+	if err := api.RegisterSessionRevocationServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
 	if err := v1.RegisterRoleServiceHandler(ctx, mux, conn); err != nil {
 		log.Fatalln("Failed to register gateway:", err)
 	}
 	if err := v1.RegisterPermissionServiceHandler(ctx, mux, conn); err != nil {
 		log.Fatalln("Failed to register gateway:", err)
 	}
+	if err := api.RegisterAuditServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterWebhookServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterAccountServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterEmailChangeServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterNotificationServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterDeviceServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterDataExportServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterJobServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterDataExportDownloadServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterUserImportServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if err := api.RegisterSignInConfirmationServiceHandler(ctx, mux, conn); err != nil {
+		log.Fatalln("Failed to register gateway:", err)
+	}
+	if a.breachSettings.ServeRange {
+		rangeHandler := password.RangeHandler(a.passwordPolicy.Breaches)
+		err := mux.HandlePath(http.MethodGet, "/v1/pwned/range/{prefix}", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			rangeHandler.ServeHTTP(w, r)
+		})
+		if err != nil {
+			log.Fatalln("Failed to register breach range endpoint:", err)
+		}
+	}
 }
@@ -0,0 +1,258 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/userimport"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// commands lists the subcommands with their usage
+var commands = []string{
+	"import-users --file <file> [--format ndjson|csv] [--resume]",
+	"migrate up | down [--steps n] | status",
+	"create-user --email <email> --name <name> [--password-file <file>] [--admin] [--verified]",
+	"reset-password --user <email|id> [--password-file <file>] [--keep-sessions]",
+	"assign-role --user <email|id> --role <key> [--remove]",
+	"list-sessions --user <email|id>",
+	"revoke-sessions --user <email|id> [--session <id>]",
+	"add-client --name <name> [--id <id>]",
+	"remove-client --id <id>",
+	"generate-keys [--private <file>] [--public <file>] [--bits n] [--force]",
+	"check-config",
+	"config print [--redacted] [--format yaml|toml] | validate",
+}
+
+// RunCommand runs a subcommand of the binary instead of the server. Every command but import-users, which always
+// prints JSON, prints JSON with --json.
+func (a *Application) RunCommand(name string, args []string) error {
+	out := os.Stdout
+	switch name {
+	case "import-users":
+		return a.importUsers(args, out)
+	case "migrate":
+		return a.migrate(args, out)
+	case "create-user":
+		return a.createUser(args, out)
+	case "reset-password":
+		return a.resetPassword(args, out)
+	case "assign-role":
+		return a.assignRole(args, out)
+	case "list-sessions":
+		return a.listSessions(args, out)
+	case "revoke-sessions":
+		return a.revokeSessions(args, out)
+	case "add-client":
+		return a.addClient(args, out)
+	case "remove-client":
+		return a.removeClient(args, out)
+	case "generate-keys":
+		return a.generateKeys(args, out)
+	case "check-config":
+		return a.checkConfig(args, out)
+	case "config":
+		return a.configCommand(args, out)
+	case "help":
+		fmt.Fprintln(out, "Commands:")
+		for _, command := range commands {
+			fmt.Fprintln(out, "  "+command)
+		}
+		fmt.Fprintln(out, "Add --json for machine-readable output, run without a command to start the server.")
+		fmt.Fprintln(out, "Put --config <file> before the command to read a YAML or TOML configuration file.")
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, run help for the list of commands", name)
+	}
+}
+
+// importUsers imports a file of users. The number of processed rows is written to the checkpoint file after every
+// batch, so that an interrupted import continues where it stopped when run again with --resume
+func (a *Application) importUsers(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	file := flags.String("file", "", "NDJSON or CSV file of users")
+	format := flags.String("format", "", "ndjson or csv, from the file extension when empty")
+	checkpoint := flags.String("checkpoint", "", "file keeping the progress, <file>.checkpoint when empty")
+	errorsFile := flags.String("errors", "", "NDJSON file the row errors are appended to, <file>.errors.ndjson when empty")
+	resume := flags.Bool("resume", false, "continue after the rows recorded in the checkpoint file")
+	batchSize := flags.Int("batch-size", 500, "users created per transaction")
+	flags.StringVar(&a.firebaseScrypt.SaltSeparator, "firebase-salt-separator", a.firebaseScrypt.SaltSeparator, "base64 salt separator of the Firebase project")
+	flags.IntVar(&a.firebaseScrypt.Rounds, "firebase-rounds", a.firebaseScrypt.Rounds, "rounds of the Firebase project")
+	flags.IntVar(&a.firebaseScrypt.MemCost, "firebase-mem-cost", a.firebaseScrypt.MemCost, "memory cost of the Firebase project")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("--file is required")
+	}
+	if *format == "" {
+		*format = userimport.FormatNDJSON
+		if strings.HasSuffix(strings.ToLower(*file), ".csv") {
+			*format = userimport.FormatCSV
+		}
+	}
+	if *checkpoint == "" {
+		*checkpoint = *file + ".checkpoint"
+	}
+	if *errorsFile == "" {
+		*errorsFile = *file + ".errors.ndjson"
+	}
+	firebase, err := a.firebaseScrypt.Parameters()
+	if err != nil {
+		return err
+	}
+
+	skipRows := 0
+	if *resume {
+		content, err := os.ReadFile(*checkpoint)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if len(content) > 0 {
+			if skipRows, err = strconv.Atoi(strings.TrimSpace(string(content))); err != nil {
+				return fmt.Errorf("invalid checkpoint file %s: %w", *checkpoint, err)
+			}
+		}
+	}
+
+	a.ConfigureLogger()
+	a.ConfigurePasswordHashing()
+	a.ConnectToDatabase()
+
+	input, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, importErr := userimport.Import(ctx, input, userimport.Options{
+		Format:    *format,
+		SkipRows:  skipRows,
+		Firebase:  firebase,
+		BatchSize: *batchSize,
+		Checkpoint: func(rows int) error {
+			return os.WriteFile(*checkpoint, []byte(strconv.Itoa(rows)), 0o600)
+		},
+	})
+	if result == nil {
+		return importErr
+	}
+	if err := appendRowErrors(*errorsFile, result.Errors); err != nil {
+		return errors.Join(importErr, err)
+	}
+	summary := *result
+	summary.Errors = nil
+	if err := json.NewEncoder(out).Encode(summary); err != nil {
+		return errors.Join(importErr, err)
+	}
+	return importErr
+}
+
+type migrationOutput struct {
+	Version uint64 `json:"version"`
+	Name    string `json:"name"`
+}
+
+func newMigrationOutput(migrations []database.Migration) []migrationOutput {
+	output := make([]migrationOutput, len(migrations))
+	for i, migration := range migrations {
+		output[i] = migrationOutput{Version: migration.Version, Name: migration.Name}
+	}
+	return output
+}
+
+// migrate applies, reverts or lists the schema migrations. It connects without running the startup migration mode.
+func (a *Application) migrate(args []string, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		return errors.New("usage: migrate up | down [--steps n] | status")
+	}
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "migrations to revert")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if args[0] == "down" && *steps <= 0 {
+		return errors.New("--steps must be positive")
+	}
+
+	a.connectForCommand("")
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp()
+		output := map[string]interface{}{"applied": newMigrationOutput(applied)}
+		if err != nil {
+			output["error"] = err.Error()
+		}
+		if writeErr := writeOutput(out, *asJSON, output, func(w io.Writer) {
+			for _, migration := range applied {
+				fmt.Fprintf(w, "applied %d_%s\n", migration.Version, migration.Name)
+			}
+			if err == nil && len(applied) == 0 {
+				fmt.Fprintln(w, "schema is up to date")
+			}
+		}); writeErr != nil {
+			return writeErr
+		}
+		return err
+	case "down":
+		reverted, err := database.MigrateDown(*steps)
+		output := map[string]interface{}{"reverted": newMigrationOutput(reverted)}
+		if err != nil {
+			output["error"] = err.Error()
+		}
+		if writeErr := writeOutput(out, *asJSON, output, func(w io.Writer) {
+			for _, migration := range reverted {
+				fmt.Fprintf(w, "reverted %d_%s\n", migration.Version, migration.Name)
+			}
+		}); writeErr != nil {
+			return writeErr
+		}
+		return err
+	default:
+		statuses, err := database.GetMigrationStatus()
+		if err != nil {
+			return err
+		}
+		return writeOutput(out, *asJSON, statuses, func(w io.Writer) {
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+			for _, status := range statuses {
+				appliedAt := "pending"
+				if status.AppliedAt != nil {
+					appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+			}
+		})
+	}
+}
+
+func appendRowErrors(path string, rowErrors []userimport.RowError) error {
+	if len(rowErrors) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, rowErr := range rowErrors {
+		if err := encoder.Encode(rowErr); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
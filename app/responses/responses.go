@@ -1,29 +1,49 @@
 package responses
 
 const (
-	InvalidRequest         = "invalid_request"
-	ValidationError        = "validation_error"
-	ServerError            = "server_error"
-	InvalidCredentials     = "invalid_credentials"
-	ClientRequired         = "client_required"
-	InvalidClient          = "invalid_client"
-	NotImplemented         = "not_implemented"
-	Forbidden              = "forbidden"
-	UserExists             = "user_exists"
-	NotFound               = "not_found"
-	ProfileNotFound        = "profile_not_found"
-	SessionNotFound        = "session_not_found"
-	SessionExpired         = "session_expired"
-	TooManyVerifyRequest   = "too_many_verify_request"
-	TooManyResetRequest    = "too_many_reset_request"
-	InvalidCode            = "invalid_reset_code"
-	CodeExpired            = "reset_code_expired"
-	AlreadyVerified        = "already_verified"
-	AlreadyExists          = "already_exists"
-	SocialProviderNotFound = "social_provider_not_found"
-	ResetPasswordCodeSent  = "reset_password_code_sent"
-	TokenExpired           = "token_expired"
-	InvalidToken           = "invalid_token"
-	TokenMalformed         = "token_malformed"
-	NotSupported           = "not_supported"
+	InvalidRequest             = "invalid_request"
+	ValidationError            = "validation_error"
+	ServerError                = "server_error"
+	InvalidCredentials         = "invalid_credentials"
+	ClientRequired             = "client_required"
+	InvalidClient              = "invalid_client"
+	NotImplemented             = "not_implemented"
+	Forbidden                  = "forbidden"
+	UserExists                 = "user_exists"
+	NotFound                   = "not_found"
+	ProfileNotFound            = "profile_not_found"
+	SessionNotFound            = "session_not_found"
+	SessionExpired             = "session_expired"
+	SessionIdleTimeout         = "session_idle_timeout"
+	SessionLifetimeExceeded    = "session_lifetime_exceeded"
+	SessionRevoked             = "session_revoked"
+	TooManyVerifyRequest       = "too_many_verify_request"
+	TooManyResetRequest        = "too_many_reset_request"
+	InvalidCode                = "invalid_reset_code"
+	CodeExpired                = "reset_code_expired"
+	AlreadyVerified            = "already_verified"
+	AlreadyExists              = "already_exists"
+	SocialProviderNotFound     = "social_provider_not_found"
+	ResetPasswordCodeSent      = "reset_password_code_sent"
+	TokenExpired               = "token_expired"
+	InvalidToken               = "invalid_token"
+	TokenMalformed             = "token_malformed"
+	NotSupported               = "not_supported"
+	ExportInProgress           = "export_in_progress"
+	ExportNotReady             = "export_not_ready"
+	TooManyAttempts            = "too_many_attempts"
+	RateLimited                = "rate_limited"
+	PasswordChangeTooSoon      = "password_change_too_soon"
+	EmailChangePending         = "email_change_pending"
+	TooManyEmailChange         = "too_many_email_change_request"
+	InvalidEmailChangeCode     = "invalid_email_change_code"
+	EmailChangeExpired         = "email_change_expired"
+	NotificationMandatory      = "notification_mandatory"
+	DeviceNotFound             = "device_not_found"
+	SessionRequired            = "session_required"
+	SignInBlocked              = "sign_in_blocked"
+	SignInConfirmationRequired = "sign_in_confirmation_required"
+	InvalidSignInCode          = "invalid_sign_in_code"
+	SignInCodeExpired          = "sign_in_code_expired"
+	ReauthenticationRequired   = "reauthentication_required"
 )
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/internal/token"
+	"log/slog"
+)
+
+type AccountServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedAccountServiceServer
+	Logger *slog.Logger
+}
+
+func (s *AccountServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *AccountServer) DeleteAccount(ctx context.Context, in *api.DeleteAccountRequest) (*v1.DefaultResponse, error) {
+	return deleteUser(ctx, s.Logger, in.Password)
+}
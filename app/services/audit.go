@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/dateutil"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+type AuditServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedAuditServiceServer
+	Logger *slog.Logger
+}
+
+func (s *AuditServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *AuditServer) ListAuditEvents(ctx context.Context, in *api.ListAuditEventsRequest) (*api.ListAuditEventsResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	filter := database.AuditEventFilter{
+		Action: in.Action,
+	}
+	if in.UserId != "" {
+		userID, err := uuid.Parse(in.UserId)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		filter.UserID = &userID
+	}
+	if in.From != "" {
+		if filter.From = dateutil.FormatTime(in.From); filter.From == nil {
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+	}
+	if in.To != "" {
+		if filter.To = dateutil.FormatTime(in.To); filter.To == nil {
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+	}
+
+	md := pagination.PageMetadata{
+		PageSize: in.PageSize,
+		Page:     in.Page,
+	}
+	if md.PageSize <= 0 {
+		md.PageSize = pagination.DefaultPageSize
+	}
+	events, count, err := database.GetAuditEvents(filter, md)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to list audit events", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	var eventsResponse []*api.AuditEvent
+	for _, event := range events {
+		eventsResponse = append(eventsResponse, auditEventToResponse(event))
+	}
+
+	md.SetTotalCount(int32(count))
+	md.SetPage(in.Page)
+	return &api.ListAuditEventsResponse{
+		Events: eventsResponse,
+		Meta: &v1.Meta{
+			Page:       md.Page,
+			TotalCount: md.TotalCount,
+			TotalPages: md.TotalPages,
+			PageSize:   md.PageSize,
+			HasNext:    md.HasNext,
+			HasPrev:    md.HasPrev,
+		},
+	}, nil
+}
+
+func (s *AuditServer) VerifyAuditLog(ctx context.Context, _ *api.VerifyAuditLogRequest) (*api.VerifyAuditLogResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	result, err := database.VerifyAuditChain()
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to verify audit chain", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !result.Valid {
+		s.Logger.ErrorContext(ctx, "audit chain is broken", "broken_at", *result.BrokenAt)
+	}
+
+	return &api.VerifyAuditLogResponse{
+		Valid:    result.Valid,
+		Checked:  result.Checked,
+		BrokenAt: result.BrokenAt,
+	}, nil
+}
+
+func auditEventToResponse(event database.AuditEvent) *api.AuditEvent {
+	response := &api.AuditEvent{
+		Id:        event.ID,
+		ClientId:  event.ClientID,
+		Ip:        event.IP,
+		UserAgent: event.UserAgent,
+		Action:    event.Action,
+		Outcome:   event.Outcome,
+		Metadata:  event.GetMetadata(),
+		PrevHash:  event.PrevHash,
+		Hash:      event.Hash,
+		CreatedAt: event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if event.ActorID != nil {
+		response.ActorId = event.ActorID.String()
+	}
+	if event.UserID != nil {
+		response.UserId = event.UserID.String()
+	}
+	return response
+}
@@ -5,24 +5,61 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/talut/dotenv"
 	v1 "github.com/usercoredev/proto/api/v1"
 	"github.com/usercoredev/usercore/app/responses"
 	"github.com/usercoredev/usercore/app/validations"
+	"github.com/usercoredev/usercore/internal/audit"
 	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
 	"github.com/usercoredev/usercore/internal/database"
-	"github.com/usercoredev/usercore/internal/dateutil"
+	"github.com/usercoredev/usercore/internal/lockout"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/password"
+	"github.com/usercoredev/usercore/internal/ratelimit"
+	"github.com/usercoredev/usercore/internal/risk"
 	"github.com/usercoredev/usercore/internal/textutil"
 	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type AuthenticationServer struct {
 	token.AuthorizationRequired
 	v1.UnimplementedAuthenticationServiceServer
+	Logger         *slog.Logger
+	Lockout        *lockout.Guard
+	PasswordPolicy *password.Policy
+	PasswordReset  PasswordResetSettings
+	Mailer         mailer.Sender
+	Notifier       *notify.Notifier
+	// Risk scores sign-ins and refreshes, nil allows them all
+	Risk               *risk.Engine
+	SignInConfirmation SignInConfirmationSettings
+	// OTPLength is the length of the password reset, email verification and sign-in confirmation codes
+	OTPLength int
+	// AccountDeletionGracePeriod is how long a deleted account can be restored by signing in
+	AccountDeletionGracePeriod time.Duration
+}
+
+// defaultOTPLength is used when no OTP length is configured
+const defaultOTPLength = 6
+
+// newOTPCode returns a random code of the length, or of the default length when it is not set
+func newOTPCode(length int) string {
+	if length <= 0 {
+		length = defaultOTPLength
+	}
+	return textutil.RandomString(length)
 }
 
 func (s *AuthenticationServer) IsAuthorizationRequired() bool {
@@ -39,6 +76,12 @@ func (s *AuthenticationServer) SignUp(ctx context.Context, in *v1.SignUpRequest)
 	if validationErr != nil {
 		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
 	}
+	if err := checkPasswordPolicy(ctx, s.PasswordPolicy, s.Logger, signUpRequest.Password, password.Context{
+		Name:  signUpRequest.Name,
+		Email: signUpRequest.Email,
+	}); err != nil {
+		return nil, err
+	}
 
 	user, err := database.GetUserByEmail(signUpRequest.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -55,18 +98,24 @@ func (s *AuthenticationServer) SignUp(ctx context.Context, in *v1.SignUpRequest)
 	}
 	err = newUser.SetPassword(signUpRequest.Password)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, responses.ServerError)
+		return nil, setPasswordError(ctx, s.Logger, err)
 	}
 	newUser.ID = uuid.New()
 
-	if err := database.DB.Model(&database.User{}).Create(&newUser).Error; err != nil {
+	historyDepth := 0
+	if s.PasswordPolicy != nil {
+		historyDepth = s.PasswordPolicy.HistoryDepth
+	}
+	if err = newUser.Create(historyDepth); err != nil {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	audit.SetSubject(ctx, newUser.ID)
 
 	result, err := newUser.CreateSession(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	recordSignInDevice(ctx, s.Logger, s.Notifier, &newUser)
 
 	return &v1.AuthenticationResponse{
 		AccessToken:  result.AccessToken,
@@ -84,19 +133,36 @@ func (s *AuthenticationServer) SignIn(ctx context.Context, in *v1.SignInRequest)
 		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
 	}
 
-	user, err := database.GetUserByEmail(signInRequest.Email)
+	ip := clientip.FromContext(ctx)
+	wait, err := s.Lockout.Check(ctx, signInRequest.Email, ip)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+		s.Logger.ErrorContext(ctx, "failed to check sign-in lockout", "error", err)
+	}
+	if wait > 0 {
+		audit.AddMetadata(ctx, "reason", "locked_out")
+		_ = grpc.SetHeader(ctx, metadata.Pairs(ratelimit.RetryAfterHeader, strconv.Itoa(int(math.Ceil(wait.Seconds())))))
+		return nil, status.Errorf(codes.ResourceExhausted, responses.TooManyAttempts)
 	}
 
-	if !user.ComparePassword(signInRequest.Password) {
-		return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+	user, err := s.authenticate(ctx, signInRequest)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			s.recordSignInFailure(ctx, signInRequest.Email, ip, user)
+		}
+		return nil, err
+	}
+	if err = s.Lockout.Succeed(ctx, signInRequest.Email); err != nil {
+		s.Logger.WarnContext(ctx, "failed to clear sign-in failures", "error", err)
+	}
+	if err = s.checkSignInRisk(ctx, user); err != nil {
+		return nil, err
 	}
 
 	result, err := user.CreateSession(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	recordSignInDevice(ctx, s.Logger, s.Notifier, user)
 
 	return &v1.AuthenticationResponse{
 		AccessToken:  result.AccessToken,
@@ -104,6 +170,72 @@ func (s *AuthenticationServer) SignIn(ctx context.Context, in *v1.SignInRequest)
 	}, nil
 }
 
+// authenticate checks the credentials. The user is returned whenever it exists, even if the password is wrong.
+func (s *AuthenticationServer) authenticate(ctx context.Context, in validations.SignInRequest) (*database.User, error) {
+	user, err := database.GetUserByEmail(in.Email)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+		}
+		return s.restoreDeletedUser(ctx, in)
+	}
+	audit.SetSubject(ctx, user.ID)
+
+	if !user.ComparePassword(in.Password) {
+		return user, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+	}
+	return user, nil
+}
+
+// restoreDeletedUser authenticates a user that deleted the account during the grace period and restores the account
+func (s *AuthenticationServer) restoreDeletedUser(ctx context.Context, in validations.SignInRequest) (*database.User, error) {
+	user, err := database.GetDeletedUserByEmail(in.Email, time.Now().Add(-s.AccountDeletionGracePeriod))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+	}
+	audit.SetSubject(ctx, user.ID)
+
+	if !user.ComparePassword(in.Password) {
+		return user, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+	}
+
+	if err = user.Restore(); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// The email belongs to a new account now
+			return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
+		}
+		s.Logger.ErrorContext(ctx, "failed to restore user", "user_id", user.ID.String(), "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "restored", "true")
+	s.Logger.InfoContext(ctx, "deleted user restored", "user_id", user.ID.String())
+	return user, nil
+}
+
+// recordSignInFailure counts the failed sign-in and records a security event for every lock it causes
+func (s *AuthenticationServer) recordSignInFailure(ctx context.Context, email, ip string, user *database.User) {
+	locks, err := s.Lockout.Fail(ctx, email, ip)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to record sign-in failure", "error", err)
+	}
+	for _, lock := range locks {
+		event := audit.Event{
+			Action:  audit.ActionSignInLockout,
+			Outcome: database.AuditOutcomeSuccess,
+			Metadata: map[string]string{
+				"scope":        lock.Scope,
+				"failures":     strconv.Itoa(lock.Failures),
+				"locked_until": timestamppb.New(lock.Until).AsTime().String(),
+			},
+		}
+		if lock.Scope == lockout.ScopeEmail && user != nil {
+			event.UserID = &user.ID
+		}
+		s.Logger.WarnContext(ctx, "sign-in locked out", "scope", lock.Scope, "failures", lock.Failures)
+		audit.RecordOrLog(ctx, s.Logger, event)
+	}
+}
+
 func (s *AuthenticationServer) RefreshToken(ctx context.Context, in *v1.RefreshTokenRequest) (*v1.AuthenticationResponse, error) {
 	ctxClient := ctx.Value(client.Key).(*client.Item)
 
@@ -123,6 +255,7 @@ func (s *AuthenticationServer) RefreshToken(ctx context.Context, in *v1.RefreshT
 		}
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	audit.SetSubject(ctx, session.UserID)
 
 	if session.ExpiresAt.Before(time.Now()) {
 		return nil, status.Errorf(codes.PermissionDenied, responses.SessionExpired)
@@ -131,8 +264,14 @@ func (s *AuthenticationServer) RefreshToken(ctx context.Context, in *v1.RefreshT
 	if session.ClientID != ctxClient.ID {
 		return nil, status.Errorf(codes.PermissionDenied, responses.InvalidClient)
 	}
+	if err = checkSessionLifetime(ctx, s.Logger, session); err != nil {
+		return nil, err
+	}
+	if err = s.checkRefreshRisk(ctx, session); err != nil {
+		return nil, err
+	}
 
-	response, err := session.RefreshUserToken()
+	response, err := session.RefreshUserToken(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
@@ -144,7 +283,9 @@ func (s *AuthenticationServer) RefreshToken(ctx context.Context, in *v1.RefreshT
 
 }
 
-func (s *AuthenticationServer) ResetPassword(_ context.Context, in *v1.ResetPasswordRequest) (*v1.ResetPasswordResponse, error) {
+// ResetPassword emails a reset code to the user. It responds the same whether the email has an account or not, and
+// whether a code was sent or held back by the reset interval, so that it cannot be used to find out registered emails.
+func (s *AuthenticationServer) ResetPassword(ctx context.Context, in *v1.ResetPasswordRequest) (*v1.ResetPasswordResponse, error) {
 	resetPasswordRequest := validations.ResetPasswordRequest{
 		Email: in.Email,
 	}
@@ -152,66 +293,71 @@ func (s *AuthenticationServer) ResetPassword(_ context.Context, in *v1.ResetPass
 	if validationErr != nil {
 		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
 	}
+	if s.Mailer == nil {
+		s.Logger.WarnContext(ctx, "password reset requested but no mailer is configured")
+		return nil, status.Errorf(codes.Unimplemented, responses.NotImplemented)
+	}
 
-	otpCode := textutil.RandomString(dotenv.GetInt("OTP_LENGTH", 6))
+	otpCode := newOTPCode(s.OTPLength)
 	if otpCode == "" {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
 
-	// TODO: Remove this line
-	fmt.Println(otpCode)
-
+	accepted := &v1.ResetPasswordResponse{Email: resetPasswordRequest.Email}
 	user, err := database.GetUserByEmail(resetPasswordRequest.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, status.Errorf(codes.Aborted, responses.InvalidCredentials)
+			return accepted, nil
 		}
+		s.Logger.ErrorContext(ctx, "failed to load user for password reset", "error", err)
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	audit.SetSubject(ctx, user.ID)
 
-	lastReset, err := user.GetLastPasswordReset()
-	if err != nil {
-		lastReset = &database.PasswordReset{
-			UserID: user.ID,
-		}
+	lastReset, err := database.GetLatestPasswordReset(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Logger.ErrorContext(ctx, "failed to load last password reset", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
-	if !dateutil.CompareTimesByGivenMinute(time.Now(), &lastReset.CreatedAt, 15) {
-		return nil, status.Errorf(codes.ResourceExhausted, responses.TooManyResetRequest)
+	if lastReset != nil && time.Since(lastReset.CreatedAt) < s.PasswordReset.Interval {
+		s.Logger.InfoContext(ctx, "password reset held back by the interval", "user_id", user.ID.String())
+		return accepted, nil
 	}
 
-	// TODO: Implement email sending
-	/*
-		language := os.Getenv("APP_DEFAULT_LANGUAGE")
-		if user.Profile != nil && len(user.Profile.Locale) > 0 {
-			language = utils.GetLanguage(user.Profile.Locale)
+	reset, err := database.CreatePasswordReset(user.ID, otpCode, s.PasswordReset.TTL)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create password reset", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if err = s.Mailer.Send(ctx, s.passwordResetMessage(ctx, user, reset, otpCode)); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to send password reset email", "error", err)
+		// the user could not receive the code, so it must not hold back the next request
+		if err = database.DB.Unscoped().Delete(reset).Error; err != nil {
+			s.Logger.ErrorContext(ctx, "failed to remove unsent password reset", "error", err)
 		}
-		isEmailSent := make(chan bool)
-	*/
-	if user.Email == "" {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	s.Logger.InfoContext(ctx, "password reset sent", "user_id", user.ID.String())
+	return accepted, nil
+}
 
-	return nil, status.Errorf(codes.Unimplemented, responses.NotImplemented)
-	/*
-		go email.Send(isEmailSent, otpCode, user.Email, language, user.Name, notification.PasswordReset)
-
-		if <-isEmailSent {
-			lastReset.ResetToken = otpCode
-
-			if err = database.DB.Model(&database.PasswordReset{}).Create(&lastReset).Error; err != nil {
-				return nil, status.Errorf(codes.Internal, responses.ServerError)
-			}
-
-			return &v1.ResetPasswordResponse{
-				Email: user.Email,
-			}, nil
-		} else {
-			return nil, status.Errorf(codes.Internal, responses.ServerError)
-		}
-	*/
+// passwordResetMessage returns the email with the reset code and, when PASSWORD_RESET_URL is set, a signed link
+func (s *AuthenticationServer) passwordResetMessage(ctx context.Context, user *database.User, reset *database.PasswordReset, code string) mailer.Message {
+	text := fmt.Sprintf("Hello %s,\n\nUse this code to reset your password: %s\n", user.Name, code)
+	if link, err := s.PasswordReset.link(user.ID, reset); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create password reset link", "error", err)
+	} else if link != "" {
+		text += fmt.Sprintf("\nOr open this link to choose a new password:\n%s\n", link)
+	}
+	text += fmt.Sprintf("\nThe code expires in %s. If you did not ask to reset your password, you can ignore this email.\n", s.PasswordReset.TTL)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text:    text,
+	}
 }
 
-func (s *AuthenticationServer) ResetPasswordConfirm(_ context.Context, in *v1.ResetPasswordConfirmRequest) (*v1.DefaultResponse, error) {
+func (s *AuthenticationServer) ResetPasswordConfirm(ctx context.Context, in *v1.ResetPasswordConfirmRequest) (*v1.DefaultResponse, error) {
 	resetPasswordConfirmRequest := validations.ResetPasswordCompleteRequest{
 		Email:    in.Email,
 		Password: in.Password,
@@ -222,64 +368,123 @@ func (s *AuthenticationServer) ResetPasswordConfirm(_ context.Context, in *v1.Re
 		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
 	}
 
-	user, err := database.GetUserByEmail(resetPasswordConfirmRequest.Email)
+	user, resetID, err := s.passwordResetUser(ctx, resetPasswordConfirmRequest)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, status.Errorf(codes.Aborted, responses.InvalidCredentials)
-		}
-		return nil, status.Errorf(codes.Internal, responses.ServerError)
+		return nil, err
 	}
+	audit.SetSubject(ctx, user.ID)
 
-	lastReset, err := user.GetLastPasswordReset()
-	if err != nil {
-		return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
+	if err := checkPasswordPolicy(ctx, s.PasswordPolicy, s.Logger, resetPasswordConfirmRequest.Password, password.Context{
+		Name:  user.Name,
+		Email: user.Email,
+	}); err != nil {
+		return nil, err
 	}
 
-	if dateutil.CompareTimesByGivenDay(time.Now(), &lastReset.CreatedAt, 1) {
-		return nil, status.Errorf(codes.Aborted, responses.CodeExpired)
+	reset, err := s.usablePasswordReset(ctx, user, resetID, resetPasswordConfirmRequest.Token)
+	if err != nil {
+		return nil, err
 	}
 
-	if !user.CheckPasswordResetCode(resetPasswordConfirmRequest.Token) {
-		return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
+	if err := checkPasswordHistory(ctx, s.PasswordPolicy, s.Logger, user, resetPasswordConfirmRequest.Password, false); err != nil {
+		return nil, err
 	}
-
 	if err := user.SetPassword(resetPasswordConfirmRequest.Password); err != nil {
-		return nil, status.Errorf(codes.Internal, responses.ServerError)
+		return nil, setPasswordError(ctx, s.Logger, err)
 	}
-	if err = database.DB.Session(&gorm.Session{FullSaveAssociations: true}).Save(&user).Error; err != nil {
-		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	historyDepth := 0
+	if s.PasswordPolicy != nil {
+		historyDepth = s.PasswordPolicy.HistoryDepth
 	}
-	/*
-
-		language := os.Getenv("APP_DEFAULT_LANGUAGE")
-		if user.Profile != nil && len(user.Profile.Locale) > 0 {
-			language = utils.GetLanguage(user.Profile.Locale)
+	revoked, err := user.CompletePasswordReset(reset, historyDepth)
+	if err != nil {
+		if errors.Is(err, database.ErrPasswordResetUsed) {
+			return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
 		}
-
-		isEmailSent := make(chan bool)
-	*/
-
-	if user.Email == "" {
+		s.Logger.ErrorContext(ctx, "failed to reset password", "error", err)
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	audit.AddMetadata(ctx, "revoked_sessions", strconv.Itoa(len(revoked)))
+	s.Logger.InfoContext(ctx, "password reset completed", "user_id", user.ID.String())
+	s.Notifier.Notify(ctx, user.ID, notify.EventPasswordChanged, notify.Data{IP: clientip.FromContext(ctx)})
 
-	return nil, status.Errorf(codes.Unimplemented, responses.NotImplemented)
-
-	/*
-		go email.Send(isEmailSent, "", user.Email, language, user.Name, notification.PasswordChanged)
-
-		if <-isEmailSent {
+	return &v1.DefaultResponse{
+		Success: true,
+	}, nil
+}
 
-			if err = database.DB.Delete(&lastReset).Error; err != nil {
-				return nil, status.Errorf(codes.Internal, responses.ServerError)
+// passwordResetUser returns the user of a reset link token with its reset, or the user of the email for a reset code
+func (s *AuthenticationServer) passwordResetUser(ctx context.Context, in validations.ResetPasswordCompleteRequest) (*database.User, uint64, error) {
+	if !isSignedToken(in.Token) {
+		if in.Email == "" {
+			return nil, 0, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		user, err := database.GetUserByEmail(in.Email)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, status.Errorf(codes.Aborted, responses.InvalidCredentials)
 			}
+			s.Logger.ErrorContext(ctx, "failed to load user for password reset", "error", err)
+			return nil, 0, status.Errorf(codes.Internal, responses.ServerError)
+		}
+		return user, 0, nil
+	}
 
-			return &v1.DefaultResponse{
-				Success: true,
-			}, nil
-		} else {
-			return nil, status.Errorf(codes.Internal, responses.ServerError)
+	userID, resetID, err := verifySignedLink(in.Token, PasswordResetPurpose)
+	if err != nil {
+		if err.Error() == responses.TokenExpired {
+			return nil, 0, status.Errorf(codes.Aborted, responses.CodeExpired)
+		}
+		return nil, 0, status.Errorf(codes.Aborted, responses.InvalidCode)
+	}
+	user, err := database.GetUserByID(userID, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, status.Errorf(codes.Aborted, responses.InvalidCode)
 		}
-	*/
+		s.Logger.ErrorContext(ctx, "failed to load user for password reset", "error", err)
+		return nil, 0, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if in.Email != "" && !strings.EqualFold(in.Email, user.Email) {
+		return nil, 0, status.Errorf(codes.Aborted, responses.InvalidCode)
+	}
+	return user, resetID, nil
+}
 
+// usablePasswordReset returns the reset of a link, or checks the code against the last reset of the user. Wrong codes
+// use up the attempts of the reset.
+func (s *AuthenticationServer) usablePasswordReset(ctx context.Context, user *database.User, resetID uint64, code string) (*database.PasswordReset, error) {
+	var reset *database.PasswordReset
+	var err error
+	if resetID != 0 {
+		reset, err = database.GetPasswordResetById(resetID, user.ID)
+	} else {
+		reset, err = database.GetLatestPasswordReset(user.ID)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
+		}
+		s.Logger.ErrorContext(ctx, "failed to load password reset", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	now := time.Now()
+	if !reset.IsUsable(now, s.PasswordReset.MaxAttempts) {
+		if reset.UsedAt == nil && !now.Before(reset.ExpiresAt) {
+			return nil, status.Errorf(codes.Aborted, responses.CodeExpired)
+		}
+		return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
+	}
+	if resetID != 0 {
+		return reset, nil
+	}
+	ok, err := reset.CheckToken(code)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to record password reset attempt", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.Aborted, responses.InvalidCode)
+	}
+	return reset, nil
 }
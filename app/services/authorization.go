@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// authorizeAdmin ensures the authenticated user holds the admin role and returns their ID
+func authorizeAdmin(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	if !ok {
+		return uuid.Nil, status.Errorf(codes.Unauthenticated, responses.InvalidToken)
+	}
+	userID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.Unauthenticated, responses.InvalidToken)
+	}
+	isAdmin, err := database.UserHasRole(userID, database.AdminRoleKey)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !isAdmin {
+		return uuid.Nil, status.Errorf(codes.PermissionDenied, responses.Forbidden)
+	}
+	return userID, nil
+}
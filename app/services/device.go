@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/google/uuid"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/app/validations"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"log/slog"
+	"sort"
+	"strconv"
+)
+
+type DeviceServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedDeviceServiceServer
+	Logger *slog.Logger
+}
+
+func (s *DeviceServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *DeviceServer) RegisterDevice(ctx context.Context, in *api.RegisterDeviceRequest) (*v1.Device, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	deviceRequest := validations.StoreDeviceInfoRequest{
+		DeviceID:       in.DeviceId,
+		Name:           in.Name,
+		OS:             in.Os,
+		OSVersion:      in.OsVersion,
+		Browser:        in.Browser,
+		BrowserVersion: in.BrowserVersion,
+		Token:          in.Token,
+	}
+	if err := validations.ValidateStruct(deviceRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	sessionID, ok := token.SessionID(claims)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, responses.SessionRequired)
+	}
+	session, err := database.GetSessionById(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.SessionNotFound)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !session.SessionBelongsToUser(uuid.MustParse(claims.ID)) {
+		return nil, status.Errorf(codes.PermissionDenied, responses.Forbidden)
+	}
+
+	device, err := session.SaveDevice(database.Device{
+		DeviceID:       deviceRequest.DeviceID,
+		Name:           deviceRequest.Name,
+		IP:             clientip.FromContext(ctx),
+		OS:             deviceRequest.OS,
+		OSVersion:      deviceRequest.OSVersion,
+		Browser:        deviceRequest.Browser,
+		BrowserVersion: deviceRequest.BrowserVersion,
+		Token:          deviceRequest.Token,
+	})
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to save device", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "device_id", device.DeviceID)
+	audit.AddMetadata(ctx, "session_id", device.SessionID)
+	return deviceResponse(device), nil
+}
+
+func (s *DeviceServer) ListDevices(ctx context.Context, _ *api.ListDevicesRequest) (*api.ListDevicesResponse, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	userID := uuid.MustParse(claims.ID)
+
+	devices, err := database.GetDevicesByUserId(userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to load devices", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	sessions, err := database.GetSessionsByUserId(userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to load sessions", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	sessionsByID := make(map[string]database.Session, len(sessions))
+	for _, session := range sessions {
+		sessionsByID[strconv.FormatUint(session.ID, 10)] = session
+	}
+	currentSession := ""
+	if id, ok := token.SessionID(claims); ok {
+		currentSession = strconv.FormatUint(id, 10)
+	}
+
+	// the rows of a device are grouped by its device ID, the most recently updated row describes the device
+	sort.SliceStable(devices, func(i, j int) bool {
+		return devices[i].UpdatedAt.After(devices[j].UpdatedAt)
+	})
+	byDeviceID := make(map[string]*api.UserDevice)
+	response := &api.ListDevicesResponse{}
+	for i := range devices {
+		device := &devices[i]
+		session, ok := sessionsByID[device.SessionID]
+		if !ok {
+			continue
+		}
+		deviceID := device.DeviceID
+		if deviceID == "" {
+			deviceID = "session-" + device.SessionID
+		}
+		userDevice, ok := byDeviceID[deviceID]
+		if !ok {
+			userDevice = &api.UserDevice{Device: deviceResponse(device)}
+			byDeviceID[deviceID] = userDevice
+			response.Devices = append(response.Devices, userDevice)
+		}
+		userDevice.Sessions = append(userDevice.Sessions, sessionResponse(&session, device))
+		if device.SessionID == currentSession {
+			userDevice.Current = true
+		}
+	}
+	return response, nil
+}
+
+func (s *DeviceServer) RenameDevice(ctx context.Context, in *api.RenameDeviceRequest) (*v1.Device, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	userID := uuid.MustParse(claims.ID)
+	renameRequest := validations.RenameDeviceRequest{
+		DeviceID: in.DeviceId,
+		Name:     in.Name,
+	}
+	if err := validations.ValidateStruct(renameRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+	audit.AddMetadata(ctx, "device_id", renameRequest.DeviceID)
+
+	if err := database.RenameDevice(userID, renameRequest.DeviceID, renameRequest.Name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.DeviceNotFound)
+		}
+		s.Logger.ErrorContext(ctx, "failed to rename device", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	devices, err := database.GetUserDevice(userID, renameRequest.DeviceID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	return deviceResponse(&devices[len(devices)-1]), nil
+}
+
+func (s *DeviceServer) RevokeDevice(ctx context.Context, in *api.RevokeDeviceRequest) (*v1.DefaultResponse, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	if in.DeviceId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+	audit.AddMetadata(ctx, "device_id", in.DeviceId)
+
+	sessionIDs, err := database.RevokeDevice(uuid.MustParse(claims.ID), in.DeviceId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.DeviceNotFound)
+		}
+		s.Logger.ErrorContext(ctx, "failed to revoke device", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "sessions", strconv.Itoa(len(sessionIDs)))
+	return &v1.DefaultResponse{Success: true}, nil
+}
+
+// deviceResponse converts a device, its ID is the device ID shared by the sessions of the device. The push token is
+// left out: it is only written by the client and would let anyone reading the sessions push to the device.
+func deviceResponse(device *database.Device) *v1.Device {
+	return &v1.Device{
+		Id:             device.DeviceID,
+		UserId:         device.UserID.String(),
+		SessionId:      device.SessionID,
+		Name:           device.Name,
+		Os:             device.OS,
+		OsVersion:      device.OSVersion,
+		Browser:        device.Browser,
+		BrowserVersion: device.BrowserVersion,
+		Ip:             device.IP,
+		CreatedAt:      timestamppb.New(device.CreatedAt).AsTime().String(),
+		UpdatedAt:      timestamppb.New(device.UpdatedAt).AsTime().String(),
+	}
+}
+
+// devicesBySession maps the devices to the IDs of their sessions, for sessionResponse
+func devicesBySession(devices []database.Device) map[string]*database.Device {
+	sessionDevices := make(map[string]*database.Device, len(devices))
+	for i := range devices {
+		sessionDevices[devices[i].SessionID] = &devices[i]
+	}
+	return sessionDevices
+}
+
+// sessionResponse converts a session with its device. For sessions without a registered device the device describes
+// the user agent and the address the session was last seen from.
+func sessionResponse(session *database.Session, device *database.Device) *v1.Session {
+	response := &v1.Session{
+		Id:         session.ID,
+		ClientName: session.ClientName,
+		ClientId:   session.ClientID,
+		ExpiresAt:  timestamppb.New(session.ExpiresAt).AsTime().String(),
+		CreatedAt:  timestamppb.New(session.CreatedAt).AsTime().String(),
+		UpdatedAt:  timestamppb.New(session.UpdatedAt).AsTime().String(),
+	}
+	if device != nil {
+		response.Device = deviceResponse(device)
+		if response.Device.Ip == "" {
+			response.Device.Ip = session.LastSeenIP
+		}
+	} else if session.LastSeenIP != "" || session.UserAgent != "" {
+		response.Device = &v1.Device{
+			UserId:         session.UserID.String(),
+			SessionId:      strconv.FormatUint(session.ID, 10),
+			Name:           session.DeviceType,
+			Os:             session.OS,
+			OsVersion:      session.OSVersion,
+			Browser:        session.Browser,
+			BrowserVersion: session.BrowserVersion,
+			Ip:             session.LastSeenIP,
+			CreatedAt:      timestamppb.New(session.CreatedAt).AsTime().String(),
+			UpdatedAt:      timestamppb.New(session.UpdatedAt).AsTime().String(),
+		}
+	}
+	return response
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/app/validations"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+const (
+	// EmailChangeConfirmPurpose is the audience of the signed tokens in the links sent to the new email
+	EmailChangeConfirmPurpose = "email_change_confirm"
+	// EmailChangeRevertPurpose is the audience of the signed tokens in the links sent to the previous email
+	EmailChangeRevertPurpose = "email_change_revert"
+)
+
+// EmailChangeSettings configures the confirmation of a new email and the revert links sent to the previous one
+type EmailChangeSettings struct {
+	// TTL is how long the new email can be confirmed
+	TTL time.Duration
+	// MaxAttempts is how many wrong codes cancel the change, 0 allows any number
+	MaxAttempts int
+	// Interval is the minimum time between two email change requests of a user
+	Interval time.Duration
+	// RevertTTL is how long the previous email can revert the change
+	RevertTTL time.Duration
+	// ConfirmURL and RevertURL are the pages of the client that confirm and revert a change, the signed token is added
+	// as the token query parameter. The confirmation link is not sent when ConfirmURL is empty
+	ConfirmURL string
+	RevertURL  string
+}
+
+// EmailChangeServer confirms and reverts the email changes requested with UserServer.ChangeEmail. The codes and signed
+// links sent by email identify the change, so it does not require an access token.
+type EmailChangeServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedEmailChangeServiceServer
+	Logger      *slog.Logger
+	EmailChange EmailChangeSettings
+	Notifier    *notify.Notifier
+}
+
+func (s *EmailChangeServer) IsAuthorizationRequired() bool {
+	return false
+}
+
+func (s *EmailChangeServer) ConfirmEmailChange(ctx context.Context, in *api.ConfirmEmailChangeRequest) (*v1.DefaultResponse, error) {
+	confirmRequest := validations.ConfirmEmailChangeRequest{
+		Email: in.Email,
+		Code:  in.Code,
+		Token: in.Token,
+	}
+	if err := validations.ValidateStruct(confirmRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	change, err := s.pendingEmailChange(ctx, confirmRequest)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetSubject(ctx, change.UserID)
+
+	if _, err = change.Confirm(); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, status.Errorf(codes.AlreadyExists, responses.UserExists)
+		case errors.Is(err, database.ErrEmailChangeUsed), errors.Is(err, database.ErrEmailChangeStale):
+			return nil, status.Errorf(codes.Aborted, responses.InvalidEmailChangeCode)
+		}
+		s.Logger.ErrorContext(ctx, "failed to confirm email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if err = invalidateUserCache(change.UserID.String()); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to invalidate user cache", "error", err)
+	}
+	s.Notifier.Notify(ctx, change.UserID, notify.EventEmailChanged, notify.Data{
+		IP:       clientip.FromContext(ctx),
+		NewEmail: change.NewEmail,
+		To:       change.PreviousEmail,
+	})
+
+	return &v1.DefaultResponse{
+		Success: true,
+	}, nil
+}
+
+// pendingEmailChange returns the change of a confirmation link, or checks the code against the last pending change to
+// the email. Wrong codes use up the attempts of the change.
+func (s *EmailChangeServer) pendingEmailChange(ctx context.Context, in validations.ConfirmEmailChangeRequest) (*database.EmailChange, error) {
+	var change *database.EmailChange
+	var err error
+	if in.Token != "" {
+		userID, changeID, linkErr := verifySignedLink(in.Token, EmailChangeConfirmPurpose)
+		if linkErr != nil {
+			if linkErr.Error() == responses.TokenExpired {
+				return nil, status.Errorf(codes.Aborted, responses.EmailChangeExpired)
+			}
+			return nil, status.Errorf(codes.Aborted, responses.InvalidEmailChangeCode)
+		}
+		change, err = database.GetEmailChangeById(changeID, userID)
+	} else {
+		if in.Email == "" || in.Code == "" {
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		change, err = database.GetPendingEmailChangeByEmail(in.Email)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.Aborted, responses.InvalidEmailChangeCode)
+		}
+		s.Logger.ErrorContext(ctx, "failed to load email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	now := time.Now()
+	if !change.IsPending(now, s.EmailChange.MaxAttempts) {
+		if change.ConfirmedAt == nil && change.RevertedAt == nil && !now.Before(change.ExpiresAt) {
+			return nil, status.Errorf(codes.Aborted, responses.EmailChangeExpired)
+		}
+		return nil, status.Errorf(codes.Aborted, responses.InvalidEmailChangeCode)
+	}
+	if in.Token != "" {
+		return change, nil
+	}
+	ok, err := change.CheckCode(in.Code)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to record email change attempt", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !ok {
+		return nil, status.Errorf(codes.Aborted, responses.InvalidEmailChangeCode)
+	}
+	return change, nil
+}
+
+func (s *EmailChangeServer) RevertEmailChange(ctx context.Context, in *api.RevertEmailChangeRequest) (*v1.DefaultResponse, error) {
+	revertRequest := validations.RevertEmailChangeRequest{
+		Token: in.Token,
+	}
+	if err := validations.ValidateStruct(revertRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	userID, changeID, err := verifySignedLink(revertRequest.Token, EmailChangeRevertPurpose)
+	if err != nil {
+		if err.Error() == responses.TokenExpired {
+			return nil, status.Errorf(codes.Aborted, responses.EmailChangeExpired)
+		}
+		return nil, status.Errorf(codes.Aborted, responses.InvalidToken)
+	}
+	audit.SetSubject(ctx, userID)
+	change, err := database.GetEmailChangeById(changeID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.Aborted, responses.InvalidToken)
+		}
+		s.Logger.ErrorContext(ctx, "failed to load email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !time.Now().Before(change.RevertExpiresAt) {
+		return nil, status.Errorf(codes.Aborted, responses.EmailChangeExpired)
+	}
+
+	if _, err = change.Revert(); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, status.Errorf(codes.AlreadyExists, responses.UserExists)
+		case errors.Is(err, database.ErrEmailChangeUsed):
+			return nil, status.Errorf(codes.Aborted, responses.InvalidToken)
+		}
+		s.Logger.ErrorContext(ctx, "failed to revert email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if err = invalidateUserCache(userID.String()); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to invalidate user cache", "error", err)
+	}
+	s.Logger.InfoContext(ctx, "email change reverted", "user_id", userID.String(), "restored", change.ConfirmedAt != nil)
+
+	return &v1.DefaultResponse{
+		Success: true,
+	}, nil
+}
+
+// emailChangeMessages returns the confirmation sent to the new email and the notice with the revert link sent to the
+// previous one
+func emailChangeMessages(settings EmailChangeSettings, user *database.User, change *database.EmailChange, code string) (mailer.Message, mailer.Message, error) {
+	confirmText := fmt.Sprintf("Hello %s,\n\nUse this code to confirm your new email address: %s\n", user.Name, code)
+	confirmLink, err := signedLink(settings.ConfirmURL, EmailChangeConfirmPurpose, user.ID, change.ID, change.ExpiresAt)
+	if err != nil {
+		return mailer.Message{}, mailer.Message{}, err
+	}
+	if confirmLink != "" {
+		confirmText += fmt.Sprintf("\nOr open this link to confirm it:\n%s\n", confirmLink)
+	}
+	confirmText += fmt.Sprintf("\nThe code expires in %s. Your email address does not change until you confirm it.\n", settings.TTL)
+
+	noticeText := fmt.Sprintf("Hello %s,\n\nA change of the email address of your account to %s was requested.\n", user.Name, change.NewEmail)
+	revertLink, err := signedLink(settings.RevertURL, EmailChangeRevertPurpose, user.ID, change.ID, change.RevertExpiresAt)
+	if err != nil {
+		return mailer.Message{}, mailer.Message{}, err
+	}
+	if revertLink != "" {
+		noticeText += fmt.Sprintf("\nIf you did not ask for it, open this link to cancel the change or get your email address back, and sign out all devices:\n%s\n", revertLink)
+	} else {
+		noticeText += "\nIf you did not ask for it, change your password and contact support.\n"
+	}
+
+	confirm := mailer.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email address",
+		Text:    confirmText,
+	}
+	notice := mailer.Message{
+		To:      change.PreviousEmail,
+		Subject: "Your email address is being changed",
+		Text:    noticeText,
+	}
+	return confirm, notice, nil
+}
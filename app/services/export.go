@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/google/uuid"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/textutil"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+type DataExportServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedDataExportServiceServer
+	Logger *slog.Logger
+}
+
+func (s *DataExportServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *DataExportServer) RequestDataExport(ctx context.Context, _ *api.RequestDataExportRequest) (*api.RequestDataExportResponse, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	userID := uuid.MustParse(claims.ID)
+
+	pending, err := database.HasPendingDataExport(userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if pending {
+		return nil, status.Errorf(codes.FailedPrecondition, responses.ExportInProgress)
+	}
+
+	downloadToken, err := textutil.SecureToken(32)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	export, err := database.CreateDataExport(userID, downloadToken)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create data export", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "export_id", strconv.FormatUint(export.ID, 10))
+
+	return &api.RequestDataExportResponse{
+		Export:        dataExportToResponse(export),
+		DownloadToken: downloadToken,
+	}, nil
+}
+
+func (s *DataExportServer) GetDataExport(ctx context.Context, in *api.GetDataExportRequest) (*api.DataExport, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+
+	export, err := database.GetDataExportById(in.Id, uuid.MustParse(claims.ID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.NotFound)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	return dataExportToResponse(export), nil
+}
+
+// DataExportDownloadServer serves finished archives. The download token is the credential, so no access token is
+// required.
+type DataExportDownloadServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedDataExportDownloadServiceServer
+	Logger *slog.Logger
+}
+
+func (s *DataExportDownloadServer) IsAuthorizationRequired() bool {
+	return false
+}
+
+func (s *DataExportDownloadServer) DownloadDataExport(ctx context.Context, in *api.DownloadDataExportRequest) (*httpbody.HttpBody, error) {
+	if in.Token == "" {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	export, err := database.GetDataExportByToken(in.Token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.InvalidToken)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.SetSubject(ctx, export.UserID)
+	audit.AddMetadata(ctx, "export_id", strconv.FormatUint(export.ID, 10))
+
+	switch {
+	case export.IsDownloadable(time.Now()):
+	case export.Status == database.DataExportStatusPending || export.Status == database.DataExportStatusProcessing:
+		return nil, status.Errorf(codes.FailedPrecondition, responses.ExportNotReady)
+	case export.Status == database.DataExportStatusFailed:
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	default:
+		return nil, status.Errorf(codes.NotFound, responses.TokenExpired)
+	}
+
+	return &httpbody.HttpBody{
+		ContentType: "application/json",
+		Data:        export.Archive,
+	}, nil
+}
+
+func dataExportToResponse(export *database.DataExport) *api.DataExport {
+	response := &api.DataExport{
+		Id:        export.ID,
+		Status:    export.Status,
+		CreatedAt: timestamppb.New(export.CreatedAt).AsTime().String(),
+	}
+	if export.ExpiresAt != nil {
+		expiresAt := timestamppb.New(*export.ExpiresAt).AsTime().String()
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/password"
+	"github.com/usercoredev/usercore/internal/token"
+	"github.com/usercoredev/usercore/internal/userimport"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strconv"
+)
+
+// maxImportErrors bounds the row errors returned for one chunk
+const maxImportErrors = 1000
+
+type UserImportServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedUserImportServiceServer
+	Logger *slog.Logger
+	// Firebase is used for firebase_scrypt hashes when the request has no parameters of its own. A request can only
+	// change the other parameters, the hashes reference this signer key.
+	Firebase *password.FirebaseScryptParameters
+}
+
+func (s *UserImportServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *UserImportServer) ImportUsers(ctx context.Context, in *api.ImportUsersRequest) (*api.ImportUsersResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	firebase := s.Firebase
+	if in.Firebase != nil {
+		params, err := firebaseParametersFromRequest(in.Firebase, s.Firebase)
+		if err != nil {
+			s.Logger.WarnContext(ctx, "rejected firebase parameters of user import", "error", err)
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		firebase = params
+	}
+
+	result, err := userimport.Import(ctx, bytes.NewReader(in.Data), userimport.Options{
+		Format:    in.Format,
+		SkipRows:  int(in.SkipRows),
+		Firebase:  firebase,
+		MaxErrors: maxImportErrors,
+	})
+	if result == nil {
+		s.Logger.WarnContext(ctx, "rejected user import", "error", err)
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+	audit.AddMetadata(ctx, "imported", strconv.Itoa(result.Imported))
+	audit.AddMetadata(ctx, "skipped", strconv.Itoa(result.Skipped))
+	audit.AddMetadata(ctx, "failed", strconv.Itoa(result.Failed))
+	response := importResponse(result)
+	if err != nil {
+		// The rows before the failure are imported, the response with the progress is attached to the error so that the
+		// caller can resume after result.Rows
+		s.Logger.WarnContext(ctx, "user import stopped", "rows", result.Rows, "error", err)
+		code, message := codes.InvalidArgument, responses.ValidationError
+		if ctx.Err() != nil {
+			code, message = codes.Canceled, responses.ServerError
+		}
+		st, detailErr := status.New(code, message).WithDetails(response)
+		if detailErr != nil {
+			s.Logger.ErrorContext(ctx, "failed to attach user import progress", "error", detailErr)
+			return nil, status.Errorf(code, message)
+		}
+		return nil, st.Err()
+	}
+	return response, nil
+}
+
+// importResponse returns the counts and row errors of an import
+func importResponse(result *userimport.Result) *api.ImportUsersResponse {
+	response := &api.ImportUsersResponse{
+		Rows:     int32(result.Rows),
+		Imported: int32(result.Imported),
+		Skipped:  int32(result.Skipped),
+		Failed:   int32(result.Failed),
+	}
+	for _, rowErr := range result.Errors {
+		response.Errors = append(response.Errors, &api.ImportRowError{
+			Row:     int32(rowErr.Row),
+			Email:   rowErr.Email,
+			Reason:  rowErr.Reason,
+			Message: rowErr.Message,
+		})
+	}
+	return response
+}
+
+func firebaseParametersFromRequest(in *api.FirebaseScryptParameters, configured *password.FirebaseScryptParameters) (*password.FirebaseScryptParameters, error) {
+	if configured == nil {
+		return nil, password.ErrFirebaseSignerKey
+	}
+	signerKey := configured.SignerKey
+	if in.SignerKey != "" {
+		requested, err := base64.StdEncoding.DecodeString(in.SignerKey)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(requested, signerKey) {
+			return nil, password.ErrFirebaseSignerKey
+		}
+	}
+	saltSeparator, err := base64.StdEncoding.DecodeString(in.SaltSeparator)
+	if err != nil {
+		return nil, err
+	}
+	params := &password.FirebaseScryptParameters{
+		SignerKey:     signerKey,
+		SaltSeparator: saltSeparator,
+		Rounds:        int(in.Rounds),
+		MemCost:       int(in.MemCost),
+	}
+	return params, params.Validate()
+}
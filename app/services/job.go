@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"github.com/usercoredev/usercore/internal/scheduler"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"log/slog"
+)
+
+type JobServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedJobServiceServer
+	Logger    *slog.Logger
+	Scheduler *scheduler.Scheduler
+}
+
+func (s *JobServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *JobServer) ListJobs(ctx context.Context, _ *api.ListJobsRequest) (*api.ListJobsResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	response := &api.ListJobsResponse{}
+	lease, err := database.GetJobLease(scheduler.LeaseName)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Logger.ErrorContext(ctx, "failed to load scheduler lease", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if lease != nil {
+		response.Leader = lease.Holder
+		response.LeaderUntil = timestamppb.New(lease.ExpiresAt).AsTime().String()
+	}
+	if s.Scheduler == nil {
+		return response, nil
+	}
+
+	for _, job := range s.Scheduler.Jobs() {
+		item := &api.Job{
+			Name:     job.Name,
+			Interval: job.Interval.String(),
+		}
+		last, err := database.GetLatestJobRun(job.Name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.Logger.ErrorContext(ctx, "failed to load job run", "job", job.Name, "error", err)
+			return nil, status.Errorf(codes.Internal, responses.ServerError)
+		}
+		if last != nil {
+			item.LastRun = jobRunToResponse(*last)
+			item.NextRunAt = timestamppb.New(last.StartedAt.Add(job.Interval)).AsTime().String()
+		}
+		response.Jobs = append(response.Jobs, item)
+	}
+	return response, nil
+}
+
+func (s *JobServer) ListJobRuns(ctx context.Context, in *api.ListJobRunsRequest) (*api.ListJobRunsResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if in.Job == "" {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	md := pagination.PageMetadata{
+		PageSize: in.PageSize,
+		Page:     in.Page,
+	}
+	if md.PageSize <= 0 {
+		md.PageSize = pagination.DefaultPageSize
+	}
+	runs, count, err := database.GetJobRuns(in.Job, md)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to list job runs", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	var runsResponse []*api.JobRun
+	for _, run := range runs {
+		runsResponse = append(runsResponse, jobRunToResponse(run))
+	}
+
+	md.SetTotalCount(int32(count))
+	md.SetPage(in.Page)
+	return &api.ListJobRunsResponse{
+		Runs: runsResponse,
+		Meta: &v1.Meta{
+			Page:       md.Page,
+			TotalCount: md.TotalCount,
+			TotalPages: md.TotalPages,
+			PageSize:   md.PageSize,
+			HasNext:    md.HasNext,
+			HasPrev:    md.HasPrev,
+		},
+	}, nil
+}
+
+func jobRunToResponse(run database.JobRun) *api.JobRun {
+	response := &api.JobRun{
+		Id:         run.ID,
+		Job:        run.Job,
+		Holder:     run.Holder,
+		StartedAt:  timestamppb.New(run.StartedAt).AsTime().String(),
+		DurationMs: run.Duration,
+		Outcome:    run.Outcome,
+		Result:     run.Result,
+		Error:      run.Error,
+	}
+	if run.FinishedAt != nil {
+		response.FinishedAt = timestamppb.New(*run.FinishedAt).AsTime().String()
+	}
+	return response
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/token"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signedLink adds a token signed for the purpose to the base URL as the token query parameter. The token identifies
+// the user and the record it was sent for, like a password reset. It returns an empty link when no base URL is set.
+func signedLink(baseURL, purpose string, userID uuid.UUID, id uint64, expiresAt time.Time) (string, error) {
+	if baseURL == "" {
+		return "", nil
+	}
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	signed, err := token.CreateSignedToken(purpose, userID.String(), strconv.FormatUint(id, 10), expiresAt)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", signed)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// verifySignedLink returns the user and the record of a token created by signedLink for the purpose
+func verifySignedLink(signed, purpose string) (uuid.UUID, uint64, error) {
+	claims, err := token.VerifySignedToken(signed, purpose)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	id, err := strconv.ParseUint(claims.ID, 10, 64)
+	if err != nil {
+		return uuid.Nil, 0, err
+	}
+	return userID, id, nil
+}
+
+// isSignedToken tells the JWTs of links from the alphanumeric codes
+func isSignedToken(value string) bool {
+	return strings.Count(value, ".") == 2
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/google/uuid"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+type NotificationServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedNotificationServiceServer
+	Logger *slog.Logger
+}
+
+func (s *NotificationServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *NotificationServer) GetNotificationPreferences(ctx context.Context, _ *api.GetNotificationPreferencesRequest) (*api.NotificationPreferences, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	return s.preferences(ctx, uuid.MustParse(claims.ID))
+}
+
+func (s *NotificationServer) UpdateNotificationPreferences(ctx context.Context, in *api.UpdateNotificationPreferencesRequest) (*api.NotificationPreferences, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	userID := uuid.MustParse(claims.ID)
+
+	var preferences []database.NotificationPreference
+	for _, preference := range in.Preferences {
+		event, ok := notify.LookupEvent(preference.Event)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		if event.Mandatory {
+			if !preference.Email || !preference.Push {
+				return nil, status.Errorf(codes.InvalidArgument, responses.NotificationMandatory)
+			}
+			continue
+		}
+		preferences = append(preferences, database.NotificationPreference{
+			Event: event.Name,
+			Email: preference.Email,
+			Push:  preference.Push,
+		})
+	}
+	if err := database.SaveNotificationPreferences(userID, preferences); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to save notification preferences", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	return s.preferences(ctx, userID)
+}
+
+// preferences returns the channels of every event for the user, with the defaults for the events the user did not
+// change
+func (s *NotificationServer) preferences(ctx context.Context, userID uuid.UUID) (*api.NotificationPreferences, error) {
+	stored, err := database.GetNotificationPreferences(userID)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to load notification preferences", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	byEvent := map[string]database.NotificationPreference{}
+	for _, preference := range stored {
+		byEvent[preference.Event] = preference
+	}
+
+	response := &api.NotificationPreferences{}
+	for _, event := range notify.Events {
+		preference := &api.NotificationPreference{Event: event.Name, Email: true, Push: true, Mandatory: event.Mandatory}
+		if saved, ok := byEvent[event.Name]; ok && !event.Mandatory {
+			preference.Email = saved.Email
+			preference.Push = saved.Push
+		}
+		response.Preferences = append(response.Preferences, preference)
+	}
+	return response, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/password"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// errorDomain identifies usercore as the source of the reasons in error details
+const errorDomain = "usercore"
+
+// checkPasswordPolicy returns an InvalidArgument error listing the broken rules. The breach corpus failing to answer
+// does not block the user
+func checkPasswordPolicy(ctx context.Context, policy *password.Policy, log *slog.Logger, newPassword string, passwordCtx password.Context) error {
+	violations := policy.Check(newPassword, passwordCtx)
+	breached, err := policy.CheckBreached(ctx, newPassword)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to screen password against breach data", "error", err)
+	}
+	if breached != nil {
+		violations = append(violations, *breached)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violationsError(ctx, log, codes.InvalidArgument, responses.ValidationError, violations)
+}
+
+// checkPasswordHistory rejects the current and the last passwords of the user, and with enforceMinAge a change before
+// the minimum age of the current password
+func checkPasswordHistory(ctx context.Context, policy *password.Policy, log *slog.Logger, user *database.User, newPassword string, enforceMinAge bool) error {
+	if policy == nil {
+		return nil
+	}
+	if enforceMinAge {
+		if violation := policy.CheckMinAge(user.PasswordChangedAt, time.Now()); violation != nil {
+			return violationsError(ctx, log, codes.FailedPrecondition, responses.PasswordChangeTooSoon, []password.Violation{*violation})
+		}
+	}
+	reused, err := user.IsPasswordReused(newPassword, policy.HistoryDepth)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to load password history", "error", err)
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if reused {
+		return violationsError(ctx, log, codes.InvalidArgument, responses.ValidationError, []password.Violation{{
+			Reason: password.ReasonReused,
+			Params: map[string]string{"history_depth": strconv.Itoa(policy.HistoryDepth)},
+		}})
+	}
+	return nil
+}
+
+// savePassword stores the new password of the user with its history
+func savePassword(policy *password.Policy, user *database.User) error {
+	depth := 0
+	if policy != nil {
+		depth = policy.HistoryDepth
+	}
+	return user.SavePassword(depth)
+}
+
+// violationsError returns an error carrying one ErrorInfo detail per violation, so that clients can tell the user what
+// to change
+func violationsError(ctx context.Context, log *slog.Logger, code codes.Code, message string, violations []password.Violation) error {
+	st := status.New(code, message)
+	for _, violation := range violations {
+		withDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   violation.Reason,
+			Domain:   errorDomain,
+			Metadata: violation.Params,
+		})
+		if err != nil {
+			log.ErrorContext(ctx, "failed to attach password policy details", "error", err)
+			break
+		}
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// setPasswordError maps a failure to hash a new password to a response, bcrypt cannot hash more than 72 bytes
+func setPasswordError(ctx context.Context, log *slog.Logger, err error) error {
+	if errors.Is(err, password.ErrPasswordTooLong) {
+		st, detailErr := status.New(codes.InvalidArgument, responses.ValidationError).WithDetails(&errdetails.ErrorInfo{
+			Reason: password.ReasonTooLong,
+			Domain: errorDomain,
+		})
+		if detailErr != nil {
+			return status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		return st.Err()
+	}
+	log.ErrorContext(ctx, "failed to hash password", "error", err)
+	return status.Errorf(codes.Internal, responses.ServerError)
+}
+
+// PasswordResetPurpose is the audience of the signed tokens in password reset links
+const PasswordResetPurpose = "password_reset"
+
+// PasswordResetSettings configures the codes and links sent to reset a password
+type PasswordResetSettings struct {
+	// TTL is how long a code or link can be used
+	TTL time.Duration
+	// MaxAttempts is how many wrong codes invalidate a reset, 0 allows any number
+	MaxAttempts int
+	// Interval is the minimum time between two reset requests of a user
+	Interval time.Duration
+	// URL is the page of the client where the user chooses the new password, the signed token is added as the token
+	// query parameter. Links are not sent when it is empty
+	URL string
+}
+
+// link returns the reset link of the user, empty when no URL is configured
+func (p PasswordResetSettings) link(userID uuid.UUID, reset *database.PasswordReset) (string, error) {
+	return signedLink(p.URL, PasswordResetPurpose, userID, reset.ID, reset.ExpiresAt)
+}
@@ -3,11 +3,13 @@ package services
 import (
 	v1 "github.com/usercoredev/proto/api/v1"
 	"github.com/usercoredev/usercore/internal/token"
+	"log/slog"
 )
 
 type RoleServer struct {
 	token.AuthorizationRequired
 	v1.UnimplementedRoleServiceServer
+	Logger *slog.Logger
 }
 
 func (s *RoleServer) IsAuthorizationRequired() bool {
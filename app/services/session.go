@@ -3,20 +3,29 @@ package services
 import (
 	"context"
 	"errors"
+	"github.com/cristalhq/jwt/v4"
 	"github.com/google/uuid"
 	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
 	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/client"
 	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
 	token2 "github.com/usercoredev/usercore/internal/token"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
+	"time"
 )
 
 type SessionServer struct {
 	token2.AuthorizationRequired
 	v1.UnimplementedSessionServiceServer
+	Logger *slog.Logger
 }
 
 func (s *SessionServer) IsAuthorizationRequired() bool {
@@ -24,7 +33,7 @@ func (s *SessionServer) IsAuthorizationRequired() bool {
 }
 
 func (s *SessionServer) GetSessions(ctx context.Context, _ *v1.GetSessionsRequest) (*v1.GetSessionsResponse, error) {
-	claims := ctx.Value(token2.Claims).(*token2.Token)
+	claims := ctx.Value(token2.Claims).(jwt.RegisteredClaims)
 
 	userSessions, err := database.GetSessionsByUserId(uuid.MustParse(claims.ID))
 	if err != nil {
@@ -34,23 +43,23 @@ func (s *SessionServer) GetSessions(ctx context.Context, _ *v1.GetSessionsReques
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
 
+	devices, err := database.GetDevicesByUserId(uuid.MustParse(claims.ID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	sessionDevices := devicesBySession(devices)
+
 	var sessions []*v1.Session
-	for _, session := range userSessions {
-		sessions = append(sessions, &v1.Session{
-			Id:         session.ID,
-			ClientName: session.ClientName,
-			ClientId:   session.ClientID,
-			ExpiresAt:  timestamppb.New(session.ExpiresAt).AsTime().String(),
-			CreatedAt:  timestamppb.New(session.CreatedAt).AsTime().String(),
-			UpdatedAt:  timestamppb.New(session.UpdatedAt).AsTime().String(),
-		})
+	for i := range userSessions {
+		session := &userSessions[i]
+		sessions = append(sessions, sessionResponse(session, sessionDevices[strconv.FormatUint(session.ID, 10)]))
 	}
 
 	return &v1.GetSessionsResponse{Sessions: sessions}, nil
 }
 
 func (s *SessionServer) DeleteSession(ctx context.Context, in *v1.DeleteSessionRequest) (*v1.DefaultResponse, error) {
-	claims := ctx.Value(token2.Claims).(*token2.Token)
+	claims := ctx.Value(token2.Claims).(jwt.RegisteredClaims)
 
 	session, err := database.GetSessionById(in.Id)
 	if err != nil {
@@ -60,7 +69,8 @@ func (s *SessionServer) DeleteSession(ctx context.Context, in *v1.DeleteSessionR
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
 	if session.SessionBelongsToUser(uuid.MustParse(claims.ID)) {
-		if err = database.DB.Delete(&session).Error; err != nil {
+		audit.AddMetadata(ctx, "session_id", strconv.FormatUint(session.ID, 10))
+		if err = session.Delete(); err != nil {
 			return nil, status.Errorf(codes.Internal, responses.ServerError)
 		}
 
@@ -70,7 +80,7 @@ func (s *SessionServer) DeleteSession(ctx context.Context, in *v1.DeleteSessionR
 }
 
 func (s *SessionServer) SignOut(ctx context.Context, in *v1.SignOutRequest) (*v1.DefaultResponse, error) {
-	claims := ctx.Value(token2.Claims).(*token2.Token)
+	claims := ctx.Value(token2.Claims).(jwt.RegisteredClaims)
 
 	session, err := database.GetSessionByRefreshToken(in.RefreshToken)
 	if err != nil {
@@ -81,7 +91,8 @@ func (s *SessionServer) SignOut(ctx context.Context, in *v1.SignOutRequest) (*v1
 	}
 
 	if session.SessionBelongsToUser(uuid.MustParse(claims.ID)) {
-		if err = database.DB.Delete(&session).Error; err != nil {
+		audit.AddMetadata(ctx, "session_id", strconv.FormatUint(session.ID, 10))
+		if err = session.Delete(); err != nil {
 			return nil, status.Errorf(codes.Internal, responses.ServerError)
 		}
 
@@ -90,3 +101,101 @@ func (s *SessionServer) SignOut(ctx context.Context, in *v1.SignOutRequest) (*v1
 
 	return nil, status.Errorf(codes.PermissionDenied, responses.Forbidden)
 }
+
+// checkSessionLifetime ends a session that was idle for too long or outlived its maximum lifetime under the session
+// policy of the calling client, and tells the client why
+func checkSessionLifetime(ctx context.Context, logger *slog.Logger, session *database.Session) error {
+	return endExpiredSession(ctx, logger, session, time.Now())
+}
+
+func endExpiredSession(ctx context.Context, logger *slog.Logger, session *database.Session, now time.Time) error {
+	var policy *sessionpolicy.Policy
+	if sessionClient, ok := ctx.Value(client.Key).(*client.Item); ok && sessionClient != nil {
+		policy = sessionClient.Sessions
+	}
+	reason := session.Expired(sessionpolicy.Resolve(policy), now)
+	if reason == "" {
+		return nil
+	}
+	audit.AddMetadata(ctx, "reason", reason)
+	if err := session.Delete(); err != nil {
+		logger.ErrorContext(ctx, "failed to end expired session", "error", err)
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if reason == sessionpolicy.ReasonIdleTimeout {
+		return status.Errorf(codes.PermissionDenied, responses.SessionIdleTimeout)
+	}
+	return status.Errorf(codes.PermissionDenied, responses.SessionLifetimeExceeded)
+}
+
+// EndExpiredSession is the session policy check of the auth interceptor: it ends the session of an access token that
+// was idle for too long or outlived its maximum lifetime, like a refresh does
+func EndExpiredSession(ctx context.Context, logger *slog.Logger, sessionID uint64, state token2.Session, now time.Time) error {
+	session := &database.Session{LastSeenAt: &state.LastSeenAt}
+	session.ID = sessionID
+	session.CreatedAt = state.CreatedAt
+	return endExpiredSession(ctx, logger, session, now)
+}
+
+// revokeSessions signs the user out of every session except exceptID, 0 revokes them all
+func revokeSessions(ctx context.Context, logger *slog.Logger, userID uuid.UUID, exceptID uint64) ([]database.Session, error) {
+	sessions, err := database.RevokeSessions(userID, exceptID)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to revoke sessions", "user_id", userID.String(), "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "revoked_sessions", strconv.Itoa(len(sessions)))
+	return sessions, nil
+}
+
+// SessionRevocationServer signs users out of many sessions at once
+type SessionRevocationServer struct {
+	token2.AuthorizationRequired
+	api.UnimplementedSessionRevocationServiceServer
+	Logger   *slog.Logger
+	Notifier *notify.Notifier
+}
+
+func (s *SessionRevocationServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *SessionRevocationServer) RevokeOtherSessions(ctx context.Context, _ *api.RevokeOtherSessionsRequest) (*api.RevokeSessionsResponse, error) {
+	claims := ctx.Value(token2.Claims).(jwt.RegisteredClaims)
+	sessionID, ok := token2.SessionID(claims)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, responses.SessionRequired)
+	}
+
+	sessions, err := revokeSessions(ctx, s.Logger, uuid.MustParse(claims.ID), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.RevokeSessionsResponse{Revoked: uint32(len(sessions))}, nil
+}
+
+func (s *SessionRevocationServer) RevokeUserSessions(ctx context.Context, in *api.RevokeUserSessionsRequest) (*api.RevokeSessionsResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(in.UserId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+	if _, err = database.GetUserByID(userID, false); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.NotFound)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.SetSubject(ctx, userID)
+
+	sessions, err := revokeSessions(ctx, s.Logger, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) > 0 {
+		s.Notifier.Notify(ctx, userID, notify.EventSessionRevokedByAdmin, notify.RevokedSessions(sessions))
+	}
+	return &api.RevokeSessionsResponse{Revoked: uint32(len(sessions))}, nil
+}
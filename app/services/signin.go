@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/app/validations"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/risk"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignInConfirmPurpose is the audience of the signed tokens in the links that confirm a held back sign-in
+const SignInConfirmPurpose = "sign_in_confirm"
+
+// SignInConfirmationSettings configures the email confirmation of the sign-ins the risk engine holds back
+type SignInConfirmationSettings struct {
+	// TTL is how long a sign-in can be confirmed
+	TTL time.Duration
+	// MaxAttempts is how many wrong codes cancel the confirmation, 0 allows any number
+	MaxAttempts int
+	// URL is the page of the client that confirms a sign-in, the signed token is added as the token query parameter.
+	// No link is sent when it is empty
+	URL string
+}
+
+// link returns the confirmation link of the challenge, or an empty link when no URL is set
+func (s SignInConfirmationSettings) link(challenge *database.SignInChallenge) (string, error) {
+	return signedLink(s.URL, SignInConfirmPurpose, challenge.UserID, challenge.ID, challenge.ExpiresAt)
+}
+
+// signInFingerprint identifies the client and the user agent of a sign-in
+func signInFingerprint(ctx context.Context) string {
+	clientID := ""
+	if sessionClient, ok := ctx.Value(client.Key).(*client.Item); ok && sessionClient != nil {
+		clientID = sessionClient.ID
+	}
+	fingerprint := sha256.Sum256([]byte(clientID + "\n" + clientip.UserAgent(ctx)))
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// recordSignInDevice remembers the client and user agent of a sign-in, and notifies the user when the user signed in
+// from them for the first time
+func recordSignInDevice(ctx context.Context, logger *slog.Logger, notifier *notify.Notifier, user *database.User) {
+	clientName := ""
+	if sessionClient, ok := ctx.Value(client.Key).(*client.Item); ok && sessionClient != nil {
+		clientName = sessionClient.Name
+	}
+	newDevice, err := database.RecordSignInFingerprint(user.ID, signInFingerprint(ctx), time.Now())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to record sign-in device", "error", err)
+		return
+	}
+	if newDevice {
+		device := clientName
+		if userAgent := clientip.UserAgent(ctx); userAgent != "" {
+			device = fmt.Sprintf("%s (%s)", clientName, userAgent)
+		}
+		notifier.Notify(ctx, user.ID, notify.EventNewDeviceSignIn, notify.Data{
+			IP:     clientip.FromContext(ctx),
+			Device: device,
+		})
+	}
+}
+
+// evaluateRisk runs the risk engine for a sign-in or a refresh. The engine failing does not block the user.
+func evaluateRisk(ctx context.Context, engine *risk.Engine, logger *slog.Logger, attempt risk.Attempt) *risk.Decision {
+	attempt.Client, _ = ctx.Value(client.Key).(*client.Item)
+	attempt.IP = clientip.FromContext(ctx)
+	attempt.Fingerprint = signInFingerprint(ctx)
+	attempt.Time = time.Now()
+	decision, err := engine.Evaluate(ctx, attempt)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to evaluate sign-in risk", "error", err)
+		return &risk.Decision{Action: risk.ActionAllow}
+	}
+	if decision.Action != risk.ActionAllow || decision.Score > 0 {
+		audit.AddMetadata(ctx, "risk_score", strconv.Itoa(decision.Score))
+		audit.AddMetadata(ctx, "risk_action", decision.Action)
+	}
+	return decision
+}
+
+// checkSignInRisk lets the sign-in through, blocks it, or holds it back until the user confirms it by email. Usercore
+// has no second factor yet, so sign-ins that require MFA are confirmed by email too.
+func (s *AuthenticationServer) checkSignInRisk(ctx context.Context, user *database.User) error {
+	decision := evaluateRisk(ctx, s.Risk, s.Logger, risk.Attempt{Kind: risk.KindSignIn, UserID: user.ID})
+	switch decision.Action {
+	case risk.ActionBlock:
+		return status.Errorf(codes.PermissionDenied, responses.SignInBlocked)
+	case risk.ActionRequireMFA, risk.ActionRequireEmailConfirmation:
+		return s.challengeSignIn(ctx, user, decision)
+	}
+	return nil
+}
+
+// challengeSignIn sends a confirmation code to the user and tells the client the sign-in must be confirmed. Without a
+// mailer the sign-in cannot be confirmed, so it is blocked.
+func (s *AuthenticationServer) challengeSignIn(ctx context.Context, user *database.User, decision *risk.Decision) error {
+	if s.Mailer == nil {
+		s.Logger.WarnContext(ctx, "sign-in requires an email confirmation but no mailer is configured")
+		return status.Errorf(codes.PermissionDenied, responses.SignInBlocked)
+	}
+	code := newOTPCode(s.OTPLength)
+	if code == "" {
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	clientID := ""
+	if sessionClient, ok := ctx.Value(client.Key).(*client.Item); ok && sessionClient != nil {
+		clientID = sessionClient.ID
+	}
+	challenge, err := database.CreateSignInChallenge(user.ID, clientID, decision.ID, code, s.SignInConfirmation.TTL)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create sign-in challenge", "error", err)
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if err = s.Mailer.Send(ctx, s.signInConfirmationMessage(ctx, user, challenge, code)); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to send sign-in confirmation email", "error", err)
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	return status.Errorf(codes.FailedPrecondition, responses.SignInConfirmationRequired)
+}
+
+// signInConfirmationMessage returns the email with the confirmation code and, when SIGN_IN_CONFIRMATION_URL is set, a
+// signed link
+func (s *AuthenticationServer) signInConfirmationMessage(ctx context.Context, user *database.User, challenge *database.SignInChallenge, code string) mailer.Message {
+	text := fmt.Sprintf("Hello %s,\n\nWe noticed a sign-in to your account from a new location or device", user.Name)
+	if ip := clientip.FromContext(ctx); ip != "" {
+		text += fmt.Sprintf(" (%s)", ip)
+	}
+	text += fmt.Sprintf(".\nUse this code to confirm it was you: %s\n", code)
+	if link, err := s.SignInConfirmation.link(challenge); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create sign-in confirmation link", "error", err)
+	} else if link != "" {
+		text += fmt.Sprintf("\nOr open this link to confirm it:\n%s\n", link)
+	}
+	text += fmt.Sprintf("\nThe code expires in %s. If it was not you, change your password.\n", s.SignInConfirmation.TTL)
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your sign-in",
+		Text:    text,
+	}
+}
+
+// checkRefreshRisk ends a session whose refresh the risk engine does not allow, its user has to sign in again
+func (s *AuthenticationServer) checkRefreshRisk(ctx context.Context, session *database.Session) error {
+	decision := evaluateRisk(ctx, s.Risk, s.Logger, risk.Attempt{Kind: risk.KindRefresh, UserID: session.UserID, SessionID: session.ID})
+	if decision.Action == risk.ActionAllow {
+		return nil
+	}
+	if err := session.Delete(); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to end risky session", "error", err)
+		return status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if decision.Action == risk.ActionBlock {
+		return status.Errorf(codes.PermissionDenied, responses.SignInBlocked)
+	}
+	return status.Errorf(codes.Unauthenticated, responses.ReauthenticationRequired)
+}
+
+// SignInConfirmationServer completes the sign-ins held back by the risk engine. The code or the signed link sent by
+// email identify the sign-in, so it does not require an access token.
+type SignInConfirmationServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedSignInConfirmationServiceServer
+	Logger             *slog.Logger
+	SignInConfirmation SignInConfirmationSettings
+	Notifier           *notify.Notifier
+}
+
+func (s *SignInConfirmationServer) IsAuthorizationRequired() bool {
+	return false
+}
+
+func (s *SignInConfirmationServer) ConfirmSignIn(ctx context.Context, in *api.ConfirmSignInRequest) (*v1.AuthenticationResponse, error) {
+	confirmRequest := validations.ConfirmSignInRequest{
+		Email: in.Email,
+		Code:  in.Code,
+		Token: in.Token,
+	}
+	if err := validations.ValidateStruct(confirmRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	user, challenge, err := s.pendingChallenge(ctx, confirmRequest)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetSubject(ctx, user.ID)
+	if err = challenge.Complete(); err != nil {
+		if errors.Is(err, database.ErrSignInChallengeUsed) {
+			return nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+		}
+		s.Logger.ErrorContext(ctx, "failed to complete sign-in challenge", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	result, err := user.CreateSession(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	recordSignInDevice(ctx, s.Logger, s.Notifier, user)
+
+	return &v1.AuthenticationResponse{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}, nil
+}
+
+// pendingChallenge returns the challenge of a confirmation link, or checks the code against the last challenge of the
+// user for the calling client. Wrong codes use up the attempts of the challenge.
+func (s *SignInConfirmationServer) pendingChallenge(ctx context.Context, in validations.ConfirmSignInRequest) (*database.User, *database.SignInChallenge, error) {
+	sessionClient := ctx.Value(client.Key).(*client.Item)
+	var user *database.User
+	var challenge *database.SignInChallenge
+	var err error
+	if in.Token != "" {
+		userID, challengeID, linkErr := verifySignedLink(in.Token, SignInConfirmPurpose)
+		if linkErr != nil {
+			if linkErr.Error() == responses.TokenExpired {
+				return nil, nil, status.Errorf(codes.Aborted, responses.SignInCodeExpired)
+			}
+			return nil, nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+		}
+		if user, err = database.GetUserByID(userID, false); err == nil {
+			challenge, err = database.GetSignInChallengeById(challengeID, userID)
+		}
+	} else {
+		if in.Email == "" || in.Code == "" {
+			return nil, nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+		}
+		if user, err = database.GetUserByEmail(in.Email); err == nil {
+			challenge, err = database.GetLatestSignInChallenge(user.ID, sessionClient.ID)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+		}
+		s.Logger.ErrorContext(ctx, "failed to load sign-in challenge", "error", err)
+		return nil, nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if in.Email != "" && !strings.EqualFold(in.Email, user.Email) {
+		return nil, nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+	}
+	if challenge.ClientID != sessionClient.ID {
+		return nil, nil, status.Errorf(codes.PermissionDenied, responses.InvalidClient)
+	}
+
+	now := time.Now()
+	if !challenge.IsUsable(now, s.SignInConfirmation.MaxAttempts) {
+		if challenge.UsedAt == nil && !now.Before(challenge.ExpiresAt) {
+			return nil, nil, status.Errorf(codes.Aborted, responses.SignInCodeExpired)
+		}
+		return nil, nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+	}
+	if in.Token != "" {
+		return user, challenge, nil
+	}
+	ok, err := challenge.CheckCode(in.Code)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to record sign-in confirmation attempt", "error", err)
+		return nil, nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if !ok {
+		return nil, nil, status.Errorf(codes.Aborted, responses.InvalidSignInCode)
+	}
+	return user, challenge, nil
+}
@@ -7,26 +7,38 @@ import (
 	"github.com/cristalhq/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
-	"github.com/talut/dotenv"
 	v1 "github.com/usercoredev/proto/api/v1"
 	"github.com/usercoredev/usercore/app/responses"
 	"github.com/usercoredev/usercore/app/validations"
+	"github.com/usercoredev/usercore/internal/audit"
 	"github.com/usercoredev/usercore/internal/cache"
+	"github.com/usercoredev/usercore/internal/clientip"
 	"github.com/usercoredev/usercore/internal/database"
 	"github.com/usercoredev/usercore/internal/dateutil"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
 	"github.com/usercoredev/usercore/internal/pagination"
-	"github.com/usercoredev/usercore/internal/textutil"
+	"github.com/usercoredev/usercore/internal/password"
 	"github.com/usercoredev/usercore/internal/token"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
 	"time"
 )
 
 type UserServer struct {
 	token.AuthorizationRequired
 	v1.UnimplementedUserServiceServer
+	Logger         *slog.Logger
+	PasswordPolicy *password.Policy
+	EmailChange    EmailChangeSettings
+	Mailer         mailer.Sender
+	Notifier       *notify.Notifier
+	// OTPLength is the length of the email verification and email change codes
+	OTPLength int
 }
 
 func userCacheKey(id string) string {
@@ -57,15 +69,18 @@ func (s *UserServer) VerifyToken(ctx context.Context, in *v1.VerifyTokenRequest)
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
 
-	if !session.IsActive() {
+	if !session.SessionBelongsToUser(uuid.MustParse(claims.ID)) {
 		return nil, status.Errorf(codes.PermissionDenied, responses.InvalidToken)
 	}
 
-	if !session.SessionBelongsToUser(uuid.MustParse(claims.ID)) {
-		return nil, status.Errorf(codes.PermissionDenied, responses.InvalidToken)
+	if !session.IsActive() {
+		return nil, status.Errorf(codes.PermissionDenied, responses.SessionExpired)
+	}
+	if err = checkSessionLifetime(ctx, s.Logger, session); err != nil {
+		return nil, err
 	}
 
-	response, err := session.RefreshUserToken()
+	response, err := session.RefreshUserToken(ctx)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
@@ -153,7 +168,7 @@ func profileToGetUserProfileResponse(profile *database.Profile) *v1.GetUserProfi
 	var birthdate string
 
 	if profile.Birthdate != nil {
-		birthdate = profile.Birthdate.Format(dotenv.GetString("BIRTHDATE_LAYOUT", "2006-01-02"))
+		birthdate = dateutil.DateString(*profile.Birthdate)
 	}
 
 	return &v1.GetUserProfileResponse{
@@ -241,11 +256,61 @@ func (s *UserServer) UpdateUser(ctx context.Context, in *v1.UpdateUserRequest) (
 	}, nil
 }
 
-// DeleteUser TODO: Implement this function
-func (s *UserServer) DeleteUser(ctx context.Context, _ *v1.DeleteUserRequest) (*v1.DefaultResponse, error) {
+// DeleteUser is not implemented: DeleteUserRequest has no field for the password that re-authenticates the user, and
+// headers are logged without redaction. Clients use AccountService.DeleteAccount instead.
+func (s *UserServer) DeleteUser(context.Context, *v1.DeleteUserRequest) (*v1.DefaultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, responses.NotImplemented)
 }
 
+// deleteUser re-authenticates the user with the password, then revokes the sessions, invalidates the cache and
+// soft-deletes the user. The account can be restored by signing in until the grace period is over.
+func deleteUser(ctx context.Context, log *slog.Logger, password string) (*v1.DefaultResponse, error) {
+	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+
+	deleteUserRequest := validations.DeleteUserRequest{
+		Password: password,
+	}
+	validationErr := validations.ValidateStruct(deleteUserRequest)
+	if validationErr != nil {
+		return nil, status.Errorf(codes.InvalidArgument, responses.ValidationError)
+	}
+
+	user, err := database.GetUserByID(uuid.MustParse(claims.ID), false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.NotFound)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	if !user.ComparePassword(deleteUserRequest.Password) {
+		return nil, status.Errorf(codes.PermissionDenied, responses.InvalidCredentials)
+	}
+
+	if err = user.SoftDelete(); err != nil {
+		log.ErrorContext(ctx, "failed to delete user", "user_id", claims.ID, "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	if err = invalidateUserCache(claims.ID); err != nil {
+		log.WarnContext(ctx, "failed to invalidate user cache", "user_id", claims.ID, "error", err)
+	}
+
+	return &v1.DefaultResponse{
+		Success: true,
+	}, nil
+}
+
+// invalidateUserCache removes the cached user and profile
+func invalidateUserCache(id string) error {
+	if cache.Client == nil {
+		return nil
+	}
+	return cache.Delete(userCacheKey(id), userProfileCacheKey(id))
+}
+
+// ChangeEmail keeps the new email pending until the code or link sent to it is confirmed with
+// EmailChangeServer.ConfirmEmailChange, and notifies the previous email with a link to revert the change
 func (s *UserServer) ChangeEmail(ctx context.Context, in *v1.ChangeEmailRequest) (*v1.DefaultResponse, error) {
 	claims := ctx.Value(token.Claims).(jwt.RegisteredClaims)
 
@@ -274,21 +339,63 @@ func (s *UserServer) ChangeEmail(ctx context.Context, in *v1.ChangeEmailRequest)
 		return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
 	}
 
-	user.UpdateUserEmail(changeEmailRequest.Email)
-	if err = database.DB.Session(&gorm.Session{FullSaveAssociations: true}).Save(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			return nil, status.Errorf(codes.AlreadyExists, responses.UserExists)
-		}
-		return nil, status.Errorf(codes.Internal, err.Error())
+	if s.Mailer == nil {
+		s.Logger.WarnContext(ctx, "email change requested but no mailer is configured")
+		return nil, status.Errorf(codes.Unimplemented, responses.NotImplemented)
 	}
 
-	if err := cache.Set(userCacheKey(claims.ID), user, cache.Client.UserCacheExpiration); err != nil {
+	// an email taken by another account, or with a change of another account pending, gets the response of a sent code
+	// without a code, so that the request cannot be used to find out registered emails
+	message := responses.EmailChangePending
+	accepted := &v1.DefaultResponse{Success: true, Message: &message}
+
+	// the email is checked again when the change is confirmed, the unique index settles races with sign-ups
+	if _, err = database.GetUserByEmail(changeEmailRequest.Email); err == nil {
+		return accepted, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Logger.ErrorContext(ctx, "failed to look up new email", "error", err)
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
 
-	return &v1.DefaultResponse{
-		Success: true,
-	}, nil
+	lastChange, err := database.GetLatestEmailChange(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Logger.ErrorContext(ctx, "failed to load last email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if lastChange != nil && time.Since(lastChange.CreatedAt) < s.EmailChange.Interval {
+		return nil, status.Errorf(codes.ResourceExhausted, responses.TooManyEmailChange)
+	}
+
+	otpCode := newOTPCode(s.OTPLength)
+	if otpCode == "" {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	change, err := database.CreateEmailChange(user, changeEmailRequest.Email, otpCode, s.EmailChange.TTL, s.EmailChange.RevertTTL)
+	if errors.Is(err, database.ErrEmailChangePending) {
+		return accepted, nil
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to create email change", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "new_email", change.NewEmail)
+
+	confirm, notice, err := emailChangeMessages(s.EmailChange, user, change, otpCode)
+	if err == nil {
+		err = s.Mailer.Send(ctx, confirm)
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to send email change confirmation", "error", err)
+		// the new email could not receive the code, so the change must not hold back the next request
+		if err = database.DB.Unscoped().Delete(change).Error; err != nil {
+			s.Logger.ErrorContext(ctx, "failed to remove unsent email change", "error", err)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	if err = s.Mailer.Send(ctx, notice); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to notify previous email of email change", "error", err)
+	}
+	return accepted, nil
 }
 
 func (s *UserServer) ChangePassword(ctx context.Context, in *v1.ChangePasswordRequest) (*v1.DefaultResponse, error) {
@@ -316,14 +423,32 @@ func (s *UserServer) ChangePassword(ctx context.Context, in *v1.ChangePasswordRe
 		return nil, status.Errorf(codes.InvalidArgument, responses.InvalidCredentials)
 	}
 
+	if err := checkPasswordPolicy(ctx, s.PasswordPolicy, s.Logger, changePasswordRequest.NewPassword, password.Context{
+		Name:  user.Name,
+		Email: user.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := checkPasswordHistory(ctx, s.PasswordPolicy, s.Logger, user, changePasswordRequest.NewPassword, true); err != nil {
+		return nil, err
+	}
+
 	err = user.SetPassword(changePasswordRequest.NewPassword)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, responses.ServerError)
+		return nil, setPasswordError(ctx, s.Logger, err)
 	}
 
-	if err = database.DB.Session(&gorm.Session{FullSaveAssociations: true}).Save(&user).Error; err != nil {
+	if err = savePassword(s.PasswordPolicy, user); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to save password", "error", err)
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
+	// the other sessions may belong to whoever knew the previous password
+	currentSession, _ := token.SessionID(claims)
+	if _, err = revokeSessions(ctx, s.Logger, user.ID, currentSession); err != nil {
+		return nil, err
+	}
+	s.Notifier.Notify(ctx, user.ID, notify.EventPasswordChanged, notify.Data{IP: clientip.FromContext(ctx)})
 
 	return &v1.DefaultResponse{
 		Success: true,
@@ -349,12 +474,11 @@ func (s *UserServer) SendVerificationCode(ctx context.Context, in *v1.SendVerifi
 		if !dateutil.CompareTimesByGivenMinute(time.Now(), user.EmailVerifySentAt, 3) {
 			return nil, status.Errorf(codes.ResourceExhausted, responses.TooManyVerifyRequest)
 		}
-		otpCode := textutil.RandomString(dotenv.GetInt("OTP_LENGTH", 6))
+		otpCode := newOTPCode(s.OTPLength)
 		if otpCode == "" {
 			return nil, status.Errorf(codes.Internal, responses.ServerError)
 		}
-		// TODO: Remove this line
-		fmt.Println(otpCode)
+		s.Logger.InfoContext(ctx, "email verification code generated", "user_id", user.ID.String())
 
 		/*
 			language := os.Getenv("APP_DEFAULT_LANGUAGE")
@@ -416,7 +540,13 @@ func (s *UserServer) Verify(ctx context.Context, in *v1.VerifyRequest) (*v1.Defa
 
 	if in.Type == v1.VerificationType_EMAIL {
 		if user.VerifyEmail(in.Code) {
-			if err = database.DB.Session(&gorm.Session{FullSaveAssociations: true}).Save(&user).Error; err != nil {
+			err = database.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&user).Error; err != nil {
+					return err
+				}
+				return database.EnqueueOutboxEvent(tx, database.EventUserEmailVerified, user.ID, user.EventData())
+			})
+			if err != nil {
 				return nil, status.Errorf(codes.Internal, responses.ServerError)
 			}
 			if err = cache.Set(userCacheKey(claims.ID), user, cache.Client.UserCacheExpiration); err != nil {
@@ -434,7 +564,7 @@ func (s *UserServer) Verify(ctx context.Context, in *v1.VerifyRequest) (*v1.Defa
 func (s *UserServer) GetUsers(ctx context.Context, in *v1.ListRequest) (*v1.GetUsersResponse, error) {
 	// TODO: Implement role system
 
-	md := pagination.Metadata{
+	md := pagination.PageMetadata{
 		OrderBy:  in.OrderBy,
 		Order:    in.Order,
 		PageSize: in.PageSize,
@@ -442,15 +572,11 @@ func (s *UserServer) GetUsers(ctx context.Context, in *v1.ListRequest) (*v1.GetU
 		Page:     in.Page,
 	}
 
-	userToSessionsResponse := func(sessions []database.Session) []*v1.Session {
+	userToSessionsResponse := func(sessions []database.Session, devices map[string]*database.Device) []*v1.Session {
 		var sessionsResponse []*v1.Session
-		for _, session := range sessions {
-			sessionsResponse = append(sessionsResponse, &v1.Session{
-				Id:         session.ID,
-				ClientId:   session.ClientID,
-				ClientName: session.ClientName,
-				ExpiresAt:  timestamppb.New(session.ExpiresAt).AsTime().String(),
-			})
+		for i := range sessions {
+			session := &sessions[i]
+			sessionsResponse = append(sessionsResponse, sessionResponse(session, devices[strconv.FormatUint(session.ID, 10)]))
 		}
 		return sessionsResponse
 	}
@@ -459,7 +585,7 @@ func (s *UserServer) GetUsers(ctx context.Context, in *v1.ListRequest) (*v1.GetU
 		if profile == nil {
 			return nil
 		}
-		birthdate := profile.Birthdate.Format(dotenv.GetString("DATE_FORMAT", "2006-01-02"))
+		birthdate := dateutil.DateString(*profile.Birthdate)
 		return &v1.Profile{
 			Birthdate: &birthdate,
 			Picture:   &profile.Picture,
@@ -470,12 +596,12 @@ func (s *UserServer) GetUsers(ctx context.Context, in *v1.ListRequest) (*v1.GetU
 		}
 	}
 
-	transformResponse := func(users []*database.User) []*v1.User {
+	transformResponse := func(users []*database.User, devices map[string]*database.Device) []*v1.User {
 		var usersResponse []*v1.User
 
 		for _, user := range users {
 			userProfile := userToProfileResponse(user.Profile)
-			userSessions := userToSessionsResponse(user.Sessions)
+			userSessions := userToSessionsResponse(user.Sessions, devices)
 			usersResponse = append(usersResponse, &v1.User{
 				Id:        user.ID.String(),
 				Name:      user.Name,
@@ -496,7 +622,15 @@ func (s *UserServer) GetUsers(ctx context.Context, in *v1.ListRequest) (*v1.GetU
 		}
 		return nil, status.Errorf(codes.Internal, responses.ServerError)
 	}
-	var usersResponse = transformResponse(users)
+	userIds := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.ID)
+	}
+	devices, err := database.GetDevicesByUserIds(userIds)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	var usersResponse = transformResponse(users, devicesBySession(devices))
 
 	md.SetTotalCount(int32(count))
 	md.SetPage(in.Page)
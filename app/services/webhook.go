@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/audit"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"log/slog"
+	"strconv"
+)
+
+type WebhookServer struct {
+	token.AuthorizationRequired
+	api.UnimplementedWebhookServiceServer
+	Logger *slog.Logger
+}
+
+func (s *WebhookServer) IsAuthorizationRequired() bool {
+	return true
+}
+
+func (s *WebhookServer) ListWebhookDeliveries(ctx context.Context, in *api.ListWebhookDeliveriesRequest) (*api.ListWebhookDeliveriesResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	md := pagination.PageMetadata{
+		PageSize: in.PageSize,
+		Page:     in.Page,
+	}
+	if md.PageSize <= 0 {
+		md.PageSize = pagination.DefaultPageSize
+	}
+	filter := database.WebhookDeliveryFilter{
+		Status:   in.Status,
+		ClientID: in.ClientId,
+	}
+	deliveries, count, err := database.GetWebhookDeliveries(filter, md)
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "failed to list webhook deliveries", "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	var deliveriesResponse []*api.WebhookDelivery
+	for _, delivery := range deliveries {
+		deliveriesResponse = append(deliveriesResponse, webhookDeliveryToResponse(delivery))
+	}
+
+	md.SetTotalCount(int32(count))
+	md.SetPage(in.Page)
+	return &api.ListWebhookDeliveriesResponse{
+		Deliveries: deliveriesResponse,
+		Meta: &v1.Meta{
+			Page:       md.Page,
+			TotalCount: md.TotalCount,
+			TotalPages: md.TotalPages,
+			PageSize:   md.PageSize,
+			HasNext:    md.HasNext,
+			HasPrev:    md.HasPrev,
+		},
+	}, nil
+}
+
+func (s *WebhookServer) RedeliverWebhook(ctx context.Context, in *api.RedeliverWebhookRequest) (*v1.DefaultResponse, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	delivery, err := database.GetWebhookDeliveryById(in.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, responses.NotFound)
+		}
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+	audit.AddMetadata(ctx, "delivery_id", strconv.FormatUint(delivery.ID, 10))
+
+	if err = delivery.Redeliver(); err != nil {
+		s.Logger.ErrorContext(ctx, "failed to schedule webhook redelivery", "delivery_id", delivery.ID, "error", err)
+		return nil, status.Errorf(codes.Internal, responses.ServerError)
+	}
+
+	return &v1.DefaultResponse{Success: true}, nil
+}
+
+func webhookDeliveryToResponse(delivery database.WebhookDelivery) *api.WebhookDelivery {
+	response := &api.WebhookDelivery{
+		Id:             delivery.ID,
+		EventId:        delivery.OutboxEventID,
+		EventType:      delivery.OutboxEvent.EventType,
+		ClientId:       delivery.ClientID,
+		Url:            delivery.URL,
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		NextAttemptAt:  timestamppb.New(delivery.NextAttemptAt).AsTime().String(),
+		LastError:      delivery.LastError,
+		LastStatusCode: int32(delivery.LastStatusCode),
+		CreatedAt:      timestamppb.New(delivery.CreatedAt).AsTime().String(),
+	}
+	if delivery.DeliveredAt != nil {
+		deliveredAt := timestamppb.New(*delivery.DeliveredAt).AsTime().String()
+		response.DeliveredAt = &deliveredAt
+	}
+	return response
+}
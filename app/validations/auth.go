@@ -11,11 +11,22 @@ type SignInRequest struct {
 	Password string `validate:"required,password" json:"password"`
 }
 
+// StoreDeviceInfoRequest is the request body for registering the device of a session, the IP address is taken from the
+// connection
 type StoreDeviceInfoRequest struct {
-	Name  string `json:"device_name"`
-	IP    string `json:"device_ip"`
-	OS    string `json:"device_os"`
-	Token string `json:"device_token"`
+	DeviceID       string `validate:"omitempty,max=64" json:"device_id"`
+	Name           string `validate:"max=64" json:"device_name"`
+	OS             string `validate:"max=64" json:"device_os"`
+	OSVersion      string `validate:"max=32" json:"device_os_version"`
+	Browser        string `validate:"max=64" json:"device_browser"`
+	BrowserVersion string `validate:"max=32" json:"device_browser_version"`
+	Token          string `validate:"max=512" json:"device_token"`
+}
+
+// RenameDeviceRequest is the request body for renaming a device
+type RenameDeviceRequest struct {
+	DeviceID string `validate:"required,max=64" json:"device_id"`
+	Name     string `validate:"required,max=64" json:"name"`
 }
 
 type SocialAuth struct {
@@ -33,7 +44,7 @@ type SocialAuthComplete struct {
 
 type SignInWithPhoneNumber struct {
 	PhoneNumber string `validate:"required,min=10" json:"phone_number"`
-	Password    string `validate:"required,min=8,max=64,password" json:"password"`
+	Password    string `validate:"required,password" json:"password"`
 }
 
 type RefreshTokenRequest struct {
@@ -56,10 +67,11 @@ type ResetPasswordRequest struct {
 	Email string `validate:"required,email,min=5,max=64" json:"email"`
 }
 
+// ResetPasswordCompleteRequest takes the email with a reset code, a reset link token carries the user itself
 type ResetPasswordCompleteRequest struct {
-	Email    string `validate:"required,email,min=5,max=64" json:"email"`
+	Email    string `validate:"omitempty,email,min=5,max=64" json:"email"`
 	Token    string `validate:"required" json:"token"`
-	Password string `validate:"required,min=8,max=64,password" json:"password"`
+	Password string `validate:"required,password" json:"password"`
 }
 
 type ResetPasswordWithPhoneNumberRequest struct {
@@ -68,12 +80,20 @@ type ResetPasswordWithPhoneNumberRequest struct {
 
 type ChangePasswordWithEmailRequest struct {
 	Email            string `validate:"required,email,min=5,max=64" json:"email"`
-	NewPassword      string `validate:"required,min=8,max=64,password" json:"new_password"`
+	NewPassword      string `validate:"required,password" json:"new_password"`
 	VerificationCode string `validate:"required" json:"verification_code"`
 }
 
 type ChangePasswordWithPhoneNumberRequest struct {
 	PhoneNumber      string `validate:"required,min=10" json:"phone_number"`
-	NewPassword      string `validate:"required,min=8,max=64,password" json:"new_password"`
+	NewPassword      string `validate:"required,password" json:"new_password"`
 	VerificationCode string `validate:"required" json:"verification_code"`
 }
+
+// ConfirmSignInRequest is the request body for confirming a held back sign-in, with the email and its code or a link
+// token
+type ConfirmSignInRequest struct {
+	Email string `validate:"omitempty,email,max=64" json:"email"`
+	Code  string `validate:"omitempty,max=64" json:"code"`
+	Token string `validate:"omitempty,max=2048" json:"token"`
+}
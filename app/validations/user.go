@@ -29,6 +29,11 @@ type ChangeEmailRequest struct {
 	Password string `validate:"required,password" json:"password"`
 }
 
+// DeleteUserRequest is the request body for deleting a user's account
+type DeleteUserRequest struct {
+	Password string `validate:"required,password" json:"password"`
+}
+
 // UserPhoneNumberUpdateRequest is the request body for updating a user's phone number
 type UserPhoneNumberUpdateRequest struct {
 	PhoneNumber string `validate:"required" json:"phone_number"`
@@ -43,3 +48,15 @@ type VerifyRequest struct {
 type SendVerificationCodeRequest struct {
 	Type string `validate:"required" json:"type"`
 }
+
+// ConfirmEmailChangeRequest is the request body for confirming a new email, with the email and its code or a link token
+type ConfirmEmailChangeRequest struct {
+	Email string `validate:"omitempty,email,max=64" json:"email"`
+	Code  string `validate:"omitempty,max=64" json:"code"`
+	Token string `validate:"omitempty,max=2048" json:"token"`
+}
+
+// RevertEmailChangeRequest is the request body for reverting an email change with the link sent to the previous email
+type RevertEmailChangeRequest struct {
+	Token string `validate:"required,max=2048" json:"token"`
+}
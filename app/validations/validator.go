@@ -2,11 +2,13 @@ package validations
 
 import (
 	"github.com/go-playground/validator/v10"
-	"github.com/talut/dotenv"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
+// maxPasswordBytes bounds the work hashing and policy checks do on untrusted input
+const maxPasswordBytes = 1024
+
 type ErrorResponse struct {
 	Field    string `json:"field"`
 	Reason   string `json:"reason"`
@@ -15,9 +17,12 @@ type ErrorResponse struct {
 
 var validate = validator.New()
 
+func init() {
+	_ = validate.RegisterValidation("password", passwordValidator)
+}
+
 func ValidateStruct(validatorStruct interface{}) []ErrorResponse {
 	var errors []ErrorResponse
-	_ = validate.RegisterValidation("password", passwordValidator)
 	err := validate.Struct(validatorStruct)
 	if err != nil {
 		for _, err := range err.(validator.ValidationErrors) {
@@ -31,20 +36,8 @@ func ValidateStruct(validatorStruct interface{}) []ErrorResponse {
 	return errors
 }
 
+// passwordValidator only rejects input no password can be, new passwords are checked against password.Policy
 func passwordValidator(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
-	var (
-		uppercase = regexp.MustCompile(`[A-Z]`)
-		lowercase = regexp.MustCompile(`[a-z]`)
-		number    = regexp.MustCompile(`\d`)
-		special   = regexp.MustCompile(`[^a-zA-Z\d]`)
-	)
-	passwordMinLength := dotenv.GetInt("PASSWORD_MIN_LENGTH", 8)
-	passwordMaxLength := dotenv.GetInt("PASSWORD_MAX_LENGTH", 64)
-	return len(password) >= passwordMinLength &&
-		len(password) <= passwordMaxLength &&
-		uppercase.MatchString(password) &&
-		lowercase.MatchString(password) &&
-		number.MatchString(password) &&
-		special.MatchString(password)
+	return password != "" && len(password) <= maxPasswordBytes && utf8.ValidString(password)
 }
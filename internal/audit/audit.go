@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc/metadata"
+	"log/slog"
+	"sync"
+)
+
+type entryKey string
+
+var Key entryKey = "audit"
+
+// Entry collects what a handler knows about the audited call, such as the user it acted on
+type Entry struct {
+	mu       sync.Mutex
+	userID   *uuid.UUID
+	metadata map[string]string
+}
+
+// Event is an audit event recorded outside the interceptor, e.g. by a background job
+type Event struct {
+	ActorID  *uuid.UUID
+	UserID   *uuid.UUID
+	Action   string
+	Outcome  string
+	Metadata map[string]string
+}
+
+// SetSubject sets the user the audited call acted on, when it is not the authenticated user
+func SetSubject(ctx context.Context, userID uuid.UUID) {
+	if entry := entryFromContext(ctx); entry != nil {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		entry.userID = &userID
+	}
+}
+
+// AddMetadata attaches a key/value pair to the event recorded for the call
+func AddMetadata(ctx context.Context, key, value string) {
+	if entry := entryFromContext(ctx); entry != nil {
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+		if entry.metadata == nil {
+			entry.metadata = map[string]string{}
+		}
+		entry.metadata[key] = value
+	}
+}
+
+func entryFromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(Key).(*Entry)
+	return entry
+}
+
+// Record writes an event, filling the client and origin from the context
+func Record(ctx context.Context, event Event) error {
+	clientID, ip, userAgent := origin(ctx)
+	auditEvent := database.AuditEvent{
+		ActorID:   event.ActorID,
+		UserID:    event.UserID,
+		ClientID:  clientID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Action:    event.Action,
+		Outcome:   event.Outcome,
+	}
+	if auditEvent.UserID == nil {
+		auditEvent.UserID = auditEvent.ActorID
+	}
+	if err := auditEvent.SetMetadata(event.Metadata); err != nil {
+		return err
+	}
+	return database.AppendAuditEvent(&auditEvent)
+}
+
+// RecordOrLog records the event and logs the failure instead of returning it
+func RecordOrLog(ctx context.Context, log *slog.Logger, event Event) {
+	if err := Record(ctx, event); err != nil {
+		log.ErrorContext(ctx, "failed to record audit event", "action", event.Action, "error", err)
+	}
+}
+
+// actorFromContext returns the authenticated user of the call, if any
+func actorFromContext(ctx context.Context) *uuid.UUID {
+	claims, ok := ctx.Value(token.Claims).(jwt.RegisteredClaims)
+	if !ok {
+		return nil
+	}
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+func origin(ctx context.Context) (clientID, ip, userAgent string) {
+	if ctxClient, ok := ctx.Value(client.Key).(*client.Item); ok && ctxClient != nil {
+		clientID = ctxClient.ID
+	}
+	ip = clientip.FromContext(ctx)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	return
+}
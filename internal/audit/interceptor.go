@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"context"
+	v1 "github.com/usercoredev/proto/api/v1"
+	api "github.com/usercoredev/usercore/api/v1"
+	"github.com/usercoredev/usercore/internal/database"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+const (
+	ActionSignUp               = "auth.sign_up"
+	ActionSignIn               = "auth.sign_in"
+	ActionSignInLockout        = "auth.sign_in_lockout"
+	ActionSignInConfirm        = "auth.sign_in_confirm"
+	ActionRefreshToken         = "auth.refresh_token"
+	ActionPasswordResetRequest = "auth.password_reset_request"
+	ActionPasswordResetConfirm = "auth.password_reset_confirm"
+	ActionUserUpdate           = "user.update"
+	ActionUserDelete           = "user.delete"
+	ActionUserImport           = "user.import"
+	ActionEmailChange          = "user.email_change"
+	ActionEmailChangeConfirm   = "user.email_change_confirm"
+	ActionEmailChangeRevert    = "user.email_change_revert"
+	ActionPasswordChange       = "user.password_change"
+	ActionVerificationCodeSend = "user.verification_code_send"
+	ActionVerify               = "user.verify"
+	ActionNotificationSettings = "user.notification_preferences_update"
+	ActionDataExportRequest    = "user.data_export_request"
+	ActionDataExportDownload   = "user.data_export_download"
+	ActionSessionDelete        = "session.delete"
+	ActionSignOut              = "session.sign_out"
+	ActionSessionRevokeOthers  = "session.revoke_others"
+	ActionSessionRevokeUser    = "session.revoke_user"
+	ActionDeviceRegister       = "device.register"
+	ActionDeviceRename         = "device.rename"
+	ActionDeviceRevoke         = "device.revoke"
+	ActionRoleCreate           = "role.create"
+	ActionRoleUpdate           = "role.update"
+	ActionRoleDelete           = "role.delete"
+	ActionPermissionCreate     = "permission.create"
+	ActionPermissionUpdate     = "permission.update"
+	ActionPermissionDelete     = "permission.delete"
+	ActionWebhookRedeliver     = "webhook.redeliver"
+	// the admin commands record these themselves, they have no RPC
+	ActionUserCreate   = "user.create"
+	ActionPasswordSet  = "user.password_set"
+	ActionRoleAssign   = "role.assign"
+	ActionRoleUnassign = "role.unassign"
+)
+
+// actions maps the audited RPCs to the action recorded for them
+var actions = map[string]string{
+	v1.AuthenticationService_SignUp_FullMethodName:                       ActionSignUp,
+	v1.AuthenticationService_SignIn_FullMethodName:                       ActionSignIn,
+	v1.AuthenticationService_RefreshToken_FullMethodName:                 ActionRefreshToken,
+	v1.AuthenticationService_ResetPassword_FullMethodName:                ActionPasswordResetRequest,
+	v1.AuthenticationService_ResetPasswordConfirm_FullMethodName:         ActionPasswordResetConfirm,
+	v1.UserService_UpdateUser_FullMethodName:                             ActionUserUpdate,
+	v1.UserService_DeleteUser_FullMethodName:                             ActionUserDelete,
+	api.AccountService_DeleteAccount_FullMethodName:                      ActionUserDelete,
+	api.UserImportService_ImportUsers_FullMethodName:                     ActionUserImport,
+	v1.UserService_ChangeEmail_FullMethodName:                            ActionEmailChange,
+	api.SignInConfirmationService_ConfirmSignIn_FullMethodName:           ActionSignInConfirm,
+	api.EmailChangeService_ConfirmEmailChange_FullMethodName:             ActionEmailChangeConfirm,
+	api.EmailChangeService_RevertEmailChange_FullMethodName:              ActionEmailChangeRevert,
+	v1.UserService_ChangePassword_FullMethodName:                         ActionPasswordChange,
+	v1.UserService_SendVerificationCode_FullMethodName:                   ActionVerificationCodeSend,
+	v1.UserService_Verify_FullMethodName:                                 ActionVerify,
+	api.NotificationService_UpdateNotificationPreferences_FullMethodName: ActionNotificationSettings,
+	api.DataExportService_RequestDataExport_FullMethodName:               ActionDataExportRequest,
+	api.DataExportDownloadService_DownloadDataExport_FullMethodName:      ActionDataExportDownload,
+	v1.SessionService_DeleteSession_FullMethodName:                       ActionSessionDelete,
+	v1.SessionService_SignOut_FullMethodName:                             ActionSignOut,
+	api.SessionRevocationService_RevokeOtherSessions_FullMethodName:      ActionSessionRevokeOthers,
+	api.SessionRevocationService_RevokeUserSessions_FullMethodName:       ActionSessionRevokeUser,
+	api.DeviceService_RegisterDevice_FullMethodName:                      ActionDeviceRegister,
+	api.DeviceService_RenameDevice_FullMethodName:                        ActionDeviceRename,
+	api.DeviceService_RevokeDevice_FullMethodName:                        ActionDeviceRevoke,
+	v1.RoleService_CreateRole_FullMethodName:                             ActionRoleCreate,
+	v1.RoleService_UpdateRole_FullMethodName:                             ActionRoleUpdate,
+	v1.RoleService_DeleteRole_FullMethodName:                             ActionRoleDelete,
+	v1.PermissionService_CreatePermission_FullMethodName:                 ActionPermissionCreate,
+	v1.PermissionService_UpdatePermission_FullMethodName:                 ActionPermissionUpdate,
+	v1.PermissionService_DeletePermission_FullMethodName:                 ActionPermissionDelete,
+	api.WebhookService_RedeliverWebhook_FullMethodName:                   ActionWebhookRedeliver,
+}
+
+// Interceptor records an audit event with the outcome of every audited RPC. It must run after the client and
+// authentication interceptors so that the client and the actor are known.
+func Interceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		action, ok := actions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		entry := &Entry{}
+		ctx = context.WithValue(ctx, Key, entry)
+		resp, err := handler(ctx, req)
+
+		entry.mu.Lock()
+		event := Event{
+			ActorID:  actorFromContext(ctx),
+			UserID:   entry.userID,
+			Action:   action,
+			Outcome:  outcome(err),
+			Metadata: entry.metadata,
+		}
+		entry.mu.Unlock()
+		if err != nil {
+			if event.Metadata == nil {
+				event.Metadata = map[string]string{}
+			}
+			event.Metadata["status"] = status.Code(err).String()
+		}
+		RecordOrLog(ctx, log, event)
+		return resp, err
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return database.AuditOutcomeFailure
+	}
+	return database.AuditOutcomeSuccess
+}
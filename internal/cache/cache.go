@@ -7,7 +7,6 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/usercoredev/usercore/internal/cipher"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 )
@@ -23,7 +22,6 @@ type Settings struct {
 	Host                       string
 	Port                       string
 	Password                   string
-	PasswordFile               string
 	EncryptionKey              string
 	UserCacheExpiration        time.Duration
 	UserProfileCacheExpiration time.Duration
@@ -49,16 +47,9 @@ func (s *Settings) SetupCache() error {
 		panic("cache host not set")
 	} else if s.Port == "" {
 		panic("cache port not set")
-	} else if s.Password == "" && s.PasswordFile == "" {
+	} else if s.Password == "" {
 		panic("CACHE_PASSWORD or CACHE_PASSWORD_FILE is required")
 	}
-	if s.PasswordFile != "" {
-		bin, err := os.ReadFile(s.PasswordFile)
-		if err != nil {
-			return err
-		}
-		s.Password = string(bin)
-	}
 	s.Password = url.QueryEscape(strings.TrimSpace(s.Password))
 	Client = &redisCache{
 		encryptionKey:              s.EncryptionKey,
@@ -120,3 +111,20 @@ func Get(key string, value interface{}) error {
 
 	return nil
 }
+
+func Delete(keys ...string) error {
+	if Client == nil {
+		return NotEnabled
+	}
+	ctx := context.Background()
+	return Client.redis.Del(ctx, keys...).Err()
+}
+
+// Redis returns the underlying client for features that need more than the encrypted key/value helpers, or nil when
+// the cache is disabled
+func Redis() *redis.Client {
+	if Client == nil {
+		return nil
+	}
+	return Client.redis
+}
@@ -55,6 +55,22 @@ func PublicKey(publicKeyPath string) (key *rsa.PublicKey) {
 	return
 }
 
+// GenerateKeyPair returns a new RSA key pair in PEM, the private key in PKCS #1 and the public key in PKIX as PrivateKey
+// and PublicKey read them
+func GenerateKeyPair(bits int) (privateKey []byte, publicKey []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+	return privateKey, publicKey, nil
+}
+
 func EncryptWithKey(value []byte, key string) (string, error) {
 	hash := sha256.Sum256([]byte(key))
 	block, err := aes.NewCipher(hash[:])
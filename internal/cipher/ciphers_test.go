@@ -1,6 +1,8 @@
 package cipher
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,6 +22,23 @@ func TestPublicKeyLoading(t *testing.T) {
 	assert.NotNil(t, key, "PublicKey should not be nil")
 }
 
+// TestGenerateKeyPair tests that generated keys are read back by PrivateKey and PublicKey
+func TestGenerateKeyPair(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKeyPair(2048)
+	assert.NoError(t, err, "GenerateKeyPair should not error")
+
+	dir := t.TempDir()
+	privateKeyPath := filepath.Join(dir, "jwt.private")
+	publicKeyPath := filepath.Join(dir, "jwt.public")
+	assert.NoError(t, os.WriteFile(privateKeyPath, privatePEM, 0o600))
+	assert.NoError(t, os.WriteFile(publicKeyPath, publicPEM, 0o644))
+
+	privateKey := PrivateKey(privateKeyPath)
+	publicKey := PublicKey(publicKeyPath)
+	assert.True(t, privateKey.PublicKey.Equal(publicKey), "public key should match the private key")
+	assert.Equal(t, 2048, privateKey.N.BitLen())
+}
+
 // TestEncryptDecryptWithKey tests the encryption and decryption flow
 func TestEncryptDecryptWithKey(t *testing.T) {
 	value := "hello world"
@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
 	"io"
 	"os"
 )
@@ -11,8 +12,50 @@ type clientKey string
 var Key clientKey = "client"
 
 type Item struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Webhooks []Webhook   `json:"webhooks,omitempty"`
+	Risk     *RiskPolicy `json:"risk,omitempty"`
+	// Sessions replaces the default session limits for the client
+	Sessions *sessionpolicy.Policy `json:"sessions,omitempty"`
+}
+
+// RiskPolicy is the score from which the risk engine requires more proof at sign-in or blocks it for the client. A zero
+// score turns the action off.
+type RiskPolicy struct {
+	RequireMFA               int `json:"require_mfa,omitempty"`
+	RequireEmailConfirmation int `json:"require_email_confirmation,omitempty"`
+	Block                    int `json:"block,omitempty"`
+}
+
+// Webhook is an endpoint of the client that receives user lifecycle events
+type Webhook struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events,omitempty"`
+}
+
+// Subscribes reports whether the webhook receives the given event type. A webhook without events receives all of them.
+func (w *Webhook) Subscribes(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebhook returns the webhook of the client with the given URL
+func (i *Item) GetWebhook(url string) *Webhook {
+	for _, webhook := range i.Webhooks {
+		if webhook.URL == url {
+			return &webhook
+		}
+	}
+	return nil
 }
 
 type Settings struct {
@@ -50,3 +93,16 @@ func (s *Settings) LoadClients() error {
 	s.Clients = clients
 	return nil
 }
+
+// SaveClients writes the clients back to the client file, the server reads them at startup
+func (s *Settings) SaveClients() error {
+	content, err := json.MarshalIndent(s.Clients, "", "  ")
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(s.ClientFilePath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.ClientFilePath, append(content, '\n'), info.Mode().Perm())
+}
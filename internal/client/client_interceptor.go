@@ -3,10 +3,12 @@ package client
 import (
 	"context"
 	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"log/slog"
 )
 
 func ClientInterceptor(clientSettings Settings) grpc.UnaryServerInterceptor {
@@ -23,6 +25,7 @@ func ClientInterceptor(clientSettings Settings) grpc.UnaryServerInterceptor {
 		if mdClient == nil {
 			return nil, status.Errorf(codes.Unauthenticated, responses.InvalidClient)
 		}
+		logger.AddAttrs(ctx, slog.String("client_id", mdClient.ID))
 		ctx = context.WithValue(ctx, Key, mdClient)
 		return handler(ctx, req)
 	}
@@ -0,0 +1,215 @@
+package clientip
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	forwardedForHeader = "x-forwarded-for"
+	// ClientIPKey is the metadata the HTTP gateway forwards the resolved address of the HTTP client in
+	ClientIPKey = "x-usercore-client-ip"
+	// UserAgentKey is the metadata the HTTP gateway forwards the user agent of the HTTP client in
+	UserAgentKey = "x-usercore-user-agent"
+	// SignatureKey is the metadata the HTTP gateway signs the client address and user agent in, so they are only
+	// believed when they come from the gateway
+	SignatureKey = "x-usercore-gateway-signature"
+)
+
+// reserved are the metadata keys only the gateway and trusted proxies may set
+var reserved = map[string]bool{
+	forwardedForHeader: true,
+	ClientIPKey:        true,
+	UserAgentKey:       true,
+	SignatureKey:       true,
+}
+
+// trustedProxies are the networks whose X-Forwarded-For entries are believed
+var trustedProxies []*net.IPNet
+
+// gatewayKey signs the metadata of the gateway. The gateway runs in the same process as the gRPC server, so the key
+// never has to leave it.
+var gatewayKey = newGatewayKey()
+
+func newGatewayKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Settings configures the reverse proxies in front of usercore
+type Settings struct {
+	// TrustedProxies are the IP addresses and CIDR ranges of the proxies that append the address of their caller to
+	// X-Forwarded-For. Loopback addresses are not trusted unless they are listed, like 127.0.0.1 for a proxy on the host.
+	TrustedProxies []string
+}
+
+// Setup parses the trusted proxies, it must run before the servers start
+func (s Settings) Setup() error {
+	var networks []*net.IPNet
+	for _, proxy := range s.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+func isTrusted(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve walks the X-Forwarded-For entries from the nearest hop and returns the first address that is not a trusted
+// proxy. Entries before it are set by the client and are not believed.
+func resolve(remote string, forwardedFor []string) string {
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, entry := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(entry))
+		}
+	}
+	ip := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		current := net.ParseIP(ip)
+		if current == nil || !isTrusted(current) {
+			break
+		}
+		if net.ParseIP(hops[i]) == nil {
+			break
+		}
+		ip = hops[i]
+	}
+	return ip
+}
+
+// FromRequest returns the IP address of the client of an HTTP request received by the gateway
+func FromRequest(req *http.Request) string {
+	remote := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	return resolve(remote, req.Header.Values("X-Forwarded-For"))
+}
+
+// GatewayMetadata is the metadata the gateway forwards with every call, the address and user agent of the HTTP client
+// signed with the key of the process
+func GatewayMetadata(req *http.Request) metadata.MD {
+	ip, userAgent := FromRequest(req), req.UserAgent()
+	return metadata.Pairs(
+		ClientIPKey, ip,
+		UserAgentKey, userAgent,
+		SignatureKey, sign(ip, userAgent),
+	)
+}
+
+func sign(ip, userAgent string) string {
+	mac := hmac.New(sha256.New, gatewayKey)
+	mac.Write([]byte(ip + "\n" + userAgent))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fromGateway returns the client address and user agent forwarded by the gateway, ok is false when the metadata was
+// not signed by it
+func fromGateway(md metadata.MD) (ip, userAgent string, ok bool) {
+	ips, userAgents, signatures := md.Get(ClientIPKey), md.Get(UserAgentKey), md.Get(SignatureKey)
+	if len(ips) != 1 || len(userAgents) != 1 || len(signatures) != 1 {
+		return "", "", false
+	}
+	if !hmac.Equal([]byte(signatures[0]), []byte(sign(ips[0], userAgents[0]))) {
+		return "", "", false
+	}
+	return ips[0], userAgents[0], true
+}
+
+// HeaderMatcher forwards HTTP headers to the gRPC metadata like runtime.DefaultHeaderMatcher, except for the
+// Grpc-Metadata- headers that would set the metadata only the gateway may set
+func HeaderMatcher(key string) (string, bool) {
+	forwarded, ok := runtime.DefaultHeaderMatcher(key)
+	if ok && reserved[strings.ToLower(forwarded)] {
+		return "", false
+	}
+	return forwarded, ok
+}
+
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return net.ParseIP(ip)
+}
+
+// FromContext returns the IP address of the caller. Calls proxied by the in-process HTTP gateway carry the address the
+// gateway resolved, signed by it. Calls from a trusted proxy are resolved from their X-Forwarded-For entries, direct
+// calls use the address of the connection.
+func FromContext(ctx context.Context) string {
+	ip := peerIP(ctx)
+	if ip == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if forwarded, _, ok := fromGateway(md); ok && net.ParseIP(forwarded) != nil {
+		return forwarded
+	}
+	if !isTrusted(ip) {
+		return ip.String()
+	}
+	return resolve(ip.String(), md.Get(forwardedForHeader))
+}
+
+// UserAgent returns the user agent of the caller, the one of the HTTP request for calls proxied by the gateway
+func UserAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if _, userAgent, ok := fromGateway(md); ok && userAgent != "" {
+		return userAgent
+	}
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
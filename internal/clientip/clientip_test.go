@@ -0,0 +1,133 @@
+package clientip
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func contextFrom(addr string, forwardedFor ...string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	if len(forwardedFor) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(forwardedForHeader, forwardedFor[0]))
+	}
+	return ctx
+}
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"direct caller", contextFrom("203.0.113.7:5000"), "203.0.113.7"},
+		{"direct caller ignores forwarded header", contextFrom("203.0.113.7:5000", "198.51.100.1"), "203.0.113.7"},
+		{"loopback caller is not trusted by default", contextFrom("127.0.0.1:5000", "198.51.100.1"), "127.0.0.1"},
+		{"loopback caller without header", contextFrom("127.0.0.1:5000"), "127.0.0.1"},
+		{"no peer", context.Background(), ""},
+	}
+	for _, test := range tests {
+		if ip := FromContext(test.ctx); ip != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, ip)
+		}
+	}
+}
+
+func TestFromContextTrustedProxy(t *testing.T) {
+	if err := (Settings{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1", "127.0.0.1"}}).Setup(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer Settings{}.Setup()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"trusted proxy", contextFrom("10.1.2.3:5000", "198.51.100.1"), "198.51.100.1"},
+		{"listed loopback proxy", contextFrom("127.0.0.1:5000", "10.0.0.1, 198.51.100.1"), "198.51.100.1"},
+		{"chain of trusted proxies", contextFrom("192.0.2.1:5000", "198.51.100.1, 10.0.0.5"), "198.51.100.1"},
+		{"spoofed entries before the client", contextFrom("10.1.2.3:5000", "203.0.113.9, 198.51.100.1"), "198.51.100.1"},
+		{"untrusted caller", contextFrom("203.0.113.7:5000", "198.51.100.1"), "203.0.113.7"},
+	}
+	for _, test := range tests {
+		if ip := FromContext(test.ctx); ip != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, ip)
+		}
+	}
+}
+
+func TestFromContextGateway(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+	req.RemoteAddr = "198.51.100.1:4000"
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", "127.0.0.1:5000")
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Join(GatewayMetadata(req), metadata.Pairs(forwardedForHeader, "127.0.0.1")))
+	if ip := FromContext(ctx); ip != "198.51.100.1" {
+		t.Errorf("Expected the address resolved by the gateway, got %q", ip)
+	}
+	if userAgent := UserAgent(ctx); userAgent != "Mozilla/5.0" {
+		t.Errorf("Expected the user agent forwarded by the gateway, got %q", userAgent)
+	}
+
+	// a local process calling the gRPC port directly cannot set the metadata of the gateway
+	forged := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	forged = metadata.NewIncomingContext(forged, metadata.Pairs(
+		ClientIPKey, "203.0.113.9",
+		UserAgentKey, "Mozilla/5.0",
+		SignatureKey, sign("198.51.100.1", "Mozilla/5.0"),
+		"user-agent", "grpc-go/1.62.0",
+	))
+	if ip := FromContext(forged); ip != "127.0.0.1" {
+		t.Errorf("Expected metadata without a valid signature to be ignored, got %q", ip)
+	}
+	if userAgent := UserAgent(forged); userAgent != "grpc-go/1.62.0" {
+		t.Errorf("Expected the user agent of the direct caller, got %q", userAgent)
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	if err := (Settings{TrustedProxies: []string{"10.0.0.0/8"}}).Setup(); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	defer Settings{}.Setup()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+	req.RemoteAddr = "10.0.0.2:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.1")
+	if ip := FromRequest(req); ip != "198.51.100.1" {
+		t.Errorf("Expected the client behind the trusted proxy, got %q", ip)
+	}
+	req.RemoteAddr = "203.0.113.7:4000"
+	if ip := FromRequest(req); ip != "203.0.113.7" {
+		t.Errorf("Expected the remote address of an untrusted caller, got %q", ip)
+	}
+}
+
+func TestSetupRejectsInvalidProxies(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.local"} {
+		if err := (Settings{TrustedProxies: []string{proxy}}).Setup(); err == nil {
+			t.Errorf("Expected %q to be rejected", proxy)
+		}
+	}
+}
+
+func TestHeaderMatcher(t *testing.T) {
+	for _, header := range []string{"Grpc-Metadata-X-Usercore-Client-Ip", "Grpc-Metadata-X-Forwarded-For", "Grpc-Metadata-X-Usercore-User-Agent", "Grpc-Metadata-X-Usercore-Gateway-Signature"} {
+		if _, ok := HeaderMatcher(header); ok {
+			t.Errorf("Expected %s not to be forwarded", header)
+		}
+	}
+	if key, ok := HeaderMatcher("User-Agent"); !ok || key != "grpcgateway-User-Agent" {
+		t.Errorf("Expected the user agent header to be forwarded, got %q", key)
+	}
+	if key, ok := HeaderMatcher("Grpc-Metadata-Password"); !ok || key != "Password" {
+		t.Errorf("Expected other metadata headers to be forwarded, got %q", key)
+	}
+}
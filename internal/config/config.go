@@ -0,0 +1,417 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/password"
+	"time"
+)
+
+// Config is the whole configuration of the server. It is read from a YAML or TOML file and every field can be
+// overridden by the environment variable named in its env tag, or by the file named in <NAME>_FILE. Fields tagged
+// secret are masked by Redacted.
+type Config struct {
+	App         App         `yaml:"app" toml:"app"`
+	Server      Server      `yaml:"server" toml:"server"`
+	Log         Log         `yaml:"log" toml:"log"`
+	Token       Token       `yaml:"token" toml:"token"`
+	Database    Database    `yaml:"database" toml:"database"`
+	Cache       Cache       `yaml:"cache" toml:"cache"`
+	Clients     Clients     `yaml:"clients" toml:"clients"`
+	RateLimit   RateLimit   `yaml:"rate_limit" toml:"rate_limit"`
+	Lockout     Lockout     `yaml:"lockout" toml:"lockout"`
+	Password    Password    `yaml:"password" toml:"password"`
+	Import      Import      `yaml:"import" toml:"import"`
+	Mail        Mail        `yaml:"mail" toml:"mail"`
+	Notify      Notify      `yaml:"notify" toml:"notify"`
+	EmailChange EmailChange `yaml:"email_change" toml:"email_change"`
+	Risk        Risk        `yaml:"risk" toml:"risk"`
+	Sessions    Sessions    `yaml:"sessions" toml:"sessions"`
+	Account     Account     `yaml:"account" toml:"account"`
+	Scheduler   Scheduler   `yaml:"scheduler" toml:"scheduler"`
+	DataExport  DataExport  `yaml:"data_export" toml:"data_export"`
+	Webhook     Webhook     `yaml:"webhook" toml:"webhook"`
+}
+
+type App struct {
+	// Name is the issuer of the tokens
+	Name           string   `yaml:"name" toml:"name" env:"APP_NAME"`
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// OTPLength is the length of the codes sent to verify emails and confirm sign-ins
+	OTPLength      int    `yaml:"otp_length" toml:"otp_length" env:"OTP_LENGTH"`
+	DateFormat     string `yaml:"date_format" toml:"date_format" env:"DATE_FORMAT"`
+	DateTimeFormat string `yaml:"date_time_format" toml:"date_time_format" env:"DATE_TIME_FORMAT"`
+	// CodeHashKey is the secret the password reset, email change and sign-in codes are hashed with before they are
+	// stored
+	CodeHashKey string `yaml:"code_hash_key" toml:"code_hash_key" env:"CODE_HASH_KEY" secret:"true"`
+}
+
+type Server struct {
+	GRPCHost string `yaml:"grpc_host" toml:"grpc_host" env:"GRPC_SERVER_HOST"`
+	GRPCPort string `yaml:"grpc_port" toml:"grpc_port" env:"GRPC_SERVER_PORT"`
+	HTTPHost string `yaml:"http_host" toml:"http_host" env:"HTTP_SERVER_HOST"`
+	HTTPPort string `yaml:"http_port" toml:"http_port" env:"HTTP_SERVER_PORT"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+type Token struct {
+	Scheme             string        `yaml:"scheme" toml:"scheme" env:"TOKEN_SCHEME"`
+	Audience           string        `yaml:"audience" toml:"audience" env:"JWT_AUDIENCE"`
+	PrivateKeyPath     string        `yaml:"private_key_path" toml:"private_key_path" env:"PRIVATE_KEY_PATH"`
+	PublicKeyPath      string        `yaml:"public_key_path" toml:"public_key_path" env:"PUBLIC_KEY_PATH"`
+	AccessTokenExpire  time.Duration `yaml:"access_token_expire" toml:"access_token_expire" env:"ACCESS_TOKEN_EXPIRE"`
+	RefreshTokenExpire time.Duration `yaml:"refresh_token_expire" toml:"refresh_token_expire" env:"REFRESH_TOKEN_EXPIRE"`
+	// SessionCacheTTL is how long a replica trusts that the session of an access token is active before checking again
+	SessionCacheTTL time.Duration `yaml:"session_cache_ttl" toml:"session_cache_ttl" env:"SESSION_CHECK_CACHE_TTL"`
+	// SessionActivityInterval is how often the calls with the access tokens of a session record its use at most
+	SessionActivityInterval time.Duration `yaml:"session_activity_interval" toml:"session_activity_interval" env:"SESSION_ACTIVITY_INTERVAL"`
+}
+
+type Database struct {
+	// Engine is mysql, postgres or sqlite
+	Engine          string `yaml:"engine" toml:"engine" env:"DB_ENGINE"`
+	Host            string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port            string `yaml:"port" toml:"port" env:"DB_PORT"`
+	User            string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password        string `yaml:"password" toml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name            string `yaml:"name" toml:"name" env:"DB_NAME"`
+	FilePath        string `yaml:"file_path" toml:"file_path" env:"DB_FILE_PATH"`
+	Charset         string `yaml:"charset" toml:"charset" env:"DB_CHARSET"`
+	CertificateFile string `yaml:"certificate_file" toml:"certificate_file" env:"DB_CERTIFICATE_FILE"`
+	// Migrate is true to apply the pending migrations at startup, or check to refuse to start while some are pending
+	Migrate string `yaml:"migrate" toml:"migrate" env:"DB_MIGRATE"`
+}
+
+type Cache struct {
+	Enabled               bool          `yaml:"enabled" toml:"enabled" env:"CACHE_ENABLED"`
+	Host                  string        `yaml:"host" toml:"host" env:"CACHE_HOST"`
+	Port                  string        `yaml:"port" toml:"port" env:"CACHE_PORT"`
+	Password              string        `yaml:"password" toml:"password" env:"CACHE_PASSWORD" secret:"true"`
+	EncryptionKey         string        `yaml:"encryption_key" toml:"encryption_key" env:"CACHE_ENCRYPTION_KEY" secret:"true"`
+	UserExpiration        time.Duration `yaml:"user_expiration" toml:"user_expiration" env:"USER_CACHE_EXPIRATION"`
+	UserProfileExpiration time.Duration `yaml:"user_profile_expiration" toml:"user_profile_expiration" env:"USER_PROFILE_CACHE_EXPIRATION"`
+	UserPrefix            string        `yaml:"user_prefix" toml:"user_prefix" env:"USER_CACHE_PREFIX"`
+	UserProfilePrefix     string        `yaml:"user_profile_prefix" toml:"user_profile_prefix" env:"USER_PROFILE_CACHE_PREFIX"`
+}
+
+type Clients struct {
+	FilePath string `yaml:"file_path" toml:"file_path" env:"CLIENTS_FILE_PATH"`
+}
+
+type RateLimit struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// FilePath holds the policies, the default policies are used without it
+	FilePath string `yaml:"file_path" toml:"file_path" env:"RATE_LIMIT_FILE_PATH"`
+}
+
+type Lockout struct {
+	Enabled          bool          `yaml:"enabled" toml:"enabled" env:"LOCKOUT_ENABLED"`
+	MaxEmailFailures int           `yaml:"max_email_failures" toml:"max_email_failures" env:"LOCKOUT_MAX_EMAIL_FAILURES"`
+	MaxIPFailures    int           `yaml:"max_ip_failures" toml:"max_ip_failures" env:"LOCKOUT_MAX_IP_FAILURES"`
+	DelayAfter       int           `yaml:"delay_after" toml:"delay_after" env:"LOCKOUT_DELAY_AFTER"`
+	BaseDelay        time.Duration `yaml:"base_delay" toml:"base_delay" env:"LOCKOUT_BASE_DELAY"`
+	MaxDelay         time.Duration `yaml:"max_delay" toml:"max_delay" env:"LOCKOUT_MAX_DELAY"`
+	Duration         time.Duration `yaml:"duration" toml:"duration" env:"LOCKOUT_DURATION"`
+	Window           time.Duration `yaml:"window" toml:"window" env:"LOCKOUT_WINDOW"`
+}
+
+type Password struct {
+	MinLength        int           `yaml:"min_length" toml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MaxLength        int           `yaml:"max_length" toml:"max_length" env:"PASSWORD_MAX_LENGTH"`
+	RequireUppercase bool          `yaml:"require_uppercase" toml:"require_uppercase" env:"PASSWORD_REQUIRE_UPPERCASE"`
+	RequireLowercase bool          `yaml:"require_lowercase" toml:"require_lowercase" env:"PASSWORD_REQUIRE_LOWERCASE"`
+	RequireDigit     bool          `yaml:"require_digit" toml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSymbol    bool          `yaml:"require_symbol" toml:"require_symbol" env:"PASSWORD_REQUIRE_SYMBOL"`
+	Blocklist        bool          `yaml:"blocklist" toml:"blocklist" env:"PASSWORD_BLOCKLIST"`
+	MinScore         int           `yaml:"min_score" toml:"min_score" env:"PASSWORD_MIN_SCORE"`
+	HistoryDepth     int           `yaml:"history_depth" toml:"history_depth" env:"PASSWORD_HISTORY_DEPTH"`
+	MinAge           time.Duration `yaml:"min_age" toml:"min_age" env:"PASSWORD_MIN_AGE"`
+	Breach           Breach        `yaml:"breach" toml:"breach"`
+	Hash             Hash          `yaml:"hash" toml:"hash"`
+	Reset            PasswordReset `yaml:"reset" toml:"reset"`
+}
+
+type Breach struct {
+	DataPath     string        `yaml:"data_path" toml:"data_path" env:"PASSWORD_BREACH_DATA_PATH"`
+	RangeURL     string        `yaml:"range_url" toml:"range_url" env:"PASSWORD_BREACH_RANGE_URL"`
+	RangeTimeout time.Duration `yaml:"range_timeout" toml:"range_timeout" env:"PASSWORD_BREACH_RANGE_TIMEOUT"`
+	ServeRange   bool          `yaml:"serve_range" toml:"serve_range" env:"PASSWORD_BREACH_SERVE_RANGE"`
+}
+
+type Hash struct {
+	Algorithm string `yaml:"algorithm" toml:"algorithm" env:"PASSWORD_HASH_ALGORITHM"`
+	// Argon2Memory is in KiB
+	Argon2Memory      int `yaml:"argon2_memory" toml:"argon2_memory" env:"PASSWORD_ARGON2_MEMORY"`
+	Argon2Iterations  int `yaml:"argon2_iterations" toml:"argon2_iterations" env:"PASSWORD_ARGON2_ITERATIONS"`
+	Argon2Parallelism int `yaml:"argon2_parallelism" toml:"argon2_parallelism" env:"PASSWORD_ARGON2_PARALLELISM"`
+	BcryptCost        int `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"PASSWORD_BCRYPT_COST"`
+	ScryptLogN        int `yaml:"scrypt_log_n" toml:"scrypt_log_n" env:"PASSWORD_SCRYPT_LOG_N"`
+	ScryptR           int `yaml:"scrypt_r" toml:"scrypt_r" env:"PASSWORD_SCRYPT_R"`
+	ScryptP           int `yaml:"scrypt_p" toml:"scrypt_p" env:"PASSWORD_SCRYPT_P"`
+}
+
+type PasswordReset struct {
+	TTL         time.Duration `yaml:"ttl" toml:"ttl" env:"PASSWORD_RESET_TTL"`
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"PASSWORD_RESET_MAX_ATTEMPTS"`
+	Interval    time.Duration `yaml:"interval" toml:"interval" env:"PASSWORD_RESET_INTERVAL"`
+	URL         string        `yaml:"url" toml:"url" env:"PASSWORD_RESET_URL"`
+}
+
+type Import struct {
+	Firebase Firebase `yaml:"firebase" toml:"firebase"`
+}
+
+// Firebase holds the base64 project parameters of Firebase Auth used to import its password hashes
+type Firebase struct {
+	SignerKey     string `yaml:"signer_key" toml:"signer_key" env:"IMPORT_FIREBASE_SIGNER_KEY" secret:"true"`
+	SaltSeparator string `yaml:"salt_separator" toml:"salt_separator" env:"IMPORT_FIREBASE_SALT_SEPARATOR"`
+	Rounds        int    `yaml:"rounds" toml:"rounds" env:"IMPORT_FIREBASE_ROUNDS"`
+	MemCost       int    `yaml:"mem_cost" toml:"mem_cost" env:"IMPORT_FIREBASE_MEM_COST"`
+}
+
+// Parameters decodes the parameters, nil when no signer key is set
+func (f Firebase) Parameters() (*password.FirebaseScryptParameters, error) {
+	if f.SignerKey == "" {
+		return nil, nil
+	}
+	signerKey, err := base64.StdEncoding.DecodeString(f.SignerKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firebase signer key: %w", err)
+	}
+	saltSeparator, err := base64.StdEncoding.DecodeString(f.SaltSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firebase salt separator: %w", err)
+	}
+	params := &password.FirebaseScryptParameters{
+		SignerKey:     signerKey,
+		SaltSeparator: saltSeparator,
+		Rounds:        f.Rounds,
+		MemCost:       f.MemCost,
+	}
+	return params, params.Validate()
+}
+
+type Mail struct {
+	// Driver is smtp, or log to only log the messages. Empty disables sending
+	Driver      string        `yaml:"driver" toml:"driver" env:"MAIL_DRIVER"`
+	Host        string        `yaml:"host" toml:"host" env:"MAIL_HOST"`
+	Port        string        `yaml:"port" toml:"port" env:"MAIL_PORT"`
+	Username    string        `yaml:"username" toml:"username" env:"MAIL_USERNAME"`
+	Password    string        `yaml:"password" toml:"password" env:"MAIL_PASSWORD" secret:"true"`
+	From        string        `yaml:"from" toml:"from" env:"MAIL_FROM"`
+	ImplicitTLS bool          `yaml:"implicit_tls" toml:"implicit_tls" env:"MAIL_IMPLICIT_TLS"`
+	Timeout     time.Duration `yaml:"timeout" toml:"timeout" env:"MAIL_TIMEOUT"`
+}
+
+type Notify struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled" env:"NOTIFY_ENABLED"`
+	DefaultLocale string        `yaml:"default_locale" toml:"default_locale" env:"NOTIFY_DEFAULT_LOCALE"`
+	Timeout       time.Duration `yaml:"timeout" toml:"timeout" env:"NOTIFY_TIMEOUT"`
+	PollInterval  time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"NOTIFY_POLL_INTERVAL"`
+	MaxAttempts   int           `yaml:"max_attempts" toml:"max_attempts" env:"NOTIFY_MAX_ATTEMPTS"`
+	Push          Push          `yaml:"push" toml:"push"`
+}
+
+type Push struct {
+	// Driver is http, or log to only log the notifications. Empty disables push notifications
+	Driver       string        `yaml:"driver" toml:"driver" env:"PUSH_DRIVER"`
+	GatewayURL   string        `yaml:"gateway_url" toml:"gateway_url" env:"PUSH_GATEWAY_URL"`
+	GatewayToken string        `yaml:"gateway_token" toml:"gateway_token" env:"PUSH_GATEWAY_TOKEN" secret:"true"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"PUSH_TIMEOUT"`
+}
+
+type EmailChange struct {
+	TTL         time.Duration `yaml:"ttl" toml:"ttl" env:"EMAIL_CHANGE_TTL"`
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"EMAIL_CHANGE_MAX_ATTEMPTS"`
+	Interval    time.Duration `yaml:"interval" toml:"interval" env:"EMAIL_CHANGE_INTERVAL"`
+	RevertTTL   time.Duration `yaml:"revert_ttl" toml:"revert_ttl" env:"EMAIL_CHANGE_REVERT_TTL"`
+	ConfirmURL  string        `yaml:"confirm_url" toml:"confirm_url" env:"EMAIL_CHANGE_CONFIRM_URL"`
+	RevertURL   string        `yaml:"revert_url" toml:"revert_url" env:"EMAIL_CHANGE_REVERT_URL"`
+}
+
+type Risk struct {
+	Enabled        bool     `yaml:"enabled" toml:"enabled" env:"RISK_ENABLED"`
+	GeoIPDatabases []string `yaml:"geoip_databases" toml:"geoip_databases" env:"RISK_GEOIP_DATABASES"`
+	// MaxTravelSpeed is in km/h
+	MaxTravelSpeed    int                `yaml:"max_travel_speed" toml:"max_travel_speed" env:"RISK_MAX_TRAVEL_SPEED"`
+	History           int                `yaml:"history" toml:"history" env:"RISK_HISTORY"`
+	RequireMFAScore   int                `yaml:"require_mfa_score" toml:"require_mfa_score" env:"RISK_REQUIRE_MFA_SCORE"`
+	RequireEmailScore int                `yaml:"require_email_score" toml:"require_email_score" env:"RISK_REQUIRE_EMAIL_SCORE"`
+	BlockScore        int                `yaml:"block_score" toml:"block_score" env:"RISK_BLOCK_SCORE"`
+	Confirmation      SignInConfirmation `yaml:"confirmation" toml:"confirmation"`
+}
+
+type SignInConfirmation struct {
+	TTL         time.Duration `yaml:"ttl" toml:"ttl" env:"SIGN_IN_CONFIRMATION_TTL"`
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"SIGN_IN_CONFIRMATION_MAX_ATTEMPTS"`
+	URL         string        `yaml:"url" toml:"url" env:"SIGN_IN_CONFIRMATION_URL"`
+}
+
+type Sessions struct {
+	MaxPerUser   int `yaml:"max_per_user" toml:"max_per_user" env:"MAX_SESSIONS_PER_USER"`
+	MaxPerClient int `yaml:"max_per_client" toml:"max_per_client" env:"MAX_SESSIONS_PER_CLIENT"`
+	// MaxPerDeviceType is a list like mobile=2,desktop=3
+	MaxPerDeviceType       string        `yaml:"max_per_device_type" toml:"max_per_device_type" env:"MAX_SESSIONS_PER_DEVICE_TYPE"`
+	SingleSessionPerClient bool          `yaml:"single_session_per_client" toml:"single_session_per_client" env:"SINGLE_SESSION_PER_CLIENT"`
+	IdleTimeout            time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SESSION_IDLE_TIMEOUT"`
+	MaxLifetime            time.Duration `yaml:"max_lifetime" toml:"max_lifetime" env:"SESSION_MAX_LIFETIME"`
+}
+
+type Account struct {
+	// DeletionGracePeriod is how long a deleted account can be restored before it is purged
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" toml:"deletion_grace_period" env:"ACCOUNT_DELETION_GRACE_PERIOD"`
+	PurgeInterval       time.Duration `yaml:"purge_interval" toml:"purge_interval" env:"ACCOUNT_PURGE_INTERVAL"`
+}
+
+type Scheduler struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled" env:"SCHEDULER_ENABLED"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"SCHEDULER_POLL_INTERVAL"`
+	LeaseTTL     time.Duration `yaml:"lease_ttl" toml:"lease_ttl" env:"SCHEDULER_LEASE_TTL"`
+	Jobs         Jobs          `yaml:"jobs" toml:"jobs"`
+}
+
+// Jobs configures the scheduled cleanup jobs, an interval of 0 turns a job off
+type Jobs struct {
+	SessionCleanup          time.Duration `yaml:"session_cleanup" toml:"session_cleanup" env:"JOB_SESSION_CLEANUP_INTERVAL"`
+	PasswordResetCleanup    time.Duration `yaml:"password_reset_cleanup" toml:"password_reset_cleanup" env:"JOB_PASSWORD_RESET_CLEANUP_INTERVAL"`
+	VerificationCodeCleanup time.Duration `yaml:"verification_code_cleanup" toml:"verification_code_cleanup" env:"JOB_VERIFICATION_CODE_CLEANUP_INTERVAL"`
+	VerificationCodeTTL     time.Duration `yaml:"verification_code_ttl" toml:"verification_code_ttl" env:"EMAIL_VERIFY_CODE_TTL"`
+	RunHistoryCleanup       time.Duration `yaml:"run_history_cleanup" toml:"run_history_cleanup" env:"JOB_RUN_CLEANUP_INTERVAL"`
+	RunHistoryRetention     time.Duration `yaml:"run_history_retention" toml:"run_history_retention" env:"JOB_RUN_RETENTION"`
+	RiskDecisionCleanup     time.Duration `yaml:"risk_decision_cleanup" toml:"risk_decision_cleanup" env:"JOB_RISK_DECISION_CLEANUP_INTERVAL"`
+	RiskDecisionRetention   time.Duration `yaml:"risk_decision_retention" toml:"risk_decision_retention" env:"RISK_DECISION_RETENTION"`
+	LoginAttemptCleanup     time.Duration `yaml:"login_attempt_cleanup" toml:"login_attempt_cleanup" env:"JOB_LOGIN_ATTEMPT_CLEANUP_INTERVAL"`
+	LoginAttemptRetention   time.Duration `yaml:"login_attempt_retention" toml:"login_attempt_retention" env:"LOGIN_ATTEMPT_RETENTION"`
+	NotificationCleanup     time.Duration `yaml:"notification_cleanup" toml:"notification_cleanup" env:"JOB_NOTIFICATION_CLEANUP_INTERVAL"`
+	NotificationRetention   time.Duration `yaml:"notification_retention" toml:"notification_retention" env:"NOTIFICATION_RETENTION"`
+}
+
+type DataExport struct {
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"DATA_EXPORT_POLL_INTERVAL"`
+	DownloadTTL  time.Duration `yaml:"download_ttl" toml:"download_ttl" env:"DATA_EXPORT_DOWNLOAD_TTL"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"DATA_EXPORT_TIMEOUT"`
+}
+
+type Webhook struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled" env:"WEBHOOK_ENABLED"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	MaxAttempts  int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"WEBHOOK_RETRY_BACKOFF"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF"`
+}
+
+// Default returns the configuration used for everything the file and the environment leave unset
+func Default() *Config {
+	hash := password.DefaultHashSettings()
+	return &Config{
+		App: App{
+			OTPLength:      6,
+			DateFormat:     "2006-01-02",
+			DateTimeFormat: "2006-01-02 15:04:05",
+		},
+		Log: Log{Level: "info", Format: "json"},
+		Token: Token{
+			Scheme:                  "Bearer",
+			AccessTokenExpire:       time.Hour,
+			RefreshTokenExpire:      24 * time.Hour,
+			SessionCacheTTL:         5 * time.Second,
+			SessionActivityInterval: time.Minute,
+		},
+		Cache: Cache{
+			UserExpiration:        48 * time.Hour,
+			UserProfileExpiration: 48 * time.Hour,
+			UserPrefix:            "user",
+			UserProfilePrefix:     "profile",
+		},
+		RateLimit: RateLimit{Enabled: true},
+		Lockout: Lockout{
+			Enabled:          true,
+			MaxEmailFailures: 5,
+			MaxIPFailures:    50,
+			DelayAfter:       3,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Minute,
+			Duration:         15 * time.Minute,
+			Window:           15 * time.Minute,
+		},
+		Password: Password{
+			MinLength:    password.DefaultMinLength,
+			MaxLength:    password.DefaultMaxLength,
+			Blocklist:    true,
+			MinScore:     password.DefaultMinScore,
+			HistoryDepth: 4,
+			Breach:       Breach{RangeTimeout: 2 * time.Second},
+			Hash: Hash{
+				Algorithm:         hash.Algorithm,
+				Argon2Memory:      int(hash.Argon2Memory),
+				Argon2Iterations:  int(hash.Argon2Iterations),
+				Argon2Parallelism: int(hash.Argon2Parallelism),
+				BcryptCost:        hash.BcryptCost,
+				ScryptLogN:        int(hash.ScryptLogN),
+				ScryptR:           hash.ScryptR,
+				ScryptP:           hash.ScryptP,
+			},
+			Reset: PasswordReset{TTL: 15 * time.Minute, MaxAttempts: 5, Interval: time.Minute},
+		},
+		Import: Import{Firebase: Firebase{SaltSeparator: "Bw==", Rounds: 8, MemCost: 14}},
+		Mail:   Mail{Port: "587", Timeout: 10 * time.Second},
+		Notify: Notify{
+			Enabled:       true,
+			DefaultLocale: "en",
+			Timeout:       30 * time.Second,
+			PollInterval:  5 * time.Second,
+			MaxAttempts:   5,
+			Push:          Push{Timeout: 10 * time.Second},
+		},
+		EmailChange: EmailChange{
+			TTL:         time.Hour,
+			MaxAttempts: 5,
+			Interval:    time.Minute,
+			RevertTTL:   7 * 24 * time.Hour,
+		},
+		Risk: Risk{
+			MaxTravelSpeed:    1000,
+			History:           50,
+			RequireEmailScore: 50,
+			BlockScore:        90,
+			Confirmation:      SignInConfirmation{TTL: 15 * time.Minute, MaxAttempts: 5},
+		},
+		Account: Account{DeletionGracePeriod: 30 * 24 * time.Hour, PurgeInterval: time.Hour},
+		Scheduler: Scheduler{
+			Enabled:      true,
+			PollInterval: 15 * time.Second,
+			LeaseTTL:     time.Minute,
+			Jobs: Jobs{
+				SessionCleanup:          time.Hour,
+				PasswordResetCleanup:    time.Hour,
+				VerificationCodeCleanup: time.Hour,
+				VerificationCodeTTL:     24 * time.Hour,
+				RunHistoryCleanup:       24 * time.Hour,
+				RunHistoryRetention:     30 * 24 * time.Hour,
+				RiskDecisionCleanup:     24 * time.Hour,
+				RiskDecisionRetention:   90 * 24 * time.Hour,
+				LoginAttemptCleanup:     time.Hour,
+				LoginAttemptRetention:   24 * time.Hour,
+				NotificationCleanup:     24 * time.Hour,
+				NotificationRetention:   7 * 24 * time.Hour,
+			},
+		},
+		DataExport: DataExport{
+			PollInterval: 10 * time.Second,
+			DownloadTTL:  24 * time.Hour,
+			Timeout:      10 * time.Minute,
+		},
+		Webhook: Webhook{
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			RetryBackoff: 30 * time.Second,
+			MaxBackoff:   time.Hour,
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// validConfig returns a configuration that passes validation
+func validConfig() *Config {
+	cfg := Default()
+	cfg.App.Name = "usercore"
+	cfg.Server.GRPCPort = "9000"
+	cfg.Server.HTTPPort = "8080"
+	cfg.Token.Audience = "usercore"
+	cfg.Token.PrivateKeyPath = "jwt.private"
+	cfg.Token.PublicKeyPath = "jwt.public"
+	cfg.Database.Engine = "sqlite"
+	cfg.Database.FilePath = "usercore.db"
+	cfg.Clients.FilePath = "clients.json"
+	cfg.App.CodeHashKey = "0123456789abcdef0123456789abcdef"
+	return cfg
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+app:
+  name: usercore
+  otp_length: 8
+database:
+  engine: postgres
+  host: db
+token:
+  access_token_expire: 15m
+cache:
+  enabled: true
+`)
+	secret := writeFile(t, "db_password", "s3cret\n")
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_PASSWORD_FILE", secret)
+	t.Setenv("REFRESH_TOKEN_EXPIRE", "86400")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.0.1")
+	t.Setenv("CACHE_ENABLED", "false")
+	t.Setenv("LOG_LEVEL", "")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.App.Name != "usercore" || cfg.App.OTPLength != 8 || cfg.Database.Engine != "postgres" {
+		t.Errorf("Expected the values of the file, got %+v %+v", cfg.App, cfg.Database)
+	}
+	if cfg.Database.Host != "db.internal" {
+		t.Errorf("Expected the environment to override the file, got %s", cfg.Database.Host)
+	}
+	if cfg.Database.Password != "s3cret" {
+		t.Errorf("Expected the password to be read from DB_PASSWORD_FILE, got %q", cfg.Database.Password)
+	}
+	if cfg.Token.AccessTokenExpire != 15*time.Minute || cfg.Token.RefreshTokenExpire != 24*time.Hour {
+		t.Errorf("Expected durations of 15m and 24h, got %s and %s", cfg.Token.AccessTokenExpire, cfg.Token.RefreshTokenExpire)
+	}
+	if !reflect.DeepEqual(cfg.App.TrustedProxies, []string{"10.0.0.0/8", "192.168.0.1"}) {
+		t.Errorf("Expected the trusted proxies to be split, got %v", cfg.App.TrustedProxies)
+	}
+	if cfg.Cache.Enabled {
+		t.Error("Expected CACHE_ENABLED to turn the cache off")
+	}
+	if cfg.Log.Level != "info" {
+		t.Errorf("Expected an empty variable to keep the default, got %s", cfg.Log.Level)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[app]
+name = "usercore"
+
+[password.hash]
+algorithm = "bcrypt"
+
+[sessions]
+idle_timeout = "720h"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.App.Name != "usercore" || cfg.Password.Hash.Algorithm != "bcrypt" || cfg.Sessions.IdleTimeout != 720*time.Hour {
+		t.Errorf("Expected the values of the file, got %+v %+v %+v", cfg.App, cfg.Password.Hash, cfg.Sessions)
+	}
+	if cfg.Password.MinLength == 0 {
+		t.Error("Expected the defaults to be kept")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(writeFile(t, "config.yaml", "app:\n  nmae: usercore\n")); err == nil {
+		t.Error("Expected an unknown yaml key to fail")
+	}
+	if _, err := Load(writeFile(t, "config.toml", "[app]\nnmae = \"usercore\"\n")); err == nil || !strings.Contains(err.Error(), "app.nmae") {
+		t.Errorf("Expected an unknown toml key to be named, got %v", err)
+	}
+	if _, err := Load(writeFile(t, "config.json", "{}")); err == nil {
+		t.Error("Expected an unsupported extension to fail")
+	}
+
+	t.Setenv("OTP_LENGTH", "six")
+	t.Setenv("LOCKOUT_WINDOW", "forever")
+	t.Setenv("CACHE_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err := Load("")
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected the 3 invalid variables to be reported together, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Expected the configuration to be valid, got %v", err)
+	}
+
+	var errs Errors
+	if err := Default().Validate(); !errors.As(err, &errs) {
+		t.Fatalf("Expected Errors, got %v", err)
+	}
+	for _, env := range []string{"APP_NAME", "CODE_HASH_KEY", "GRPC_SERVER_PORT", "JWT_AUDIENCE", "DB_ENGINE", "CLIENTS_FILE_PATH"} {
+		if !strings.Contains(errs.Error(), env) {
+			t.Errorf("Expected %s to be reported, got %v", env, errs)
+		}
+	}
+	if strings.Contains(errs.Error(), "CACHE_HOST") {
+		t.Error("Expected the cache host not to be required while the cache is disabled")
+	}
+
+	cfg := validConfig()
+	cfg.Cache.Enabled = true
+	cfg.Database.Engine = "mysql"
+	cfg.Mail.Driver = "smtp"
+	cfg.Lockout.Window = -time.Minute
+	cfg.Password.MaxLength = cfg.Password.MinLength - 1
+	cfg.Scheduler.Jobs.LoginAttemptRetention = time.Minute
+	err := cfg.Validate()
+	for _, expected := range []string{"CACHE_HOST", "CACHE_PASSWORD", "DB_PASSWORD", "MAIL_HOST", "lockout.window (LOCKOUT_WINDOW) must not be negative", "PASSWORD_MAX_LENGTH", "LOGIN_ATTEMPT_RETENTION"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %s to be reported, got %v", expected, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database.Password = "s3cret"
+	cfg.Notify.Push.GatewayToken = "token"
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != "[REDACTED]" || redacted.Notify.Push.GatewayToken != "[REDACTED]" {
+		t.Errorf("Expected the secrets to be masked, got %q and %q", redacted.Database.Password, redacted.Notify.Push.GatewayToken)
+	}
+	if redacted.Cache.Password != "" {
+		t.Error("Expected an unset secret to stay empty")
+	}
+	if cfg.Database.Password != "s3cret" || redacted.App.Name != cfg.App.Name {
+		t.Error("Expected only the copy to be masked")
+	}
+}
+
+func TestEncodeLoadsBack(t *testing.T) {
+	cfg := validConfig()
+	cfg.App.TrustedProxies = []string{"10.0.0.0/8"}
+	// an unset list is written as an empty one
+	cfg.Risk.GeoIPDatabases = []string{}
+	for _, format := range []string{"yaml", "toml"} {
+		var buffer bytes.Buffer
+		if err := cfg.Encode(&buffer, format); err != nil {
+			t.Fatalf("Encode(%s) failed: %v", format, err)
+		}
+		loaded, err := Load(writeFile(t, "config."+format, buffer.String()))
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %v", format, err)
+		}
+		if !reflect.DeepEqual(loaded, cfg) {
+			t.Errorf("Expected the %s output to load back the same configuration", format)
+		}
+	}
+	if err := cfg.Encode(&bytes.Buffer{}, "ini"); err == nil {
+		t.Error("Expected an unsupported format to fail")
+	}
+}
+
+func TestFileFromArgs(t *testing.T) {
+	t.Setenv(FileEnv, "env.yaml")
+	for _, test := range []struct {
+		args         []string
+		file         string
+		remainingLen int
+	}{
+		{[]string{"--config", "a.yaml", "migrate", "up"}, "a.yaml", 2},
+		{[]string{"--config=b.toml"}, "b.toml", 0},
+		{[]string{"migrate", "--config", "c.yaml"}, "env.yaml", 3},
+		{nil, "env.yaml", 0},
+	} {
+		file, rest := FileFromArgs(test.args)
+		if file != test.file || len(rest) != test.remainingLen {
+			t.Errorf("FileFromArgs(%v) = %s, %v", test.args, file, rest)
+		}
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg, err := Load("../../vault/example/config.yaml")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("Expected the example configuration to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration with the secrets that are set masked
+func (c *Config) Redacted() *Config {
+	masked := *c
+	redact(reflect.ValueOf(&masked).Elem())
+	return &masked
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			redact(value)
+		} else if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString(redacted)
+		}
+	}
+}
+
+// Encode writes the configuration as yaml or toml, in the same form Load reads it
+func (c *Config) Encode(w io.Writer, format string) error {
+	switch format {
+	case "yaml", "yml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(c); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(c)
+	default:
+		return fmt.Errorf("unsupported config format %q, use yaml or toml", format)
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileEnv names the configuration file when no --config flag is given
+const FileEnv = "CONFIG_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FileFromArgs removes a leading --config <file> or --config=<file> from the command line arguments and returns the
+// file with the remaining arguments. Without the flag the file is read from CONFIG_FILE.
+func FileFromArgs(args []string) (string, []string) {
+	if len(args) > 0 {
+		if file, ok := strings.CutPrefix(args[0], "--config="); ok {
+			return file, args[1:]
+		}
+		if args[0] == "--config" && len(args) > 1 {
+			return args[1], args[2:]
+		}
+	}
+	return os.Getenv(FileEnv), args
+}
+
+// Load returns the defaults overridden by the file, when path is not empty, and then by the environment. The format of
+// the file follows its extension: .yaml, .yml or .toml. Unknown keys and unparsable values are reported together.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.decodeFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if errs := applyEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv); len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+func (c *Config) decodeFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(content), c)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("parse %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// applyEnv sets every field whose environment variable, or the file named by <NAME>_FILE, is not empty
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) Errors {
+	var errs Errors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			if field.Type.Kind() == reflect.Struct {
+				errs = append(errs, applyEnv(value, lookup)...)
+			}
+			continue
+		}
+		raw, ok, err := lookupEnv(name, lookup)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err = setValue(value, raw); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return errs
+}
+
+// lookupEnv prefers the content of the file named by <NAME>_FILE, so secrets can be mounted instead of exported
+func lookupEnv(name string, lookup func(string) (string, bool)) (string, bool, error) {
+	if file, ok := lookup(name + "_FILE"); ok && file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimSpace(string(content)), true, nil
+	}
+	value, ok := lookup(name)
+	return value, ok && value != "", nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		duration, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(duration))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseDuration accepts Go durations like 15m, and a bare number as seconds
+func parseDuration(raw string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration like 90s, 15m or 24h", raw)
+	}
+	return duration, nil
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"github.com/usercoredev/usercore/internal/notify"
+	"github.com/usercoredev/usercore/internal/password"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Errors lists every problem found in the configuration, so they can be fixed at once
+type Errors []string
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate checks the configuration before anything is started and returns Errors with every problem found. Settings
+// of disabled features, like the cache host while the cache is off, are not required.
+func (c *Config) Validate() error {
+	var errs Errors
+	fail := func(key, env, format string, args ...any) {
+		errs = append(errs, fmt.Sprintf("%s (%s) ", key, env)+fmt.Sprintf(format, args...))
+	}
+	required := func(key, env, value string) {
+		if value == "" {
+			fail(key, env, "is required")
+		}
+	}
+	port := func(key, env, value string) {
+		if n, err := strconv.Atoi(value); value != "" && (err != nil || n < 1 || n > 65535) {
+			fail(key, env, "must be a port between 1 and 65535, got %q", value)
+		}
+	}
+	oneOf := func(key, env, value string, allowed ...string) {
+		for _, option := range allowed {
+			if value == option {
+				return
+			}
+		}
+		if value == "" {
+			fail(key, env, "is required, one of %s", strings.Join(allowed, ", "))
+			return
+		}
+		fail(key, env, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+
+	required("app.name", "APP_NAME", c.App.Name)
+	if c.App.OTPLength < 4 || c.App.OTPLength > 32 {
+		fail("app.otp_length", "OTP_LENGTH", "must be between 4 and 32, got %d", c.App.OTPLength)
+	}
+	required("app.date_format", "DATE_FORMAT", c.App.DateFormat)
+	required("app.date_time_format", "DATE_TIME_FORMAT", c.App.DateTimeFormat)
+	if len(c.App.CodeHashKey) < 32 {
+		fail("app.code_hash_key", "CODE_HASH_KEY", "must be a secret of at least 32 characters")
+	}
+
+	required("server.grpc_port", "GRPC_SERVER_PORT", c.Server.GRPCPort)
+	port("server.grpc_port", "GRPC_SERVER_PORT", c.Server.GRPCPort)
+	required("server.http_port", "HTTP_SERVER_PORT", c.Server.HTTPPort)
+	port("server.http_port", "HTTP_SERVER_PORT", c.Server.HTTPPort)
+
+	oneOf("log.level", "LOG_LEVEL", strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error")
+	oneOf("log.format", "LOG_FORMAT", strings.ToLower(c.Log.Format), "json", "text")
+
+	required("token.audience", "JWT_AUDIENCE", c.Token.Audience)
+	required("token.private_key_path", "PRIVATE_KEY_PATH", c.Token.PrivateKeyPath)
+	required("token.public_key_path", "PUBLIC_KEY_PATH", c.Token.PublicKeyPath)
+	if c.Token.AccessTokenExpire <= 0 {
+		fail("token.access_token_expire", "ACCESS_TOKEN_EXPIRE", "must be positive")
+	}
+	if c.Token.RefreshTokenExpire <= 0 {
+		fail("token.refresh_token_expire", "REFRESH_TOKEN_EXPIRE", "must be positive")
+	}
+
+	oneOf("database.engine", "DB_ENGINE", c.Database.Engine, "mysql", "postgres", "sqlite")
+	switch c.Database.Engine {
+	case "sqlite":
+		required("database.file_path", "DB_FILE_PATH", c.Database.FilePath)
+	case "mysql", "postgres":
+		required("database.host", "DB_HOST", c.Database.Host)
+		required("database.name", "DB_NAME", c.Database.Name)
+		required("database.user", "DB_USER", c.Database.User)
+		required("database.password", "DB_PASSWORD", c.Database.Password)
+		port("database.port", "DB_PORT", c.Database.Port)
+	}
+	oneOf("database.migrate", "DB_MIGRATE", c.Database.Migrate, "", "false", database.MigrationModeUp, database.MigrationModeCheck)
+
+	if c.Cache.Enabled {
+		required("cache.host", "CACHE_HOST", c.Cache.Host)
+		required("cache.port", "CACHE_PORT", c.Cache.Port)
+		port("cache.port", "CACHE_PORT", c.Cache.Port)
+		required("cache.password", "CACHE_PASSWORD", c.Cache.Password)
+	}
+
+	required("clients.file_path", "CLIENTS_FILE_PATH", c.Clients.FilePath)
+
+	if c.Password.MinLength < 1 {
+		fail("password.min_length", "PASSWORD_MIN_LENGTH", "must be at least 1, got %d", c.Password.MinLength)
+	}
+	if c.Password.MaxLength < c.Password.MinLength {
+		fail("password.max_length", "PASSWORD_MAX_LENGTH", "must not be less than the minimum length %d, got %d", c.Password.MinLength, c.Password.MaxLength)
+	}
+	if c.Password.MinScore > 4 {
+		fail("password.min_score", "PASSWORD_MIN_SCORE", "must be between 0 and 4, got %d", c.Password.MinScore)
+	}
+	if c.Password.Breach.ServeRange && c.Password.Breach.DataPath == "" {
+		fail("password.breach.serve_range", "PASSWORD_BREACH_SERVE_RANGE", "requires password.breach.data_path (PASSWORD_BREACH_DATA_PATH)")
+	}
+	oneOf("password.hash.algorithm", "PASSWORD_HASH_ALGORITHM", c.Password.Hash.Algorithm, password.AlgorithmArgon2id, password.AlgorithmBcrypt, password.AlgorithmScrypt)
+
+	oneOf("mail.driver", "MAIL_DRIVER", c.Mail.Driver, "", mailer.DriverSMTP, mailer.DriverLog)
+	if c.Mail.Driver == mailer.DriverSMTP {
+		required("mail.host", "MAIL_HOST", c.Mail.Host)
+		required("mail.from", "MAIL_FROM", c.Mail.From)
+		port("mail.port", "MAIL_PORT", c.Mail.Port)
+	}
+	oneOf("notify.push.driver", "PUSH_DRIVER", c.Notify.Push.Driver, "", notify.PushDriverHTTP, notify.PushDriverLog)
+	if c.Notify.Push.Driver == notify.PushDriverHTTP {
+		required("notify.push.gateway_url", "PUSH_GATEWAY_URL", c.Notify.Push.GatewayURL)
+	}
+
+	for _, score := range []struct {
+		key, env string
+		value    int
+	}{
+		{"risk.require_mfa_score", "RISK_REQUIRE_MFA_SCORE", c.Risk.RequireMFAScore},
+		{"risk.require_email_score", "RISK_REQUIRE_EMAIL_SCORE", c.Risk.RequireEmailScore},
+		{"risk.block_score", "RISK_BLOCK_SCORE", c.Risk.BlockScore},
+	} {
+		if score.value > 100 {
+			fail(score.key, score.env, "must be between 0 and 100, got %d", score.value)
+		}
+	}
+
+	jobs := c.Scheduler.Jobs
+	if c.Lockout.Enabled && jobs.LoginAttemptCleanup > 0 && (jobs.LoginAttemptRetention < c.Lockout.Window || jobs.LoginAttemptRetention < c.Lockout.Duration) {
+		fail("scheduler.jobs.login_attempt_retention", "LOGIN_ATTEMPT_RETENTION", "must not be less than the lockout window and duration, got %s", jobs.LoginAttemptRetention)
+	}
+
+	nonNegative(reflect.ValueOf(c).Elem(), "", fail)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// nonNegative reports every number and duration below zero, none of the settings has a meaning for them
+func nonNegative(v reflect.Value, prefix string, fail func(key, env, format string, args ...any)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key := prefix + field.Tag.Get("yaml")
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			nonNegative(value, key+".", fail)
+		case field.Type.Kind() == reflect.Int || field.Type == durationType:
+			if value.Int() < 0 {
+				fail(key, field.Tag.Get("env"), "must not be negative")
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// auditChainHeadID is the primary key of the only AuditChainHead row
+const auditChainHeadID = 1
+
+// AuditEvent is an append-only record of a security-relevant action. Every event stores the hash of the previous
+// event, so editing or removing a row breaks the chain from that point on.
+type AuditEvent struct {
+	ID        uint64     `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	ActorID   *uuid.UUID `gorm:"default:null;index" json:"actor_id,omitempty"`
+	UserID    *uuid.UUID `gorm:"default:null;index" json:"user_id,omitempty"`
+	ClientID  string     `gorm:"default:null" json:"client_id,omitempty"`
+	IP        string     `gorm:"default:null" json:"ip,omitempty"`
+	UserAgent string     `gorm:"default:null" json:"user_agent,omitempty"`
+	Action    string     `gorm:"not null;index" json:"action"`
+	Outcome   string     `gorm:"not null" json:"outcome"`
+	Metadata  string     `gorm:"type:text;default:null" json:"metadata,omitempty"`
+	PrevHash  string     `gorm:"type:varchar(64);uniqueIndex" json:"prev_hash"`
+	Hash      string     `gorm:"type:varchar(64);not null" json:"hash"`
+}
+
+// AuditChainHead holds the hash of the last event in the chain. Appends lock its single row, so they are written one
+// at a time in chain order.
+type AuditChainHead struct {
+	ID   uint64 `gorm:"primaryKey;autoIncrement:false" json:"id"`
+	Hash string `gorm:"type:varchar(64);not null" json:"hash"`
+}
+
+// AuditEventFilter narrows down the events returned by GetAuditEvents
+type AuditEventFilter struct {
+	UserID *uuid.UUID
+	Action string
+	From   *time.Time
+	To     *time.Time
+}
+
+// AuditChainResult is the outcome of verifying the audit chain
+type AuditChainResult struct {
+	Valid    bool
+	Checked  uint64
+	BrokenAt *uint64
+}
+
+// SetMetadata stores the metadata as a JSON object with sorted keys, so the hash input is deterministic
+func (e *AuditEvent) SetMetadata(metadata map[string]string) error {
+	if len(metadata) == 0 {
+		e.Metadata = ""
+		return nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	e.Metadata = string(encoded)
+	return nil
+}
+
+// GetMetadata decodes the metadata of the event
+func (e *AuditEvent) GetMetadata() map[string]string {
+	metadata := map[string]string{}
+	if e.Metadata != "" {
+		_ = json.Unmarshal([]byte(e.Metadata), &metadata)
+	}
+	return metadata
+}
+
+// ComputeHash returns the hash of the event content chained to its previous hash
+func (e *AuditEvent) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		fmt.Sprint(e.CreatedAt.UTC().UnixMilli()),
+		uuidString(e.ActorID),
+		uuidString(e.UserID),
+		e.ClientID,
+		e.IP,
+		e.UserAgent,
+		e.Action,
+		e.Outcome,
+		e.Metadata,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// AppendAuditEvent links the event to the last event in the chain and stores it. The chain head row is locked for the
+// duration of the transaction, so concurrent appends wait for each other instead of pointing at the same predecessor.
+func AppendAuditEvent(event *AuditEvent) error {
+	// Millisecond precision survives a round trip through every supported database
+	event.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var head AuditChainHead
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", auditChainHeadID).Take(&head).Error
+		if err != nil {
+			return err
+		}
+		event.ID = 0
+		event.PrevHash = head.Hash
+		event.Hash = event.ComputeHash()
+		if err = tx.Create(event).Error; err != nil {
+			return err
+		}
+		return tx.Model(&head).Update("hash", event.Hash).Error
+	})
+}
+
+// VerifyAuditChain walks the chain in insertion order and reports the first event whose link or content does not
+// match its stored hash
+func VerifyAuditChain() (*AuditChainResult, error) {
+	result := &AuditChainResult{Valid: true}
+	var events []AuditEvent
+	prevHash := ""
+	err := DB.Model(&AuditEvent{}).Order("id asc").FindInBatches(&events, 500, func(tx *gorm.DB, batch int) error {
+		for i := range events {
+			event := events[i]
+			result.Checked++
+			if event.PrevHash != prevHash || event.ComputeHash() != event.Hash {
+				result.Valid = false
+				result.BrokenAt = &event.ID
+				return errStopVerification
+			}
+			prevHash = event.Hash
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errStopVerification) {
+		return nil, err
+	}
+	return result, nil
+}
+
+var errStopVerification = errors.New("audit chain broken")
+
+// GetAuditEvents returns a page of events matching the filter, newest first
+func GetAuditEvents(filter AuditEventFilter, md pagination.PageMetadata) ([]AuditEvent, int64, error) {
+	var count int64
+	var events []AuditEvent
+
+	query := DB.Model(&AuditEvent{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ? OR actor_id = ?", *filter.UserID, *filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id desc").Offset(int(md.Offset())).Limit(int(md.PageSize)).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, count, nil
+}
+
+// GetAuditEventsByUserId returns every audit event about the user, oldest first
+func GetAuditEventsByUserId(userID uuid.UUID) ([]AuditEvent, error) {
+	var events []AuditEvent
+	if err := DB.Where("user_id = ?", userID).Order("id asc").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
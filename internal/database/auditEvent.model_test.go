@@ -0,0 +1,93 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestAuditEventComputeHash(t *testing.T) {
+	userID := uuid.New()
+	event := AuditEvent{UserID: &userID, Action: "auth.sign_in", Outcome: AuditOutcomeSuccess}
+	hash := event.ComputeHash()
+	if hash != event.ComputeHash() {
+		t.Errorf("Expected hash to be deterministic")
+	}
+
+	event.Outcome = AuditOutcomeFailure
+	if hash == event.ComputeHash() {
+		t.Errorf("Expected hash to change when the content changes")
+	}
+
+	event.Outcome = AuditOutcomeSuccess
+	event.PrevHash = "abc"
+	if hash == event.ComputeHash() {
+		t.Errorf("Expected hash to change when the previous hash changes")
+	}
+}
+
+func TestAuditEventSetMetadata(t *testing.T) {
+	event := AuditEvent{}
+	if err := event.SetMetadata(map[string]string{"b": "2", "a": "1"}); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+	if event.Metadata != `{"a":"1","b":"2"}` {
+		t.Errorf("Expected sorted metadata, got %s", event.Metadata)
+	}
+	if event.GetMetadata()["b"] != "2" {
+		t.Errorf("Expected metadata to round trip, got %v", event.GetMetadata())
+	}
+}
+
+func TestAuditChain(t *testing.T) {
+	SetupTestDB(t)
+
+	userID := uuid.New()
+	for _, action := range []string{"auth.sign_up", "auth.sign_in", "user.password_change"} {
+		if err := AppendAuditEvent(&AuditEvent{UserID: &userID, Action: action, Outcome: AuditOutcomeSuccess}); err != nil {
+			t.Fatalf("AppendAuditEvent failed: %v", err)
+		}
+	}
+
+	result, err := VerifyAuditChain()
+	if err != nil {
+		t.Fatalf("VerifyAuditChain failed: %v", err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Errorf("Expected a valid chain of 3 events, got valid=%v checked=%d", result.Valid, result.Checked)
+	}
+	var head AuditChainHead
+	var last AuditEvent
+	if err = DB.Take(&head, auditChainHeadID).Error; err != nil {
+		t.Fatalf("Failed to load the chain head: %v", err)
+	}
+	if err = DB.Order("id desc").Take(&last).Error; err != nil {
+		t.Fatalf("Failed to load the last event: %v", err)
+	}
+	if head.Hash != last.Hash {
+		t.Errorf("Expected the chain head to hold the last hash %s, got %s", last.Hash, head.Hash)
+	}
+
+	if err = DB.Model(&AuditEvent{}).Where("action = ?", "auth.sign_in").Update("outcome", AuditOutcomeFailure).Error; err != nil {
+		t.Fatalf("Failed to tamper with event: %v", err)
+	}
+	result, err = VerifyAuditChain()
+	if err != nil {
+		t.Fatalf("VerifyAuditChain failed: %v", err)
+	}
+	if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 2 {
+		t.Errorf("Expected the chain to break at event 2, got valid=%v broken_at=%v", result.Valid, result.BrokenAt)
+	}
+}
+
+func TestAuditChainRejectsForkedEvent(t *testing.T) {
+	SetupTestDB(t)
+
+	if err := AppendAuditEvent(&AuditEvent{Action: "auth.sign_in", Outcome: AuditOutcomeSuccess}); err != nil {
+		t.Fatalf("AppendAuditEvent failed: %v", err)
+	}
+	fork := AuditEvent{Action: "auth.sign_in", Outcome: AuditOutcomeSuccess}
+	fork.Hash = fork.ComputeHash()
+	if err := DB.Create(&fork).Error; err == nil {
+		t.Errorf("Expected a second event with the same previous hash to be rejected")
+	}
+}
@@ -1,10 +1,13 @@
 package database
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/google/uuid"
 	"github.com/usercoredev/usercore/internal/pagination"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
 	"reflect"
 	"time"
 )
@@ -46,9 +49,9 @@ func getSortableFields(v interface{}) map[string]bool {
 	return fields
 }
 
-func (bm *BaseModel) ConvertToOrder(metadata pagination.Metadata) string {
+func (bm *BaseModel) ConvertToOrder(metadata pagination.PageMetadata) string {
 	if _, ok := getSortableFields(bm)[metadata.OrderBy]; !ok {
-		log.Println("Invalid order by field, defaulting to created_at")
+		slog.Warn("invalid order by field, defaulting to created_at", "order_by", metadata.OrderBy)
 		metadata.OrderBy = "created_at"
 	}
 
@@ -63,3 +66,14 @@ func (bm *BaseModel) ConvertToOrder(metadata pagination.Metadata) string {
 
 	return metadata.OrderBy + " " + metadata.Order
 }
+
+// codeHashKey is the server secret of HashCode, set by Connect
+var codeHashKey []byte
+
+// HashCode returns the stored form of a one-time code or token sent to a user, an HMAC with the server secret so that
+// short codes cannot be found from a leaked database by trying them all
+func HashCode(code string) string {
+	mac := hmac.New(sha256.New, codeHashKey)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
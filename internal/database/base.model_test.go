@@ -1,7 +1,7 @@
 package database
 
 import (
-	"github.com/usercoredev/usercore/pagination"
+	"github.com/usercoredev/usercore/internal/pagination"
 	"testing"
 )
 
@@ -9,6 +9,7 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"log/slog"
 	"net/url"
 	"os"
 	"strings"
@@ -18,29 +19,39 @@ import (
 var DB *gorm.DB
 
 type Database struct {
-	Engine          string
-	Database        string
-	DatabaseFile    string // For SQLite
-	Charset         string
-	User            string
-	Password        string
-	PasswordFile    string
-	Host            string
-	Port            string
-	Certificate     string
+	Engine       string
+	Database     string
+	DatabaseFile string // For SQLite
+	Charset      string
+	User         string
+	Password     string
+	Host         string
+	Port         string
+	Certificate  string
+	// CodeHashKey is the secret one-time codes are hashed with before they are stored
+	CodeHashKey string
+	// EnableMigration applies the pending migrations at startup when "true", and refuses to start while migrations are
+	// pending when "check"
 	EnableMigration string
 }
 
 func (d *Database) Connect() (err error) {
+	codeHashKey = []byte(d.CodeHashKey)
 	DB, err = d.configuration()
 	if err != nil {
 		return
 	} else {
-		fmt.Println("Database connection successful")
-		if d.EnableMigration == "true" {
-			fmt.Println("Migrating database")
-			Migrate()
-			fmt.Println("Database migration successful")
+		slog.Info("database connection successful", "engine", d.Engine)
+		switch d.EnableMigration {
+		case MigrationModeUp:
+			slog.Info("migrating database")
+			var applied []Migration
+			if applied, err = MigrateUp(); err != nil {
+				return
+			}
+			slog.Info("database migration successful", "applied", len(applied))
+		case MigrationModeCheck:
+			err = CheckMigrations()
 		}
 	}
 
@@ -48,15 +59,8 @@ func (d *Database) Connect() (err error) {
 }
 
 func (d *Database) configuration() (*gorm.DB, error) {
-	if d.PasswordFile == "" && d.Password == "" {
-		return nil, fmt.Errorf("password or password file is required")
-	}
-	if d.PasswordFile != "" {
-		bin, err := os.ReadFile(d.PasswordFile)
-		if err != nil {
-			return nil, err
-		}
-		d.Password = string(bin)
+	if d.Engine != "sqlite" && d.Password == "" {
+		return nil, fmt.Errorf("password is required")
 	}
 
 	d.Password = url.QueryEscape(strings.TrimSpace(d.Password))
@@ -94,7 +98,7 @@ func (d *Database) configuration() (*gorm.DB, error) {
 
 func (d *Database) connectSQLite() (db *gorm.DB, dbError error) {
 	dbDSN := fmt.Sprintf("%s?parseTime=true&charset=utf8mb4", d.DatabaseFile)
-	db, dbError = gorm.Open(sqlite.Open(dbDSN), &gorm.Config{})
+	db, dbError = gorm.Open(sqlite.Open(dbDSN), &gorm.Config{TranslateError: true})
 	return
 }
 
@@ -150,19 +154,3 @@ func (d *Database) connectPostgres() (*gorm.DB, error) {
 
 	return db, nil
 }
-
-func Migrate() {
-	err := DB.AutoMigrate(
-		User{},
-		Profile{},
-		PasswordReset{},
-		Device{},
-		Session{},
-		Role{},
-		Permission{},
-		SocialProvider{},
-	)
-	if err != nil {
-		panic(err)
-	}
-}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusReady      = "ready"
+	DataExportStatusFailed     = "failed"
+	DataExportStatusExpired    = "expired"
+)
+
+// DataExport is an archive of everything stored about a user, generated in the background. Only the hash of the
+// download token is stored.
+type DataExport struct {
+	UINTBaseModel
+	UserID      uuid.UUID  `gorm:"not null;index" json:"-"`
+	Status      string     `gorm:"not null;index" json:"status"`
+	TokenHash   string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Archive     []byte     `gorm:"default:null" json:"-"`
+	Error       string     `gorm:"type:text;default:null" json:"-"`
+	LockedUntil *time.Time `gorm:"default:null" json:"-"`
+	CompletedAt *time.Time `gorm:"default:null" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `gorm:"default:null;index" json:"expires_at,omitempty"`
+}
+
+// HashDataExportToken returns the stored form of a download token
+func HashDataExportToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateDataExport stores a pending export for the user, downloadable with the given token once it is ready
+func CreateDataExport(userID uuid.UUID, token string) (*DataExport, error) {
+	export := DataExport{
+		UserID:    userID,
+		Status:    DataExportStatusPending,
+		TokenHash: HashDataExportToken(token),
+	}
+	if err := DB.Create(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func GetDataExportById(id uint64, userID uuid.UUID) (*DataExport, error) {
+	var export DataExport
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func GetDataExportByToken(token string) (*DataExport, error) {
+	var export DataExport
+	if err := DB.Where("token_hash = ?", HashDataExportToken(token)).First(&export).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// HasPendingDataExport reports whether an export of the user is still being generated
+func HasPendingDataExport(userID uuid.UUID) (bool, error) {
+	var count int64
+	err := DB.Model(&DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{DataExportStatusPending, DataExportStatusProcessing}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ClaimPendingDataExport locks the oldest pending export, or an export whose worker stopped, until the given time. It
+// returns gorm.ErrRecordNotFound when there is nothing to do.
+func ClaimPendingDataExport(now, until time.Time) (*DataExport, error) {
+	for {
+		var export DataExport
+		err := DB.Where("status = ? OR (status = ? AND locked_until < ?)", DataExportStatusPending, DataExportStatusProcessing, now).
+			Order("id asc").
+			First(&export).Error
+		if err != nil {
+			return nil, err
+		}
+		result := DB.Model(&DataExport{}).
+			Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", export.ID, export.Status, now).
+			Updates(map[string]interface{}{"status": DataExportStatusProcessing, "locked_until": until})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			export.Status = DataExportStatusProcessing
+			export.LockedUntil = &until
+			return &export, nil
+		}
+	}
+}
+
+// Complete stores the archive and makes it downloadable until the given time
+func (e *DataExport) Complete(archive []byte, now, expiresAt time.Time) error {
+	e.Status = DataExportStatusReady
+	e.Archive = archive
+	e.CompletedAt = &now
+	e.ExpiresAt = &expiresAt
+	e.LockedUntil = nil
+	return DB.Model(e).Select("Status", "Archive", "CompletedAt", "ExpiresAt", "LockedUntil").Updates(e).Error
+}
+
+// Fail records why the archive could not be generated
+func (e *DataExport) Fail(reason string) error {
+	e.Status = DataExportStatusFailed
+	e.Error = reason
+	e.LockedUntil = nil
+	return DB.Model(e).Select("Status", "Error", "LockedUntil").Updates(e).Error
+}
+
+// IsDownloadable reports whether the archive is ready and its download token has not expired
+func (e *DataExport) IsDownloadable(now time.Time) bool {
+	return e.Status == DataExportStatusReady && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
+
+// ExpireDataExports drops the archives whose download token expired
+func ExpireDataExports(now time.Time) (int64, error) {
+	result := DB.Model(&DataExport{}).
+		Where("status = ? AND expires_at < ?", DataExportStatusReady, now).
+		Updates(map[string]interface{}{"status": DataExportStatusExpired, "archive": gorm.Expr("NULL")})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,20 @@
+// Package dbtest sets up the database of tests in the packages that use it
+package dbtest
+
+import (
+	"github.com/usercoredev/usercore/internal/database"
+	"testing"
+)
+
+// Setup replaces database.DB with a migrated in-memory sqlite database named after the test, and restores it when the
+// test ends
+func Setup(t testing.TB) {
+	t.Helper()
+	database.SetupTestDB(t)
+}
+
+// CreateUser stores a user with the email
+func CreateUser(t testing.TB, email string) *database.User {
+	t.Helper()
+	return database.CreateTestUser(t, email)
+}
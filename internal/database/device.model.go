@@ -1,17 +1,26 @@
 package database
 
 import (
+	"errors"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strconv"
 )
 
+// Device is the device a session was signed in from. DeviceID is the installation identifier sent by the client, the
+// sessions of the same installation share it, so a device can list and revoke all of them at once.
 type Device struct {
 	UINTBaseModel
-	UserID    uuid.UUID `gorm:"default:null" json:"-"`
-	SessionID string    `gorm:"default:null" json:"session_id,omitempty"`
-	Name      string    `gorm:"default:null" json:"name,omitempty"`
-	IP        string    `gorm:"default:null" json:"ip,omitempty"`
-	OS        string    `gorm:"default:null" json:"os,omitempty"`
-	Token     string    `gorm:"default:null" json:"token,omitempty"`
+	UserID         uuid.UUID `gorm:"default:null;index" json:"-"`
+	SessionID      string    `gorm:"default:null;index" json:"session_id,omitempty"`
+	DeviceID       string    `gorm:"type:varchar(64);default:null;index" json:"device_id,omitempty"`
+	Name           string    `gorm:"default:null" json:"name,omitempty"`
+	IP             string    `gorm:"default:null" json:"ip,omitempty"`
+	OS             string    `gorm:"default:null" json:"os,omitempty"`
+	OSVersion      string    `gorm:"default:null" json:"os_version,omitempty"`
+	Browser        string    `gorm:"default:null" json:"browser,omitempty"`
+	BrowserVersion string    `gorm:"default:null" json:"browser_version,omitempty"`
+	Token          string    `gorm:"default:null" json:"token,omitempty"`
 }
 
 // GetDevicesByUserId gets all devices of a user
@@ -22,3 +31,103 @@ func GetDevicesByUserId(userId uuid.UUID) ([]Device, error) {
 	}
 	return devices, nil
 }
+
+// GetDevicesByUserIds returns the devices of the users, for listing many users without a query per user
+func GetDevicesByUserIds(userIds []uuid.UUID) ([]Device, error) {
+	var devices []Device
+	if len(userIds) == 0 {
+		return devices, nil
+	}
+	if err := DB.Where("user_id IN ?", userIds).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// GetUserDevice gets the rows of a device of a user, one for each of its sessions
+func GetUserDevice(userId uuid.UUID, deviceId string) ([]Device, error) {
+	var devices []Device
+	if err := DB.Where("user_id = ? AND device_id = ?", userId, deviceId).Order("id asc").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return devices, nil
+}
+
+// SaveDevice stores the device of the session, replacing what was registered for it before. A device without a
+// DeviceID keeps the one it was registered with, or gets a new one. A push token belongs to one device only, so it is
+// removed from any other device that registered it.
+func (session *Session) SaveDevice(device Device) (*Device, error) {
+	sessionID := strconv.FormatUint(session.ID, 10)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing Device
+		err := tx.Where("session_id = ?", sessionID).First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if device.DeviceID == "" {
+			device.DeviceID = existing.DeviceID
+		}
+		if device.DeviceID == "" {
+			device.DeviceID = uuid.NewString()
+		}
+		device.ID = existing.ID
+		device.CreatedAt = existing.CreatedAt
+		device.UserID = session.UserID
+		device.SessionID = sessionID
+		if err = tx.Save(&device).Error; err != nil {
+			return err
+		}
+		if device.Token != "" {
+			return tx.Model(&Device{}).Where("token = ? AND id <> ?", device.Token, device.ID).Update("token", nil).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// RenameDevice renames every session of a device of the user
+func RenameDevice(userId uuid.UUID, deviceId string, name string) error {
+	result := DB.Model(&Device{}).Where("user_id = ? AND device_id = ?", userId, deviceId).Update("name", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeDevice deletes a device of the user together with its sessions and returns the IDs of the deleted sessions
+func RevokeDevice(userId uuid.UUID, deviceId string) ([]uint64, error) {
+	var sessionIDs []uint64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var devices []Device
+		if err := tx.Where("user_id = ? AND device_id = ?", userId, deviceId).Find(&devices).Error; err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		for _, device := range devices {
+			if id, err := strconv.ParseUint(device.SessionID, 10, 64); err == nil {
+				sessionIDs = append(sessionIDs, id)
+			}
+		}
+		if len(sessionIDs) > 0 {
+			if err := tx.Where("user_id = ? AND id IN ?", userId, sessionIDs).Delete(&Session{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ? AND device_id = ?", userId, deviceId).Delete(&Device{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessionIDs, nil
+}
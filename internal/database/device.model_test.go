@@ -0,0 +1,156 @@
+package database
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func createTestSession(t *testing.T, user *User, refreshToken string) *Session {
+	t.Helper()
+	session := Session{UserID: user.ID, RefreshToken: refreshToken, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := DB.Create(&session).Error; err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	return &session
+}
+
+func TestSessionSaveDevice(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	session := createTestSession(t, user, "refresh")
+
+	first, err := session.SaveDevice(Device{Name: "Phone", OS: "Android", Token: "push"})
+	if err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+	if first.DeviceID == "" {
+		t.Fatal("Expected a device ID to be assigned")
+	}
+
+	second, err := session.SaveDevice(Device{Name: "My phone", OS: "Android", OSVersion: "14"})
+	if err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+	if second.ID != first.ID || second.DeviceID != first.DeviceID {
+		t.Errorf("Expected the device of the session to be updated, got %+v", second)
+	}
+	devices, err := session.GetDevices()
+	if err != nil || len(devices) != 1 || devices[0].Name != "My phone" || devices[0].OSVersion != "14" {
+		t.Errorf("Expected one updated device, got %+v (%v)", devices, err)
+	}
+}
+
+func TestSessionSaveDeviceMovesPushToken(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	old := createTestSession(t, user, "old")
+	current := createTestSession(t, user, "current")
+
+	if _, err := old.SaveDevice(Device{DeviceID: "install-1", Token: "push"}); err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+	if _, err := current.SaveDevice(Device{DeviceID: "install-1", Token: "push"}); err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+	previous, err := old.GetDevice()
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if previous.Token != "" {
+		t.Errorf("Expected the push token to be removed from the old session, got %q", previous.Token)
+	}
+}
+
+func TestRenameAndRevokeDevice(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	other := CreateTestUser(t, "john@example.com")
+	first := createTestSession(t, user, "first")
+	second := createTestSession(t, user, "second")
+	kept := createTestSession(t, user, "kept")
+	for _, session := range []*Session{first, second} {
+		if _, err := session.SaveDevice(Device{DeviceID: "install-1"}); err != nil {
+			t.Fatalf("SaveDevice failed: %v", err)
+		}
+	}
+	if _, err := kept.SaveDevice(Device{DeviceID: "install-2"}); err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+
+	if err := RenameDevice(other.ID, "install-1", "Laptop"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected another user not to rename the device, got %v", err)
+	}
+	if err := RenameDevice(user.ID, "install-1", "Laptop"); err != nil {
+		t.Fatalf("RenameDevice failed: %v", err)
+	}
+	devices, err := GetUserDevice(user.ID, "install-1")
+	if err != nil || len(devices) != 2 || devices[0].Name != "Laptop" || devices[1].Name != "Laptop" {
+		t.Errorf("Expected every session of the device to be renamed, got %+v (%v)", devices, err)
+	}
+
+	if _, err = RevokeDevice(other.ID, "install-1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected another user not to revoke the device, got %v", err)
+	}
+	sessionIDs, err := RevokeDevice(user.ID, "install-1")
+	if err != nil {
+		t.Fatalf("RevokeDevice failed: %v", err)
+	}
+	if len(sessionIDs) != 2 {
+		t.Errorf("Expected two revoked sessions, got %v", sessionIDs)
+	}
+	for _, refreshToken := range []string{"first", "second"} {
+		if _, err = GetSessionByRefreshToken(refreshToken); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Expected session %q to be revoked, got %v", refreshToken, err)
+		}
+	}
+	if _, err = GetSessionByRefreshToken("kept"); err != nil {
+		t.Errorf("Expected the session of the other device to be kept, got %v", err)
+	}
+	if _, err = GetUserDevice(user.ID, "install-1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the device to be deleted, got %v", err)
+	}
+}
+
+func TestSessionDeleteRemovesDevice(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	session := createTestSession(t, user, "refresh")
+	if _, err := session.SaveDevice(Device{DeviceID: "install-1", Token: "push"}); err != nil {
+		t.Fatalf("SaveDevice failed: %v", err)
+	}
+
+	if err := session.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := GetUserDevice(user.ID, "install-1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the device of the session to be deleted, got %v", err)
+	}
+}
+
+func TestGetDevicesByUserIds(t *testing.T) {
+	SetupTestDB(t)
+	jane := CreateTestUser(t, "jane@example.com")
+	john := CreateTestUser(t, "john@example.com")
+	other := CreateTestUser(t, "other@example.com")
+	for _, user := range []*User{jane, john, other} {
+		if _, err := createTestSession(t, user, user.Email).SaveDevice(Device{Name: user.Email}); err != nil {
+			t.Fatalf("SaveDevice failed: %v", err)
+		}
+	}
+
+	devices, err := GetDevicesByUserIds([]uuid.UUID{jane.ID, john.ID})
+	if err != nil || len(devices) != 2 {
+		t.Fatalf("Expected the devices of two users, got %+v (%v)", devices, err)
+	}
+	for _, device := range devices {
+		if device.UserID == other.ID {
+			t.Errorf("Expected the devices of other users to be left out, got %+v", device)
+		}
+	}
+	if devices, err = GetDevicesByUserIds(nil); err != nil || len(devices) != 0 {
+		t.Errorf("Expected no devices without users, got %+v (%v)", devices, err)
+	}
+}
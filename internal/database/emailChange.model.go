@@ -0,0 +1,240 @@
+package database
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	// ErrEmailChangeUsed is returned when an email change was confirmed, reverted or replaced in the meantime
+	ErrEmailChangeUsed = errors.New("email change already used")
+	// ErrEmailChangeStale is returned when the email of the user changed since the email change was requested
+	ErrEmailChangeStale = errors.New("email of the user changed in the meantime")
+	// ErrEmailChangePending is returned when another user has a pending change to the email
+	ErrEmailChangePending = errors.New("another email change to the email is pending")
+)
+
+// EmailChange is a pending or confirmed change of the email of a user. The new email is only set on the user once the
+// code sent to it is confirmed, and the previous email can revert the change until RevertExpiresAt.
+type EmailChange struct {
+	UINTBaseModel
+	UserID           uuid.UUID  `gorm:"default:null;index" json:"-"`
+	PreviousEmail    string     `json:"previous_email"`
+	PreviousVerified bool       `json:"-"`
+	NewEmail         string     `gorm:"index" json:"new_email"`
+	CodeHash         string     `gorm:"default:null" json:"-"`
+	Attempts         int        `gorm:"default:0" json:"-"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevertExpiresAt  time.Time  `json:"-"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+	RevertedAt       *time.Time `json:"reverted_at,omitempty"`
+	// PendingEmail is the new email while the change can be confirmed and null afterwards, its unique index allows one
+	// pending change per email so that a code is always checked against the change of the user it was sent to
+	PendingEmail *string `gorm:"uniqueIndex;default:null" json:"-"`
+}
+
+// CreateEmailChange stores a pending change of the email of the user and cancels the previous pending changes. It
+// fails with ErrEmailChangePending when another user has a change to the email that has not expired.
+func CreateEmailChange(user *User, newEmail, code string, ttl, revertTTL time.Duration) (*EmailChange, error) {
+	now := time.Now()
+	change := &EmailChange{
+		UserID:           user.ID,
+		PreviousEmail:    user.Email,
+		PreviousVerified: user.EmailVerified,
+		NewEmail:         newEmail,
+		PendingEmail:     &newEmail,
+		CodeHash:         HashCode(code),
+		ExpiresAt:        now.Add(ttl),
+		RevertExpiresAt:  now.Add(revertTTL),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&EmailChange{}).
+			Where("user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL", user.ID).
+			Updates(map[string]interface{}{"reverted_at": now, "pending_email": gorm.Expr("NULL")}).Error
+		if err != nil {
+			return err
+		}
+		// an expired change no longer holds the email
+		err = tx.Model(&EmailChange{}).
+			Where("pending_email = ? AND expires_at <= ?", newEmail, now).
+			Update("pending_email", gorm.Expr("NULL")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, ErrEmailChangePending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// GetPendingEmailChangeByEmail returns the change to the email that was neither confirmed nor cancelled, there is at
+// most one
+func GetPendingEmailChangeByEmail(email string) (*EmailChange, error) {
+	var change EmailChange
+	if err := DB.Where("pending_email = ?", email).First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetLatestEmailChange returns the last email change requested by the user
+func GetLatestEmailChange(userID uuid.UUID) (*EmailChange, error) {
+	var change EmailChange
+	if err := DB.Where("user_id = ?", userID).Order("created_at desc").Order("id desc").First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetEmailChangeById returns an email change of the user
+func GetEmailChangeById(id uint64, userID uuid.UUID) (*EmailChange, error) {
+	var change EmailChange
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetEmailChangesByUserId returns the email changes of a user, newest first
+func GetEmailChangesByUserId(userID uuid.UUID) ([]EmailChange, error) {
+	var changes []EmailChange
+	if err := DB.Where("user_id = ?", userID).Order("created_at desc").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// IsPending reports whether the change can still be confirmed
+func (change *EmailChange) IsPending(now time.Time, maxAttempts int) bool {
+	if change.ConfirmedAt != nil || change.RevertedAt != nil || !now.Before(change.ExpiresAt) {
+		return false
+	}
+	return maxAttempts <= 0 || change.Attempts < maxAttempts
+}
+
+// CheckCode compares the code with the stored hash and counts a wrong code as a failed attempt
+func (change *EmailChange) CheckCode(code string) (bool, error) {
+	if subtle.ConstantTimeCompare([]byte(HashCode(code)), []byte(change.CodeHash)) == 1 {
+		return true, nil
+	}
+	err := DB.Model(&EmailChange{}).Where("id = ?", change.ID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return false, err
+	}
+	change.Attempts++
+	return false, nil
+}
+
+// Confirm sets the new email on the user as verified. It fails with gorm.ErrDuplicatedKey when the email was taken in
+// the meantime, ErrEmailChangeUsed when the change is no longer pending and ErrEmailChangeStale when the email of the
+// user changed since the request.
+func (change *EmailChange) Confirm() (*User, error) {
+	var user User
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&EmailChange{}).
+			Where("id = ? AND confirmed_at IS NULL AND reverted_at IS NULL", change.ID).
+			Updates(map[string]interface{}{"confirmed_at": now, "pending_email": gorm.Expr("NULL")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmailChangeUsed
+		}
+		result = tx.Model(&User{}).
+			Where("id = ? AND email = ?", change.UserID, change.PreviousEmail).
+			Updates(map[string]interface{}{
+				"email":                change.NewEmail,
+				"email_verified":       true,
+				"email_verify_code":    gorm.Expr("NULL"),
+				"email_verify_sent_at": gorm.Expr("NULL"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmailChangeStale
+		}
+		if err := tx.First(&user, "id = ?", change.UserID).Error; err != nil {
+			return err
+		}
+		change.ConfirmedAt = &now
+		change.PendingEmail = nil
+		eventData := user.EventData()
+		eventData.PreviousEmail = change.PreviousEmail
+		return EnqueueOutboxEvent(tx, EventUserEmailChanged, user.ID, eventData)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Revert cancels a pending change, or gives the user back the previous email of a confirmed change, cancels the other
+// pending changes and revokes all sessions of the user. The previous email is restored even when the email was changed
+// again since, so that its owner can recover the account. It returns the user when the email was restored.
+func (change *EmailChange) Revert() (*User, error) {
+	var user *User
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&EmailChange{}).
+			Where("id = ? AND reverted_at IS NULL", change.ID).
+			Updates(map[string]interface{}{"reverted_at": now, "pending_email": gorm.Expr("NULL")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmailChangeUsed
+		}
+		change.RevertedAt = &now
+		change.PendingEmail = nil
+		if change.ConfirmedAt == nil {
+			return nil
+		}
+		user = &User{}
+		if err := tx.First(user, "id = ?", change.UserID).Error; err != nil {
+			return err
+		}
+		currentEmail := user.Email
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"email":          change.PreviousEmail,
+			"email_verified": change.PreviousVerified,
+		}).Error
+		if err != nil {
+			return err
+		}
+		user.Email = change.PreviousEmail
+		user.EmailVerified = change.PreviousVerified
+		err = tx.Model(&EmailChange{}).
+			Where("user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL", change.UserID).
+			Updates(map[string]interface{}{"reverted_at": now, "pending_email": gorm.Expr("NULL")}).Error
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("user_id = ?", change.UserID).Delete(&Session{}).Error; err != nil {
+			return err
+		}
+		eventData := user.EventData()
+		eventData.PreviousEmail = currentEmail
+		return EnqueueOutboxEvent(tx, EventUserEmailChanged, user.ID, eventData)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteStaleEmailChanges removes the changes that can neither be confirmed nor reverted since the time
+func DeleteStaleEmailChanges(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("expires_at < ? AND revert_expires_at < ?", before, before).Delete(&EmailChange{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestEmailChangeConfirm(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+
+	change, err := CreateEmailChange(user, "jane@example.org", "abc123", time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CreateEmailChange failed: %v", err)
+	}
+	stored, _ := GetUserByID(user.ID, false)
+	if stored.Email != "jane@example.com" {
+		t.Fatalf("Expected the email to stay unchanged until confirmed, got %s", stored.Email)
+	}
+
+	pending, err := GetPendingEmailChangeByEmail("jane@example.org")
+	if err != nil || pending.ID != change.ID {
+		t.Fatalf("GetPendingEmailChangeByEmail = %v, %v", pending, err)
+	}
+	if ok, _ := pending.CheckCode("wrong1"); ok {
+		t.Error("Expected a wrong code to be rejected")
+	}
+	if ok, _ := pending.CheckCode("abc123"); !ok {
+		t.Error("Expected the code to be accepted")
+	}
+
+	updated, err := pending.Confirm()
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if updated.Email != "jane@example.org" || !updated.EmailVerified {
+		t.Errorf("Expected the verified new email, got %s (verified %v)", updated.Email, updated.EmailVerified)
+	}
+	if _, err = pending.Confirm(); !errors.Is(err, ErrEmailChangeUsed) {
+		t.Errorf("Expected a change to be confirmed once, got %v", err)
+	}
+	var events int64
+	DB.Model(&OutboxEvent{}).Where("event_type = ?", EventUserEmailChanged).Count(&events)
+	if events != 1 {
+		t.Errorf("Expected one email changed event, got %d", events)
+	}
+}
+
+func TestEmailChangeConfirmTakenEmail(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	change, err := CreateEmailChange(user, "taken@example.com", "abc123", time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CreateTestUser(t, "taken@example.com")
+
+	if _, err = change.Confirm(); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Expected the unique email to fail the confirmation, got %v", err)
+	}
+	stored, _ := GetEmailChangeById(change.ID, user.ID)
+	if stored.ConfirmedAt != nil {
+		t.Error("Expected a failed confirmation to be rolled back")
+	}
+}
+
+func TestEmailChangePendingForOneUser(t *testing.T) {
+	SetupTestDB(t)
+	jane := CreateTestUser(t, "jane@example.com")
+	john := CreateTestUser(t, "john@example.com")
+	change, err := CreateEmailChange(jane, "shared@example.com", "abc123", time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = CreateEmailChange(john, "shared@example.com", "def456", time.Hour, 24*time.Hour); !errors.Is(err, ErrEmailChangePending) {
+		t.Errorf("Expected a second pending change to the email to be rejected, got %v", err)
+	}
+	pending, err := GetPendingEmailChangeByEmail("shared@example.com")
+	if err != nil || pending.ID != change.ID {
+		t.Fatalf("Expected the change of the first user to stay pending, got %v, %v", pending, err)
+	}
+	if ok, _ := pending.CheckCode("abc123"); !ok {
+		t.Error("Expected the code of the first user to be accepted")
+	}
+
+	if _, err = CreateEmailChange(jane, "shared@example.com", "ghi789", time.Hour, 24*time.Hour); err != nil {
+		t.Errorf("Expected the user to request the change again, got %v", err)
+	}
+	DB.Model(&EmailChange{}).Where("pending_email IS NOT NULL").Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err = CreateEmailChange(john, "shared@example.com", "def456", time.Hour, 24*time.Hour); err != nil {
+		t.Errorf("Expected an expired change not to hold the email, got %v", err)
+	}
+}
+
+func TestEmailChangeRevert(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	DB.Model(user).Update("email_verified", true)
+	user.EmailVerified = true
+	if err := DB.Create(&Session{UserID: user.ID, RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := CreateEmailChange(user, "first@example.org", "abc123", time.Hour, 24*time.Hour)
+	second, _ := CreateEmailChange(user, "second@example.org", "def456", time.Hour, 24*time.Hour)
+	if stored, _ := GetEmailChangeById(first.ID, user.ID); stored.IsPending(time.Now(), 5) {
+		t.Error("Expected a newer change to cancel the pending one")
+	}
+	if _, err := second.Confirm(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := second.Revert()
+	if err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if restored == nil || restored.Email != "jane@example.com" || !restored.EmailVerified {
+		t.Fatalf("Expected the previous email to be restored, got %+v", restored)
+	}
+	var sessions int64
+	DB.Model(&Session{}).Where("user_id = ?", user.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected the sessions to be revoked, %d left", sessions)
+	}
+	if _, err = second.Revert(); !errors.Is(err, ErrEmailChangeUsed) {
+		t.Errorf("Expected a change to be reverted once, got %v", err)
+	}
+}
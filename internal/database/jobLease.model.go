@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"math"
+	"time"
+)
+
+// JobLease is held by the replica that runs the scheduled jobs. The holder renews it while it runs, another replica
+// takes it over once it expires.
+type JobLease struct {
+	Name      string    `gorm:"type:varchar(64);primaryKey" json:"name"`
+	Holder    string    `gorm:"type:varchar(128);not null" json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Now returns the time of the database server, so replicas with drifting clocks agree on when a lease expires. A SQLite
+// database is local to the host, its time is the one of the host.
+func Now() (time.Time, error) {
+	var query string
+	switch DB.Dialector.Name() {
+	case "postgres":
+		query = "SELECT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)"
+	case "mysql":
+		query = "SELECT UNIX_TIMESTAMP(NOW(6))"
+	default:
+		return time.Now(), nil
+	}
+	var seconds float64
+	if err := DB.Raw(query).Scan(&seconds).Error; err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(int64(math.Round(seconds * 1e6))), nil
+}
+
+// AcquireJobLease takes or renews the lease for the holder until now plus ttl, now being the time of the database from
+// Now. It returns false while another holder has an unexpired lease.
+func AcquireJobLease(name, holder string, now time.Time, ttl time.Duration) (bool, error) {
+	result := DB.Model(&JobLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": now.Add(ttl)})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+	err := DB.Create(&JobLease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseJobLease gives up the lease of the holder, so another replica can take it over right away
+func ReleaseJobLease(name, holder string) error {
+	return DB.Where("name = ? AND holder = ?", name, holder).Delete(&JobLease{}).Error
+}
+
+// GetJobLease returns the current lease
+func GetJobLease(name string) (*JobLease, error) {
+	var lease JobLease
+	if err := DB.Where("name = ?", name).First(&lease).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"github.com/usercoredev/usercore/internal/pagination"
+	"time"
+)
+
+const (
+	JobOutcomeRunning = "running"
+	JobOutcomeSuccess = "success"
+	JobOutcomeFailure = "failure"
+)
+
+// JobRun records a run of a scheduled job. It is created when the job starts and updated once it finished.
+type JobRun struct {
+	UINTBaseModel
+	Job        string     `gorm:"type:varchar(64);not null;index" json:"job"`
+	Holder     string     `gorm:"type:varchar(128)" json:"holder"`
+	StartedAt  time.Time  `gorm:"index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Duration   int64      `json:"duration_ms"`
+	Outcome    string     `gorm:"type:varchar(16)" json:"outcome"`
+	Result     string     `gorm:"default:null" json:"result,omitempty"`
+	Error      string     `gorm:"type:text;default:null" json:"error,omitempty"`
+}
+
+func CreateJobRun(run *JobRun) error {
+	return DB.Create(run).Error
+}
+
+func UpdateJobRun(run *JobRun) error {
+	return DB.Save(run).Error
+}
+
+// GetLatestJobRun returns the last run of the job
+func GetLatestJobRun(job string) (*JobRun, error) {
+	var run JobRun
+	if err := DB.Where("job = ?", job).Order("started_at desc").Order("id desc").First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetJobRuns returns the runs of the job, newest first
+func GetJobRuns(job string, md pagination.PageMetadata) ([]JobRun, int64, error) {
+	var count int64
+	var runs []JobRun
+	query := DB.Model(&JobRun{}).Where("job = ?", job)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("started_at desc").Order("id desc").Offset(int(md.Offset())).Limit(int(md.PageSize)).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, count, nil
+}
+
+// DeleteJobRuns removes the runs started before the time
+func DeleteJobRuns(startedBefore time.Time) (int64, error) {
+	result := DB.Unscoped().Where("started_at < ?", startedBefore).Delete(&JobRun{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// LoginAttempt counts the failed sign-ins of an email or an IP address. It is used when the cache is unavailable.
+type LoginAttempt struct {
+	UINTBaseModel
+	Identifier    string     `gorm:"type:varchar(128);not null;uniqueIndex" json:"identifier"`
+	Failures      int        `gorm:"default:0" json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `gorm:"default:null" json:"locked_until,omitempty"`
+}
+
+// LoginAttemptEmailKey is the identifier of the failed sign-ins of an email. It hashes the normalized email, so that
+// the stores never hold the address itself.
+func LoginAttemptEmailKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "email:" + hex.EncodeToString(sum[:])
+}
+
+// GetLoginAttempt returns the failed sign-ins of the identifier. Failures older than the window are ignored.
+func GetLoginAttempt(identifier string, now time.Time, window time.Duration) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	err := DB.Where("identifier = ?", identifier).First(&attempt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &LoginAttempt{Identifier: identifier}, nil
+		}
+		return nil, err
+	}
+	attempt.expire(now, window)
+	return &attempt, nil
+}
+
+// RecordLoginFailure counts a failed sign-in of the identifier and locks it until lockUntil returns a non-zero time
+func RecordLoginFailure(identifier string, now time.Time, window time.Duration, lockUntil func(failures int) time.Time) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("identifier = ?", identifier).First(&attempt).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		attempt.Identifier = identifier
+		attempt.expire(now, window)
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		if until := lockUntil(attempt.Failures); !until.IsZero() {
+			attempt.LockedUntil = &until
+		}
+		return tx.Save(&attempt).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another failure of the same key created the row first
+		return RecordLoginFailure(identifier, now, window, lockUntil)
+	}
+	return &attempt, err
+}
+
+// DeleteLoginAttempt clears the failed sign-ins of the identifier
+func DeleteLoginAttempt(identifier string) error {
+	return DB.Unscoped().Where("identifier = ?", identifier).Delete(&LoginAttempt{}).Error
+}
+
+// DeleteStaleLoginAttempts removes the attempts whose last failure was before the time and that are not locked at now
+func DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) (int64, error) {
+	result := DB.Unscoped().Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", lastFailureBefore, now).
+		Delete(&LoginAttempt{})
+	return result.RowsAffected, result.Error
+}
+
+func (a *LoginAttempt) expire(now time.Time, window time.Duration) {
+	if a.LockedUntil != nil && now.After(*a.LockedUntil) {
+		a.LockedUntil = nil
+		a.Failures = 0
+	}
+	if a.LockedUntil == nil && now.Sub(a.LastFailureAt) > window {
+		a.Failures = 0
+	}
+}
@@ -0,0 +1,415 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrSchemaBehind is returned when the database misses migrations of this version
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// ErrMigrationLockLost is returned when another replica took over the migration lock while the migrations ran
+var ErrMigrationLockLost = errors.New("migration lock lost")
+
+// Startup modes of the migrations, see Database.EnableMigration
+const (
+	MigrationModeUp    = "true"
+	MigrationModeCheck = "check"
+)
+
+const (
+	migrationLockID = 1
+	// migrationLockTTL is how long a lock that is no longer renewed is kept before it is considered left behind by a
+	// crashed migration
+	migrationLockTTL = 2 * time.Minute
+	// migrationLockRenewal is how often the holder renews the lock while the migrations run
+	migrationLockRenewal = 30 * time.Second
+	// migrationLockWait is how long a migration waits for the lock held by another replica
+	migrationLockWait = 5 * time.Minute
+)
+
+// Migration is a versioned change of the schema, read from migrations/<dialect>/<version>_<name>.<up|down>.sql
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   uint64    `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// SchemaMigrationLock is held while migrations run, so replicas starting at once don't apply them twice
+type SchemaMigrationLock struct {
+	ID       int       `gorm:"primaryKey;autoIncrement:false"`
+	Holder   string    `gorm:"type:varchar(128);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigrationLock) TableName() string {
+	return "schema_migration_lock"
+}
+
+// MigrationStatus is a known migration and when it was applied, nil while pending
+type MigrationStatus struct {
+	Version   uint64     `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// migrationTables creates the tables keeping track of the migrations, they are the only ones created outside of them
+var migrationTables = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS "schema_migrations" ("version" bigint NOT NULL, "name" varchar(255) NOT NULL, "applied_at" timestamptz NOT NULL, PRIMARY KEY ("version"))`,
+		`CREATE TABLE IF NOT EXISTS "schema_migration_lock" ("id" integer NOT NULL, "holder" varchar(128) NOT NULL, "locked_at" timestamptz NOT NULL, PRIMARY KEY ("id"))`,
+	},
+	"mysql": {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` bigint unsigned NOT NULL, `name` varchar(255) NOT NULL, `applied_at` datetime(3) NOT NULL, PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migration_lock` (`id` int NOT NULL, `holder` varchar(128) NOT NULL, `locked_at` datetime(3) NOT NULL, PRIMARY KEY (`id`))",
+	},
+	"sqlite": {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` integer NOT NULL, `name` text NOT NULL, `applied_at` datetime NOT NULL, PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migration_lock` (`id` integer NOT NULL, `holder` text NOT NULL, `locked_at` datetime NOT NULL, PRIMARY KEY (`id`))",
+	},
+}
+
+// Migrate applies the pending migrations and panics when one fails
+func Migrate() {
+	if _, err := MigrateUp(); err != nil {
+		panic(err)
+	}
+}
+
+// LoadMigrations returns the migrations of a dialect sorted by version
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database engine %q", dialect)
+	}
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, found := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		version, title, ok := strings.Cut(base, "_")
+		if !found || !ok || !strings.HasSuffix(name, ".sql") || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+		number, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+		content, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migration, exists := byVersion[number]
+		if !exists {
+			migration = &Migration{Version: number, Name: title}
+			byVersion[number] = migration
+		} else if migration.Name != title {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", number, migration.Name, title)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies the pending migrations in order and returns them. Each migration runs in a transaction, but MySQL
+// commits every schema change at once, so a migration failing there can be left half applied.
+func MigrateUp() ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(func(ctx context.Context, migrations []Migration, done map[uint64]SchemaMigration) error {
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			slog.Info("applying migration", "version", migration.Version, "name", migration.Name)
+			err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := execStatements(tx, migration.Up); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and returns them
+func MigrateDown(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := withMigrationLock(func(ctx context.Context, migrations []Migration, done map[uint64]SchemaMigration) error {
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+			slog.Info("reverting migration", "version", migration.Version, "name", migration.Name)
+			err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if err := execStatements(tx, migration.Down); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetMigrationStatus returns the known migrations with when they were applied, followed by the applied migrations this
+// version does not know about
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	migrations, done, err := loadMigrationState()
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if applied, ok := done[migration.Version]; ok {
+			status.AppliedAt = &applied.AppliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	var unknown []MigrationStatus
+	for _, applied := range done {
+		appliedAt := applied.AppliedAt
+		unknown = append(unknown, MigrationStatus{Version: applied.Version, Name: applied.Name, AppliedAt: &appliedAt})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Version < unknown[j].Version })
+	return append(statuses, unknown...), nil
+}
+
+// CheckMigrations returns ErrSchemaBehind when migrations of this version were not applied yet
+func CheckMigrations() error {
+	statuses, err := GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, pending migrations: %s", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+func loadMigrationState() ([]Migration, map[uint64]SchemaMigration, error) {
+	dialect := DB.Dialector.Name()
+	statements, ok := migrationTables[dialect]
+	if !ok {
+		return nil, nil, fmt.Errorf("no migrations for database engine %q", dialect)
+	}
+	for _, statement := range statements {
+		if err := DB.Exec(statement).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	var applied []SchemaMigration
+	if err = DB.Find(&applied).Error; err != nil {
+		return nil, nil, err
+	}
+	done := map[uint64]SchemaMigration{}
+	for _, migration := range applied {
+		done[migration.Version] = migration
+	}
+	return migrations, done, nil
+}
+
+// withMigrationLock runs fn while holding the migration lock, with the state read after the lock was taken. The lock
+// is renewed while fn runs, and the context of fn is cancelled with ErrMigrationLockLost once it is lost.
+func withMigrationLock(fn func(ctx context.Context, migrations []Migration, done map[uint64]SchemaMigration) error) error {
+	if _, _, err := loadMigrationState(); err != nil {
+		return err
+	}
+	holder := migrationLockHolder()
+	if err := acquireMigrationLock(holder, migrationLockWait); err != nil {
+		return err
+	}
+	defer func() {
+		if err := DB.Where("id = ? AND holder = ?", migrationLockID, holder).Delete(&SchemaMigrationLock{}).Error; err != nil {
+			slog.Error("failed to release migration lock", "error", err)
+		}
+	}()
+	migrations, done, err := loadMigrationState()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	renewing := make(chan struct{})
+	go func() {
+		defer close(renewing)
+		keepMigrationLock(ctx, holder, cancel)
+	}()
+	err = fn(ctx, migrations, done)
+	lost := errors.Is(context.Cause(ctx), ErrMigrationLockLost)
+	cancel(nil)
+	<-renewing
+	if lost {
+		return ErrMigrationLockLost
+	}
+	return err
+}
+
+// keepMigrationLock renews the lock every migrationLockRenewal until ctx is done, and cancels the migrations when
+// another replica holds the lock or it could not be renewed for longer than migrationLockTTL
+func keepMigrationLock(ctx context.Context, holder string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(migrationLockRenewal)
+	defer ticker.Stop()
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		held, err := renewMigrationLock(holder)
+		switch {
+		case err == nil && held:
+			renewedAt = time.Now()
+		case err == nil:
+			cancel(ErrMigrationLockLost)
+			return
+		case time.Since(renewedAt) >= migrationLockTTL:
+			slog.Error("failed to renew migration lock", "error", err)
+			cancel(ErrMigrationLockLost)
+			return
+		default:
+			slog.Warn("failed to renew migration lock, retrying", "error", err)
+		}
+	}
+}
+
+// renewMigrationLock moves the lock time of a lock held by holder to now, and reports whether it still holds it
+func renewMigrationLock(holder string) (bool, error) {
+	result := DB.Model(&SchemaMigrationLock{}).
+		Where("id = ? AND holder = ?", migrationLockID, holder).
+		Update("locked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// acquireMigrationLock waits up to wait for the lock, and takes over a lock older than migrationLockTTL
+func acquireMigrationLock(holder string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		now := time.Now()
+		err := DB.Create(&SchemaMigrationLock{ID: migrationLockID, Holder: holder, LockedAt: now}).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		result := DB.Model(&SchemaMigrationLock{}).
+			Where("id = ? AND locked_at < ?", migrationLockID, now.Add(-migrationLockTTL)).
+			Updates(map[string]interface{}{"holder": holder, "locked_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			slog.Warn("took over a stale migration lock")
+			return nil
+		}
+		if now.After(deadline) {
+			return errors.New("timed out waiting for the migration lock")
+		}
+		slog.Info("waiting for the migration lock")
+		time.Sleep(time.Second)
+	}
+}
+
+func migrationLockHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "usercore"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// execStatements runs the statements of a migration file one by one
+func execStatements(tx *gorm.DB, content string) error {
+	for _, statement := range splitStatements(content) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements returns the statements of a migration file. Statements end with a semicolon at the end of a line,
+// lines starting with -- are comments.
+func splitStatements(content string) []string {
+	var statements []string
+	var statement strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(statement.String()))
+			statement.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
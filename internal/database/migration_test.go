@@ -0,0 +1,288 @@
+package database
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMigrationsOfAllDialectsMatch(t *testing.T) {
+	sqlite, err := LoadMigrations("sqlite")
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+	for _, dialect := range []string{"postgres", "mysql"} {
+		migrations, err := LoadMigrations(dialect)
+		if err != nil {
+			t.Fatalf("LoadMigrations(%s) failed: %v", dialect, err)
+		}
+		if len(migrations) != len(sqlite) {
+			t.Fatalf("Expected %s to have %d migrations, got %d", dialect, len(sqlite), len(migrations))
+		}
+		for i, migration := range migrations {
+			if migration.Version != sqlite[i].Version || migration.Name != sqlite[i].Name {
+				t.Errorf("Expected %s migration %d to be %d_%s, got %d_%s", dialect, i, sqlite[i].Version, sqlite[i].Name, migration.Version, migration.Name)
+			}
+			if migration.Down == "" {
+				t.Errorf("Expected %s migration %d_%s to have a down migration", dialect, migration.Version, migration.Name)
+			}
+		}
+	}
+	if _, err = LoadMigrations("oracle"); err == nil {
+		t.Error("Expected an unknown dialect to fail")
+	}
+}
+
+// schemaModels are the models whose tables are created by the migrations
+var schemaModels = []interface{}{
+	&User{}, &Profile{}, &PasswordReset{}, &PasswordHistory{}, &EmailChange{}, &NotificationPreference{},
+	&SignInFingerprint{}, &SignInChallenge{}, &RiskDecision{}, &Device{}, &Session{}, &Role{}, &Permission{},
+	&SocialProvider{}, &AuditEvent{}, &AuditChainHead{}, &OutboxEvent{}, &WebhookDelivery{}, &DataExport{}, &LoginAttempt{},
+	&JobLease{}, &JobRun{}, &Notification{},
+}
+
+var (
+	createTablePattern = regexp.MustCompile("(?s)^CREATE TABLE IF NOT EXISTS [`\"](\\w+)[`\"] \\((.*)\\);$")
+	alterTablePattern  = regexp.MustCompile("(?s)^ALTER TABLE [`\"](\\w+)[`\"]\\s+(.*);$")
+	dropTablePattern   = regexp.MustCompile("^DROP TABLE IF EXISTS [`\"](\\w+)[`\"];$")
+	columnPattern      = regexp.MustCompile("^(ADD|DROP) COLUMN [`\"](\\w+)[`\"]")
+)
+
+// tableColumns maps the tables to their columns
+type tableColumns map[string]map[string]bool
+
+// modelColumns returns the tables and columns the models expect, with the join tables of their many to many relations
+func modelColumns(t *testing.T) tableColumns {
+	t.Helper()
+	expected := tableColumns{}
+	for _, model := range schemaModels {
+		parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		add := func(table string, fields []*schema.Field) {
+			expected[table] = map[string]bool{}
+			for _, field := range fields {
+				if field.DBName != "" {
+					expected[table][field.DBName] = true
+				}
+			}
+		}
+		add(parsed.Table, parsed.Fields)
+		for _, relation := range parsed.Relationships.Many2Many {
+			add(relation.JoinTable.Table, relation.JoinTable.Fields)
+		}
+	}
+	return expected
+}
+
+// applySQL applies the table and column changes of a migration file to the columns, so the files of dialects without a
+// test database can be checked too
+func applySQL(t *testing.T, columns tableColumns, content string) {
+	t.Helper()
+	for _, statement := range splitStatements(content) {
+		if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+			columns[match[1]] = map[string]bool{}
+			for _, line := range strings.Split(match[2], "\n") {
+				line = strings.TrimSpace(line)
+				if strings.HasPrefix(line, "`") || strings.HasPrefix(line, `"`) {
+					columns[match[1]][strings.Trim(strings.Fields(line)[0], "`\"")] = true
+				}
+			}
+		} else if match = alterTablePattern.FindStringSubmatch(statement); match != nil {
+			if columns[match[1]] == nil {
+				t.Fatalf("Expected table %s to exist before %q", match[1], statement)
+			}
+			for _, clause := range strings.Split(match[2], ",\n") {
+				change := columnPattern.FindStringSubmatch(strings.TrimSpace(clause))
+				if change == nil {
+					continue
+				}
+				if exists := columns[match[1]][change[2]]; exists != (change[1] == "DROP") {
+					t.Fatalf("Expected %q to change column %s.%s", statement, match[1], change[2])
+				}
+				if change[1] == "ADD" {
+					columns[match[1]][change[2]] = true
+				} else {
+					delete(columns[match[1]], change[2])
+				}
+			}
+		} else if match = dropTablePattern.FindStringSubmatch(statement); match != nil {
+			delete(columns, match[1])
+		}
+	}
+}
+
+func (c tableColumns) clone() tableColumns {
+	cloned := tableColumns{}
+	for table, columns := range c {
+		cloned[table] = map[string]bool{}
+		for column := range columns {
+			cloned[table][column] = true
+		}
+	}
+	return cloned
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	expected := modelColumns(t)
+	for _, dialect := range []string{"sqlite", "postgres", "mysql"} {
+		migrations, err := LoadMigrations(dialect)
+		if err != nil {
+			t.Fatalf("LoadMigrations(%s) failed: %v", dialect, err)
+		}
+		columns := tableColumns{}
+		var before []tableColumns
+		for _, migration := range migrations {
+			before = append(before, columns.clone())
+			applySQL(t, columns, migration.Up)
+		}
+		if !reflect.DeepEqual(columns, expected) {
+			for table := range expected {
+				if !reflect.DeepEqual(columns[table], expected[table]) {
+					t.Errorf("Expected the %s migrations to create %s with %v, got %v", dialect, table, expected[table], columns[table])
+				}
+			}
+			for table := range columns {
+				if expected[table] == nil {
+					t.Errorf("Expected the %s migrations not to create %s", dialect, table)
+				}
+			}
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			applySQL(t, columns, migrations[i].Down)
+			if !reflect.DeepEqual(columns, before[i]) {
+				t.Errorf("Expected the %s down migration %d_%s to revert its up migration", dialect, migrations[i].Version, migrations[i].Name)
+				columns = before[i].clone()
+			}
+		}
+	}
+}
+
+func TestMigrationsCreateModelTables(t *testing.T) {
+	SetupTestDB(t)
+	migrator := DB.Migrator()
+	for _, model := range schemaModels {
+		statement := DB.Model(model).Statement
+		if err := statement.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		if !migrator.HasTable(model) {
+			t.Errorf("Expected table %s to be created by the migrations", statement.Schema.Table)
+			continue
+		}
+		for _, field := range statement.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				t.Errorf("Expected column %s.%s to be created by the migrations", statement.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	SetupTestDB(t)
+	if err := CheckMigrations(); err != nil {
+		t.Fatalf("Expected the schema to be current, got %v", err)
+	}
+	applied, err := MigrateUp()
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("Expected nothing to apply, got %d (%v)", len(applied), err)
+	}
+	statuses, err := GetMigrationStatus()
+	if err != nil {
+		t.Fatalf("GetMigrationStatus failed: %v", err)
+	}
+
+	// back to the schema of the last release created by AutoMigrate, with a user that has to keep its data
+	reverted, err := MigrateDown(len(statuses) - 1)
+	if err != nil {
+		t.Fatalf("MigrateDown failed: %v", err)
+	}
+	if len(reverted) != len(statuses)-1 {
+		t.Fatalf("Expected %d reverted migrations, got %d", len(statuses)-1, len(reverted))
+	}
+	if DB.Migrator().HasColumn(&User{}, "password_changed_at") || DB.Migrator().HasTable(&AuditEvent{}) {
+		t.Error("Expected the later migrations to be reverted")
+	}
+	if err = DB.Exec("INSERT INTO users (id, name, email) VALUES (?, ?, ?)", uuid.NewString(), "Baseline", "baseline@example.com").Error; err != nil {
+		t.Fatalf("Failed to insert a baseline user: %v", err)
+	}
+	if err = CheckMigrations(); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("Expected ErrSchemaBehind, got %v", err)
+	}
+
+	if applied, err = MigrateUp(); err != nil || len(applied) != len(statuses)-1 {
+		t.Fatalf("Expected %d applied migrations, got %d (%v)", len(statuses)-1, len(applied), err)
+	}
+	var user User
+	if err = DB.Where("email = ?", "baseline@example.com").First(&user).Error; err != nil {
+		t.Errorf("Expected the baseline user to be kept, got %v", err)
+	}
+	if !DB.Migrator().HasColumn(&User{}, "password_changed_at") || !DB.Migrator().HasColumn(&Session{}, "last_seen_at") {
+		t.Error("Expected the later migrations to add the columns to the baseline tables")
+	}
+
+	if reverted, err = MigrateDown(len(statuses)); err != nil || len(reverted) != len(statuses) {
+		t.Fatalf("Expected %d reverted migrations, got %d (%v)", len(statuses), len(reverted), err)
+	}
+	if DB.Migrator().HasTable(&User{}) {
+		t.Error("Expected the baseline down migration to drop the tables")
+	}
+	if statuses, err = GetMigrationStatus(); err != nil || statuses[0].AppliedAt != nil {
+		t.Fatalf("Expected the baseline to be pending, got %+v (%v)", statuses, err)
+	}
+	if applied, err = MigrateUp(); err != nil || len(applied) != len(statuses) {
+		t.Fatalf("Expected %d applied migrations, got %d (%v)", len(statuses), len(applied), err)
+	}
+	var lock int64
+	DB.Model(&SchemaMigrationLock{}).Count(&lock)
+	if lock != 0 {
+		t.Error("Expected the migration lock to be released")
+	}
+}
+
+func TestMigrationLock(t *testing.T) {
+	SetupTestDB(t)
+	DB.Create(&SchemaMigrationLock{ID: migrationLockID, Holder: "other", LockedAt: time.Now()})
+	if err := acquireMigrationLock("me", 0); err == nil {
+		t.Fatal("Expected the lock held by another replica not to be acquired")
+	}
+
+	DB.Model(&SchemaMigrationLock{}).Where("id = ?", migrationLockID).Update("locked_at", time.Now().Add(-2*migrationLockTTL))
+	if err := acquireMigrationLock("me", 0); err != nil {
+		t.Fatalf("Expected a stale lock to be taken over, got %v", err)
+	}
+	var lock SchemaMigrationLock
+	DB.First(&lock, migrationLockID)
+	if lock.Holder != "me" {
+		t.Errorf("Expected the lock to be held by me, got %s", lock.Holder)
+	}
+}
+
+func TestRenewMigrationLock(t *testing.T) {
+	SetupTestDB(t)
+	lockedAt := time.Now().Add(-migrationLockTTL)
+	DB.Create(&SchemaMigrationLock{ID: migrationLockID, Holder: "me", LockedAt: lockedAt})
+
+	if held, err := renewMigrationLock("me"); err != nil || !held {
+		t.Fatalf("Expected the holder to renew the lock, got %v (%v)", held, err)
+	}
+	var lock SchemaMigrationLock
+	DB.First(&lock, migrationLockID)
+	if !lock.LockedAt.After(lockedAt) {
+		t.Errorf("Expected the lock time to move forward from %v, got %v", lockedAt, lock.LockedAt)
+	}
+	if err := acquireMigrationLock("other", 0); err == nil {
+		t.Error("Expected a renewed lock not to be taken over")
+	}
+	if held, err := renewMigrationLock("other"); err != nil || held {
+		t.Errorf("Expected another replica not to renew the lock, got %v (%v)", held, err)
+	}
+}
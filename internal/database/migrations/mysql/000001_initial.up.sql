@@ -0,0 +1,151 @@
+-- Schema of the last release created by AutoMigrate. The tables are only created when missing, so those databases adopt it
+-- and the later migrations bring them up to date.
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` varchar(191),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` longtext NOT NULL,
+    `email` varchar(191),
+    `email_verified` boolean DEFAULT false,
+    `email_verify_code` varchar(191) DEFAULT null,
+    `email_verify_sent_at` datetime(3) NULL DEFAULT null,
+    `password` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_users_deleted_at` (`deleted_at`),
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+
+CREATE TABLE IF NOT EXISTS `roles` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` varchar(255) NOT NULL,
+    `key` varchar(255) NOT NULL,
+    `description` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_roles_deleted_at` (`deleted_at`),
+    CONSTRAINT `uni_roles_key` UNIQUE (`key`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_roles` (
+    `user_id` varchar(191),
+    `role_id` bigint unsigned,
+    PRIMARY KEY (`user_id`,`role_id`),
+    CONSTRAINT `fk_user_roles_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_user_roles_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `profiles` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `picture` varchar(191) DEFAULT null,
+    `gender` varchar(191) DEFAULT null,
+    `education` varchar(191) DEFAULT null,
+    `birthdate` datetime(3) NULL DEFAULT null,
+    `locale` varchar(191) DEFAULT null,
+    `timezone` varchar(191) DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_profiles_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_users_profile` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `password_resets` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `reset_token` varchar(191) DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_password_resets_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_users_password_reset` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `refresh_token` varchar(191) DEFAULT null,
+    `expires_at` datetime(3) NULL DEFAULT null,
+    `client_id` varchar(191) DEFAULT null,
+    `client_name` varchar(191) DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_sessions_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_users_sessions` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `devices` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `session_id` bigint unsigned DEFAULT null,
+    `name` varchar(191) DEFAULT null,
+    `ip` varchar(191) DEFAULT null,
+    `os` varchar(191) DEFAULT null,
+    `token` varchar(191) DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_devices_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_sessions_device` FOREIGN KEY (`session_id`) REFERENCES `sessions`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `permissions` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `name` varchar(255) NOT NULL,
+    `key` varchar(255) NOT NULL,
+    `description` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_permissions_deleted_at` (`deleted_at`),
+    CONSTRAINT `uni_permissions_key` UNIQUE (`key`)
+);
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (
+    `role_id` bigint unsigned,
+    `permission_id` bigint unsigned,
+    PRIMARY KEY (`role_id`,`permission_id`),
+    CONSTRAINT `fk_role_permissions_role` FOREIGN KEY (`role_id`) REFERENCES `roles`(`id`),
+    CONSTRAINT `fk_role_permissions_permission` FOREIGN KEY (`permission_id`) REFERENCES `permissions`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `social_providers` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `provider` varchar(191) DEFAULT null,
+    `provider_user_id` varchar(191) DEFAULT null,
+    `access_token` varchar(191) DEFAULT null,
+    `refresh_token` varchar(191) DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_social_providers_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_users_social_providers` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS `audit_events` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `actor_id` varchar(191) DEFAULT null,
+    `user_id` varchar(191) DEFAULT null,
+    `client_id` varchar(191) DEFAULT null,
+    `ip` varchar(191) DEFAULT null,
+    `user_agent` varchar(191) DEFAULT null,
+    `action` varchar(191) NOT NULL,
+    `outcome` longtext NOT NULL,
+    `metadata` text DEFAULT null,
+    `prev_hash` varchar(64),
+    `hash` varchar(64) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_events_user_id` (`user_id`),
+    INDEX `idx_audit_events_action` (`action`),
+    UNIQUE INDEX `idx_audit_events_prev_hash` (`prev_hash`),
+    INDEX `idx_audit_events_created_at` (`created_at`),
+    INDEX `idx_audit_events_actor_id` (`actor_id`)
+);
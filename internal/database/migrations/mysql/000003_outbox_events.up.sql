@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `event_type` varchar(191) NOT NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `payload` text,
+    `processed_at` datetime(3) NULL DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_outbox_events_deleted_at` (`deleted_at`),
+    INDEX `idx_outbox_events_event_type` (`event_type`),
+    INDEX `idx_outbox_events_user_id` (`user_id`),
+    INDEX `idx_outbox_events_processed_at` (`processed_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `outbox_event_id` bigint unsigned NOT NULL,
+    `client_id` varchar(191) NOT NULL,
+    `url` longtext NOT NULL,
+    `status` varchar(191) NOT NULL,
+    `attempts` bigint DEFAULT 0,
+    `next_attempt_at` datetime(3) NULL,
+    `locked_until` datetime(3) NULL DEFAULT null,
+    `last_error` text DEFAULT null,
+    `last_status_code` bigint DEFAULT 0,
+    `delivered_at` datetime(3) NULL DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_webhook_deliveries_client_id` (`client_id`),
+    INDEX `idx_webhook_deliveries_status` (`status`),
+    INDEX `idx_webhook_deliveries_next_attempt_at` (`next_attempt_at`),
+    INDEX `idx_webhook_deliveries_deleted_at` (`deleted_at`),
+    INDEX `idx_webhook_deliveries_outbox_event_id` (`outbox_event_id`),
+    CONSTRAINT `fk_webhook_deliveries_outbox_event` FOREIGN KEY (`outbox_event_id`) REFERENCES `outbox_events`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
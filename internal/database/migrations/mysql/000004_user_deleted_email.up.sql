@@ -0,0 +1,3 @@
+ALTER TABLE `users`
+    ADD COLUMN `deleted_email` varchar(191) DEFAULT null,
+    ADD INDEX `idx_users_deleted_email` (`deleted_email`);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `data_exports` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) NOT NULL,
+    `status` varchar(191) NOT NULL,
+    `token_hash` varchar(64) NOT NULL,
+    `archive` text DEFAULT null,
+    `error` text DEFAULT null,
+    `locked_until` datetime(3) NULL DEFAULT null,
+    `completed_at` datetime(3) NULL DEFAULT null,
+    `expires_at` datetime(3) NULL DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_data_exports_deleted_at` (`deleted_at`),
+    INDEX `idx_data_exports_user_id` (`user_id`),
+    INDEX `idx_data_exports_status` (`status`),
+    UNIQUE INDEX `idx_data_exports_token_hash` (`token_hash`),
+    INDEX `idx_data_exports_expires_at` (`expires_at`)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `identifier` varchar(128) NOT NULL,
+    `failures` bigint DEFAULT 0,
+    `last_failure_at` datetime(3) NULL,
+    `locked_until` datetime(3) NULL DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_login_attempts_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_login_attempts_identifier` (`identifier`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `password_histories`;
+ALTER TABLE `users` DROP COLUMN `password_changed_at`;
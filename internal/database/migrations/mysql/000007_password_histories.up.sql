@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `password_histories` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) NOT NULL,
+    `password` longtext NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_password_histories_deleted_at` (`deleted_at`),
+    INDEX `idx_password_histories_user_id` (`user_id`)
+);
+
+ALTER TABLE `users` ADD COLUMN `password_changed_at` datetime(3) NULL DEFAULT null;
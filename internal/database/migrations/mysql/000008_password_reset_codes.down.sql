@@ -0,0 +1,9 @@
+ALTER TABLE `password_resets`
+    DROP FOREIGN KEY `fk_users_password_reset`,
+    DROP INDEX `idx_password_resets_user_id`,
+    ADD COLUMN `reset_token` varchar(191) DEFAULT null,
+    DROP COLUMN `used_at`,
+    DROP COLUMN `expires_at`,
+    DROP COLUMN `attempts`,
+    DROP COLUMN `token_hash`;
+ALTER TABLE `password_resets` ADD CONSTRAINT `fk_users_password_reset` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,9 @@
+-- Reset tokens of the previous scheme cannot be redeemed as codes, so they are removed with their column.
+DELETE FROM `password_resets`;
+ALTER TABLE `password_resets`
+    ADD COLUMN `token_hash` varchar(191) DEFAULT null,
+    ADD COLUMN `attempts` bigint DEFAULT 0,
+    ADD COLUMN `expires_at` datetime(3) NULL,
+    ADD COLUMN `used_at` datetime(3) NULL,
+    DROP COLUMN `reset_token`,
+    ADD INDEX `idx_password_resets_user_id` (`user_id`);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `email_changes` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) DEFAULT null,
+    `previous_email` longtext,
+    `previous_verified` boolean,
+    `new_email` varchar(191),
+    `code_hash` varchar(191) DEFAULT null,
+    `attempts` bigint DEFAULT 0,
+    `expires_at` datetime(3) NULL,
+    `revert_expires_at` datetime(3) NULL,
+    `confirmed_at` datetime(3) NULL,
+    `reverted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_email_changes_new_email` (`new_email`),
+    INDEX `idx_email_changes_deleted_at` (`deleted_at`),
+    INDEX `idx_email_changes_user_id` (`user_id`)
+);
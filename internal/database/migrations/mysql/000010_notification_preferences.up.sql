@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS `notification_preferences` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` longtext NOT NULL,
+    `event` varchar(64) NOT NULL,
+    `email` boolean,
+    `push` boolean,
+    PRIMARY KEY (`id`),
+    INDEX `idx_notification_preferences_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_notification_preferences_user_event` (`user_id`,`event`)
+);
+
+CREATE TABLE IF NOT EXISTS `sign_in_fingerprints` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` longtext NOT NULL,
+    `fingerprint` varchar(64) NOT NULL,
+    `last_seen_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_sign_in_fingerprints_user_fingerprint` (`user_id`,`fingerprint`),
+    INDEX `idx_sign_in_fingerprints_deleted_at` (`deleted_at`)
+);
@@ -0,0 +1,10 @@
+ALTER TABLE `devices`
+    DROP FOREIGN KEY `fk_sessions_device`,
+    DROP INDEX `idx_devices_user_id`,
+    DROP INDEX `idx_devices_session_id`,
+    DROP INDEX `idx_devices_device_id`,
+    DROP COLUMN `browser_version`,
+    DROP COLUMN `browser`,
+    DROP COLUMN `os_version`,
+    DROP COLUMN `device_id`;
+ALTER TABLE `devices` ADD CONSTRAINT `fk_sessions_device` FOREIGN KEY (`session_id`) REFERENCES `sessions`(`id`);
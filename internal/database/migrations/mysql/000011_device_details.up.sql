@@ -0,0 +1,8 @@
+ALTER TABLE `devices`
+    ADD COLUMN `device_id` varchar(64) DEFAULT null,
+    ADD COLUMN `os_version` varchar(191) DEFAULT null,
+    ADD COLUMN `browser` varchar(191) DEFAULT null,
+    ADD COLUMN `browser_version` varchar(191) DEFAULT null,
+    ADD INDEX `idx_devices_user_id` (`user_id`),
+    ADD INDEX `idx_devices_session_id` (`session_id`),
+    ADD INDEX `idx_devices_device_id` (`device_id`);
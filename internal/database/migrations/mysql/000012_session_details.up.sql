@@ -0,0 +1,10 @@
+ALTER TABLE `sessions`
+    ADD COLUMN `ip` varchar(191) DEFAULT null,
+    ADD COLUMN `user_agent` varchar(512) DEFAULT null,
+    ADD COLUMN `browser` varchar(191) DEFAULT null,
+    ADD COLUMN `browser_version` varchar(191) DEFAULT null,
+    ADD COLUMN `os` varchar(191) DEFAULT null,
+    ADD COLUMN `os_version` varchar(191) DEFAULT null,
+    ADD COLUMN `device_type` varchar(191) DEFAULT null,
+    ADD COLUMN `last_seen_ip` varchar(191) DEFAULT null,
+    ADD COLUMN `last_seen_at` datetime(3) NULL DEFAULT null;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS `sign_in_challenges` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) NOT NULL,
+    `client_id` varchar(191) DEFAULT null,
+    `risk_decision_id` bigint unsigned DEFAULT null,
+    `code_hash` varchar(191) DEFAULT null,
+    `attempts` bigint DEFAULT 0,
+    `expires_at` datetime(3) NULL,
+    `used_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_sign_in_challenges_deleted_at` (`deleted_at`),
+    INDEX `idx_sign_in_challenges_user_id` (`user_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `risk_decisions` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) NOT NULL,
+    `client_id` varchar(191) DEFAULT null,
+    `session_id` bigint unsigned DEFAULT null,
+    `kind` varchar(32) NOT NULL,
+    `ip` varchar(191) DEFAULT null,
+    `country` varchar(2) DEFAULT null,
+    `asn` bigint unsigned DEFAULT null,
+    `latitude` double DEFAULT null,
+    `longitude` double DEFAULT null,
+    `fingerprint` varchar(64) DEFAULT null,
+    `score` bigint NOT NULL,
+    `reasons` varchar(191) DEFAULT null,
+    `action` varchar(32) NOT NULL,
+    `trusted` boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (`id`),
+    INDEX `idx_risk_decisions_deleted_at` (`deleted_at`),
+    INDEX `idx_risk_decisions_user_id` (`user_id`)
+);
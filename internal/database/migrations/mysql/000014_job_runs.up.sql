@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS `job_leases` (
+    `name` varchar(64),
+    `holder` varchar(128) NOT NULL,
+    `expires_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `job_runs` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `job` varchar(64) NOT NULL,
+    `holder` varchar(128),
+    `started_at` datetime(3) NULL,
+    `finished_at` datetime(3) NULL,
+    `duration` bigint,
+    `outcome` varchar(16),
+    `result` varchar(191) DEFAULT null,
+    `error` text DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_job_runs_deleted_at` (`deleted_at`),
+    INDEX `idx_job_runs_job` (`job`),
+    INDEX `idx_job_runs_started_at` (`started_at`)
+);
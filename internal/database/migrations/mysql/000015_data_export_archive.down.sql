@@ -0,0 +1 @@
+ALTER TABLE `data_exports` MODIFY COLUMN `archive` text DEFAULT null;
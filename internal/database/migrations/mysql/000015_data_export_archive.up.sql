@@ -0,0 +1,2 @@
+-- A text column holds 64 KB on MySQL, too little for the archive of an active user.
+ALTER TABLE `data_exports` MODIFY COLUMN `archive` longblob DEFAULT null;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS `notifications` (
+    `order_by` longtext,
+    `order` longtext,
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_id` varchar(191) NOT NULL,
+    `event` varchar(64) NOT NULL,
+    `data` text,
+    `status` varchar(191) NOT NULL,
+    `attempts` bigint DEFAULT 0,
+    `next_attempt_at` datetime(3) NULL,
+    `locked_until` datetime(3) NULL DEFAULT null,
+    `last_error` text DEFAULT null,
+    PRIMARY KEY (`id`),
+    INDEX `idx_notifications_user_id` (`user_id`),
+    INDEX `idx_notifications_status` (`status`),
+    INDEX `idx_notifications_next_attempt_at` (`next_attempt_at`),
+    INDEX `idx_notifications_deleted_at` (`deleted_at`)
+);
@@ -0,0 +1,10 @@
+ALTER TABLE `email_changes`
+    ADD COLUMN `pending_email` varchar(191) DEFAULT null,
+    ADD UNIQUE INDEX `idx_email_changes_pending_email` (`pending_email`);
+UPDATE `email_changes` SET `pending_email` = `new_email` WHERE `id` IN (
+    SELECT `id` FROM (
+        SELECT MAX(`id`) AS `id` FROM `email_changes`
+        WHERE `confirmed_at` IS NULL AND `reverted_at` IS NULL
+        GROUP BY `new_email`
+    ) AS `latest`
+);
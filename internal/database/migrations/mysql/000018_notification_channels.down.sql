@@ -0,0 +1,3 @@
+ALTER TABLE `notifications`
+    DROP COLUMN `email_sent_at`,
+    DROP COLUMN `push_sent_at`;
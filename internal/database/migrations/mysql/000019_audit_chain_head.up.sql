@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `audit_chain_heads` (
+    `id` bigint unsigned,
+    `hash` varchar(64) NOT NULL,
+    PRIMARY KEY (`id`)
+);
+
+INSERT INTO `audit_chain_heads` (`id`, `hash`)
+SELECT 1, COALESCE((SELECT `hash` FROM `audit_events` ORDER BY `id` DESC LIMIT 1), '');
@@ -0,0 +1,151 @@
+-- Schema of the last release created by AutoMigrate. The tables are only created when missing, so those databases adopt it
+-- and the later migrations bring them up to date.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "order_by" text,
+    "order" text,
+    "id" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text NOT NULL,
+    "email" text,
+    "email_verified" boolean DEFAULT false,
+    "email_verify_code" text DEFAULT null,
+    "email_verify_sent_at" timestamptz DEFAULT null,
+    "password" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "roles" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(255) NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "description" varchar(255) NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_roles_key" UNIQUE ("key")
+);
+CREATE INDEX IF NOT EXISTS "idx_roles_deleted_at" ON "roles" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "user_roles" (
+    "user_id" text,
+    "role_id" bigint,
+    PRIMARY KEY ("user_id","role_id"),
+    CONSTRAINT "fk_user_roles_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_user_roles_role" FOREIGN KEY ("role_id") REFERENCES "roles"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "profiles" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "picture" text DEFAULT null,
+    "gender" text DEFAULT null,
+    "education" text DEFAULT null,
+    "birthdate" timestamptz DEFAULT null,
+    "locale" text DEFAULT null,
+    "timezone" text DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_profile" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_profiles_deleted_at" ON "profiles" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "password_resets" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "reset_token" text DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_password_reset" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_password_resets_deleted_at" ON "password_resets" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "refresh_token" text DEFAULT null,
+    "expires_at" timestamptz DEFAULT null,
+    "client_id" text DEFAULT null,
+    "client_name" text DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_sessions" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_deleted_at" ON "sessions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "devices" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "session_id" bigint DEFAULT null,
+    "name" text DEFAULT null,
+    "ip" text DEFAULT null,
+    "os" text DEFAULT null,
+    "token" text DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_sessions_device" FOREIGN KEY ("session_id") REFERENCES "sessions"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_devices_deleted_at" ON "devices" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "permissions" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(255) NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "description" varchar(255) NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_permissions_key" UNIQUE ("key")
+);
+CREATE INDEX IF NOT EXISTS "idx_permissions_deleted_at" ON "permissions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "role_permissions" (
+    "role_id" bigint,
+    "permission_id" bigint,
+    PRIMARY KEY ("role_id","permission_id"),
+    CONSTRAINT "fk_role_permissions_role" FOREIGN KEY ("role_id") REFERENCES "roles"("id"),
+    CONSTRAINT "fk_role_permissions_permission" FOREIGN KEY ("permission_id") REFERENCES "permissions"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "social_providers" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "provider" text DEFAULT null,
+    "provider_user_id" text DEFAULT null,
+    "access_token" text DEFAULT null,
+    "refresh_token" text DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_users_social_providers" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_social_providers_deleted_at" ON "social_providers" ("deleted_at");
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "audit_events" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "actor_id" text DEFAULT null,
+    "user_id" text DEFAULT null,
+    "client_id" text DEFAULT null,
+    "ip" text DEFAULT null,
+    "user_agent" text DEFAULT null,
+    "action" text NOT NULL,
+    "outcome" text NOT NULL,
+    "metadata" text DEFAULT null,
+    "prev_hash" varchar(64),
+    "hash" varchar(64) NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_events_action" ON "audit_events" ("action");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_actor_id" ON "audit_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_created_at" ON "audit_events" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_events_user_id" ON "audit_events" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_events_prev_hash" ON "audit_events" ("prev_hash");
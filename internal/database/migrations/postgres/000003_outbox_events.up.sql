@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS "outbox_events" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "event_type" text NOT NULL,
+    "user_id" text DEFAULT null,
+    "payload" text,
+    "processed_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_deleted_at" ON "outbox_events" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_event_type" ON "outbox_events" ("event_type");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_processed_at" ON "outbox_events" ("processed_at");
+CREATE INDEX IF NOT EXISTS "idx_outbox_events_user_id" ON "outbox_events" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "outbox_event_id" bigint NOT NULL,
+    "client_id" text NOT NULL,
+    "url" text NOT NULL,
+    "status" text NOT NULL,
+    "attempts" bigint DEFAULT 0,
+    "next_attempt_at" timestamptz,
+    "locked_until" timestamptz DEFAULT null,
+    "last_error" text DEFAULT null,
+    "last_status_code" bigint DEFAULT 0,
+    "delivered_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_outbox_event" FOREIGN KEY ("outbox_event_id") REFERENCES "outbox_events"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_client_id" ON "webhook_deliveries" ("client_id");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_deleted_at" ON "webhook_deliveries" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_outbox_event_id" ON "webhook_deliveries" ("outbox_event_id");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_status" ON "webhook_deliveries" ("status");
@@ -0,0 +1,2 @@
+ALTER TABLE "users" ADD COLUMN "deleted_email" text DEFAULT null;
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_email" ON "users" ("deleted_email");
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS "data_exports" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text NOT NULL,
+    "status" text NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "archive" text DEFAULT null,
+    "error" text DEFAULT null,
+    "locked_until" timestamptz DEFAULT null,
+    "completed_at" timestamptz DEFAULT null,
+    "expires_at" timestamptz DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_data_exports_deleted_at" ON "data_exports" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_expires_at" ON "data_exports" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_status" ON "data_exports" ("status");
+CREATE INDEX IF NOT EXISTS "idx_data_exports_user_id" ON "data_exports" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_data_exports_token_hash" ON "data_exports" ("token_hash");
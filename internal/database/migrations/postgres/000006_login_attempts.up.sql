@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "login_attempts" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "identifier" varchar(128) NOT NULL,
+    "failures" bigint DEFAULT 0,
+    "last_failure_at" timestamptz,
+    "locked_until" timestamptz DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_attempts_deleted_at" ON "login_attempts" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_attempts_identifier" ON "login_attempts" ("identifier");
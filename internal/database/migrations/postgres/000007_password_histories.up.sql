@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS "password_histories" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text NOT NULL,
+    "password" text NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_password_histories_deleted_at" ON "password_histories" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_password_histories_user_id" ON "password_histories" ("user_id");
+
+ALTER TABLE "users" ADD COLUMN "password_changed_at" timestamptz DEFAULT null;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "idx_password_resets_user_id";
+ALTER TABLE "password_resets"
+    ADD COLUMN "reset_token" text DEFAULT null,
+    DROP COLUMN "used_at",
+    DROP COLUMN "expires_at",
+    DROP COLUMN "attempts",
+    DROP COLUMN "token_hash";
@@ -0,0 +1,9 @@
+-- Reset tokens of the previous scheme cannot be redeemed as codes, so they are removed with their column.
+DELETE FROM "password_resets";
+ALTER TABLE "password_resets"
+    ADD COLUMN "token_hash" text DEFAULT null,
+    ADD COLUMN "attempts" bigint DEFAULT 0,
+    ADD COLUMN "expires_at" timestamptz,
+    ADD COLUMN "used_at" timestamptz,
+    DROP COLUMN "reset_token";
+CREATE INDEX IF NOT EXISTS "idx_password_resets_user_id" ON "password_resets" ("user_id");
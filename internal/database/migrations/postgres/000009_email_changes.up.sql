@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS "email_changes" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text DEFAULT null,
+    "previous_email" text,
+    "previous_verified" boolean,
+    "new_email" text,
+    "code_hash" text DEFAULT null,
+    "attempts" bigint DEFAULT 0,
+    "expires_at" timestamptz,
+    "revert_expires_at" timestamptz,
+    "confirmed_at" timestamptz,
+    "reverted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_email_changes_deleted_at" ON "email_changes" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_email_changes_new_email" ON "email_changes" ("new_email");
+CREATE INDEX IF NOT EXISTS "idx_email_changes_user_id" ON "email_changes" ("user_id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "sign_in_fingerprints";
+DROP TABLE IF EXISTS "notification_preferences";
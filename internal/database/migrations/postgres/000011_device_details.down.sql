@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "idx_devices_user_id", "idx_devices_session_id", "idx_devices_device_id";
+ALTER TABLE "devices"
+    DROP COLUMN "browser_version",
+    DROP COLUMN "browser",
+    DROP COLUMN "os_version",
+    DROP COLUMN "device_id";
@@ -0,0 +1,10 @@
+ALTER TABLE "sessions"
+    DROP COLUMN "last_seen_at",
+    DROP COLUMN "last_seen_ip",
+    DROP COLUMN "device_type",
+    DROP COLUMN "os_version",
+    DROP COLUMN "os",
+    DROP COLUMN "browser_version",
+    DROP COLUMN "browser",
+    DROP COLUMN "user_agent",
+    DROP COLUMN "ip";
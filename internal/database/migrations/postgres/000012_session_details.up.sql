@@ -0,0 +1,10 @@
+ALTER TABLE "sessions"
+    ADD COLUMN "ip" text DEFAULT null,
+    ADD COLUMN "user_agent" varchar(512) DEFAULT null,
+    ADD COLUMN "browser" text DEFAULT null,
+    ADD COLUMN "browser_version" text DEFAULT null,
+    ADD COLUMN "os" text DEFAULT null,
+    ADD COLUMN "os_version" text DEFAULT null,
+    ADD COLUMN "device_type" text DEFAULT null,
+    ADD COLUMN "last_seen_ip" text DEFAULT null,
+    ADD COLUMN "last_seen_at" timestamptz DEFAULT null;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "risk_decisions";
+DROP TABLE IF EXISTS "sign_in_challenges";
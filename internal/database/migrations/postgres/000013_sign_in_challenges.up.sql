@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS "sign_in_challenges" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text NOT NULL,
+    "client_id" text DEFAULT null,
+    "risk_decision_id" bigint DEFAULT null,
+    "code_hash" text DEFAULT null,
+    "attempts" bigint DEFAULT 0,
+    "expires_at" timestamptz,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sign_in_challenges_deleted_at" ON "sign_in_challenges" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_sign_in_challenges_user_id" ON "sign_in_challenges" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "risk_decisions" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text NOT NULL,
+    "client_id" text DEFAULT null,
+    "session_id" bigint DEFAULT null,
+    "kind" varchar(32) NOT NULL,
+    "ip" text DEFAULT null,
+    "country" varchar(2) DEFAULT null,
+    "asn" bigint DEFAULT null,
+    "latitude" decimal DEFAULT null,
+    "longitude" decimal DEFAULT null,
+    "fingerprint" varchar(64) DEFAULT null,
+    "score" bigint NOT NULL,
+    "reasons" text DEFAULT null,
+    "action" varchar(32) NOT NULL,
+    "trusted" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_risk_decisions_deleted_at" ON "risk_decisions" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_risk_decisions_user_id" ON "risk_decisions" ("user_id");
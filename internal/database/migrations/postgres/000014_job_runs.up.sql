@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "job_leases" (
+    "name" varchar(64),
+    "holder" varchar(128) NOT NULL,
+    "expires_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "job_runs" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "job" varchar(64) NOT NULL,
+    "holder" varchar(128),
+    "started_at" timestamptz,
+    "finished_at" timestamptz,
+    "duration" bigint,
+    "outcome" varchar(16),
+    "result" text DEFAULT null,
+    "error" text DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_job_runs_deleted_at" ON "job_runs" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_job_runs_job" ON "job_runs" ("job");
+CREATE INDEX IF NOT EXISTS "idx_job_runs_started_at" ON "job_runs" ("started_at");
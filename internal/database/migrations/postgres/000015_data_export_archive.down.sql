@@ -0,0 +1 @@
+ALTER TABLE "data_exports" ALTER COLUMN "archive" TYPE text USING convert_from("archive", 'UTF8');
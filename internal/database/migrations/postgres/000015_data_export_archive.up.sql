@@ -0,0 +1,2 @@
+-- The archive is stored as bytes like on MySQL, where a text column holds 64 KB.
+ALTER TABLE "data_exports" ALTER COLUMN "archive" TYPE bytea USING convert_to("archive", 'UTF8');
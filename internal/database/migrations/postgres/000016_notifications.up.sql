@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "order_by" text,
+    "order" text,
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_id" text NOT NULL,
+    "event" varchar(64) NOT NULL,
+    "data" text,
+    "status" text NOT NULL,
+    "attempts" bigint DEFAULT 0,
+    "next_attempt_at" timestamptz,
+    "locked_until" timestamptz DEFAULT null,
+    "last_error" text DEFAULT null,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_deleted_at" ON "notifications" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_next_attempt_at" ON "notifications" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_status" ON "notifications" ("status");
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_id" ON "notifications" ("user_id");
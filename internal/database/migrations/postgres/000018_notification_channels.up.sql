@@ -0,0 +1,3 @@
+ALTER TABLE "notifications"
+    ADD COLUMN "email_sent_at" timestamptz DEFAULT null,
+    ADD COLUMN "push_sent_at" timestamptz DEFAULT null;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS `social_providers`;
+DROP TABLE IF EXISTS `role_permissions`;
+DROP TABLE IF EXISTS `permissions`;
+DROP TABLE IF EXISTS `devices`;
+DROP TABLE IF EXISTS `sessions`;
+DROP TABLE IF EXISTS `password_resets`;
+DROP TABLE IF EXISTS `profiles`;
+DROP TABLE IF EXISTS `user_roles`;
+DROP TABLE IF EXISTS `roles`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `event_type` text NOT NULL,
+    `user_id` text DEFAULT null,
+    `payload` text,
+    `processed_at` datetime DEFAULT null
+);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_deleted_at` ON `outbox_events`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_event_type` ON `outbox_events`(`event_type`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_processed_at` ON `outbox_events`(`processed_at`);
+CREATE INDEX IF NOT EXISTS `idx_outbox_events_user_id` ON `outbox_events`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `outbox_event_id` integer NOT NULL,
+    `client_id` text NOT NULL,
+    `url` text NOT NULL,
+    `status` text NOT NULL,
+    `attempts` integer DEFAULT 0,
+    `next_attempt_at` datetime,
+    `locked_until` datetime DEFAULT null,
+    `last_error` text DEFAULT null,
+    `last_status_code` integer DEFAULT 0,
+    `delivered_at` datetime DEFAULT null,
+    CONSTRAINT `fk_webhook_deliveries_outbox_event` FOREIGN KEY (`outbox_event_id`) REFERENCES `outbox_events`(`id`) ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_client_id` ON `webhook_deliveries`(`client_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_deleted_at` ON `webhook_deliveries`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_next_attempt_at` ON `webhook_deliveries`(`next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_outbox_event_id` ON `webhook_deliveries`(`outbox_event_id`);
+CREATE INDEX IF NOT EXISTS `idx_webhook_deliveries_status` ON `webhook_deliveries`(`status`);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_users_deleted_email`;
+ALTER TABLE `users` DROP COLUMN `deleted_email`;
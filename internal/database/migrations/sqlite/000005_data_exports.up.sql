@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS `data_exports` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `status` text NOT NULL,
+    `token_hash` varchar(64) NOT NULL,
+    `archive` text DEFAULT null,
+    `error` text DEFAULT null,
+    `locked_until` datetime DEFAULT null,
+    `completed_at` datetime DEFAULT null,
+    `expires_at` datetime DEFAULT null
+);
+CREATE INDEX IF NOT EXISTS `idx_data_exports_deleted_at` ON `data_exports`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_data_exports_expires_at` ON `data_exports`(`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_data_exports_status` ON `data_exports`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_data_exports_user_id` ON `data_exports`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_data_exports_token_hash` ON `data_exports`(`token_hash`);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `login_attempts` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `identifier` varchar(128) NOT NULL,
+    `failures` integer DEFAULT 0,
+    `last_failure_at` datetime,
+    `locked_until` datetime DEFAULT null
+);
+CREATE INDEX IF NOT EXISTS `idx_login_attempts_deleted_at` ON `login_attempts`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_login_attempts_identifier` ON `login_attempts`(`identifier`);
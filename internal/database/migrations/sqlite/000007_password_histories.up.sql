@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `password_histories` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `password` text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_password_histories_deleted_at` ON `password_histories`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_password_histories_user_id` ON `password_histories`(`user_id`);
+
+ALTER TABLE `users` ADD COLUMN `password_changed_at` datetime DEFAULT null;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS `idx_password_resets_user_id`;
+ALTER TABLE `password_resets` ADD COLUMN `reset_token` text DEFAULT null;
+ALTER TABLE `password_resets` DROP COLUMN `used_at`;
+ALTER TABLE `password_resets` DROP COLUMN `expires_at`;
+ALTER TABLE `password_resets` DROP COLUMN `attempts`;
+ALTER TABLE `password_resets` DROP COLUMN `token_hash`;
@@ -0,0 +1,8 @@
+-- Reset tokens of the previous scheme cannot be redeemed as codes, so they are removed with their column.
+DELETE FROM `password_resets`;
+ALTER TABLE `password_resets` ADD COLUMN `token_hash` text DEFAULT null;
+ALTER TABLE `password_resets` ADD COLUMN `attempts` integer DEFAULT 0;
+ALTER TABLE `password_resets` ADD COLUMN `expires_at` datetime;
+ALTER TABLE `password_resets` ADD COLUMN `used_at` datetime;
+ALTER TABLE `password_resets` DROP COLUMN `reset_token`;
+CREATE INDEX IF NOT EXISTS `idx_password_resets_user_id` ON `password_resets`(`user_id`);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS `email_changes` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text DEFAULT null,
+    `previous_email` text,
+    `previous_verified` numeric,
+    `new_email` text,
+    `code_hash` text DEFAULT null,
+    `attempts` integer DEFAULT 0,
+    `expires_at` datetime,
+    `revert_expires_at` datetime,
+    `confirmed_at` datetime,
+    `reverted_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_email_changes_deleted_at` ON `email_changes`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_email_changes_new_email` ON `email_changes`(`new_email`);
+CREATE INDEX IF NOT EXISTS `idx_email_changes_user_id` ON `email_changes`(`user_id`);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS `notification_preferences` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `event` varchar(64) NOT NULL,
+    `email` numeric,
+    `push` numeric
+);
+CREATE INDEX IF NOT EXISTS `idx_notification_preferences_deleted_at` ON `notification_preferences`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_notification_preferences_user_event` ON `notification_preferences`(`user_id`,`event`);
+
+CREATE TABLE IF NOT EXISTS `sign_in_fingerprints` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `fingerprint` varchar(64) NOT NULL,
+    `last_seen_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_sign_in_fingerprints_deleted_at` ON `sign_in_fingerprints`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_sign_in_fingerprints_user_fingerprint` ON `sign_in_fingerprints`(`user_id`,`fingerprint`);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS `idx_devices_user_id`;
+DROP INDEX IF EXISTS `idx_devices_session_id`;
+DROP INDEX IF EXISTS `idx_devices_device_id`;
+ALTER TABLE `devices` DROP COLUMN `browser_version`;
+ALTER TABLE `devices` DROP COLUMN `browser`;
+ALTER TABLE `devices` DROP COLUMN `os_version`;
+ALTER TABLE `devices` DROP COLUMN `device_id`;
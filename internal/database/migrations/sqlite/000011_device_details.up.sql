@@ -0,0 +1,7 @@
+ALTER TABLE `devices` ADD COLUMN `device_id` varchar(64) DEFAULT null;
+ALTER TABLE `devices` ADD COLUMN `os_version` text DEFAULT null;
+ALTER TABLE `devices` ADD COLUMN `browser` text DEFAULT null;
+ALTER TABLE `devices` ADD COLUMN `browser_version` text DEFAULT null;
+CREATE INDEX IF NOT EXISTS `idx_devices_user_id` ON `devices`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_devices_session_id` ON `devices`(`session_id`);
+CREATE INDEX IF NOT EXISTS `idx_devices_device_id` ON `devices`(`device_id`);
@@ -0,0 +1,9 @@
+ALTER TABLE `sessions` DROP COLUMN `last_seen_at`;
+ALTER TABLE `sessions` DROP COLUMN `last_seen_ip`;
+ALTER TABLE `sessions` DROP COLUMN `device_type`;
+ALTER TABLE `sessions` DROP COLUMN `os_version`;
+ALTER TABLE `sessions` DROP COLUMN `os`;
+ALTER TABLE `sessions` DROP COLUMN `browser_version`;
+ALTER TABLE `sessions` DROP COLUMN `browser`;
+ALTER TABLE `sessions` DROP COLUMN `user_agent`;
+ALTER TABLE `sessions` DROP COLUMN `ip`;
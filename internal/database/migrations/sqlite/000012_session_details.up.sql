@@ -0,0 +1,9 @@
+ALTER TABLE `sessions` ADD COLUMN `ip` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `user_agent` varchar(512) DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `browser` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `browser_version` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `os` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `os_version` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `device_type` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `last_seen_ip` text DEFAULT null;
+ALTER TABLE `sessions` ADD COLUMN `last_seen_at` datetime DEFAULT null;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS `sign_in_challenges` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `client_id` text DEFAULT null,
+    `risk_decision_id` integer DEFAULT null,
+    `code_hash` text DEFAULT null,
+    `attempts` integer DEFAULT 0,
+    `expires_at` datetime,
+    `used_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_sign_in_challenges_deleted_at` ON `sign_in_challenges`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_sign_in_challenges_user_id` ON `sign_in_challenges`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `risk_decisions` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `client_id` text DEFAULT null,
+    `session_id` integer DEFAULT null,
+    `kind` varchar(32) NOT NULL,
+    `ip` text DEFAULT null,
+    `country` varchar(2) DEFAULT null,
+    `asn` integer DEFAULT null,
+    `latitude` real DEFAULT null,
+    `longitude` real DEFAULT null,
+    `fingerprint` varchar(64) DEFAULT null,
+    `score` integer NOT NULL,
+    `reasons` text DEFAULT null,
+    `action` varchar(32) NOT NULL,
+    `trusted` numeric NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS `idx_risk_decisions_deleted_at` ON `risk_decisions`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_risk_decisions_user_id` ON `risk_decisions`(`user_id`);
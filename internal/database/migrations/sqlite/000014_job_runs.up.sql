@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `job_leases` (
+    `name` varchar(64),
+    `holder` varchar(128) NOT NULL,
+    `expires_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`name`)
+);
+
+CREATE TABLE IF NOT EXISTS `job_runs` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `job` varchar(64) NOT NULL,
+    `holder` varchar(128),
+    `started_at` datetime,
+    `finished_at` datetime,
+    `duration` integer,
+    `outcome` varchar(16),
+    `result` text DEFAULT null,
+    `error` text DEFAULT null
+);
+CREATE INDEX IF NOT EXISTS `idx_job_runs_deleted_at` ON `job_runs`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_job_runs_job` ON `job_runs`(`job`);
+CREATE INDEX IF NOT EXISTS `idx_job_runs_started_at` ON `job_runs`(`started_at`);
@@ -0,0 +1,2 @@
+-- SQLite stores the archive bytes in the text column as they are and does not limit their size, so there is nothing to
+-- change. The migration exists to keep the versions of the engines aligned.
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS `notifications` (
+    `order_by` text,
+    `order` text,
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_id` text NOT NULL,
+    `event` varchar(64) NOT NULL,
+    `data` text,
+    `status` text NOT NULL,
+    `attempts` integer DEFAULT 0,
+    `next_attempt_at` datetime,
+    `locked_until` datetime DEFAULT null,
+    `last_error` text DEFAULT null
+);
+CREATE INDEX IF NOT EXISTS `idx_notifications_deleted_at` ON `notifications`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_notifications_next_attempt_at` ON `notifications`(`next_attempt_at`);
+CREATE INDEX IF NOT EXISTS `idx_notifications_status` ON `notifications`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_notifications_user_id` ON `notifications`(`user_id`);
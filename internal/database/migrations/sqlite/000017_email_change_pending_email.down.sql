@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_email_changes_pending_email`;
+ALTER TABLE `email_changes` DROP COLUMN `pending_email`;
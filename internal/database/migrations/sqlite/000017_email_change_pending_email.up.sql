@@ -0,0 +1,7 @@
+ALTER TABLE `email_changes` ADD COLUMN `pending_email` text DEFAULT null;
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_email_changes_pending_email` ON `email_changes`(`pending_email`);
+UPDATE `email_changes` SET `pending_email` = `new_email` WHERE `id` IN (
+    SELECT MAX(`id`) FROM `email_changes`
+    WHERE `confirmed_at` IS NULL AND `reverted_at` IS NULL
+    GROUP BY `new_email`
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `notifications` DROP COLUMN `email_sent_at`;
+ALTER TABLE `notifications` DROP COLUMN `push_sent_at`;
@@ -0,0 +1,2 @@
+ALTER TABLE `notifications` ADD COLUMN `email_sent_at` datetime DEFAULT null;
+ALTER TABLE `notifications` ADD COLUMN `push_sent_at` datetime DEFAULT null;
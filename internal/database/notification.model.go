@@ -0,0 +1,85 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Notification is a security notification waiting to be sent. The request that caused it only stores it, the
+// notification worker sends it and retries it while the mailer or the push gateway fails, also across restarts. Each
+// channel is recorded once it is delivered, so a retry only sends the channels that failed.
+type Notification struct {
+	UINTBaseModel
+	UserID        uuid.UUID  `gorm:"not null;index" json:"user_id"`
+	Event         string     `gorm:"type:varchar(64);not null" json:"event"`
+	Data          string     `gorm:"type:text" json:"-"`
+	Status        string     `gorm:"not null;index" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	LockedUntil   *time.Time `gorm:"default:null" json:"-"`
+	LastError     string     `gorm:"type:text;default:null" json:"last_error,omitempty"`
+	EmailSentAt   *time.Time `gorm:"default:null" json:"email_sent_at,omitempty"`
+	PushSentAt    *time.Time `gorm:"default:null" json:"push_sent_at,omitempty"`
+}
+
+// EnqueueNotification stores a notification for the worker, data is the JSON of the message fields
+func EnqueueNotification(userID uuid.UUID, event string, data []byte) error {
+	return DB.Create(&Notification{
+		UserID:        userID,
+		Event:         event,
+		Data:          string(data),
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// GetDueNotifications returns the pending notifications whose next attempt is due, oldest first
+func GetDueNotifications(now time.Time, limit int) ([]Notification, error) {
+	var notifications []Notification
+	err := DB.Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, now).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// Lock claims the notification until the given time, so that only one worker sends it
+func (n *Notification) Lock(now, until time.Time) (bool, error) {
+	result := DB.Model(&Notification{}).
+		Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", n.ID, DeliveryStatusPending, now).
+		Update("locked_until", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Save stores the outcome of a failed attempt and releases the lock
+func (n *Notification) Save() error {
+	n.LockedUntil = nil
+	return DB.Model(n).Select("Status", "Attempts", "NextAttemptAt", "LockedUntil", "LastError", "EmailSentAt", "PushSentAt").Updates(n).Error
+}
+
+// Sent removes the notification once it is delivered, the audit events already record what caused it
+func (n *Notification) Sent() error {
+	return DB.Unscoped().Delete(n).Error
+}
+
+// DeleteNotifications removes up to limit notifications queued before the time, the ones given up on included, and
+// returns how many were removed
+func DeleteNotifications(createdBefore time.Time, limit int) (int, error) {
+	var ids []uint64
+	err := DB.Unscoped().Model(&Notification{}).Where("created_at < ?", createdBefore).
+		Order("id asc").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	if err = DB.Unscoped().Where("id IN ?", ids).Delete(&Notification{}).Error; err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
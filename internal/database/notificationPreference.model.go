@@ -0,0 +1,45 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// NotificationPreference turns the email and push channels of a security notification on or off for a user. Without
+// a preference both channels are on.
+type NotificationPreference struct {
+	UINTBaseModel
+	UserID uuid.UUID `gorm:"not null;uniqueIndex:idx_notification_preferences_user_event" json:"-"`
+	Event  string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_notification_preferences_user_event" json:"event"`
+	Email  bool      `json:"email"`
+	Push   bool      `json:"push"`
+}
+
+// GetNotificationPreferences returns the preferences the user changed
+func GetNotificationPreferences(userID uuid.UUID) ([]NotificationPreference, error) {
+	var preferences []NotificationPreference
+	if err := DB.Where("user_id = ?", userID).Order("event asc").Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// SaveNotificationPreferences creates or updates the preferences of the user
+func SaveNotificationPreferences(userID uuid.UUID, preferences []NotificationPreference) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range preferences {
+		preferences[i].UserID = userID
+		preferences[i].UpdatedAt = now
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email", "push", "updated_at"}),
+		}).Create(&preferences).Error
+	})
+}
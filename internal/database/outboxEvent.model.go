@@ -0,0 +1,183 @@
+package database
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	EventUserCreated       = "user.created"
+	EventUserEmailVerified = "user.email_verified"
+	EventUserEmailChanged  = "user.email_changed"
+	EventUserDeleted       = "user.deleted"
+	EventUserRestored      = "user.restored"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// OutboxEvent is a user lifecycle event written in the same transaction as the change it describes. The webhook
+// dispatcher fans it out into one WebhookDelivery per subscribed endpoint.
+type OutboxEvent struct {
+	UINTBaseModel
+	EventType   string     `gorm:"not null;index" json:"event_type"`
+	UserID      uuid.UUID  `gorm:"default:null;index" json:"user_id"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	ProcessedAt *time.Time `gorm:"default:null;index" json:"processed_at,omitempty"`
+}
+
+// WebhookDelivery tracks the delivery of an outbox event to one webhook endpoint of a client
+type WebhookDelivery struct {
+	UINTBaseModel
+	OutboxEventID  uint64      `gorm:"not null;index" json:"outbox_event_id"`
+	OutboxEvent    OutboxEvent `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ClientID       string      `gorm:"not null;index" json:"client_id"`
+	URL            string      `gorm:"not null" json:"url"`
+	Status         string      `gorm:"not null;index" json:"status"`
+	Attempts       int         `gorm:"default:0" json:"attempts"`
+	NextAttemptAt  time.Time   `gorm:"index" json:"next_attempt_at"`
+	LockedUntil    *time.Time  `gorm:"default:null" json:"-"`
+	LastError      string      `gorm:"type:text;default:null" json:"last_error,omitempty"`
+	LastStatusCode int         `gorm:"default:0" json:"last_status_code,omitempty"`
+	DeliveredAt    *time.Time  `gorm:"default:null" json:"delivered_at,omitempty"`
+}
+
+// UserEventData is the payload of the user lifecycle events
+type UserEventData struct {
+	UserID        string `json:"user_id"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	EmailVerified bool   `json:"email_verified"`
+	PreviousEmail string `json:"previous_email,omitempty"`
+}
+
+// WebhookDeliveryFilter narrows down the deliveries returned by GetWebhookDeliveries
+type WebhookDeliveryFilter struct {
+	Status   string
+	ClientID string
+}
+
+// EnqueueOutboxEvent stores an event in the outbox using the given transaction
+func EnqueueOutboxEvent(tx *gorm.DB, eventType string, userID uuid.UUID, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&OutboxEvent{
+		EventType: eventType,
+		UserID:    userID,
+		Payload:   string(payload),
+	}).Error
+}
+
+// GetUnprocessedOutboxEvents returns the oldest events that have not been fanned out yet
+func GetUnprocessedOutboxEvents(limit int) ([]OutboxEvent, error) {
+	var events []OutboxEvent
+	if err := DB.Where("processed_at IS NULL").Order("id asc").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ProcessOutboxEvent marks the event as processed and creates its deliveries in one transaction. It returns false when
+// another dispatcher processed the event first.
+func ProcessOutboxEvent(event *OutboxEvent, deliveries []WebhookDelivery) (bool, error) {
+	claimed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&OutboxEvent{}).
+			Where("id = ? AND processed_at IS NULL", event.ID).
+			Update("processed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		claimed = true
+		event.ProcessedAt = &now
+		if len(deliveries) == 0 {
+			return nil
+		}
+		return tx.Create(&deliveries).Error
+	})
+	return claimed, err
+}
+
+// GetDueWebhookDeliveries returns pending deliveries whose next attempt is due
+func GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := DB.Preload("OutboxEvent").
+		Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, now).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Lock claims the delivery until the given time, so that only one dispatcher sends it
+func (d *WebhookDelivery) Lock(now, until time.Time) (bool, error) {
+	result := DB.Model(&WebhookDelivery{}).
+		Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", d.ID, DeliveryStatusPending, now).
+		Update("locked_until", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Save stores the outcome of a delivery attempt and releases the lock
+func (d *WebhookDelivery) Save() error {
+	d.LockedUntil = nil
+	return DB.Model(d).Select("Status", "Attempts", "NextAttemptAt", "LockedUntil", "LastError", "LastStatusCode", "DeliveredAt").Updates(d).Error
+}
+
+// Redeliver resets a delivery so that the dispatcher sends it again with a fresh retry budget
+func (d *WebhookDelivery) Redeliver() error {
+	d.Status = DeliveryStatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	d.LastError = ""
+	d.LastStatusCode = 0
+	d.DeliveredAt = nil
+	return d.Save()
+}
+
+func GetWebhookDeliveryById(id uint64) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := DB.Where("id = ?", id).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries returns a page of deliveries matching the filter, newest first
+func GetWebhookDeliveries(filter WebhookDeliveryFilter, md pagination.PageMetadata) ([]WebhookDelivery, int64, error) {
+	var count int64
+	var deliveries []WebhookDelivery
+
+	query := DB.Model(&WebhookDelivery{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ClientID != "" {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Preload("OutboxEvent").Order("id desc").Offset(int(md.Offset())).Limit(int(md.PageSize)).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, count, nil
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/password"
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps the hashes of the passwords a user had, to prevent reusing them
+type PasswordHistory struct {
+	UINTBaseModel
+	UserID   uuid.UUID `gorm:"not null;index" json:"-"`
+	Password string    `gorm:"not null" json:"-"`
+}
+
+// GetPasswordHistory returns the last password hashes of a user, newest first
+func GetPasswordHistory(userID uuid.UUID, limit int) ([]PasswordHistory, error) {
+	var history []PasswordHistory
+	err := DB.Where("user_id = ?", userID).Order("created_at desc, id desc").Limit(limit).Find(&history).Error
+	return history, err
+}
+
+// AddPasswordHistory records the current password hash of the user and forgets all but the last depth hashes
+func (u *User) AddPasswordHistory(tx *gorm.DB, depth int) error {
+	if depth <= 0 || u.Password == "" {
+		return nil
+	}
+	if err := tx.Create(&PasswordHistory{UserID: u.ID, Password: u.Password}).Error; err != nil {
+		return err
+	}
+	var keep []uint64
+	err := tx.Model(&PasswordHistory{}).Where("user_id = ?", u.ID).Order("created_at desc, id desc").Limit(depth).Pluck("id", &keep).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("user_id = ? AND id NOT IN ?", u.ID, keep).Delete(&PasswordHistory{}).Error
+}
+
+// SavePassword stores the password set with SetPassword and records it in the history
+func (u *User) SavePassword(historyDepth int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(u).Select("password", "password_changed_at").Updates(u).Error; err != nil {
+			return err
+		}
+		return u.AddPasswordHistory(tx, historyDepth)
+	})
+}
+
+// IsPasswordReused reports whether the password is the current one or one of the last depth passwords of the user
+func (u *User) IsPasswordReused(plain string, depth int) (bool, error) {
+	if depth <= 0 {
+		return false, nil
+	}
+	hashes := []string{u.Password}
+	history, err := GetPasswordHistory(u.ID, depth)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range history {
+		hashes = append(hashes, entry.Password)
+	}
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		// Hashes in an unknown format cannot match, they are not a reason to refuse the change
+		if ok, _, _ := password.Verify(plain, hash); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestPasswordHistory(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+
+	for _, plain := range []string{"first-Passw0rd", "second-Passw0rd", "third-Passw0rd"} {
+		if err := user.SetPassword(plain); err != nil {
+			t.Fatal(err)
+		}
+		if err := user.AddPasswordHistory(DB, 2); err != nil {
+			t.Fatalf("AddPasswordHistory failed: %v", err)
+		}
+	}
+	if user.PasswordChangedAt == nil {
+		t.Error("Expected SetPassword to record when the password changed")
+	}
+
+	history, err := GetPasswordHistory(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected the history to keep 2 hashes, got %d", len(history))
+	}
+
+	for plain, want := range map[string]bool{
+		"third-Passw0rd":  true,
+		"second-Passw0rd": true,
+		"first-Passw0rd":  false,
+		"fourth-Passw0rd": false,
+	} {
+		reused, err := user.IsPasswordReused(plain, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reused != want {
+			t.Errorf("IsPasswordReused(%q) = %v, want %v", plain, reused, want)
+		}
+	}
+	if reused, _ := user.IsPasswordReused("third-Passw0rd", 0); reused {
+		t.Error("Expected a depth of 0 to disable the check")
+	}
+}
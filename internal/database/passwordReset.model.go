@@ -1,16 +1,134 @@
 package database
 
 import (
+	"crypto/subtle"
+	"errors"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
 )
 
+// ErrPasswordResetUsed is returned when a password reset was used or replaced in the meantime
+var ErrPasswordResetUsed = errors.New("password reset already used")
+
+// PasswordReset is a password reset request. Only the hash of its code is stored, it expires at ExpiresAt, after
+// MaxAttempts wrong codes, once it is used and when a newer reset is requested.
 type PasswordReset struct {
 	UINTBaseModel
-	UserID     uuid.UUID `gorm:"default:null" json:"-"`
-	ResetToken string    `gorm:"default:null" json:"-"`
+	UserID    uuid.UUID  `gorm:"default:null;index" json:"-"`
+	TokenHash string     `gorm:"default:null" json:"-"`
+	Attempts  int        `gorm:"default:0" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// CreatePasswordReset stores a new password reset for the user and invalidates the previous ones that were not used
+func CreatePasswordReset(userID uuid.UUID, token string, ttl time.Duration) (*PasswordReset, error) {
+	now := time.Now()
+	reset := &PasswordReset{
+		UserID:    userID,
+		TokenHash: HashCode(token),
+		ExpiresAt: now.Add(ttl),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, now).
+			Update("expires_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reset, nil
+}
+
+// GetLatestPasswordReset returns the last password reset requested by the user, used or not
+func GetLatestPasswordReset(userID uuid.UUID) (*PasswordReset, error) {
+	var reset PasswordReset
+	if err := DB.Where("user_id = ?", userID).Order("created_at desc").Order("id desc").First(&reset).Error; err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// GetPasswordResetById returns a password reset of the user
+func GetPasswordResetById(id uint64, userID uuid.UUID) (*PasswordReset, error) {
+	var reset PasswordReset
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&reset).Error; err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// GetPasswordResetsByUserId returns the password resets of a user, newest first
+func GetPasswordResetsByUserId(userID uuid.UUID) ([]PasswordReset, error) {
+	var resets []PasswordReset
+	if err := DB.Where("user_id = ?", userID).Order("created_at desc").Find(&resets).Error; err != nil {
+		return nil, err
+	}
+	return resets, nil
+}
+
+// IsUsable reports whether the reset can still be used
+func (pReset *PasswordReset) IsUsable(now time.Time, maxAttempts int) bool {
+	if pReset.UsedAt != nil || !now.Before(pReset.ExpiresAt) {
+		return false
+	}
+	return maxAttempts <= 0 || pReset.Attempts < maxAttempts
+}
+
+// CheckToken compares the code with the stored hash and counts a wrong code as a failed attempt
+func (pReset *PasswordReset) CheckToken(token string) (bool, error) {
+	if subtle.ConstantTimeCompare([]byte(HashCode(token)), []byte(pReset.TokenHash)) == 1 {
+		return true, nil
+	}
+	err := DB.Model(&PasswordReset{}).Where("id = ?", pReset.ID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return false, err
+	}
+	pReset.Attempts++
+	return false, nil
+}
+
+// CompletePasswordReset marks the reset as used, saves the new password of the user set with SetPassword and revokes
+// all sessions of the user with their devices. It returns the revoked sessions and fails with ErrPasswordResetUsed when
+// the reset was used concurrently.
+func (u *User) CompletePasswordReset(reset *PasswordReset, historyDepth int) ([]Session, error) {
+	var revoked []Session
+	now := time.Now()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPasswordResetUsed
+		}
+		if err := tx.Model(u).Select("password", "password_changed_at").Updates(u).Error; err != nil {
+			return err
+		}
+		if err := u.AddPasswordHistory(tx, historyDepth); err != nil {
+			return err
+		}
+		var err error
+		revoked, err = revokeSessions(tx, u.ID, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	reset.UsedAt = &now
+	return revoked, nil
 }
 
-// CheckResetToken checks if the password reset token is valid
-func (pReset *PasswordReset) CheckResetToken(token string) bool {
-	return pReset.ResetToken == token
+// DeleteStalePasswordResets removes the resets that expired before the time, used or not
+func DeleteStalePasswordResets(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("expires_at < ?", before).Delete(&PasswordReset{})
+	return result.RowsAffected, result.Error
 }
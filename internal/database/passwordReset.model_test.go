@@ -0,0 +1,109 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetInvalidatesPreviousReset(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+
+	first, err := CreatePasswordReset(user.ID, "first1", time.Hour)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset failed: %v", err)
+	}
+	if first.TokenHash == "first1" || first.TokenHash != HashCode("first1") {
+		t.Fatalf("Expected only the hash of the code to be stored, got %q", first.TokenHash)
+	}
+	second, err := CreatePasswordReset(user.ID, "second", time.Hour)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset failed: %v", err)
+	}
+
+	latest, err := GetLatestPasswordReset(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("Expected the latest reset to be %d, got %d", second.ID, latest.ID)
+	}
+	previous, err := GetPasswordResetById(first.ID, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.IsUsable(time.Now(), 5) {
+		t.Error("Expected a newer reset to invalidate the previous one")
+	}
+	if !latest.IsUsable(time.Now(), 5) {
+		t.Error("Expected the latest reset to be usable")
+	}
+}
+
+func TestPasswordResetAttempts(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	reset, err := CreatePasswordReset(user.ID, "abc123", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if ok, err := reset.CheckToken("wrong1"); err != nil || ok {
+			t.Fatalf("CheckToken(wrong) = %v, %v", ok, err)
+		}
+	}
+	if ok, err := reset.CheckToken("abc123"); err != nil || !ok {
+		t.Fatalf("CheckToken(right) = %v, %v", ok, err)
+	}
+	stored, _ := GetLatestPasswordReset(user.ID)
+	if stored.Attempts != 2 {
+		t.Errorf("Expected 2 recorded attempts, got %d", stored.Attempts)
+	}
+	if stored.IsUsable(time.Now(), 2) {
+		t.Error("Expected the reset to be unusable after the maximum attempts")
+	}
+	if stored.IsUsable(stored.ExpiresAt, 0) {
+		t.Error("Expected the reset to expire at ExpiresAt")
+	}
+}
+
+func TestCompletePasswordReset(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	if err := DB.Create(&Session{UserID: user.ID, RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	reset, err := CreatePasswordReset(user.ID, "abc123", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = user.SetPassword("new-Passw0rd"); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := user.CompletePasswordReset(reset, 4)
+	if err != nil {
+		t.Fatalf("CompletePasswordReset failed: %v", err)
+	}
+	if len(revoked) != 1 {
+		t.Errorf("Expected the revoked session to be returned, got %d", len(revoked))
+	}
+	if _, err = user.CompletePasswordReset(reset, 4); !errors.Is(err, ErrPasswordResetUsed) {
+		t.Errorf("Expected a reset to be usable once, got %v", err)
+	}
+
+	var sessions int64
+	DB.Model(&Session{}).Where("user_id = ?", user.ID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected the sessions to be revoked, %d left", sessions)
+	}
+	stored, _ := GetUserByID(user.ID, false)
+	if !stored.ComparePassword("new-Passw0rd") {
+		t.Error("Expected the new password to be saved")
+	}
+	if reused, _ := stored.IsPasswordReused("new-Passw0rd", 4); !reused {
+		t.Error("Expected the new password to be recorded in the history")
+	}
+}
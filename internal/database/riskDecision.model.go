@@ -0,0 +1,81 @@
+package database
+
+import (
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// RiskDecision is the outcome of the risk checks of a sign-in or a token refresh. Trusted decisions, the allowed ones
+// and the ones the user confirmed, form the history new requests are compared against.
+type RiskDecision struct {
+	UINTBaseModel
+	UserID      uuid.UUID `gorm:"not null;index" json:"-"`
+	ClientID    string    `gorm:"default:null" json:"client_id,omitempty"`
+	SessionID   uint64    `gorm:"default:null" json:"session_id,omitempty"`
+	Kind        string    `gorm:"type:varchar(32);not null" json:"kind"`
+	IP          string    `gorm:"default:null" json:"ip,omitempty"`
+	Country     string    `gorm:"type:varchar(2);default:null" json:"country,omitempty"`
+	ASN         uint      `gorm:"default:null" json:"asn,omitempty"`
+	Latitude    float64   `gorm:"default:null" json:"latitude,omitempty"`
+	Longitude   float64   `gorm:"default:null" json:"longitude,omitempty"`
+	Fingerprint string    `gorm:"type:varchar(64);default:null" json:"-"`
+	Score       int       `gorm:"not null" json:"score"`
+	Reasons     string    `gorm:"default:null" json:"reasons,omitempty"`
+	Action      string    `gorm:"type:varchar(32);not null" json:"action"`
+	Trusted     bool      `gorm:"not null;default:false" json:"trusted"`
+}
+
+// GetReasons returns the reasons of the score
+func (d *RiskDecision) GetReasons() []string {
+	if d.Reasons == "" {
+		return nil
+	}
+	return strings.Split(d.Reasons, ",")
+}
+
+// SetReasons stores the reasons of the score
+func (d *RiskDecision) SetReasons(reasons []string) {
+	d.Reasons = strings.Join(reasons, ",")
+}
+
+// CreateRiskDecision records a decision
+func CreateRiskDecision(decision *RiskDecision) error {
+	return DB.Create(decision).Error
+}
+
+// GetRiskDecisionsByUserId returns the decisions of a user, newest first
+func GetRiskDecisionsByUserId(userID uuid.UUID) ([]RiskDecision, error) {
+	var decisions []RiskDecision
+	if err := DB.Where("user_id = ?", userID).Order("created_at desc").Order("id desc").Find(&decisions).Error; err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// GetTrustedRiskDecisions returns the latest trusted decisions of the user, newest first
+func GetTrustedRiskDecisions(userID uuid.UUID, limit int) ([]RiskDecision, error) {
+	var decisions []RiskDecision
+	query := DB.Where("user_id = ? AND trusted = ?", userID, true).Order("created_at desc").Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&decisions).Error; err != nil {
+		return nil, err
+	}
+	return decisions, nil
+}
+
+// DeleteRiskDecisions removes up to limit decisions made before the time, and returns how many were removed
+func DeleteRiskDecisions(createdBefore time.Time, limit int) (int, error) {
+	var ids []uint64
+	err := DB.Unscoped().Model(&RiskDecision{}).Where("created_at < ?", createdBefore).
+		Order("id asc").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	if err = DB.Unscoped().Where("id IN ?", ids).Delete(&RiskDecision{}).Error; err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
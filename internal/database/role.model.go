@@ -1,5 +1,10 @@
 package database
 
+import "github.com/google/uuid"
+
+// AdminRoleKey is the key of the role that grants access to the admin RPCs
+const AdminRoleKey = "admin"
+
 type Role struct {
 	UINTBaseModel
 	Name        string       `json:"name" gorm:"type:varchar(255);not null"`
@@ -7,3 +12,54 @@ type Role struct {
 	Description string       `json:"description" gorm:"type:varchar(255);not null"`
 	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
 }
+
+// UserHasRole checks whether the user has been assigned the role with the given key
+func UserHasRole(userID uuid.UUID, key string) (bool, error) {
+	var count int64
+	err := DB.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id = ? AND roles.key = ?", userID, key).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// EnsureRole returns the role with the given key, created when it does not exist yet
+func EnsureRole(key, name, description string) (*Role, error) {
+	role := Role{Key: key, Name: name, Description: description}
+	if err := DB.Where(Role{Key: key}).Attrs(role).FirstOrCreate(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetRoleByKey gets a role by its key
+func GetRoleByKey(key string) (*Role, error) {
+	var role Role
+	if err := DB.Where(&Role{Key: key}).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// AssignRole gives the role to the user, assigning it twice has no effect
+func AssignRole(user *User, role *Role) error {
+	return DB.Model(user).Association("Roles").Append(role)
+}
+
+// UnassignRole takes the role from the user
+func UnassignRole(user *User, role *Role) error {
+	return DB.Model(user).Association("Roles").Delete(role)
+}
+
+// GetUserRoles returns the roles assigned to the user
+func GetUserRoles(userID uuid.UUID) ([]Role, error) {
+	var roles []Role
+	err := DB.Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.key").
+		Find(&roles).Error
+	return roles, err
+}
@@ -0,0 +1,69 @@
+package database
+
+import "testing"
+
+func TestAssignRole(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+
+	role, err := EnsureRole(AdminRoleKey, "Admin", "Access to the admin RPCs")
+	if err != nil {
+		t.Fatalf("EnsureRole failed: %v", err)
+	}
+	again, err := EnsureRole(AdminRoleKey, "Other", "")
+	if err != nil || again.ID != role.ID || again.Name != "Admin" {
+		t.Fatalf("Expected EnsureRole to return the existing role, got %+v (%v)", again, err)
+	}
+	if found, err := GetRoleByKey(AdminRoleKey); err != nil || found.ID != role.ID {
+		t.Fatalf("Expected GetRoleByKey to find the role, got %+v (%v)", found, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = AssignRole(user, role); err != nil {
+			t.Fatalf("AssignRole failed: %v", err)
+		}
+	}
+	roles, err := GetUserRoles(user.ID)
+	if err != nil || len(roles) != 1 || roles[0].Key != AdminRoleKey {
+		t.Fatalf("Expected the user to have the admin role once, got %+v (%v)", roles, err)
+	}
+	if isAdmin, _ := UserHasRole(user.ID, AdminRoleKey); !isAdmin {
+		t.Error("Expected UserHasRole to report the assigned role")
+	}
+
+	if err = UnassignRole(user, role); err != nil {
+		t.Fatalf("UnassignRole failed: %v", err)
+	}
+	if isAdmin, _ := UserHasRole(user.ID, AdminRoleKey); isAdmin {
+		t.Error("Expected the role to be taken away")
+	}
+}
+
+func TestUserCreate(t *testing.T) {
+	SetupTestDB(t)
+	user := User{Name: "Jane", Email: "jane@example.com", Password: "hash"}
+	if err := user.Create(2); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	history, err := GetPasswordHistory(user.ID, 2)
+	if err != nil || len(history) != 1 {
+		t.Errorf("Expected the password to be recorded in the history, got %d (%v)", len(history), err)
+	}
+	var events int64
+	DB.Model(&OutboxEvent{}).Where("user_id = ? AND event_type = ?", user.ID, EventUserCreated).Count(&events)
+	if events != 1 {
+		t.Errorf("Expected a user.created event, got %d", events)
+	}
+
+	user.Password = "new-hash"
+	if err = user.SavePassword(2); err != nil {
+		t.Fatalf("SavePassword failed: %v", err)
+	}
+	stored, _ := GetUserByID(user.ID, false)
+	if stored.Password != "new-hash" {
+		t.Error("Expected the new password to be stored")
+	}
+	if history, _ = GetPasswordHistory(user.ID, 5); len(history) != 2 {
+		t.Errorf("Expected 2 passwords in the history, got %d", len(history))
+	}
+}
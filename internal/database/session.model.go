@@ -1,8 +1,17 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
 	"github.com/usercoredev/usercore/internal/token"
+	"github.com/usercoredev/usercore/internal/useragent"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +23,165 @@ type Session struct {
 	ClientID     string    `gorm:"default:null" json:"client_id,omitempty"`
 	ClientName   string    `gorm:"default:null" json:"client_name,omitempty"`
 	Device       Device    `gorm:"foreignKey:SessionID" json:"device,omitempty"`
+	// IP is the address the session was created from, LastSeenIP and LastSeenAt are updated when it is refreshed, and
+	// LastSeenAt also by the calls with its access tokens
+	IP             string     `gorm:"default:null" json:"ip,omitempty"`
+	UserAgent      string     `gorm:"type:varchar(512);default:null" json:"user_agent,omitempty"`
+	Browser        string     `gorm:"default:null" json:"browser,omitempty"`
+	BrowserVersion string     `gorm:"default:null" json:"browser_version,omitempty"`
+	OS             string     `gorm:"default:null" json:"os,omitempty"`
+	OSVersion      string     `gorm:"default:null" json:"os_version,omitempty"`
+	DeviceType     string     `gorm:"default:null" json:"device_type,omitempty"`
+	LastSeenIP     string     `gorm:"default:null" json:"last_seen_ip,omitempty"`
+	LastSeenAt     *time.Time `gorm:"default:null" json:"last_seen_at,omitempty"`
+}
+
+// maxUserAgentLength is the size of the user agent column, longer user agents are cut
+const maxUserAgentLength = 512
+
+// recordOrigin stores the address and the user agent of the caller, the user agent is kept when the caller sends none
+func (session *Session) recordOrigin(ctx context.Context, now time.Time) {
+	ip := clientip.FromContext(ctx)
+	if session.IP == "" {
+		session.IP = ip
+	}
+	session.LastSeenIP = ip
+	session.LastSeenAt = &now
+	if userAgent := clientip.UserAgent(ctx); userAgent != "" {
+		if len(userAgent) > maxUserAgentLength {
+			userAgent = userAgent[:maxUserAgentLength]
+		}
+		agent := useragent.Parse(userAgent)
+		session.UserAgent = userAgent
+		session.Browser = agent.Browser
+		session.BrowserVersion = agent.BrowserVersion
+		session.OS = agent.OS
+		session.OSVersion = agent.OSVersion
+		session.DeviceType = agent.Device
+	}
+}
+
+// clientSessionPolicy returns the session policy of the calling client
+func clientSessionPolicy(ctx context.Context) sessionpolicy.Policy {
+	if sessionClient, ok := ctx.Value(client.Key).(*client.Item); ok && sessionClient != nil {
+		return sessionpolicy.Resolve(sessionClient.Sessions)
+	}
+	return sessionpolicy.Resolve(nil)
+}
+
+// evictSessions ends the least recently used sessions of the user so the new session fits the policy. The user row is
+// locked until the transaction ends, so concurrent sign-ins of the user count the sessions one after the other and
+// cannot both exceed the limits.
+func evictSessions(tx *gorm.DB, policy sessionpolicy.Policy, next *Session) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", next.UserID).Take(&User{}).Error
+	if err != nil {
+		return err
+	}
+	var sessions []Session
+	err = tx.Select("id", "client_id", "device_type", "last_seen_at", "created_at").
+		Where("user_id = ?", next.UserID).Find(&sessions).Error
+	if err != nil {
+		return err
+	}
+	existing := make([]sessionpolicy.Session, 0, len(sessions))
+	for _, session := range sessions {
+		lastUsedAt := session.CreatedAt
+		if session.LastSeenAt != nil {
+			lastUsedAt = *session.LastSeenAt
+		}
+		existing = append(existing, sessionpolicy.Session{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			DeviceType: session.DeviceType,
+			LastUsedAt: lastUsedAt,
+		})
+	}
+	ids := policy.Evict(existing, sessionpolicy.Session{ClientID: next.ClientID, DeviceType: next.DeviceType})
+	if len(ids) == 0 {
+		return nil
+	}
+	sessionIDs := make([]string, len(ids))
+	for i, id := range ids {
+		sessionIDs[i] = strconv.FormatUint(id, 10)
+	}
+	if err = tx.Where("session_id IN ?", sessionIDs).Delete(&Device{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ? AND id IN ?", next.UserID, ids).Delete(&Session{}).Error
+}
+
+// LoadTokenSession returns the session of an access token for the auth interceptor, nil when it was deleted so that
+// the access tokens of deleted sessions are rejected
+func LoadTokenSession(_ context.Context, id uint64) (*token.Session, error) {
+	var session Session
+	err := DB.Select("id", "created_at", "last_seen_at").Where("id = ?", id).Take(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lastSeenAt := session.CreatedAt
+	if session.LastSeenAt != nil {
+		lastSeenAt = *session.LastSeenAt
+	}
+	return &token.Session{CreatedAt: session.CreatedAt, LastSeenAt: lastSeenAt}, nil
+}
+
+// TouchSession records a call with an access token of the session, LastSeenAt never moves back
+func TouchSession(_ context.Context, id uint64, now time.Time) error {
+	return DB.Model(&Session{}).Where("id = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", id, now).
+		UpdateColumn("last_seen_at", now).Error
+}
+
+// RevokeSessions deletes the sessions of the user together with their devices, except the session exceptID when it is
+// not 0. It returns the revoked sessions with their devices.
+func RevokeSessions(userID uuid.UUID, exceptID uint64) ([]Session, error) {
+	var sessions []Session
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		sessions, err = revokeSessions(tx, userID, exceptID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// revokeSessions is RevokeSessions within the transaction tx
+func revokeSessions(tx *gorm.DB, userID uuid.UUID, exceptID uint64) ([]Session, error) {
+	var sessions []Session
+	if err := tx.Where("user_id = ? AND id <> ?", userID, exceptID).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint64, len(sessions))
+	sessionIDs := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+		sessionIDs[i] = strconv.FormatUint(session.ID, 10)
+	}
+	var devices []Device
+	if err := tx.Where("user_id = ? AND session_id IN ?", userID, sessionIDs).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		for i := range sessions {
+			if sessionIDs[i] == device.SessionID {
+				sessions[i].Device = device
+			}
+		}
+	}
+	if err := tx.Unscoped().Where("user_id = ? AND session_id IN ?", userID, sessionIDs).Delete(&Device{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("user_id = ? AND id IN ?", userID, ids).Delete(&Session{}).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
 }
 
 // GetSessionByRefreshToken returns a session by refresh token
@@ -57,11 +225,14 @@ func (session *Session) Create() error {
 	return nil
 }
 
+// Delete deletes the session together with its device
 func (session *Session) Delete() error {
-	if err := DB.Model(&Session{}).Delete(&session).Error; err != nil {
-		return err
-	}
-	return nil
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", strconv.FormatUint(session.ID, 10)).Delete(&Device{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(session).Error
+	})
 }
 
 func (session *Session) Update() error {
@@ -102,16 +273,28 @@ func (session *Session) IsActive() bool {
 	return true
 }
 
-func (session *Session) RefreshUserToken() (*token.DefaultToken, error) {
+// Expired returns why the policy ended the session at now, or an empty reason while it lasts. LastSeenAt advances on
+// a refresh and, at most every SESSION_ACTIVITY_INTERVAL, on calls with an access token of the session.
+func (session *Session) Expired(policy sessionpolicy.Policy, now time.Time) string {
+	lastUsedAt := session.CreatedAt
+	if session.LastSeenAt != nil {
+		lastUsedAt = *session.LastSeenAt
+	}
+	return policy.Expired(session.CreatedAt, lastUsedAt, now)
+}
 
-	jwt, err := token.CreateJWT(session.UserID)
+// RefreshUserToken issues new tokens for the session. The refresh token never outlives the maximum lifetime of the
+// session set by the policy of the client.
+func (session *Session) RefreshUserToken(ctx context.Context) (*token.DefaultToken, error) {
+	session.recordOrigin(ctx, time.Now())
+	jwt, err := token.CreateJWT(session.UserID, session.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	rToken, refreshTokenExpiresAt := token.CreateRefreshToken(session.UserID)
 	session.RefreshToken = rToken
-	session.ExpiresAt = *refreshTokenExpiresAt
+	session.ExpiresAt = clientSessionPolicy(ctx).ExpiresAt(session.CreatedAt, *refreshTokenExpiresAt)
 
 	if err = DB.Save(session).Error; err != nil {
 		return nil, err
@@ -122,3 +305,29 @@ func (session *Session) RefreshUserToken() (*token.DefaultToken, error) {
 		RefreshToken: rToken,
 	}, nil
 }
+
+// DeleteExpiredSessions removes up to limit sessions whose refresh token expired before the time, and the sessions
+// deleted before it, together with their devices
+func DeleteExpiredSessions(before time.Time, limit int) (int, error) {
+	var ids []uint64
+	err := DB.Unscoped().Model(&Session{}).
+		Where("expires_at < ? OR (deleted_at IS NOT NULL AND deleted_at < ?)", before, before).
+		Order("id asc").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	sessionIDs := make([]string, len(ids))
+	for i, id := range ids {
+		sessionIDs[i] = strconv.FormatUint(id, 10)
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("session_id IN ?", sessionIDs).Delete(&Device{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&Session{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
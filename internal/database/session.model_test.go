@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func callerContext(addr string, userAgent string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("user-agent", userAgent))
+}
+
+func TestSessionRecordOrigin(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	session := createTestSession(t, user, "refresh")
+
+	created := time.Now().Add(-time.Hour)
+	session.recordOrigin(callerContext("203.0.113.7:5000", "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"), created)
+	if err := DB.Save(session).Error; err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	refreshed := time.Now()
+	session.recordOrigin(callerContext("198.51.100.1:5000", ""), refreshed)
+	if err := DB.Save(session).Error; err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	stored, err := GetSessionById(session.ID)
+	if err != nil {
+		t.Fatalf("GetSessionById failed: %v", err)
+	}
+	if stored.IP != "203.0.113.7" || stored.LastSeenIP != "198.51.100.1" {
+		t.Errorf("Expected the creation and last seen addresses to be kept apart, got %q and %q", stored.IP, stored.LastSeenIP)
+	}
+	if stored.LastSeenAt == nil || !stored.LastSeenAt.Equal(refreshed) {
+		t.Errorf("Expected the session to be last seen at %v, got %v", refreshed, stored.LastSeenAt)
+	}
+	if stored.Browser != "Firefox" || stored.OS != "Linux" || stored.DeviceType != "desktop" {
+		t.Errorf("Expected the user agent to be kept when none is sent, got %+v", stored)
+	}
+
+	if err = TouchSession(context.Background(), session.ID, refreshed.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := LoadTokenSession(context.Background(), session.ID); loaded == nil || !loaded.LastSeenAt.Equal(refreshed) {
+		t.Errorf("Expected the last use not to move back, got %+v", loaded)
+	}
+	used := refreshed.Add(time.Minute)
+	_ = TouchSession(context.Background(), session.ID, used)
+	if loaded, _ := LoadTokenSession(context.Background(), session.ID); loaded == nil || !loaded.LastSeenAt.Equal(used) {
+		t.Errorf("Expected a call with the access token to record the use, got %+v", loaded)
+	}
+}
+
+func TestEvictSessions(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	other := CreateTestUser(t, "john@example.com")
+	createTestSession(t, other, "other")
+
+	now := time.Now()
+	var sessions []*Session
+	for i, clientID := range []string{"web", "web", "app"} {
+		session := createTestSession(t, user, "refresh"+strconv.Itoa(i))
+		lastSeenAt := now.Add(-time.Duration(3-i) * time.Hour)
+		session.ClientID = clientID
+		session.LastSeenAt = &lastSeenAt
+		if err := DB.Save(session).Error; err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	// The first session was used last, the second is now the oldest
+	if err := DB.Model(sessions[0]).Update("last_seen_at", now).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions[1].SaveDevice(Device{Name: "Laptop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := &Session{UserID: user.ID, ClientID: "web"}
+	if err := evictSessions(DB, sessionpolicy.Policy{MaxPerUser: 3, MaxPerClient: 2}, next); err != nil {
+		t.Fatalf("evictSessions failed: %v", err)
+	}
+	remaining, _ := GetSessionsByUserId(user.ID)
+	if len(remaining) != 2 {
+		t.Fatalf("Expected one session to be evicted, %d left", len(remaining))
+	}
+	for _, session := range remaining {
+		if session.ID == sessions[1].ID {
+			t.Error("Expected the least recently used session to be evicted")
+		}
+	}
+	if devices, _ := GetDevicesByUserId(user.ID); len(devices) != 0 {
+		t.Errorf("Expected the device of the evicted session to be deleted, got %d", len(devices))
+	}
+	if others, _ := GetSessionsByUserId(other.ID); len(others) != 1 {
+		t.Error("Expected the sessions of other users to be kept")
+	}
+
+	if err := evictSessions(DB, sessionpolicy.Policy{SingleSessionPerClient: true}, next); err != nil {
+		t.Fatalf("evictSessions failed: %v", err)
+	}
+	remaining, _ = GetSessionsByUserId(user.ID)
+	if len(remaining) != 1 || remaining[0].ClientID != "app" {
+		t.Errorf("Expected only the session of the other client to be left, got %d", len(remaining))
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	other := CreateTestUser(t, "john@example.com")
+	current := createTestSession(t, user, "current")
+	revoked := createTestSession(t, user, "revoked")
+	createTestSession(t, other, "other")
+	if _, err := revoked.SaveDevice(Device{Name: "Phone", Token: "push"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := RevokeSessions(user.ID, current.ID)
+	if err != nil {
+		t.Fatalf("RevokeSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != revoked.ID || sessions[0].Device.Token != "push" {
+		t.Fatalf("Expected the other session to be revoked with its device, got %+v", sessions)
+	}
+	if loaded, _ := LoadTokenSession(context.Background(), revoked.ID); loaded != nil {
+		t.Error("Expected the revoked session to be deleted")
+	}
+	if loaded, _ := LoadTokenSession(context.Background(), current.ID); loaded == nil {
+		t.Error("Expected the current session to be kept")
+	}
+	var rows int64
+	DB.Unscoped().Model(&Session{}).Where("id = ?", revoked.ID).Count(&rows)
+	if rows != 0 {
+		t.Error("Expected the revoked session row to be removed")
+	}
+	if devices, _ := GetDevicesByUserId(user.ID); len(devices) != 0 {
+		t.Errorf("Expected the device of the revoked session to be deleted, got %d", len(devices))
+	}
+
+	if sessions, err = RevokeSessions(user.ID, 0); err != nil || len(sessions) != 1 {
+		t.Errorf("Expected the current session to be revoked too, got %d (%v)", len(sessions), err)
+	}
+	if others, _ := GetSessionsByUserId(other.ID); len(others) != 1 {
+		t.Error("Expected the sessions of other users to be kept")
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ErrSignInChallengeUsed is returned when a sign-in challenge was used or replaced in the meantime
+var ErrSignInChallengeUsed = errors.New("sign-in challenge already used")
+
+// SignInChallenge is a sign-in the risk engine held back until the user confirms it with the code sent by email. Only
+// the hash of the code is stored, it is bound to the client the sign-in came from.
+type SignInChallenge struct {
+	UINTBaseModel
+	UserID         uuid.UUID  `gorm:"not null;index" json:"-"`
+	ClientID       string     `gorm:"default:null" json:"client_id,omitempty"`
+	RiskDecisionID uint64     `gorm:"default:null" json:"-"`
+	CodeHash       string     `gorm:"default:null" json:"-"`
+	Attempts       int        `gorm:"default:0" json:"-"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+}
+
+// CreateSignInChallenge stores a new challenge for the user and expires the pending ones of the same client
+func CreateSignInChallenge(userID uuid.UUID, clientID string, riskDecisionID uint64, code string, ttl time.Duration) (*SignInChallenge, error) {
+	now := time.Now()
+	challenge := &SignInChallenge{
+		UserID:         userID,
+		ClientID:       clientID,
+		RiskDecisionID: riskDecisionID,
+		CodeHash:       HashCode(code),
+		ExpiresAt:      now.Add(ttl),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&SignInChallenge{}).
+			Where("user_id = ? AND client_id = ? AND used_at IS NULL AND expires_at > ?", userID, clientID, now).
+			Update("expires_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(challenge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// GetLatestSignInChallenge returns the last challenge of the user for the client
+func GetLatestSignInChallenge(userID uuid.UUID, clientID string) (*SignInChallenge, error) {
+	var challenge SignInChallenge
+	err := DB.Where("user_id = ? AND client_id = ?", userID, clientID).
+		Order("created_at desc").Order("id desc").First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// GetSignInChallengeById returns a challenge of the user
+func GetSignInChallengeById(id uint64, userID uuid.UUID) (*SignInChallenge, error) {
+	var challenge SignInChallenge
+	if err := DB.Where("id = ? AND user_id = ?", id, userID).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// IsUsable reports whether the challenge can still be confirmed
+func (c *SignInChallenge) IsUsable(now time.Time, maxAttempts int) bool {
+	if c.UsedAt != nil || !now.Before(c.ExpiresAt) {
+		return false
+	}
+	return maxAttempts <= 0 || c.Attempts < maxAttempts
+}
+
+// CheckCode compares the code with the stored hash and counts a wrong code as a failed attempt
+func (c *SignInChallenge) CheckCode(code string) (bool, error) {
+	if subtle.ConstantTimeCompare([]byte(HashCode(code)), []byte(c.CodeHash)) == 1 {
+		return true, nil
+	}
+	err := DB.Model(&SignInChallenge{}).Where("id = ?", c.ID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return false, err
+	}
+	c.Attempts++
+	return false, nil
+}
+
+// Complete marks the challenge as used and trusts the risk decision that raised it. It fails with
+// ErrSignInChallengeUsed when the challenge was used concurrently.
+func (c *SignInChallenge) Complete() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&SignInChallenge{}).
+			Where("id = ? AND used_at IS NULL", c.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSignInChallengeUsed
+		}
+		if c.RiskDecisionID != 0 {
+			if err := tx.Model(&RiskDecision{}).Where("id = ?", c.RiskDecisionID).Update("trusted", true).Error; err != nil {
+				return err
+			}
+		}
+		c.UsedAt = &now
+		return nil
+	})
+}
+
+// DeleteStaleSignInChallenges removes the challenges that expired before the time
+func DeleteStaleSignInChallenges(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("expires_at < ?", before).Delete(&SignInChallenge{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignInChallengeComplete(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	decision := RiskDecision{UserID: user.ID, ClientID: "web", Action: "require_email_confirmation"}
+	if err := CreateRiskDecision(&decision); err != nil {
+		t.Fatalf("CreateRiskDecision failed: %v", err)
+	}
+
+	first, err := CreateSignInChallenge(user.ID, "web", decision.ID, "abc123", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSignInChallenge failed: %v", err)
+	}
+	second, err := CreateSignInChallenge(user.ID, "web", decision.ID, "def456", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSignInChallenge failed: %v", err)
+	}
+	replaced, _ := GetSignInChallengeById(first.ID, user.ID)
+	if replaced.IsUsable(time.Now(), 5) {
+		t.Error("Expected a new challenge to expire the pending one of the same client")
+	}
+
+	latest, err := GetLatestSignInChallenge(user.ID, "web")
+	if err != nil || latest.ID != second.ID {
+		t.Fatalf("GetLatestSignInChallenge = %v, %v", latest, err)
+	}
+	if ok, _ := latest.CheckCode("abc123"); ok {
+		t.Error("Expected the code of the replaced challenge to be rejected")
+	}
+	if latest.IsUsable(time.Now(), 1) {
+		t.Error("Expected the wrong code to use up the attempts")
+	}
+	if ok, _ := latest.CheckCode("def456"); !ok {
+		t.Error("Expected the code to be accepted")
+	}
+
+	if err = latest.Complete(); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if err = second.Complete(); !errors.Is(err, ErrSignInChallengeUsed) {
+		t.Errorf("Expected a challenge to be completed once, got %v", err)
+	}
+	trusted, err := GetTrustedRiskDecisions(user.ID, 0)
+	if err != nil || len(trusted) != 1 || trusted[0].ID != decision.ID {
+		t.Errorf("Expected the confirmed decision to be trusted, got %v (%v)", trusted, err)
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// SignInFingerprint is a client and device combination a user signed in from, used to notice sign-ins from new devices
+type SignInFingerprint struct {
+	UINTBaseModel
+	UserID      uuid.UUID `gorm:"not null;uniqueIndex:idx_sign_in_fingerprints_user_fingerprint" json:"-"`
+	Fingerprint string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_sign_in_fingerprints_user_fingerprint" json:"-"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// RecordSignInFingerprint remembers the fingerprint of a sign-in. It reports whether the fingerprint is new for a user
+// that signed in before, the first sign-in of a user is not a new device.
+func RecordSignInFingerprint(userID uuid.UUID, fingerprint string, now time.Time) (bool, error) {
+	newDevice := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var known SignInFingerprint
+		err := tx.Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&known).Error
+		if err == nil {
+			return tx.Model(&known).Update("last_seen_at", now).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		var count int64
+		if err = tx.Model(&SignInFingerprint{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		newDevice = count > 0
+		return tx.Create(&SignInFingerprint{UserID: userID, Fingerprint: fingerprint, LastSeenAt: now}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// a concurrent sign-in from the same device recorded it first
+			return false, nil
+		}
+		return false, err
+	}
+	return newDevice, nil
+}
+
+// SignInFingerprintKnown reports whether the user signed in with the fingerprint before, and whether any fingerprint of
+// the user was recorded at all
+func SignInFingerprintKnown(userID uuid.UUID, fingerprint string) (bool, bool, error) {
+	var fingerprints []string
+	if err := DB.Model(&SignInFingerprint{}).Where("user_id = ?", userID).Pluck("fingerprint", &fingerprints).Error; err != nil {
+		return false, false, err
+	}
+	for _, recorded := range fingerprints {
+		if recorded == fingerprint {
+			return true, true, nil
+		}
+	}
+	return false, len(fingerprints) > 0, nil
+}
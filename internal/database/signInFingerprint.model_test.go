@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordSignInFingerprint(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+
+	for _, step := range []struct {
+		fingerprint string
+		newDevice   bool
+	}{
+		{"laptop", false},
+		{"laptop", false},
+		{"phone", true},
+		{"phone", false},
+	} {
+		newDevice, err := RecordSignInFingerprint(user.ID, step.fingerprint, time.Now())
+		if err != nil {
+			t.Fatalf("RecordSignInFingerprint failed: %v", err)
+		}
+		if newDevice != step.newDevice {
+			t.Errorf("RecordSignInFingerprint(%q) = %v, want %v", step.fingerprint, newDevice, step.newDevice)
+		}
+	}
+}
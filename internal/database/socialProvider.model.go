@@ -10,3 +10,12 @@ type SocialProvider struct {
 	AccessToken    string    `gorm:"default:null" json:"access_token,omitempty"`
 	RefreshToken   string    `gorm:"default:null" json:"refresh_token,omitempty"`
 }
+
+// GetSocialProvidersByUserId returns the social providers linked to a user
+func GetSocialProvidersByUserId(userID uuid.UUID) ([]SocialProvider, error) {
+	var providers []SocialProvider
+	if err := DB.Where("user_id = ?", userID).Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
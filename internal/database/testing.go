@@ -0,0 +1,40 @@
+package database
+
+import (
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+// SetupTestDB replaces DB with a migrated in-memory sqlite database named after the test, and restores it when the
+// test ends. The tests of other packages use it through dbtest, which this package cannot import.
+func SetupTestDB(t testing.TB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	previous := DB
+	DB = db
+	Migrate()
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		DB = previous
+	})
+}
+
+// CreateTestUser stores a user with the email for a test
+func CreateTestUser(t testing.TB, email string) *User {
+	t.Helper()
+	user := User{Name: "Jane", Email: email, Password: "hash"}
+	if err := DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return &user
+}
@@ -7,12 +7,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/usercoredev/usercore/internal/client"
 	"github.com/usercoredev/usercore/internal/pagination"
+	"github.com/usercoredev/usercore/internal/password"
+	"github.com/usercoredev/usercore/internal/sessionpolicy"
 	"github.com/usercoredev/usercore/internal/token"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"os"
-	"strconv"
+	"log/slog"
 	"time"
 )
 
@@ -27,11 +27,17 @@ type User struct {
 	EmailVerifySentAt *time.Time `gorm:"default:null" json:"-"`
 
 	Password string `json:"-"`
+	// PasswordChangedAt is when the password was last set, null for users who never set one since it was recorded
+	PasswordChangedAt *time.Time `gorm:"default:null" json:"-"`
+
+	// DeletedEmail keeps the email of a soft-deleted user so that the account can be restored during the grace period
+	DeletedEmail string `gorm:"default:null;index" json:"-"`
 
 	Sessions        []Session        `json:"sessions,omitempty" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Profile         *Profile         `json:"profile,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	PasswordReset   []PasswordReset  `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	SocialProviders []SocialProvider `json:"-" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Roles           []Role           `json:"roles,omitempty" gorm:"many2many:user_roles;"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
@@ -39,39 +45,47 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
-// ComparePassword compares the password of a user
-func (u *User) ComparePassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+// ComparePassword compares the password of a user. A hash made with an outdated algorithm or parameters is replaced
+// after a successful comparison
+func (u *User) ComparePassword(plain string) bool {
+	if u.Password == "" {
+		return false
+	}
+	ok, rehash, err := password.Verify(plain, u.Password)
+	if err != nil {
+		slog.Error("failed to verify password hash", "user_id", u.ID.String(), "error", err)
+		return false
+	}
+	if ok && rehash {
+		if err := u.rehashPassword(plain); err != nil {
+			slog.Error("failed to upgrade password hash", "user_id", u.ID.String(), "error", err)
+		}
+	}
+	return ok
 }
 
-// SetPassword sets the password of a user
-func (u *User) SetPassword(password string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+func (u *User) rehashPassword(plain string) error {
+	hash, err := password.Hash(plain)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hash)
-	return nil
-}
-
-func (u *User) UserSessionLimiter() error {
-	limit, err := strconv.Atoi(os.Getenv("MAX_SESSIONS_PER_USER"))
-
-	if len(u.Sessions) >= limit {
-		if err = DB.Delete(&u.Sessions[0]).Error; err != nil {
-			return err
-		}
+	if err := DB.Model(&User{}).Where("id = ? AND password = ?", u.ID, u.Password).UpdateColumn("password", hash).Error; err != nil {
+		return err
 	}
+	u.Password = hash
 	return nil
 }
 
-// CheckPasswordResetToken checks the password reset code of a user
-func (u *User) CheckPasswordResetToken(token string) bool {
-	if len(u.PasswordReset) > 0 {
-		return u.PasswordReset[0].CheckResetToken(token)
+// SetPassword sets the password of a user
+func (u *User) SetPassword(plain string) error {
+	hash, err := password.Hash(plain)
+	if err != nil {
+		return err
 	}
-	return false
+	now := time.Now()
+	u.Password = hash
+	u.PasswordChangedAt = &now
+	return nil
 }
 
 // SetEmailVerifyCode verifies the email of a user
@@ -92,6 +106,14 @@ func (u *User) VerifyEmail(code string) bool {
 	return false
 }
 
+// ClearEmailVerifyCodes removes the email verification codes sent before the time, so they cannot be used anymore
+func ClearEmailVerifyCodes(sentBefore time.Time) (int64, error) {
+	result := DB.Model(&User{}).
+		Where("email_verify_code IS NOT NULL AND email_verify_sent_at < ?", sentBefore).
+		Updates(map[string]interface{}{"email_verify_code": nil, "email_verify_sent_at": nil})
+	return result.RowsAffected, result.Error
+}
+
 // UpdateUserEmail updates the email of a user and sets the email verified to false and email verify code to null
 func (u *User) UpdateUserEmail(email string) {
 	u.EmailVerified = false
@@ -99,6 +121,149 @@ func (u *User) UpdateUserEmail(email string) {
 	u.Email = email
 }
 
+// EventData returns the payload describing the user in lifecycle events
+func (u *User) EventData() UserEventData {
+	return UserEventData{
+		UserID:        u.ID.String(),
+		Email:         u.Email,
+		Name:          u.Name,
+		EmailVerified: u.EmailVerified,
+	}
+}
+
+// SoftDelete revokes the sessions of the user, deletes the devices with their push tokens, frees the email for
+// re-registration and soft-deletes the user. The user is purged for good by PurgeDeletedUsers once the grace period is
+// over.
+func (u *User) SoftDelete() error {
+	data := u.EventData()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(u).Updates(map[string]interface{}{
+			"email":         deletedEmailPlaceholder(u.ID),
+			"deleted_email": u.Email,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if _, err = revokeSessions(tx, u.ID, 0); err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Where("user_id = ?", u.ID).Delete(&Device{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Delete(u).Error; err != nil {
+			return err
+		}
+		return EnqueueOutboxEvent(tx, EventUserDeleted, u.ID, data)
+	})
+}
+
+// Restore undoes SoftDelete. It fails with gorm.ErrDuplicatedKey when the email was registered again in the meantime.
+func (u *User) Restore() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(u).Updates(map[string]interface{}{
+			"email":         u.DeletedEmail,
+			"deleted_email": gorm.Expr("NULL"),
+			"deleted_at":    gorm.Expr("NULL"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		u.Email = u.DeletedEmail
+		u.DeletedEmail = ""
+		u.DeletedAt = nil
+		return EnqueueOutboxEvent(tx, EventUserRestored, u.ID, u.EventData())
+	})
+}
+
+func deletedEmailPlaceholder(id uuid.UUID) string {
+	return fmt.Sprintf("deleted:%s", id)
+}
+
+// GetDeletedUserByEmail gets the most recently soft-deleted user with the email, if it was deleted after the given time
+func GetDeletedUserByEmail(email string, deletedAfter time.Time) (*User, error) {
+	var user User
+	err := DB.Unscoped().
+		Where("deleted_email = ? AND deleted_at > ?", email, deletedAfter).
+		Order("deleted_at desc").
+		First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// userModels are the models with a user_id whose rows PurgeDeletedUsers removes with the user. Audit events are kept:
+// they are chained by their hashes, and removing one would break the verification of the chain.
+var userModels = []interface{}{
+	&Profile{}, &Session{}, &Device{}, &PasswordReset{}, &PasswordHistory{}, &EmailChange{}, &NotificationPreference{},
+	&Notification{}, &SignInFingerprint{}, &SignInChallenge{}, &RiskDecision{}, &SocialProvider{}, &DataExport{},
+	&OutboxEvent{},
+}
+
+// PurgeDeletedUsers permanently removes the users soft-deleted before the given time together with the rows of
+// userModels, the webhook deliveries of their events, the failed sign-ins of their email and their roles. It returns
+// the number of purged users.
+func PurgeDeletedUsers(deletedBefore time.Time, limit int) (int, error) {
+	var users []User
+	err := DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Order("deleted_at asc").
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range users {
+		user := &users[i]
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			events := tx.Unscoped().Model(&OutboxEvent{}).Select("id").Where("user_id = ?", user.ID)
+			if err := tx.Unscoped().Where("outbox_event_id IN (?)", events).Delete(&WebhookDelivery{}).Error; err != nil {
+				return err
+			}
+			for _, model := range userModels {
+				if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if user.DeletedEmail != "" {
+				err := tx.Unscoped().Where("identifier = ?", LoginAttemptEmailKey(user.DeletedEmail)).Delete(&LoginAttempt{}).Error
+				if err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", user.ID).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(user).Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Create stores a new user with its password in the history and the given roles, and queues the user.created event
+func (u *User) Create(historyDepth int, roles ...*Role) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Create(u).Error; err != nil {
+			return err
+		}
+		if err := u.AddPasswordHistory(tx, historyDepth); err != nil {
+			return err
+		}
+		if len(roles) > 0 {
+			if err := tx.Model(u).Association("Roles").Append(roles); err != nil {
+				return err
+			}
+		}
+		return EnqueueOutboxEvent(tx, EventUserCreated, u.ID, u.EventData())
+	})
+}
+
 // GetUserByEmail gets a user by email
 func GetUserByEmail(e string) (*User, error) {
 	if len(e) > 0 {
@@ -111,13 +276,6 @@ func GetUserByEmail(e string) (*User, error) {
 	return nil, errors.New("email is empty")
 }
 
-func (u *User) CheckPasswordResetCode(code string) bool {
-	if len(u.PasswordReset) > 0 {
-		return u.PasswordReset[0].CheckResetToken(code)
-	}
-	return false
-}
-
 // GetUserByID gets a user by id
 func GetUserByID(id uuid.UUID, preload bool) (*User, error) {
 	var user User
@@ -141,38 +299,36 @@ func GetUserProfile(id uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
-func (u *User) GetLastPasswordReset() (*PasswordReset, error) {
-	if len(u.PasswordReset) > 0 {
-		return &u.PasswordReset[0], nil
-	}
-	return nil, errors.New("password reset not found")
-}
-
 func userPreload() *gorm.DB {
 	return DB.Preload(clause.Associations)
 }
 
 func (u *User) CreateSession(ctx context.Context) (*token.DefaultToken, error) {
 	sessionClient := ctx.Value(client.Key).(*client.Item)
-	jwt, err := token.CreateJWT(u.ID)
-	if err != nil {
-		return nil, err
-	}
-
 	rToken, refreshTokenExpireAt := token.CreateRefreshToken(u.ID)
 
-	if err = u.UserSessionLimiter(); err != nil {
-		return nil, err
-	}
-
+	policy := sessionpolicy.Resolve(sessionClient.Sessions)
+	now := time.Now()
 	var session = Session{
-		UserID:       u.ID,
-		RefreshToken: rToken,
-		ExpiresAt:    *refreshTokenExpireAt,
-		ClientID:     sessionClient.ID,
-		ClientName:   sessionClient.Name,
+		UINTBaseModel: UINTBaseModel{CreatedAt: now},
+		UserID:        u.ID,
+		RefreshToken:  rToken,
+		ExpiresAt:     policy.ExpiresAt(now, *refreshTokenExpireAt),
+		ClientID:      sessionClient.ID,
+		ClientName:    sessionClient.Name,
+	}
+	session.recordOrigin(ctx, now)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := evictSessions(tx, policy, &session); err != nil {
+			return err
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err = DB.Model(&Session{}).Create(&session).Error; err != nil {
+	jwt, err := token.CreateJWT(u.ID, session.ID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -182,7 +338,7 @@ func (u *User) CreateSession(ctx context.Context) (*token.DefaultToken, error) {
 	}, nil
 }
 
-func GetUsers(md pagination.Metadata) ([]*User, int64, error) {
+func GetUsers(md pagination.PageMetadata) ([]*User, int64, error) {
 	var count int64
 	var users []*User
 	likeOperator := getLikeOperator(DB)
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserSoftDeleteAndRestore(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	session := Session{UserID: user.ID, RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := DB.Create(&session).Error; err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := session.SaveDevice(Device{Name: "Phone", Token: "push"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := user.SoftDelete(); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if _, err := GetUserByEmail("jane@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the deleted user to be hidden, got %v", err)
+	}
+	if _, err := GetSessionByRefreshToken("refresh"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the sessions to be revoked, got %v", err)
+	}
+	var devices int64
+	DB.Unscoped().Model(&Device{}).Where("user_id = ?", user.ID).Count(&devices)
+	if devices != 0 {
+		t.Errorf("Expected the devices and their push tokens to be deleted, got %d", devices)
+	}
+	var events []OutboxEvent
+	DB.Where("event_type = ?", EventUserDeleted).Find(&events)
+	if len(events) != 1 {
+		t.Errorf("Expected a %s event, got %d", EventUserDeleted, len(events))
+	}
+
+	deleted, err := GetDeletedUserByEmail("jane@example.com", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetDeletedUserByEmail failed: %v", err)
+	}
+	if _, err = GetDeletedUserByEmail("jane@example.com", time.Now().Add(time.Hour)); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected users deleted before the grace period to be ignored, got %v", err)
+	}
+
+	if err = deleted.Restore(); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	restored, err := GetUserByEmail("jane@example.com")
+	if err != nil || restored.ID != user.ID {
+		t.Errorf("Expected the user to be restored, got %v (%v)", restored, err)
+	}
+}
+
+func TestUserSoftDeleteFreesEmail(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	if err := user.SoftDelete(); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	CreateTestUser(t, "jane@example.com")
+
+	deleted, err := GetDeletedUserByEmail("jane@example.com", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetDeletedUserByEmail failed: %v", err)
+	}
+	if err = deleted.Restore(); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Expected the restore to conflict with the new account, got %v", err)
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	kept := CreateTestUser(t, "john@example.com")
+	DB.Create(&Profile{UserID: user.ID, Locale: "en"})
+	DB.Create(&Device{UserID: user.ID, Name: "phone"})
+	DB.Create(&PasswordReset{UserID: user.ID})
+	DB.Create(&Notification{UserID: user.ID, Event: "password_changed", Status: DeliveryStatusPending})
+	DB.Create(&LoginAttempt{Identifier: LoginAttemptEmailKey("jane@example.com"), Failures: 1, LastFailureAt: time.Now()})
+
+	if err := user.SoftDelete(); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if err := kept.SoftDelete(); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	DB.Unscoped().Model(&User{}).Where("id = ?", user.ID).Update("deleted_at", time.Now().Add(-48*time.Hour))
+	var event OutboxEvent
+	DB.Where("user_id = ?", user.ID).First(&event)
+	DB.Create(&WebhookDelivery{OutboxEventID: event.ID, ClientID: "web", URL: "https://example.com", Status: DeliveryStatusDelivered})
+
+	purged, err := PurgeDeletedUsers(time.Now().Add(-24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("PurgeDeletedUsers failed: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("Expected 1 purged user, got %d", purged)
+	}
+
+	var count int64
+	DB.Unscoped().Model(&User{}).Where("id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the user to be purged")
+	}
+	for _, model := range userModels {
+		DB.Unscoped().Model(model).Where("user_id = ?", user.ID).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected %T rows of the user to be purged, got %d", model, count)
+		}
+	}
+	for _, model := range []interface{}{&WebhookDelivery{}, &LoginAttempt{}} {
+		DB.Unscoped().Model(model).Count(&count)
+		if count != 0 {
+			t.Errorf("Expected the %T rows of the user to be purged, got %d", model, count)
+		}
+	}
+	DB.Unscoped().Model(&User{}).Where("id = ?", kept.ID).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the user still in the grace period to be kept")
+	}
+}
+
+// TestPurgeCoversUserModels fails when a model keyed by user_id is added without being purged with the user
+func TestPurgeCoversUserModels(t *testing.T) {
+	SetupTestDB(t)
+	purged := map[string]bool{}
+	for _, model := range userModels {
+		purged[fmt.Sprintf("%T", model)] = true
+	}
+	kept := map[string]bool{"*database.AuditEvent": true}
+	for _, model := range schemaModels {
+		name := fmt.Sprintf("%T", model)
+		if DB.Migrator().HasColumn(model, "user_id") && !purged[name] && !kept[name] {
+			t.Errorf("Expected %s to be in userModels, it has a user_id", name)
+		}
+	}
+}
+
+func TestUserComparePasswordUpgradesLegacyHash(t *testing.T) {
+	SetupTestDB(t)
+	user := CreateTestUser(t, "jane@example.com")
+	legacy, err := bcrypt.GenerateFromPassword([]byte("s3cret-Passphrase"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	DB.Model(user).UpdateColumn("password", string(legacy))
+
+	if user.ComparePassword("wrong-Passphrase") {
+		t.Fatal("Expected a wrong password to be rejected")
+	}
+	if user.Password != string(legacy) {
+		t.Error("Expected the hash to be kept after a failed comparison")
+	}
+	if !user.ComparePassword("s3cret-Passphrase") {
+		t.Fatal("Expected the legacy bcrypt hash to verify")
+	}
+	stored, err := GetUserByID(user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Password, "$argon2id$") || stored.Password != user.Password {
+		t.Errorf("Expected the hash to be upgraded to argon2id, got %q", stored.Password)
+	}
+	if !stored.ComparePassword("s3cret-Passphrase") {
+		t.Error("Expected the upgraded hash to verify")
+	}
+}
@@ -1,28 +1,51 @@
 package dateutil
 
 import (
-	"github.com/talut/dotenv"
 	"time"
 )
 
 var defaultDateFormat = "2006-01-02"
 var defaultDateTimeFormat = "2006-01-02 15:04:05"
 
-// FormatDate converts string to time.Time using the format from environment variable DATE_FORMAT
+// Settings holds the layouts dates and times are read and written with
+type Settings struct {
+	DateFormat     string
+	DateTimeFormat string
+}
+
+var formats = Settings{DateFormat: defaultDateFormat, DateTimeFormat: defaultDateTimeFormat}
+
+// Setup uses the layouts for every date handled afterwards, an empty layout keeps the default
+func (s Settings) Setup() {
+	if s.DateFormat == "" {
+		s.DateFormat = defaultDateFormat
+	}
+	if s.DateTimeFormat == "" {
+		s.DateTimeFormat = defaultDateTimeFormat
+	}
+	formats = s
+}
+
+// FormatDate converts string to time.Time using the configured date format
 func FormatDate(date string) *time.Time {
-	t, err := time.Parse(dotenv.GetString("DATE_FORMAT", defaultDateFormat), date)
+	t, err := time.Parse(formats.DateFormat, date)
 	if err != nil {
 		return nil
 	}
 	return &t
 }
 
-// FormatTime converts string to time.Time using the format from environment variable DATETIME_FORMAT
+// DateString formats the date of t with the configured date format
+func DateString(t time.Time) string {
+	return t.Format(formats.DateFormat)
+}
+
+// FormatTime converts string to time.Time using the configured date time format
 func FormatTime(e string) *time.Time {
 	if len(e) == 0 {
 		return nil
 	}
-	t, err := time.Parse(dotenv.GetString("DATE_TIME_FORMAT", defaultDateTimeFormat), e)
+	t, err := time.Parse(formats.DateTimeFormat, e)
 	if err != nil {
 		return nil
 	}
@@ -2,13 +2,11 @@ package dateutil
 
 import (
 	"github.com/stretchr/testify/assert"
-	"os"
 	"testing"
 	"time"
 )
 
 func TestFormatDate(t *testing.T) {
-	os.Unsetenv("DATE_FORMAT") // Ensure DATE_FORMAT is not set
 	testDate := "2023-03-10"
 	expectedTime, _ := time.Parse(defaultDateFormat, testDate)
 
@@ -19,7 +17,8 @@ func TestFormatDate(t *testing.T) {
 
 	// Test with custom date format
 	customFormat := "02-01-2006"
-	os.Setenv("DATE_FORMAT", customFormat)
+	Settings{DateFormat: customFormat}.Setup()
+	defer Settings{}.Setup()
 	testDateCustomFormat := "10-03-2023"
 	expectedTimeCustom, _ := time.Parse(customFormat, testDateCustomFormat)
 	resultCustom := FormatDate(testDateCustomFormat)
@@ -31,12 +30,10 @@ func TestFormatDate(t *testing.T) {
 	resultInvalid := FormatDate(invalidDate)
 	assert.Nil(t, resultInvalid)
 
-	// Cleanup
-	os.Unsetenv("DATE_FORMAT")
+	assert.Equal(t, testDateCustomFormat, DateString(expectedTimeCustom))
 }
 
 func TestFormatTime(t *testing.T) {
-	os.Unsetenv("DATETIME_FORMAT") // Ensure DATETIME_FORMAT is not set
 	testDateTime := "2023-03-10 15:04:05"
 	expectedTime, _ := time.Parse(defaultDateTimeFormat, testDateTime)
 
@@ -53,9 +50,6 @@ func TestFormatTime(t *testing.T) {
 	invalidDateTime := "invalid-datetime"
 	resultInvalid := FormatTime(invalidDateTime)
 	assert.Nil(t, resultInvalid)
-
-	// Cleanup
-	os.Unsetenv("DATETIME_FORMAT")
 }
 
 func TestCompareTimesByGivenMinute(t *testing.T) {
@@ -0,0 +1,232 @@
+package export
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/database"
+	"strconv"
+	"time"
+)
+
+// Archive is the machine-readable record of everything stored about a user. Passwords, tokens, verification codes and
+// hashes are deliberately left out.
+type Archive struct {
+	GeneratedAt     time.Time        `json:"generated_at"`
+	User            User             `json:"user"`
+	Profile         *Profile         `json:"profile,omitempty"`
+	Sessions        []Session        `json:"sessions"`
+	SocialProviders []SocialProvider `json:"social_providers"`
+	PasswordResets  []PasswordReset  `json:"password_resets"`
+	EmailChanges    []EmailChange    `json:"email_changes"`
+	AuditEvents     []AuditEvent     `json:"audit_events"`
+}
+
+type User struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Roles         []string  `json:"roles"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type Profile struct {
+	Picture   string     `json:"picture,omitempty"`
+	Gender    string     `json:"gender,omitempty"`
+	Education string     `json:"education,omitempty"`
+	Birthdate *time.Time `json:"birthdate,omitempty"`
+	Locale    string     `json:"locale,omitempty"`
+	Timezone  string     `json:"timezone,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type Session struct {
+	ID         uint64     `json:"id"`
+	ClientID   string     `json:"client_id"`
+	ClientName string     `json:"client_name"`
+	Devices    []Device   `json:"devices"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	LastSeenIP string     `json:"last_seen_ip,omitempty"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type Device struct {
+	ID             uint64    `json:"id"`
+	DeviceID       string    `json:"device_id,omitempty"`
+	Name           string    `json:"name,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	OSVersion      string    `json:"os_version,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	BrowserVersion string    `json:"browser_version,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SocialProvider struct {
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"provider_user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PasswordReset struct {
+	RequestedAt time.Time  `json:"requested_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty"`
+}
+
+type EmailChange struct {
+	PreviousEmail string     `json:"previous_email"`
+	NewEmail      string     `json:"new_email"`
+	RequestedAt   time.Time  `json:"requested_at"`
+	ConfirmedAt   *time.Time `json:"confirmed_at,omitempty"`
+	RevertedAt    *time.Time `json:"reverted_at,omitempty"`
+}
+
+type AuditEvent struct {
+	Action    string            `json:"action"`
+	Outcome   string            `json:"outcome"`
+	ClientID  string            `json:"client_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Build collects the record of the user and encodes it as JSON
+func Build(userID uuid.UUID, now time.Time) ([]byte, error) {
+	user, err := database.GetUserByID(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	archive := Archive{
+		GeneratedAt: now.UTC(),
+		User: User{
+			ID:            user.ID.String(),
+			Name:          user.Name,
+			Email:         user.Email,
+			EmailVerified: user.EmailVerified,
+			Roles:         []string{},
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+		Sessions:        []Session{},
+		SocialProviders: []SocialProvider{},
+		PasswordResets:  []PasswordReset{},
+		EmailChanges:    []EmailChange{},
+		AuditEvents:     []AuditEvent{},
+	}
+	for _, role := range user.Roles {
+		archive.User.Roles = append(archive.User.Roles, role.Key)
+	}
+	if user.Profile != nil {
+		archive.Profile = &Profile{
+			Picture:   user.Profile.Picture,
+			Gender:    user.Profile.Gender,
+			Education: user.Profile.Education,
+			Birthdate: user.Profile.Birthdate,
+			Locale:    user.Profile.Locale,
+			Timezone:  user.Profile.Timezone,
+			CreatedAt: user.Profile.CreatedAt,
+			UpdatedAt: user.Profile.UpdatedAt,
+		}
+	}
+
+	devices, err := database.GetDevicesByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessionDevices := make(map[string][]Device)
+	for _, device := range devices {
+		sessionDevices[device.SessionID] = append(sessionDevices[device.SessionID], Device{
+			ID:             device.ID,
+			DeviceID:       device.DeviceID,
+			Name:           device.Name,
+			IP:             device.IP,
+			OS:             device.OS,
+			OSVersion:      device.OSVersion,
+			Browser:        device.Browser,
+			BrowserVersion: device.BrowserVersion,
+			CreatedAt:      device.CreatedAt,
+		})
+	}
+	sessions, err := database.GetSessionsByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		archiveSession := Session{
+			ID:         session.ID,
+			ClientID:   session.ClientID,
+			ClientName: session.ClientName,
+			Devices:    sessionDevices[strconv.FormatUint(session.ID, 10)],
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			LastSeenIP: session.LastSeenIP,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			CreatedAt:  session.CreatedAt,
+			UpdatedAt:  session.UpdatedAt,
+		}
+		if archiveSession.Devices == nil {
+			archiveSession.Devices = []Device{}
+		}
+		archive.Sessions = append(archive.Sessions, archiveSession)
+	}
+
+	providers, err := database.GetSocialProvidersByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, provider := range providers {
+		archive.SocialProviders = append(archive.SocialProviders, SocialProvider{
+			Provider:       provider.Provider,
+			ProviderUserID: provider.ProviderUserID,
+			CreatedAt:      provider.CreatedAt,
+		})
+	}
+
+	resets, err := database.GetPasswordResetsByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, reset := range resets {
+		archive.PasswordResets = append(archive.PasswordResets, PasswordReset{RequestedAt: reset.CreatedAt, UsedAt: reset.UsedAt})
+	}
+
+	changes, err := database.GetEmailChangesByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		archive.EmailChanges = append(archive.EmailChanges, EmailChange{
+			PreviousEmail: change.PreviousEmail,
+			NewEmail:      change.NewEmail,
+			RequestedAt:   change.CreatedAt,
+			ConfirmedAt:   change.ConfirmedAt,
+			RevertedAt:    change.RevertedAt,
+		})
+	}
+
+	events, err := database.GetAuditEventsByUserId(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		archive.AuditEvents = append(archive.AuditEvents, AuditEvent{
+			Action:    event.Action,
+			Outcome:   event.Outcome,
+			ClientID:  event.ClientID,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Metadata:  event.GetMetadata(),
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	return json.MarshalIndent(archive, "", "  ")
+}
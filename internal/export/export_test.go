@@ -0,0 +1,96 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createUserWithRecord(t *testing.T) uuid.UUID {
+	t.Helper()
+	user := database.User{Name: "Jane", Email: "jane@example.com", Password: "secret-password-hash", EmailVerifyCode: "secret-code"}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	session := database.Session{UserID: user.ID, RefreshToken: "secret-refresh-token", ClientID: "client-1", ExpiresAt: time.Now().Add(time.Hour)}
+	database.DB.Create(&session)
+	database.DB.Create(&database.Device{UserID: user.ID, SessionID: strconv.FormatUint(session.ID, 10), Name: "phone", Token: "secret-push-token"})
+	database.DB.Create(&database.SocialProvider{UserID: user.ID, Provider: "apple", ProviderUserID: "apple-1", AccessToken: "secret-access-token"})
+	database.DB.Create(&database.PasswordReset{UserID: user.ID, TokenHash: "secret-reset-token-hash"})
+	if err := database.AppendAuditEvent(&database.AuditEvent{UserID: &user.ID, Action: "auth.sign_in", Outcome: database.AuditOutcomeSuccess}); err != nil {
+		t.Fatalf("AppendAuditEvent failed: %v", err)
+	}
+	return user.ID
+}
+
+func TestBuildLeavesOutSecrets(t *testing.T) {
+	dbtest.Setup(t)
+	userID := createUserWithRecord(t)
+
+	data, err := Build(userID, time.Now())
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected secrets to be left out of the archive:\n%s", data)
+	}
+
+	var archive Archive
+	if err = json.Unmarshal(data, &archive); err != nil {
+		t.Fatalf("Expected a JSON archive: %v", err)
+	}
+	if archive.User.Email != "jane@example.com" {
+		t.Errorf("Expected the user email, got %q", archive.User.Email)
+	}
+	if len(archive.Sessions) != 1 || len(archive.Sessions[0].Devices) != 1 {
+		t.Errorf("Expected one session with its device, got %+v", archive.Sessions)
+	}
+	if len(archive.SocialProviders) != 1 || len(archive.PasswordResets) != 1 || len(archive.AuditEvents) != 1 {
+		t.Errorf("Expected the social provider, password reset and audit event, got %+v", archive)
+	}
+}
+
+func TestWorkerBuildsAndExpiresExports(t *testing.T) {
+	dbtest.Setup(t)
+	userID := createUserWithRecord(t)
+
+	export, err := database.CreateDataExport(userID, "download-token")
+	if err != nil {
+		t.Fatalf("CreateDataExport failed: %v", err)
+	}
+	if pending, _ := database.HasPendingDataExport(userID); !pending {
+		t.Errorf("Expected the export to be pending")
+	}
+
+	worker := NewWorker(Settings{DownloadTTL: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	worker.now = func() time.Time { return now }
+	if err = worker.ProcessPending(context.Background()); err != nil {
+		t.Fatalf("ProcessPending failed: %v", err)
+	}
+
+	export, err = database.GetDataExportByToken("download-token")
+	if err != nil {
+		t.Fatalf("GetDataExportByToken failed: %v", err)
+	}
+	if !export.IsDownloadable(now) || !strings.Contains(string(export.Archive), "jane@example.com") {
+		t.Errorf("Expected a downloadable archive, got status %s", export.Status)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err = worker.ProcessPending(context.Background()); err != nil {
+		t.Fatalf("ProcessPending failed: %v", err)
+	}
+	export, _ = database.GetDataExportByToken("download-token")
+	if export.Status != database.DataExportStatusExpired || export.Archive != nil {
+		t.Errorf("Expected the archive to be dropped once the token expired, got status %s", export.Status)
+	}
+}
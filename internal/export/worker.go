@@ -0,0 +1,94 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"github.com/usercoredev/usercore/internal/database"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+type Settings struct {
+	PollInterval time.Duration
+	// DownloadTTL is how long a finished archive can be downloaded with its token
+	DownloadTTL time.Duration
+	// Timeout is how long a worker may take to build an archive before another worker retries it
+	Timeout time.Duration
+}
+
+// Worker builds the requested archives in the background, so that large accounts do not block the request
+type Worker struct {
+	settings Settings
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+func NewWorker(settings Settings, logger *slog.Logger) *Worker {
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = 10 * time.Second
+	}
+	if settings.DownloadTTL <= 0 {
+		settings.DownloadTTL = 24 * time.Hour
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Minute
+	}
+	return &Worker{
+		settings: settings,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run builds pending archives until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.settings.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.ProcessPending(ctx); err != nil {
+			w.logger.ErrorContext(ctx, "data export failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessPending builds every pending archive and expires the archives whose download token is no longer valid
+func (w *Worker) ProcessPending(ctx context.Context) error {
+	if _, err := database.ExpireDataExports(w.now()); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		now := w.now()
+		export, err := database.ClaimPendingDataExport(now, now.Add(w.settings.Timeout))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		w.process(ctx, export)
+	}
+	return ctx.Err()
+}
+
+func (w *Worker) process(ctx context.Context, export *database.DataExport) {
+	archive, err := Build(export.UserID, w.now())
+	if err != nil {
+		w.logger.ErrorContext(ctx, "failed to build data export", "export_id", export.ID, "user_id", export.UserID.String(), "error", err)
+		if err = export.Fail(err.Error()); err != nil {
+			w.logger.ErrorContext(ctx, "failed to record data export failure", "export_id", export.ID, "error", err)
+		}
+		return
+	}
+	now := w.now()
+	if err = export.Complete(archive, now, now.Add(w.settings.DownloadTTL)); err != nil {
+		w.logger.ErrorContext(ctx, "failed to store data export", "export_id", export.ID, "error", err)
+		return
+	}
+	w.logger.InfoContext(ctx, "data export ready", "export_id", export.ID, "user_id", export.UserID.String(), "size", len(archive))
+}
@@ -0,0 +1,204 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"github.com/usercoredev/usercore/internal/database"
+	"log/slog"
+	"time"
+)
+
+const (
+	ScopeEmail = "email"
+	ScopeIP    = "ip"
+)
+
+var errNoStore = errors.New("no lockout store configured")
+
+type Settings struct {
+	Enabled bool
+	// MaxEmailFailures locks an email after that many failed sign-ins within the window
+	MaxEmailFailures int
+	// MaxIPFailures locks an IP address after that many failed sign-ins within the window
+	MaxIPFailures int
+	// DelayAfter is the number of failed sign-ins of an email before every further attempt has to wait
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Duration   time.Duration
+	Window     time.Duration
+}
+
+// Guard slows down and locks out repeated failed sign-ins per email and per IP address. Failures are kept in redis
+// when the cache is enabled and in the database otherwise, or when redis fails.
+type Guard struct {
+	settings Settings
+	primary  Store
+	fallback Store
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// Lock describes a lock applied by Fail
+type Lock struct {
+	Scope    string
+	Failures int
+	Until    time.Time
+}
+
+func NewGuard(settings Settings, primary, fallback Store, logger *slog.Logger) *Guard {
+	if settings.MaxEmailFailures <= 0 {
+		settings.MaxEmailFailures = 5
+	}
+	if settings.MaxIPFailures <= 0 {
+		settings.MaxIPFailures = 50
+	}
+	if settings.DelayAfter <= 0 {
+		settings.DelayAfter = 3
+	}
+	if settings.BaseDelay <= 0 {
+		settings.BaseDelay = time.Second
+	}
+	if settings.MaxDelay <= 0 {
+		settings.MaxDelay = time.Minute
+	}
+	if settings.Duration <= 0 {
+		settings.Duration = 15 * time.Minute
+	}
+	if settings.Window <= 0 {
+		settings.Window = 15 * time.Minute
+	}
+	return &Guard{
+		settings: settings,
+		primary:  primary,
+		fallback: fallback,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Check returns how long the caller has to wait before the next sign-in with the email from the IP address is
+// evaluated. The answer only depends on the failures, so it does not reveal whether the account exists.
+func (g *Guard) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	if g == nil || !g.settings.Enabled {
+		return 0, nil
+	}
+	now := g.now()
+	var wait time.Duration
+
+	emailState, err := g.get(ctx, emailKey(email), now)
+	if err != nil {
+		return 0, err
+	}
+	wait = max(wait, emailState.LockedUntil.Sub(now))
+	if emailState.Failures >= g.settings.DelayAfter {
+		wait = max(wait, emailState.LastFailureAt.Add(g.delay(emailState.Failures)).Sub(now))
+	}
+
+	if ip != "" {
+		ipState, err := g.get(ctx, ipKey(ip), now)
+		if err != nil {
+			return 0, err
+		}
+		wait = max(wait, ipState.LockedUntil.Sub(now))
+	}
+	return wait, nil
+}
+
+// Fail counts a failed sign-in and returns the locks it caused
+func (g *Guard) Fail(ctx context.Context, email, ip string) ([]Lock, error) {
+	if g == nil || !g.settings.Enabled {
+		return nil, nil
+	}
+	now := g.now()
+	var locks []Lock
+
+	state, err := g.recordFailure(ctx, emailKey(email), now, g.settings.MaxEmailFailures)
+	if err != nil {
+		return nil, err
+	}
+	if state.Failures == g.settings.MaxEmailFailures {
+		locks = append(locks, Lock{Scope: ScopeEmail, Failures: state.Failures, Until: state.LockedUntil})
+	}
+
+	if ip != "" {
+		state, err = g.recordFailure(ctx, ipKey(ip), now, g.settings.MaxIPFailures)
+		if err != nil {
+			return locks, err
+		}
+		if state.Failures == g.settings.MaxIPFailures {
+			locks = append(locks, Lock{Scope: ScopeIP, Failures: state.Failures, Until: state.LockedUntil})
+		}
+	}
+	return locks, nil
+}
+
+// Succeed clears the failures of the email. The failures of the IP address are kept so that signing in to one
+// account cannot be used to keep guessing the passwords of others.
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	if g == nil || !g.settings.Enabled {
+		return nil
+	}
+	key := emailKey(email)
+	return g.with(func(store Store) error {
+		return store.Reset(ctx, key)
+	})
+}
+
+// delay doubles the wait for every failure after DelayAfter, up to MaxDelay
+func (g *Guard) delay(failures int) time.Duration {
+	wait := g.settings.BaseDelay
+	for i := g.settings.DelayAfter; i < failures; i++ {
+		wait *= 2
+		if wait >= g.settings.MaxDelay {
+			return g.settings.MaxDelay
+		}
+	}
+	return wait
+}
+
+func (g *Guard) get(ctx context.Context, key string, now time.Time) (state State, err error) {
+	err = g.with(func(store Store) error {
+		state, err = store.Get(ctx, key, now)
+		return err
+	})
+	return state, err
+}
+
+func (g *Guard) recordFailure(ctx context.Context, key string, now time.Time, limit int) (state State, err error) {
+	lockUntil := func(failures int) time.Time {
+		if failures >= limit {
+			return now.Add(g.settings.Duration)
+		}
+		return time.Time{}
+	}
+	err = g.with(func(store Store) error {
+		state, err = store.RecordFailure(ctx, key, now, lockUntil)
+		return err
+	})
+	return state, err
+}
+
+// with runs the operation on the primary store and retries it on the fallback store when the primary fails
+func (g *Guard) with(operation func(store Store) error) error {
+	if g.primary != nil {
+		err := operation(g.primary)
+		if err == nil || g.fallback == nil {
+			return err
+		}
+		g.logger.Warn("lockout store failed, using fallback", "error", err)
+	}
+	if g.fallback == nil {
+		return errNoStore
+	}
+	return operation(g.fallback)
+}
+
+// emailKey hashes the normalized email, so that the stores never hold the address itself
+func emailKey(email string) string {
+	return database.LoginAttemptEmailKey(email)
+}
+
+func ipKey(ip string) string {
+	return ScopeIP + ":" + ip
+}
@@ -0,0 +1,113 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string, time.Time) (State, error) {
+	return State{}, errors.New("connection refused")
+}
+
+func (failingStore) RecordFailure(context.Context, string, time.Time, func(int) time.Time) (State, error) {
+	return State{}, errors.New("connection refused")
+}
+
+func (failingStore) Reset(context.Context, string) error {
+	return errors.New("connection refused")
+}
+
+func newTestGuard(settings Settings, primary Store) (*Guard, *time.Time) {
+	settings.Enabled = true
+	guard := NewGuard(settings, primary, NewDatabaseStore(settings.Window), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	guard.now = func() time.Time { return now }
+	return guard, &now
+}
+
+func TestGuardDelaysAndLocksEmail(t *testing.T) {
+	dbtest.Setup(t)
+	ctx := context.Background()
+	guard, now := newTestGuard(Settings{MaxEmailFailures: 4, DelayAfter: 2, BaseDelay: time.Second, MaxDelay: time.Minute, Duration: time.Hour, Window: time.Hour}, nil)
+
+	for i := 1; i <= 3; i++ {
+		if wait, _ := guard.Check(ctx, "Jane@Example.com", "198.51.100.1"); wait > 0 {
+			t.Fatalf("Attempt %d: expected no wait, got %v", i, wait)
+		}
+		if _, err := guard.Fail(ctx, "jane@example.com ", "198.51.100.1"); err != nil {
+			t.Fatalf("Fail failed: %v", err)
+		}
+		*now = now.Add(10 * time.Second)
+	}
+
+	*now = now.Add(-10 * time.Second)
+	wait, err := guard.Check(ctx, "jane@example.com", "198.51.100.2")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if wait != 2*time.Second {
+		t.Errorf("Expected a 2s delay after 3 failures, got %v", wait)
+	}
+
+	*now = now.Add(2 * time.Second)
+	locks, err := guard.Fail(ctx, "jane@example.com", "198.51.100.2")
+	if err != nil {
+		t.Fatalf("Fail failed: %v", err)
+	}
+	if len(locks) != 1 || locks[0].Scope != ScopeEmail {
+		t.Fatalf("Expected the email to be locked, got %+v", locks)
+	}
+	if wait, _ = guard.Check(ctx, "jane@example.com", ""); wait != time.Hour {
+		t.Errorf("Expected the lockout to last an hour, got %v", wait)
+	}
+	if wait, _ = guard.Check(ctx, "unknown@example.com", ""); wait != 0 {
+		t.Errorf("Expected other emails not to be affected, got %v", wait)
+	}
+
+	*now = now.Add(time.Hour + time.Second)
+	if wait, _ = guard.Check(ctx, "jane@example.com", ""); wait != 0 {
+		t.Errorf("Expected the lockout to end, got %v", wait)
+	}
+}
+
+func TestGuardLocksIPAndClearsEmailOnSuccess(t *testing.T) {
+	dbtest.Setup(t)
+	ctx := context.Background()
+	guard, _ := newTestGuard(Settings{MaxEmailFailures: 10, MaxIPFailures: 3, DelayAfter: 10, Duration: time.Minute, Window: time.Hour}, failingStore{})
+
+	var locks []Lock
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		var err error
+		if locks, err = guard.Fail(ctx, email, "198.51.100.1"); err != nil {
+			t.Fatalf("Expected the database fallback to be used, got %v", err)
+		}
+	}
+	if len(locks) != 1 || locks[0].Scope != ScopeIP {
+		t.Fatalf("Expected the IP to be locked, got %+v", locks)
+	}
+	if wait, _ := guard.Check(ctx, "d@example.com", "198.51.100.1"); wait != time.Minute {
+		t.Errorf("Expected every email to wait for the IP lockout, got %v", wait)
+	}
+
+	if err := guard.Succeed(ctx, "a@example.com"); err != nil {
+		t.Fatalf("Succeed failed: %v", err)
+	}
+	state, _ := NewDatabaseStore(time.Hour).Get(ctx, emailKey("a@example.com"), time.Now())
+	if state.Failures != 0 {
+		t.Errorf("Expected the email failures to be cleared, got %d", state.Failures)
+	}
+}
+
+func TestGuardDisabled(t *testing.T) {
+	var guard *Guard
+	if wait, err := guard.Check(context.Background(), "jane@example.com", ""); wait != 0 || err != nil {
+		t.Errorf("Expected a nil guard to allow every attempt")
+	}
+}
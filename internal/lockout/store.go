@@ -0,0 +1,122 @@
+package lockout
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/usercoredev/usercore/internal/database"
+	"strconv"
+	"time"
+)
+
+// State is what is known about the failed sign-ins of an email or an IP address
+type State struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store keeps the failed sign-ins. Failures are forgotten once the window passes without a new failure, and all of
+// them are forgotten when a lock ends.
+type Store interface {
+	Get(ctx context.Context, key string, now time.Time) (State, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, lockUntil func(failures int) time.Time) (State, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type redisStore struct {
+	client *redis.Client
+	window time.Duration
+}
+
+// NewRedisStore keeps the failures in redis hashes that expire with the window
+func NewRedisStore(client *redis.Client, window time.Duration) Store {
+	return &redisStore{client: client, window: window}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string, _ time.Time) (State, error) {
+	values, err := s.client.HGetAll(ctx, redisKey(key)).Result()
+	if err != nil {
+		return State{}, err
+	}
+	return stateFromHash(values), nil
+}
+
+func (s *redisStore) RecordFailure(ctx context.Context, key string, now time.Time, lockUntil func(failures int) time.Time) (State, error) {
+	var failures *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.HIncrBy(ctx, redisKey(key), "failures", 1)
+		pipe.HSet(ctx, redisKey(key), "last_failure_at", now.UnixMilli())
+		pipe.PExpire(ctx, redisKey(key), s.window)
+		return nil
+	})
+	if err != nil {
+		return State{}, err
+	}
+	state := State{Failures: int(failures.Val()), LastFailureAt: now}
+	if until := lockUntil(state.Failures); !until.IsZero() {
+		state.LockedUntil = until
+		_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, redisKey(key), "locked_until", until.UnixMilli())
+			pipe.PExpireAt(ctx, redisKey(key), until)
+			return nil
+		})
+	}
+	return state, err
+}
+
+func (s *redisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKey(key)).Err()
+}
+
+func redisKey(key string) string {
+	return "lockout:" + key
+}
+
+func stateFromHash(values map[string]string) State {
+	var state State
+	state.Failures, _ = strconv.Atoi(values["failures"])
+	if ms, err := strconv.ParseInt(values["last_failure_at"], 10, 64); err == nil {
+		state.LastFailureAt = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(values["locked_until"], 10, 64); err == nil {
+		state.LockedUntil = time.UnixMilli(ms)
+	}
+	return state
+}
+
+type databaseStore struct {
+	window time.Duration
+}
+
+// NewDatabaseStore keeps the failures in the login_attempts table
+func NewDatabaseStore(window time.Duration) Store {
+	return &databaseStore{window: window}
+}
+
+func (s *databaseStore) Get(_ context.Context, key string, now time.Time) (State, error) {
+	attempt, err := database.GetLoginAttempt(key, now, s.window)
+	if err != nil {
+		return State{}, err
+	}
+	return stateFromAttempt(attempt), nil
+}
+
+func (s *databaseStore) RecordFailure(_ context.Context, key string, now time.Time, lockUntil func(failures int) time.Time) (State, error) {
+	attempt, err := database.RecordLoginFailure(key, now, s.window, lockUntil)
+	if err != nil {
+		return State{}, err
+	}
+	return stateFromAttempt(attempt), nil
+}
+
+func (s *databaseStore) Reset(_ context.Context, key string) error {
+	return database.DeleteLoginAttempt(key)
+}
+
+func stateFromAttempt(attempt *database.LoginAttempt) State {
+	state := State{Failures: attempt.Failures, LastFailureAt: attempt.LastFailureAt}
+	if attempt.LockedUntil != nil {
+		state.LockedUntil = *attempt.LockedUntil
+	}
+	return state
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestIDInterceptor assigns a request ID to every call, reusing the one forwarded by the gateway when it is valid,
+// and echoes it back in the response headers
+func RequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(RequestIDHeader); len(values) > 0 && validRequestID.MatchString(values[0]) {
+				requestID = values[0]
+			}
+		}
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		ctx = WithRequest(ctx, requestID)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+		return handler(ctx, req)
+	}
+}
+
+// AccessLogInterceptor records the method, status and latency of every call together with the client and user
+// attached to the request by the inner interceptors
+func AccessLogInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		statusCode := status.Code(err)
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelWarn
+		}
+		log.LogAttrs(ctx, level, "request completed",
+			slog.String("method", info.FullMethod),
+			slog.String("status", statusCode.String()),
+			slog.Duration("latency", time.Since(start)),
+		)
+		return resp, err
+	}
+}
@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+type requestKey string
+
+var Key requestKey = "request"
+
+// RequestIDHeader is the metadata key used to receive and echo the request ID
+const RequestIDHeader = "x-request-id"
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never written to the log
+var sensitiveKeys = map[string]bool{
+	"password":         true,
+	"new_password":     true,
+	"old_password":     true,
+	"current_password": true,
+	"token":            true,
+	"access_token":     true,
+	"refresh_token":    true,
+	"reset_token":      true,
+	"otp":              true,
+	"otp_code":         true,
+	"code":             true,
+	"email":            true,
+	"secret":           true,
+	"authorization":    true,
+}
+
+type Settings struct {
+	Level  string
+	Format string
+	Output io.Writer
+}
+
+// Request holds the request ID and the attributes collected by the interceptors while handling a call
+type Request struct {
+	ID    string
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// New creates a slog logger which redacts sensitive attributes and annotates records with the request context
+func (s *Settings) New() *slog.Logger {
+	output := s.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	options := &slog.HandlerOptions{
+		Level:       parseLevel(s.Level),
+		ReplaceAttr: redact,
+	}
+	var handler slog.Handler
+	if strings.EqualFold(s.Format, "text") {
+		handler = slog.NewTextHandler(output, options)
+	} else {
+		handler = slog.NewJSONHandler(output, options)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// redact replaces the value of any attribute with a sensitive key
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if IsSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+// IsSensitive reports whether values stored under the given key must not be logged
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	return strings.Contains(key, "password") || strings.Contains(key, "secret") || strings.HasSuffix(key, "_token")
+}
+
+// WithRequest returns a context carrying a new request with the given ID
+func WithRequest(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, Key, &Request{ID: id})
+}
+
+// RequestID returns the ID of the request in the context, or an empty string
+func RequestID(ctx context.Context) string {
+	if request := requestFromContext(ctx); request != nil {
+		return request.ID
+	}
+	return ""
+}
+
+// AddAttrs attaches attributes to the request in the context so that every later record includes them
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	request := requestFromContext(ctx)
+	if request == nil {
+		return
+	}
+	request.mu.Lock()
+	defer request.mu.Unlock()
+	request.attrs = append(request.attrs, attrs...)
+}
+
+func requestFromContext(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	request, _ := ctx.Value(Key).(*Request)
+	return request
+}
+
+func (r *Request) snapshot() []slog.Attr {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attrs := make([]slog.Attr, 0, len(r.attrs)+1)
+	attrs = append(attrs, slog.String("request_id", r.ID))
+	return append(attrs, r.attrs...)
+}
+
+// contextHandler adds the request ID and request attributes to records logged with a context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if request := requestFromContext(ctx); request != nil {
+		record.AddAttrs(request.snapshot()...)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
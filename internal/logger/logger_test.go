@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func decodeLine(t *testing.T, buffer *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var record map[string]interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", buffer.String(), err)
+	}
+	return record
+}
+
+func TestRedactsSensitiveAttributes(t *testing.T) {
+	var buffer bytes.Buffer
+	settings := Settings{Output: &buffer}
+	log := settings.New()
+
+	log.Info("sign in", "email", "user@example.com", "password", "Secret123!", "otp", "123456", "method", "SignIn")
+	record := decodeLine(t, &buffer)
+
+	for _, key := range []string{"email", "password", "otp"} {
+		if record[key] != redacted {
+			t.Errorf("Expected %s to be redacted, got %v", key, record[key])
+		}
+	}
+	if record["method"] != "SignIn" {
+		t.Errorf("Expected method to be kept, got %v", record["method"])
+	}
+}
+
+func TestRedactsNestedAttributes(t *testing.T) {
+	var buffer bytes.Buffer
+	settings := Settings{Output: &buffer}
+	log := settings.New()
+
+	log.Info("refresh", slog.Group("session", slog.String("refresh_token", "abc"), slog.Uint64("id", 7)))
+	record := decodeLine(t, &buffer)
+
+	session, ok := record["session"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected session group, got %v", record["session"])
+	}
+	if session["refresh_token"] != redacted {
+		t.Errorf("Expected refresh_token to be redacted, got %v", session["refresh_token"])
+	}
+}
+
+func TestRequestAttributes(t *testing.T) {
+	var buffer bytes.Buffer
+	settings := Settings{Output: &buffer}
+	log := settings.New()
+
+	ctx := WithRequest(context.Background(), "request-1")
+	AddAttrs(ctx, slog.String("client_id", "client-1"))
+	log.InfoContext(ctx, "handled")
+	record := decodeLine(t, &buffer)
+
+	if record["request_id"] != "request-1" {
+		t.Errorf("Expected request_id request-1, got %v", record["request_id"])
+	}
+	if record["client_id"] != "client-1" {
+		t.Errorf("Expected client_id client-1, got %v", record["client_id"])
+	}
+	if RequestID(context.Background()) != "" {
+		t.Errorf("Expected empty request ID without a request")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"WARN":    slog.LevelWarn,
+		"error":   slog.LevelError,
+		"":        slog.LevelInfo,
+		"unknown": slog.LevelInfo,
+	}
+	for input, expected := range tests {
+		if level := parseLevel(input); level != expected {
+			t.Errorf("parseLevel(%q): expected %v, got %v", input, expected, level)
+		}
+	}
+}
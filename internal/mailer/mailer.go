@@ -0,0 +1,154 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Sender delivers emails to users
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Settings struct {
+	// Driver is smtp, or log to only log the messages during development. Empty disables sending
+	Driver   string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// ImplicitTLS connects with TLS (port 465), otherwise STARTTLS is used when the server offers it
+	ImplicitTLS bool
+	Timeout     time.Duration
+}
+
+// New returns the sender of the configured driver, nil when sending is disabled
+func (s Settings) New(logger *slog.Logger) (Sender, error) {
+	switch s.Driver {
+	case "":
+		return nil, nil
+	case DriverLog:
+		return &LogSender{logger: logger}, nil
+	case DriverSMTP:
+		if s.Host == "" || s.From == "" {
+			return nil, errors.New("smtp mailer requires a host and a from address")
+		}
+		if s.Port == "" {
+			s.Port = "587"
+		}
+		if s.Timeout <= 0 {
+			s.Timeout = 10 * time.Second
+		}
+		return &SMTPSender{settings: s}, nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver %q", s.Driver)
+	}
+}
+
+// LogSender writes the messages to the log instead of sending them. It logs their content, which contains codes and
+// links, so it must only be used during development
+type LogSender struct {
+	logger *slog.Logger
+}
+
+func (l *LogSender) Send(ctx context.Context, message Message) error {
+	l.logger.InfoContext(ctx, "email", "to", message.To, "subject", message.Subject, "text", message.Text)
+	return nil
+}
+
+type SMTPSender struct {
+	settings Settings
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	address := net.JoinHostPort(s.settings.Host, s.settings.Port)
+	dialer := &net.Dialer{Timeout: s.settings.Timeout}
+	var conn net.Conn
+	var err error
+	if s.settings.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.settings.Host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(s.settings.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.settings.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if !s.settings.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.settings.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.settings.Username, s.settings.Password, s.settings.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.settings.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(compose(s.settings.From, message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose builds the RFC 5322 message. Header values come from our own templates and validated addresses, line breaks
+// are still removed so that they cannot add headers
+func compose(from string, message Message) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(message.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", clean.Replace(message.Subject)) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Text, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
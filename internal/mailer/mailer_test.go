@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComposeStripsHeaderInjection(t *testing.T) {
+	data := string(compose("noreply@example.com", Message{
+		To:      "jane@example.com\r\nBcc: attacker@example.com",
+		Subject: "Reset your password",
+		Text:    "line one\nline two",
+	}))
+	if strings.Contains(data, "\r\nBcc:") {
+		t.Errorf("Expected line breaks to be removed from headers:\n%s", data)
+	}
+	if !strings.Contains(data, "\r\n\r\nline one\r\nline two") {
+		t.Errorf("Expected the body to use CRLF line endings:\n%s", data)
+	}
+}
+
+func TestSettingsNew(t *testing.T) {
+	if sender, err := (Settings{}).New(nil); sender != nil || err != nil {
+		t.Errorf("Expected no sender without a driver, got %v, %v", sender, err)
+	}
+	if _, err := (Settings{Driver: DriverSMTP}).New(nil); err == nil {
+		t.Error("Expected smtp without a host to fail")
+	}
+	if _, err := (Settings{Driver: "carrier-pigeon"}).New(nil); err == nil {
+		t.Error("Expected an unknown driver to fail")
+	}
+}
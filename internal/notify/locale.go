@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// message is the localized text of an event, each field is a text/template over Data
+type message struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Push    string `json:"push"`
+}
+
+type compiledMessage struct {
+	subject *template.Template
+	body    *template.Template
+	push    *template.Template
+}
+
+// catalog holds the messages of every event per language
+type catalog map[string]map[string]compiledMessage
+
+func loadCatalog() (catalog, error) {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	messages := catalog{}
+	for _, entry := range entries {
+		language := strings.TrimSuffix(entry.Name(), ".json")
+		content, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var raw map[string]message
+		if err = json.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", language, err)
+		}
+		messages[language] = map[string]compiledMessage{}
+		for event, msg := range raw {
+			compiled := compiledMessage{}
+			for _, field := range []struct {
+				target **template.Template
+				text   string
+			}{{&compiled.subject, msg.Subject}, {&compiled.body, msg.Body}, {&compiled.push, msg.Push}} {
+				*field.target, err = template.New(event).Option("missingkey=zero").Parse(field.text)
+				if err != nil {
+					return nil, fmt.Errorf("locale %s, event %s: %w", language, event, err)
+				}
+			}
+			messages[language][event] = compiled
+		}
+	}
+	return messages, nil
+}
+
+// language returns the catalog language of a locale like tr-TR or pt_BR, or the fallback
+func (c catalog) language(locale, fallback string) string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if _, ok := c[locale]; ok {
+		return locale
+	}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		if _, ok := c[base]; ok {
+			return base
+		}
+	}
+	return fallback
+}
+
+// render returns the subject, the email body and the push text of the event in the language of the locale
+func (c catalog) render(locale, fallback, event string, data Data) (string, string, string, error) {
+	msg, ok := c[c.language(locale, fallback)][event]
+	if !ok {
+		if msg, ok = c[fallback][event]; !ok {
+			return "", "", "", fmt.Errorf("no message for event %s", event)
+		}
+	}
+	var texts [3]string
+	for i, tmpl := range []*template.Template{msg.subject, msg.body, msg.push} {
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return "", "", "", err
+		}
+		texts[i] = buffer.String()
+	}
+	return texts[0], texts[1], texts[2], nil
+}
@@ -0,0 +1,305 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	EventNewDeviceSignIn       = "new_device_sign_in"
+	EventPasswordChanged       = "password_changed"
+	EventEmailChanged          = "email_changed"
+	EventSessionRevokedByAdmin = "session_revoked_by_admin"
+)
+
+// Event is a security notification. Mandatory notifications are always sent and cannot be turned off.
+type Event struct {
+	Name      string
+	Mandatory bool
+}
+
+// Events lists the security notifications in the order they are shown to the user
+var Events = []Event{
+	{Name: EventNewDeviceSignIn},
+	{Name: EventPasswordChanged, Mandatory: true},
+	{Name: EventEmailChanged, Mandatory: true},
+	{Name: EventSessionRevokedByAdmin},
+}
+
+// LookupEvent returns the event with the name
+func LookupEvent(name string) (Event, bool) {
+	for _, event := range Events {
+		if event.Name == name {
+			return event, true
+		}
+	}
+	return Event{}, false
+}
+
+// Data fills the messages of an event. Name and Time are set by the notifier.
+type Data struct {
+	Name     string
+	Time     string
+	IP       string
+	Device   string
+	NewEmail string
+	// To sends the email to another address than the current email of the user, like the previous email after a change
+	To string
+}
+
+// RevokedSessions names the clients of the revoked sessions. Their devices are already gone, the push goes to the
+// devices the user is still signed in on.
+func RevokedSessions(sessions []database.Session) Data {
+	var clients []string
+	seen := map[string]bool{}
+	for _, session := range sessions {
+		if name := session.ClientName; name != "" && !seen[name] {
+			seen[name] = true
+			clients = append(clients, name)
+		}
+	}
+	return Data{Device: strings.Join(clients, ", ")}
+}
+
+// Channels tells which channels of a notification are done, delivered or not needed
+type Channels struct {
+	Email bool
+	Push  bool
+}
+
+type Settings struct {
+	Enabled bool
+	// DefaultLocale is used for users without a profile locale, or with a locale that has no messages
+	DefaultLocale string
+	// Timeout bounds the delivery of one notification
+	Timeout time.Duration
+	// PollInterval is how often the queued notifications are checked
+	PollInterval time.Duration
+	// MaxAttempts is how many times a notification is tried before it is given up
+	MaxAttempts int
+}
+
+// batchSize bounds the notifications sent per poll
+const batchSize = 100
+
+// Notifier sends the security notifications of users by email and push. Notifications are queued in the database and
+// sent by Run, so a failing mailer or push gateway or a restart does not lose them.
+type Notifier struct {
+	settings Settings
+	mailer   mailer.Sender
+	pusher   Pusher
+	messages catalog
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+// New returns the notifier, nil when notifications are disabled. Either sender may be nil.
+func New(settings Settings, sender mailer.Sender, pusher Pusher, logger *slog.Logger) (*Notifier, error) {
+	if !settings.Enabled {
+		return nil, nil
+	}
+	messages, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	if settings.DefaultLocale == "" {
+		settings.DefaultLocale = "en"
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 30 * time.Second
+	}
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = 5 * time.Second
+	}
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = 5
+	}
+	return &Notifier{settings: settings, mailer: sender, pusher: pusher, messages: messages, logger: logger, now: time.Now}, nil
+}
+
+// Notify queues the event for the user, it is sent by Run. The queued data is kept until NotificationRetention at most,
+// push tokens are not part of it and are looked up when sending. Failures are logged.
+func (n *Notifier) Notify(ctx context.Context, userID uuid.UUID, event string, data Data) {
+	if n == nil {
+		return
+	}
+	content, err := json.Marshal(data)
+	if err == nil {
+		err = database.EnqueueNotification(userID, event, content)
+	}
+	if err != nil {
+		n.logger.ErrorContext(ctx, "failed to queue security notification", "event", event, "user_id", userID.String(), "error", err)
+	}
+}
+
+// Run sends the queued notifications until the context is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.settings.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := n.SendDue(ctx); err != nil {
+			n.logger.ErrorContext(ctx, "security notification delivery failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends the queued notifications whose attempt is due. A failed notification is retried with exponential
+// backoff until MaxAttempts, only on the channels that failed, then it is kept as dead for inspection.
+func (n *Notifier) SendDue(ctx context.Context) error {
+	now := n.now()
+	notifications, err := database.GetDueNotifications(now, batchSize)
+	if err != nil {
+		return err
+	}
+	for i := range notifications {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		notification := &notifications[i]
+		locked, err := notification.Lock(now, now.Add(2*n.settings.Timeout))
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+		if err = n.deliver(ctx, notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver sends one queued notification and records the outcome
+func (n *Notifier) deliver(ctx context.Context, notification *database.Notification) error {
+	var data Data
+	err := json.Unmarshal([]byte(notification.Data), &data)
+	if err == nil {
+		done := Channels{Email: notification.EmailSentAt != nil, Push: notification.PushSentAt != nil}
+		sendCtx, cancel := context.WithTimeout(ctx, n.settings.Timeout)
+		done, err = n.Send(sendCtx, notification.UserID, notification.Event, data, notification.CreatedAt, done)
+		cancel()
+		now := n.now()
+		if done.Email && notification.EmailSentAt == nil {
+			notification.EmailSentAt = &now
+		}
+		if done.Push && notification.PushSentAt == nil {
+			notification.PushSentAt = &now
+		}
+	}
+	if err == nil {
+		return notification.Sent()
+	}
+
+	notification.Attempts++
+	notification.LastError = err.Error()
+	if notification.Attempts >= n.settings.MaxAttempts {
+		notification.Status = database.DeliveryStatusDead
+		n.logger.WarnContext(ctx, "security notification given up", "notification_id", notification.ID, "event", notification.Event, "user_id", notification.UserID.String(), "attempts", notification.Attempts, "error", err)
+	} else {
+		notification.NextAttemptAt = n.now().Add(backoff(notification.Attempts))
+		n.logger.InfoContext(ctx, "security notification failed, retrying", "notification_id", notification.ID, "event", notification.Event, "attempts", notification.Attempts, "error", err)
+	}
+	return notification.Save()
+}
+
+// backoff doubles the wait after every failed attempt, from a minute up to an hour
+func backoff(attempts int) time.Duration {
+	wait := time.Minute << (attempts - 1)
+	if wait <= 0 || wait > time.Hour {
+		return time.Hour
+	}
+	return wait
+}
+
+// Send delivers the event to the user on the channels the user did not turn off and that are not done yet. It returns
+// the channels that are done, so that a failed channel can be retried without sending the other one again.
+func (n *Notifier) Send(ctx context.Context, userID uuid.UUID, event string, data Data, at time.Time, done Channels) (Channels, error) {
+	user, err := database.GetUserProfile(userID)
+	if err != nil {
+		return done, err
+	}
+	sendEmail, sendPush, err := channels(userID, event)
+	if err != nil {
+		return done, err
+	}
+
+	locale := ""
+	data.Name = user.Name
+	data.Time = at.UTC().Format("2006-01-02 15:04 MST")
+	if user.Profile != nil {
+		locale = user.Profile.Locale
+		if location, err := time.LoadLocation(user.Profile.Timezone); err == nil && user.Profile.Timezone != "" {
+			data.Time = at.In(location).Format("2006-01-02 15:04 MST")
+		}
+	}
+	subject, body, pushText, err := n.messages.render(locale, n.settings.DefaultLocale, event, data)
+	if err != nil {
+		return done, err
+	}
+
+	var emailErr, pushErr error
+	if !done.Email {
+		if sendEmail && n.mailer != nil {
+			to := data.To
+			if to == "" {
+				to = user.Email
+			}
+			emailErr = n.mailer.Send(ctx, mailer.Message{To: to, Subject: subject, Text: body})
+		}
+		done.Email = emailErr == nil
+	}
+	if !done.Push {
+		if sendPush && n.pusher != nil {
+			pushErr = n.push(ctx, userID, PushMessage{Event: event, Title: subject, Body: pushText})
+		}
+		done.Push = pushErr == nil
+	}
+	return done, errors.Join(emailErr, pushErr)
+}
+
+// push sends the message to the devices of the user that have a push token
+func (n *Notifier) push(ctx context.Context, userID uuid.UUID, message PushMessage) error {
+	devices, err := database.GetDevicesByUserId(userID)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if device.Token != "" {
+			message.Tokens = append(message.Tokens, device.Token)
+		}
+	}
+	if len(message.Tokens) == 0 {
+		return nil
+	}
+	return n.pusher.Push(ctx, message)
+}
+
+// channels returns whether the event goes out by email and by push for the user
+func channels(userID uuid.UUID, name string) (bool, bool, error) {
+	if event, ok := LookupEvent(name); !ok || event.Mandatory {
+		return true, true, nil
+	}
+	preferences, err := database.GetNotificationPreferences(userID)
+	if err != nil {
+		return false, false, err
+	}
+	for _, preference := range preferences {
+		if preference.Event == name {
+			return preference.Email, preference.Push, nil
+		}
+	}
+	return true, true, nil
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"github.com/usercoredev/usercore/internal/mailer"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingMailer struct {
+	messages []mailer.Message
+}
+
+func (r *recordingMailer) Send(_ context.Context, message mailer.Message) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+type recordingPusher struct {
+	messages []PushMessage
+}
+
+func (r *recordingPusher) Push(_ context.Context, message PushMessage) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestCatalogLanguage(t *testing.T) {
+	messages, err := loadCatalog()
+	if err != nil {
+		t.Fatalf("loadCatalog failed: %v", err)
+	}
+	for locale, want := range map[string]string{"tr-TR": "tr", "tr_TR": "tr", "EN": "en", "xx-YY": "en", "": "en"} {
+		if got := messages.language(locale, "en"); got != want {
+			t.Errorf("language(%q) = %q, want %q", locale, got, want)
+		}
+	}
+	for language, events := range messages {
+		for _, event := range Events {
+			if _, ok := events[event.Name]; !ok {
+				t.Errorf("Locale %s has no message for %s", language, event.Name)
+			}
+		}
+	}
+}
+
+func TestSendLocalizedAndPreferences(t *testing.T) {
+	dbtest.Setup(t)
+	user := database.User{Name: "Ayşe", Email: "ayse@example.com"}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Create(&database.Profile{UserID: user.ID, Locale: "tr-TR", Timezone: "Europe/Istanbul"})
+	database.DB.Create(&database.Device{UserID: user.ID, Name: "phone", Token: "push-token"})
+
+	mail := &recordingMailer{}
+	push := &recordingPusher{}
+	notifier, err := New(Settings{Enabled: true}, mail, push, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err = notifier.Send(context.Background(), user.ID, EventNewDeviceSignIn, Data{IP: "203.0.113.7", Device: "Web"}, at, Channels{}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(mail.messages) != 1 || len(push.messages) != 1 {
+		t.Fatalf("Expected one email and one push, got %d and %d", len(mail.messages), len(push.messages))
+	}
+	if !strings.Contains(mail.messages[0].Subject, "yeni bir cihazdan") {
+		t.Errorf("Expected a Turkish subject, got %q", mail.messages[0].Subject)
+	}
+	if !strings.Contains(mail.messages[0].Text, "2024-03-01 15:00") || !strings.Contains(mail.messages[0].Text, "203.0.113.7") {
+		t.Errorf("Expected the local time and the IP in the body:\n%s", mail.messages[0].Text)
+	}
+	if push.messages[0].Tokens[0] != "push-token" {
+		t.Errorf("Expected the push to go to the device token, got %v", push.messages[0].Tokens)
+	}
+
+	err = database.SaveNotificationPreferences(user.ID, []database.NotificationPreference{{Event: EventNewDeviceSignIn, Email: false, Push: false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, _ := notifier.Send(context.Background(), user.ID, EventNewDeviceSignIn, Data{}, at, Channels{})
+	if len(mail.messages) != 1 || len(push.messages) != 1 {
+		t.Error("Expected a turned off notification not to be sent")
+	}
+	if !done.Email || !done.Push {
+		t.Errorf("Expected turned off channels to be done, got %+v", done)
+	}
+
+	_, _ = notifier.Send(context.Background(), user.ID, EventEmailChanged, Data{NewEmail: "new@example.com", To: "ayse@example.com"}, at, Channels{})
+	if len(mail.messages) != 2 || mail.messages[1].To != "ayse@example.com" || !strings.Contains(mail.messages[1].Text, "new@example.com") {
+		t.Errorf("Expected the mandatory email changed notification, got %+v", mail.messages)
+	}
+	_, _ = notifier.Send(context.Background(), user.ID, EventPasswordChanged, Data{}, at, Channels{Email: true})
+	if len(mail.messages) != 2 || len(push.messages) != 3 {
+		t.Errorf("Expected only the channel that is not done to be sent, got %d emails and %d pushes", len(mail.messages), len(push.messages))
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mailer.Message) error {
+	return errors.New("mail server is down")
+}
+
+type failingPusher struct{}
+
+func (failingPusher) Push(context.Context, PushMessage) error {
+	return errors.New("push gateway is down")
+}
+
+func TestNotifyQueuesAndSendDue(t *testing.T) {
+	dbtest.Setup(t)
+	user := dbtest.CreateUser(t, "user@example.com")
+	mail := &recordingMailer{}
+	notifier, err := New(Settings{Enabled: true}, mail, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier.Notify(context.Background(), user.ID, EventPasswordChanged, Data{})
+	if len(mail.messages) != 0 {
+		t.Error("Expected Notify to only queue the notification")
+	}
+	if err = notifier.SendDue(context.Background()); err != nil {
+		t.Fatalf("SendDue failed: %v", err)
+	}
+	if len(mail.messages) != 1 || mail.messages[0].To != "user@example.com" {
+		t.Errorf("Expected the queued notification to be sent, got %+v", mail.messages)
+	}
+	var count int64
+	database.DB.Unscoped().Model(&database.Notification{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected the sent notification to be removed, %d left", count)
+	}
+}
+
+func TestSendDueRetriesAndGivesUp(t *testing.T) {
+	dbtest.Setup(t)
+	user := dbtest.CreateUser(t, "user@example.com")
+	notifier, err := New(Settings{Enabled: true, MaxAttempts: 2}, failingMailer{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(time.Second)
+	notifier.now = func() time.Time { return now }
+	notifier.Notify(context.Background(), user.ID, EventPasswordChanged, Data{})
+
+	if err = notifier.SendDue(context.Background()); err != nil {
+		t.Fatalf("SendDue failed: %v", err)
+	}
+	var notification database.Notification
+	if err = database.DB.First(&notification).Error; err != nil {
+		t.Fatal(err)
+	}
+	if notification.Attempts != 1 || notification.Status != database.DeliveryStatusPending || !notification.NextAttemptAt.After(now) {
+		t.Errorf("Expected a retry to be scheduled, got %+v", notification)
+	}
+	if notification.LastError != "mail server is down" {
+		t.Errorf("Expected the error to be kept, got %q", notification.LastError)
+	}
+
+	// not due before the backoff
+	_ = notifier.SendDue(context.Background())
+	database.DB.First(&notification)
+	if notification.Attempts != 1 {
+		t.Errorf("Expected no attempt before the backoff, got %d", notification.Attempts)
+	}
+
+	now = now.Add(time.Hour)
+	_ = notifier.SendDue(context.Background())
+	database.DB.First(&notification)
+	if notification.Attempts != 2 || notification.Status != database.DeliveryStatusDead {
+		t.Errorf("Expected the notification to be dead after MaxAttempts, got %+v", notification)
+	}
+}
+
+func TestSendDueRetriesOnlyTheFailedChannel(t *testing.T) {
+	dbtest.Setup(t)
+	user := dbtest.CreateUser(t, "user@example.com")
+	database.DB.Create(&database.Device{UserID: user.ID, Name: "phone", Token: "push-token"})
+	mail := &recordingMailer{}
+	notifier, err := New(Settings{Enabled: true}, mail, failingPusher{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Add(time.Second)
+	notifier.now = func() time.Time { return now }
+	notifier.Notify(context.Background(), user.ID, EventPasswordChanged, Data{})
+
+	_ = notifier.SendDue(context.Background())
+	var notification database.Notification
+	if err = database.DB.First(&notification).Error; err != nil {
+		t.Fatal(err)
+	}
+	if notification.EmailSentAt == nil || notification.PushSentAt != nil || notification.Attempts != 1 {
+		t.Errorf("Expected the email to be recorded and the push to be retried, got %+v", notification)
+	}
+	if strings.Contains(notification.Data, "push-token") {
+		t.Error("Expected the push token not to be queued")
+	}
+
+	now = now.Add(time.Hour)
+	_ = notifier.SendDue(context.Background())
+	if len(mail.messages) != 1 {
+		t.Errorf("Expected the email to be sent once, got %d", len(mail.messages))
+	}
+}
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	PushDriverHTTP = "http"
+	PushDriverLog  = "log"
+)
+
+// PushMessage is a notification for the push tokens of the devices of a user
+type PushMessage struct {
+	Tokens []string `json:"tokens"`
+	Event  string   `json:"event"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+}
+
+// Pusher delivers push notifications to devices
+type Pusher interface {
+	Push(ctx context.Context, message PushMessage) error
+}
+
+type PushSettings struct {
+	// Driver is http to post the messages to a push gateway, or log to only log them. Empty disables push
+	Driver     string
+	GatewayURL string
+	// GatewayToken is sent as a bearer token to the push gateway
+	GatewayToken string
+	Timeout      time.Duration
+}
+
+// New returns the pusher of the configured driver, nil when push is disabled
+func (s PushSettings) New(logger *slog.Logger) (Pusher, error) {
+	switch s.Driver {
+	case "":
+		return nil, nil
+	case PushDriverLog:
+		return &LogPusher{logger: logger}, nil
+	case PushDriverHTTP:
+		if s.GatewayURL == "" {
+			return nil, errors.New("http push requires a gateway url")
+		}
+		if s.Timeout <= 0 {
+			s.Timeout = 10 * time.Second
+		}
+		return &HTTPPusher{url: s.GatewayURL, token: s.GatewayToken, client: &http.Client{Timeout: s.Timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported push driver %q", s.Driver)
+	}
+}
+
+// HTTPPusher posts the messages as JSON to a push gateway that forwards them to FCM, APNs or another provider
+type HTTPPusher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (p *HTTPPusher) Push(ctx context.Context, message PushMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("push gateway responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// LogPusher writes the messages to the log instead of sending them, for development
+type LogPusher struct {
+	logger *slog.Logger
+}
+
+func (l *LogPusher) Push(ctx context.Context, message PushMessage) error {
+	l.logger.InfoContext(ctx, "push notification", "event", message.Event, "devices", len(message.Tokens), "title", message.Title, "body", message.Body)
+	return nil
+}
@@ -1,31 +1,40 @@
 package pagination
 
-type Metadata struct {
-	TotalCount int32 `json:"total_count"`
-	TotalPages int32 `json:"total_pages"`
-	PageSize   int32 `json:"page_size"`
-	Page       int32 `json:"page"`
-	HasNext    bool  `json:"has_next"`
-	HasPrev    bool  `json:"has_prev"`
-}
-
-func (p *Metadata) SetTotalCount(count int32) {
+// DefaultPageSize is used when a list request does not specify a page size
+const DefaultPageSize int32 = 20
+
+type PageMetadata struct {
+	TotalCount int32  `json:"total_count"`
+	TotalPages int32  `json:"total_pages"`
+	PageSize   int32  `json:"page_size"`
+	Page       int32  `json:"page"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+	OrderBy    string `json:"order_by"`
+	Order      string `json:"order"`
+	Search     string `json:"search"`
+}
+
+func (p *PageMetadata) SetTotalCount(count int32) {
 	p.TotalCount = max(count, 0)
-	p.TotalPages = max(p.TotalCount/p.PageSize+min(1, p.TotalCount%p.PageSize), 0)
+	p.TotalPages = 0
+	if p.PageSize > 0 {
+		p.TotalPages = p.TotalCount/p.PageSize + min(1, p.TotalCount%p.PageSize)
+	}
 	p.updateNavigation()
 }
 
-func (p *Metadata) SetPage(page int32) {
-	p.Page = clamp(page, 1, p.TotalPages)
+func (p *PageMetadata) SetPage(page int32) {
+	p.Page = clamp(page, 1, max(p.TotalPages, 1))
 	p.updateNavigation()
 }
 
-func (p *Metadata) updateNavigation() {
+func (p *PageMetadata) updateNavigation() {
 	p.HasPrev = p.Page > 1
 	p.HasNext = p.Page < p.TotalPages
 }
 
-func (p *Metadata) Offset() int32 {
+func (p *PageMetadata) Offset() int32 {
 	return max((p.Page-1)*p.PageSize, 0)
 }
 
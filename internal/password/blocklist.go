@@ -0,0 +1,41 @@
+package password
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// commonPasswords is a list of the most common passwords from public breach corpora, lowercased
+//
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// blocklist maps each common password to its rank, the most common first
+var blocklist = loadBlocklist(commonPasswords)
+
+func loadBlocklist(content string) map[string]int {
+	list := make(map[string]int)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, ok := list[line]; !ok {
+			list[line] = len(list) + 1
+		}
+	}
+	return list
+}
+
+// leetReplacer undoes the usual character substitutions, so that "p@ssw0rd" is found as "password"
+var leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t", "+", "t")
+
+// IsCommon reports whether the password, ignoring case and common substitutions, is on the blocklist
+func IsCommon(password string) bool {
+	lower := strings.ToLower(password)
+	if _, ok := blocklist[lower]; ok {
+		return true
+	}
+	_, ok := blocklist[leetReplacer.Replace(lower)]
+	return ok
+}
@@ -0,0 +1,190 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReasonBreached is reported when the password appears in a known breach corpus
+const ReasonBreached = "password_breached"
+
+const rangePrefixLength = 5
+
+var ErrInvalidRangePrefix = errors.New("range prefix must be 5 hexadecimal characters")
+
+// BreachStore looks up passwords in a breach corpus using the k-anonymity range model of Have I Been Pwned: only the
+// first 5 characters of the SHA-1 hash select a range, and the rest of the hash is matched within it
+type BreachStore interface {
+	// Range returns the hash suffixes with the number of times they were seen in breaches for the prefix
+	Range(ctx context.Context, prefix string) (io.ReadCloser, error)
+}
+
+// BreachSettings configures where breached password hashes are read from
+type BreachSettings struct {
+	// DataPath is a directory of range files as written by the HIBP downloader, named after the prefix, e.g. 21BD1.txt
+	DataPath string
+	// RangeURL is a range endpoint compatible with api.pwnedpasswords.com, used when DataPath is empty
+	RangeURL     string
+	RangeTimeout time.Duration
+	// ServeRange exposes DataPath as a range endpoint for other services
+	ServeRange bool
+}
+
+// Store returns the configured breach store, or nil when screening is disabled
+func (s BreachSettings) Store() (BreachStore, error) {
+	if s.DataPath != "" {
+		return NewDirectoryStore(s.DataPath)
+	}
+	if s.RangeURL != "" {
+		return NewRangeClient(s.RangeURL, s.RangeTimeout), nil
+	}
+	return nil, nil
+}
+
+// BreachCount returns how many times the password was seen in breaches, 0 when it was not
+func BreachCount(ctx context.Context, store BreachStore, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	body, err := store.Range(ctx, hash[:rangePrefixLength])
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return findSuffix(body, hash[rangePrefixLength:])
+}
+
+// findSuffix scans a range, lines of SUFFIX:COUNT sorted by suffix, for the suffix. Padding entries have a count of 0
+func findSuffix(r io.Reader, suffix string) (int, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+		candidate = strings.ToUpper(candidate)
+		if candidate > suffix {
+			break
+		}
+		if candidate != suffix {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid breach count %q: %w", count, err)
+		}
+		return n, nil
+	}
+	return 0, scanner.Err()
+}
+
+func validRangePrefix(prefix string) bool {
+	if len(prefix) != rangePrefixLength {
+		return false
+	}
+	_, err := hex.DecodeString(prefix + "0")
+	return err == nil
+}
+
+// DirectoryStore reads ranges from a local copy of the breach corpus, one file per prefix
+type DirectoryStore struct {
+	path string
+}
+
+func NewDirectoryStore(path string) (*DirectoryStore, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breach data path %s is not a directory", path)
+	}
+	return &DirectoryStore{path: path}, nil
+}
+
+// Range opens the file of the prefix. A missing file is an empty range, the corpus may be partial
+func (s *DirectoryStore) Range(_ context.Context, prefix string) (io.ReadCloser, error) {
+	if !validRangePrefix(prefix) {
+		return nil, ErrInvalidRangePrefix
+	}
+	prefix = strings.ToUpper(prefix)
+	for _, name := range []string{prefix + ".txt", prefix} {
+		file, err := os.Open(filepath.Join(s.path, name))
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// RangeClient queries a range endpoint such as a mirror of api.pwnedpasswords.com inside the cluster
+type RangeClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewRangeClient(baseURL string, timeout time.Duration) *RangeClient {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &RangeClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *RangeClient) Range(ctx context.Context, prefix string) (io.ReadCloser, error) {
+	if !validRangePrefix(prefix) {
+		return nil, ErrInvalidRangePrefix
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+strings.ToUpper(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("range endpoint returned %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// RangeHandler serves GET .../range/{prefix} from the store in the format of api.pwnedpasswords.com
+func RangeHandler(store BreachStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		prefix := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if !validRangePrefix(prefix) {
+			http.Error(w, ErrInvalidRangePrefix.Error(), http.StatusBadRequest)
+			return
+		}
+		body, err := store.Range(r.Context(), prefix)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.Copy(w, body)
+	})
+}
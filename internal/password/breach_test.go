@@ -0,0 +1,109 @@
+package password
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeRange writes the range file of the password's prefix with the password and some padding around it
+func writeRange(t *testing.T, dir, password string, count string) {
+	t.Helper()
+	hash := sha1Hex(password)
+	content := strings.Join([]string{
+		"0000000000000000000000000000000000:0",
+		hash[5:] + ":" + count,
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0",
+	}, "\r\n")
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectoryStore(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "hunter2", "17043")
+
+	store, err := NewDirectoryStore(dir)
+	if err != nil {
+		t.Fatalf("NewDirectoryStore() error = %v", err)
+	}
+	count, err := BreachCount(context.Background(), store, "hunter2")
+	if err != nil || count != 17043 {
+		t.Errorf("BreachCount() = %d, %v, want 17043", count, err)
+	}
+	count, err = BreachCount(context.Background(), store, "vK9#qL2!xZ7$")
+	if err != nil || count != 0 {
+		t.Errorf("BreachCount() for a missing range = %d, %v, want 0", count, err)
+	}
+	if _, err := store.Range(context.Background(), "../etc"); !errors.Is(err, ErrInvalidRangePrefix) {
+		t.Errorf("Range() error = %v, want ErrInvalidRangePrefix", err)
+	}
+	if _, err := NewDirectoryStore(filepath.Join(dir, "missing")); err == nil {
+		t.Error("NewDirectoryStore() of a missing directory did not fail")
+	}
+}
+
+func TestRangeHandlerAndClient(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "hunter2", "3")
+	store, err := NewDirectoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/v1/pwned/range/", RangeHandler(store))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewRangeClient(server.URL+"/v1/pwned/", time.Second)
+	count, err := BreachCount(context.Background(), client, "hunter2")
+	if err != nil || count != 3 {
+		t.Errorf("BreachCount() = %d, %v, want 3", count, err)
+	}
+
+	resp, err := http.Get(server.URL + "/v1/pwned/range/XYZ12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestPolicyCheckBreached(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "vK9#qL2!xZ7$", "2")
+	store, err := NewDirectoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var disabled *Policy
+	if violation, err := disabled.CheckBreached(context.Background(), "vK9#qL2!xZ7$"); violation != nil || err != nil {
+		t.Errorf("CheckBreached() without a store = %v, %v, want nil", violation, err)
+	}
+
+	policy := &Policy{Breaches: store}
+	violation, err := policy.CheckBreached(context.Background(), "vK9#qL2!xZ7$")
+	if err != nil || violation == nil || violation.Reason != ReasonBreached || violation.Params["count"] != "2" {
+		t.Errorf("CheckBreached() = %v, %v, want %s with count 2", violation, err, ReasonBreached)
+	}
+	if violation, err := policy.CheckBreached(context.Background(), "unbreached-Passphrase-9"); violation != nil || err != nil {
+		t.Errorf("CheckBreached() = %v, %v, want nil", violation, err)
+	}
+}
@@ -0,0 +1,161 @@
+package password
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"hash"
+)
+
+// Hashes imported from other identity providers are stored as they are, tagged with their algorithm in the PHC string
+// format. They verify like native hashes and are always upgraded to the configured algorithm on the next sign-in.
+const (
+	AlgorithmFirebaseScrypt = "firebase-scrypt"
+	AlgorithmPBKDF2SHA256   = "pbkdf2-sha256"
+	AlgorithmSaltedSHA1     = "salted-sha1"
+	AlgorithmSaltedSHA256   = "salted-sha256"
+	AlgorithmSaltedSHA512   = "salted-sha512"
+)
+
+const (
+	SaltPrefix = "prefix"
+	SaltSuffix = "suffix"
+)
+
+// FirebaseScryptParameters are the project wide parameters of the modified scrypt of Firebase Auth, shown in the
+// console under Authentication > Users > Password hash parameters
+type FirebaseScryptParameters struct {
+	SignerKey     []byte
+	SaltSeparator []byte
+	Rounds        int
+	MemCost       int
+}
+
+func (p FirebaseScryptParameters) Validate() error {
+	if len(p.SignerKey) == 0 || p.Rounds < 1 || p.Rounds > 8 || p.MemCost < 1 || p.MemCost > 14 {
+		return fmt.Errorf("invalid firebase scrypt parameters rounds=%d,mem_cost=%d", p.Rounds, p.MemCost)
+	}
+	return nil
+}
+
+// ErrFirebaseSignerKey is returned for a firebase-scrypt hash whose signer key is not the configured one
+var ErrFirebaseSignerKey = errors.New("the firebase signer key of the hash is not configured")
+
+// FirebaseSignerKeyID identifies a signer key in the hashes without revealing it
+func FirebaseSignerKeyID(signerKey []byte) string {
+	sum := sha256.Sum256(signerKey)
+	return phcEncoding.EncodeToString(sum[:8])
+}
+
+// FirebaseScryptHash encodes a Firebase user's password hash and salt with the project parameters. The signer key is
+// a secret of the project, so the hash only carries its ID and the key is read from the settings when verifying.
+func FirebaseScryptHash(params FirebaseScryptParameters, salt, key []byte) string {
+	return fmt.Sprintf("$%s$r=%d,m=%d,kid=%s,ss=%s$%s$%s", AlgorithmFirebaseScrypt, params.Rounds, params.MemCost,
+		FirebaseSignerKeyID(params.SignerKey), phcEncoding.EncodeToString(params.SaltSeparator),
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key))
+}
+
+// PBKDF2SHA256Hash encodes a PBKDF2-HMAC-SHA256 hash, the format Auth0 exports
+func PBKDF2SHA256Hash(iterations int, salt, key []byte) string {
+	return fmt.Sprintf("$%s$i=%d,l=%d$%s$%s", AlgorithmPBKDF2SHA256, iterations, len(key),
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key))
+}
+
+// SaltedSHAHash encodes a single round of SHA over the salt and the password, the salt placed before or after it
+func SaltedSHAHash(algorithm, position string, salt, sum []byte) (string, error) {
+	newHash, ok := saltedSHAHashes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownHashFormat, algorithm)
+	}
+	if position != SaltPrefix && position != SaltSuffix {
+		return "", fmt.Errorf("invalid salt position %q", position)
+	}
+	if len(sum) != newHash().Size() {
+		return "", ErrInvalidHash
+	}
+	return fmt.Sprintf("$%s$pos=%s$%s$%s", algorithm, position, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(sum)), nil
+}
+
+var saltedSHAHashes = map[string]func() hash.Hash{
+	AlgorithmSaltedSHA1:   sha1.New,
+	AlgorithmSaltedSHA256: sha256.New,
+	AlgorithmSaltedSHA512: sha512.New,
+}
+
+// verifyForeign checks the password against an imported hash
+func (s HashSettings) verifyForeign(password string, phc *phcString) (bool, error) {
+	switch phc.id {
+	case AlgorithmFirebaseScrypt:
+		rounds, err1 := phc.uint("r", 8)
+		memCost, err2 := phc.uint("m", 8)
+		if err1 != nil || err2 != nil || rounds < 1 || memCost < 1 || memCost > 30 {
+			return false, ErrInvalidHash
+		}
+		saltSeparator, err := phcEncoding.DecodeString(phc.params["ss"])
+		if err != nil || phc.params["kid"] == "" {
+			return false, ErrInvalidHash
+		}
+		signerKey, err := base64.StdEncoding.DecodeString(s.FirebaseSignerKey)
+		if err != nil || len(signerKey) == 0 || FirebaseSignerKeyID(signerKey) != phc.params["kid"] {
+			return false, ErrFirebaseSignerKey
+		}
+		key, err := firebaseScrypt(password, phc.salt, FirebaseScryptParameters{
+			SignerKey:     signerKey,
+			SaltSeparator: saltSeparator,
+			Rounds:        int(rounds),
+			MemCost:       int(memCost),
+		})
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare(key, phc.hash) == 1, nil
+	case AlgorithmPBKDF2SHA256:
+		iterations, err := phc.uint("i", 31)
+		if err != nil || iterations < 1 {
+			return false, ErrInvalidHash
+		}
+		key := pbkdf2.Key([]byte(password), phc.salt, int(iterations), len(phc.hash), sha256.New)
+		return subtle.ConstantTimeCompare(key, phc.hash) == 1, nil
+	case AlgorithmSaltedSHA1, AlgorithmSaltedSHA256, AlgorithmSaltedSHA512:
+		h := saltedSHAHashes[phc.id]()
+		switch phc.params["pos"] {
+		case SaltPrefix:
+			h.Write(phc.salt)
+			h.Write([]byte(password))
+		case SaltSuffix:
+			h.Write([]byte(password))
+			h.Write(phc.salt)
+		default:
+			return false, ErrInvalidHash
+		}
+		return hmac.Equal(h.Sum(nil), phc.hash), nil
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+// firebaseScrypt derives the key with scrypt and uses it to encrypt the signer key with AES-256-CTR, see
+// https://github.com/firebase/scrypt
+func firebaseScrypt(password string, salt []byte, params FirebaseScryptParameters) ([]byte, error) {
+	derived, err := scrypt.Key([]byte(password), append(append([]byte{}, salt...), params.SaltSeparator...),
+		1<<params.MemCost, params.Rounds, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, len(params.SignerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(result, params.SignerKey)
+	return result, nil
+}
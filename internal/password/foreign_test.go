@@ -0,0 +1,93 @@
+package password
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustDecode(t *testing.T, value string) []byte {
+	t.Helper()
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+// The test vector of https://github.com/firebase/scrypt
+func TestVerifyFirebaseScrypt(t *testing.T) {
+	signerKey := "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA=="
+	params := FirebaseScryptParameters{
+		SignerKey:     mustDecode(t, signerKey),
+		SaltSeparator: mustDecode(t, "Bw=="),
+		Rounds:        8,
+		MemCost:       14,
+	}
+	if err := params.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	encoded := FirebaseScryptHash(params, mustDecode(t, "42xEC+ixf3L2lw=="),
+		mustDecode(t, "lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ=="))
+
+	if strings.Contains(encoded, phcEncoding.EncodeToString(params.SignerKey)) {
+		t.Errorf("Expected the hash not to contain the signer key, got %s", encoded)
+	}
+
+	settings := fastSettings(AlgorithmArgon2id)
+	if _, _, err := settings.verify("user1password", encoded); !errors.Is(err, ErrFirebaseSignerKey) {
+		t.Errorf("verify() without the signer key = %v, want %v", err, ErrFirebaseSignerKey)
+	}
+	settings.FirebaseSignerKey = base64.StdEncoding.EncodeToString([]byte("another project"))
+	if _, _, err := settings.verify("user1password", encoded); !errors.Is(err, ErrFirebaseSignerKey) {
+		t.Errorf("verify() with another signer key = %v, want %v", err, ErrFirebaseSignerKey)
+	}
+	settings.FirebaseSignerKey = signerKey
+	ok, rehash, err := settings.verify("user1password", encoded)
+	if err != nil || !ok || !rehash {
+		t.Errorf("verify() = %v, %v, %v, want true, true, nil", ok, rehash, err)
+	}
+	if ok, _, _ := settings.verify("user2password", encoded); ok {
+		t.Error("verify() accepted a wrong password")
+	}
+}
+
+// The test vector of RFC 7914 section 11
+func TestVerifyPBKDF2SHA256(t *testing.T) {
+	key, _ := hex.DecodeString("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783")
+	encoded := PBKDF2SHA256Hash(1, []byte("salt"), key)
+
+	ok, rehash, err := fastSettings(AlgorithmArgon2id).verify("passwd", encoded)
+	if err != nil || !ok || !rehash {
+		t.Errorf("verify() = %v, %v, %v, want true, true, nil", ok, rehash, err)
+	}
+	if ok, _, _ := fastSettings(AlgorithmArgon2id).verify("password", encoded); ok {
+		t.Error("verify() accepted a wrong password")
+	}
+}
+
+func TestVerifySaltedSHA(t *testing.T) {
+	for _, position := range []string{SaltPrefix, SaltSuffix} {
+		input := "pepper" + "s3cret"
+		if position == SaltSuffix {
+			input = "s3cret" + "pepper"
+		}
+		sum := sha256.Sum256([]byte(input))
+		encoded, err := SaltedSHAHash(AlgorithmSaltedSHA256, position, []byte("pepper"), sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, rehash, err := fastSettings(AlgorithmArgon2id).verify("s3cret", encoded); err != nil || !ok || !rehash {
+			t.Errorf("verify() with the salt as %s = %v, %v, %v, want true, true, nil", position, ok, rehash, err)
+		}
+	}
+	if _, err := SaltedSHAHash(AlgorithmSaltedSHA1, SaltPrefix, nil, make([]byte, 32)); err == nil {
+		t.Error("SaltedSHAHash() accepted a digest of the wrong size")
+	}
+	if _, err := SaltedSHAHash("salted-md5", SaltPrefix, nil, make([]byte, 16)); err == nil {
+		t.Error("SaltedSHAHash() accepted an unsupported algorithm")
+	}
+}
@@ -0,0 +1,278 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+	"strconv"
+	"strings"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmScrypt   = "scrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+	// bcryptMaxBytes is where bcrypt silently stops reading the password
+	bcryptMaxBytes = 72
+)
+
+var (
+	ErrUnknownHashFormat = errors.New("unknown password hash format")
+	ErrInvalidHash       = errors.New("invalid password hash")
+	ErrPasswordTooLong   = errors.New("password is too long for the hash algorithm")
+)
+
+var phcEncoding = base64.RawStdEncoding
+
+// HashSettings selects the algorithm and its cost for new password hashes. Hashes are stored in the PHC string format,
+// e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>, so older hashes keep verifying after the settings change and are
+// upgraded the next time the user signs in. bcrypt hashes use their own $2a$ format.
+type HashSettings struct {
+	Algorithm string
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+	// ScryptLogN is the CPU/memory cost as a power of two
+	ScryptLogN uint8
+	ScryptR    int
+	ScryptP    int
+	// FirebaseSignerKey is the base64 signer key of the Firebase project the users were imported from. The imported
+	// hashes only reference it by its ID, so it must stay configured until they are upgraded.
+	FirebaseSignerKey string
+}
+
+// DefaultHashSettings follows the OWASP recommendation for argon2id
+func DefaultHashSettings() HashSettings {
+	return HashSettings{
+		Algorithm:         AlgorithmArgon2id,
+		Argon2Memory:      19 * 1024,
+		Argon2Iterations:  2,
+		Argon2Parallelism: 1,
+		BcryptCost:        12,
+		ScryptLogN:        15,
+		ScryptR:           8,
+		ScryptP:           1,
+	}
+}
+
+var hashOptions = DefaultHashSettings()
+
+// Setup validates the settings and uses them for every hash created afterwards
+func (s *HashSettings) Setup() error {
+	switch s.Algorithm {
+	case AlgorithmArgon2id:
+		if s.Argon2Memory < 8*uint32(s.Argon2Parallelism) || s.Argon2Iterations < 1 || s.Argon2Parallelism < 1 {
+			return fmt.Errorf("invalid argon2id parameters m=%d,t=%d,p=%d", s.Argon2Memory, s.Argon2Iterations, s.Argon2Parallelism)
+		}
+	case AlgorithmBcrypt:
+		if s.BcryptCost < bcrypt.MinCost || s.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("invalid bcrypt cost %d", s.BcryptCost)
+		}
+	case AlgorithmScrypt:
+		if s.ScryptLogN < 1 || s.ScryptLogN > 30 || s.ScryptR < 1 || s.ScryptP < 1 {
+			return fmt.Errorf("invalid scrypt parameters ln=%d,r=%d,p=%d", s.ScryptLogN, s.ScryptR, s.ScryptP)
+		}
+	default:
+		return fmt.Errorf("unsupported password hash algorithm %q", s.Algorithm)
+	}
+	if _, err := base64.StdEncoding.DecodeString(s.FirebaseSignerKey); err != nil {
+		return fmt.Errorf("invalid firebase signer key: %w", err)
+	}
+	hashOptions = *s
+	return nil
+}
+
+// Hash hashes the password with the configured algorithm
+func Hash(password string) (string, error) {
+	return hashOptions.hash(password)
+}
+
+// Verify reports whether the password matches the encoded hash, and whether the hash should be replaced because it was
+// made with another algorithm or weaker parameters than configured
+func Verify(password, encoded string) (ok bool, rehash bool, err error) {
+	return hashOptions.verify(password, encoded)
+}
+
+func (s HashSettings) hash(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if s.Algorithm != AlgorithmBcrypt {
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+	}
+	switch s.Algorithm {
+	case AlgorithmArgon2id:
+		key := argon2.IDKey([]byte(password), salt, s.Argon2Iterations, s.Argon2Memory, s.Argon2Parallelism, keyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, s.Argon2Memory, s.Argon2Iterations,
+			s.Argon2Parallelism, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+	case AlgorithmBcrypt:
+		if len(password) > bcryptMaxBytes {
+			return "", ErrPasswordTooLong
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), s.BcryptCost)
+		return string(hash), err
+	case AlgorithmScrypt:
+		key, err := scrypt.Key([]byte(password), salt, 1<<s.ScryptLogN, s.ScryptR, s.ScryptP, keyLength)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", s.ScryptLogN, s.ScryptR, s.ScryptP,
+			phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unsupported password hash algorithm %q", s.Algorithm)
+	}
+}
+
+func (s HashSettings) verify(password, encoded string) (bool, bool, error) {
+	if isBcrypt(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return true, s.Algorithm != AlgorithmBcrypt || cost < s.BcryptCost, err
+	}
+
+	phc, err := parsePHC(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	switch phc.id {
+	case AlgorithmArgon2id:
+		if phc.version != "" && phc.version != "v="+strconv.Itoa(argon2.Version) {
+			return false, false, ErrInvalidHash
+		}
+		memory, err1 := phc.uint("m", 32)
+		iterations, err2 := phc.uint("t", 32)
+		parallelism, err3 := phc.uint("p", 8)
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return false, false, err
+		}
+		if iterations < 1 || parallelism < 1 {
+			return false, false, ErrInvalidHash
+		}
+		key := argon2.IDKey([]byte(password), phc.salt, uint32(iterations), uint32(memory), uint8(parallelism), uint32(len(phc.hash)))
+		outdated := s.Algorithm != AlgorithmArgon2id || uint32(memory) < s.Argon2Memory ||
+			uint32(iterations) < s.Argon2Iterations || uint8(parallelism) < s.Argon2Parallelism
+		return subtle.ConstantTimeCompare(key, phc.hash) == 1, outdated, nil
+	case AlgorithmScrypt:
+		logN, err1 := phc.uint("ln", 8)
+		r, err2 := phc.uint("r", 31)
+		p, err3 := phc.uint("p", 31)
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return false, false, err
+		}
+		if logN < 1 || logN > 30 {
+			return false, false, ErrInvalidHash
+		}
+		key, err := scrypt.Key([]byte(password), phc.salt, 1<<logN, int(r), int(p), len(phc.hash))
+		if err != nil {
+			return false, false, err
+		}
+		outdated := s.Algorithm != AlgorithmScrypt || uint8(logN) < s.ScryptLogN || int(r) < s.ScryptR || int(p) < s.ScryptP
+		return subtle.ConstantTimeCompare(key, phc.hash) == 1, outdated, nil
+	default:
+		ok, err := s.verifyForeign(password, phc)
+		return ok, err == nil, err
+	}
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// phcString is a parsed $<id>[$v=<version>]$<param>=<value>(,<param>=<value>)*$<salt>$<hash>
+type phcString struct {
+	id      string
+	version string
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+func parsePHC(encoded string) (*phcString, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return nil, ErrUnknownHashFormat
+	}
+	phc := &phcString{id: fields[1], params: map[string]string{}}
+	fields = fields[2:]
+	if strings.HasPrefix(fields[0], "v=") {
+		phc.version = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return nil, ErrInvalidHash
+	}
+	for _, param := range strings.Split(fields[0], ",") {
+		key, value, found := strings.Cut(param, "=")
+		if !found {
+			return nil, ErrInvalidHash
+		}
+		phc.params[key] = value
+	}
+	var err error
+	if phc.salt, err = phcEncoding.DecodeString(fields[1]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if phc.hash, err = phcEncoding.DecodeString(fields[2]); err != nil || len(phc.hash) == 0 {
+		return nil, ErrInvalidHash
+	}
+	return phc, nil
+}
+
+func (p *phcString) uint(key string, bitSize int) (uint64, error) {
+	value, err := strconv.ParseUint(p.params[key], 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: parameter %s", ErrInvalidHash, key)
+	}
+	return value, nil
+}
+
+// requiredParams lists the numeric parameters of each PHC id that Verify needs
+var requiredParams = map[string][]string{
+	AlgorithmArgon2id:       {"m", "t", "p"},
+	AlgorithmScrypt:         {"ln", "r", "p"},
+	AlgorithmFirebaseScrypt: {"r", "m"},
+	AlgorithmPBKDF2SHA256:   {"i"},
+	AlgorithmSaltedSHA1:     {},
+	AlgorithmSaltedSHA256:   {},
+	AlgorithmSaltedSHA512:   {},
+}
+
+// ValidateHash checks that the encoded hash is in a format Verify understands, without the cost of verifying it
+func ValidateHash(encoded string) error {
+	if isBcrypt(encoded) {
+		_, err := bcrypt.Cost([]byte(encoded))
+		return err
+	}
+	phc, err := parsePHC(encoded)
+	if err != nil {
+		return err
+	}
+	params, ok := requiredParams[phc.id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHashFormat, phc.id)
+	}
+	for _, param := range params {
+		if _, err := phc.uint(param, 32); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+// fastSettings keeps the tests quick, the parameters are far below what production should use
+func fastSettings(algorithm string) HashSettings {
+	return HashSettings{
+		Algorithm:         algorithm,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        bcrypt.MinCost,
+		ScryptLogN:        4,
+		ScryptR:           8,
+		ScryptP:           1,
+	}
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt, AlgorithmScrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			settings := fastSettings(algorithm)
+			hash, err := settings.hash("s3cret-Passphrase")
+			if err != nil {
+				t.Fatalf("hash() error = %v", err)
+			}
+			if algorithm != AlgorithmBcrypt && !strings.HasPrefix(hash, "$"+algorithm+"$") {
+				t.Errorf("hash() = %q, want the PHC id %s", hash, algorithm)
+			}
+			ok, rehash, err := settings.verify("s3cret-Passphrase", hash)
+			if err != nil || !ok || rehash {
+				t.Errorf("verify() = %v, %v, %v, want true, false, nil", ok, rehash, err)
+			}
+			ok, _, err = settings.verify("wrong-Passphrase", hash)
+			if err != nil || ok {
+				t.Errorf("verify() of a wrong password = %v, %v, want false, nil", ok, err)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsOutdatedHashes(t *testing.T) {
+	legacy := fastSettings(AlgorithmBcrypt)
+	hash, err := legacy.hash("s3cret-Passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err := fastSettings(AlgorithmArgon2id).verify("s3cret-Passphrase", hash)
+	if err != nil || !ok || !rehash {
+		t.Errorf("verify() of a bcrypt hash with argon2id configured = %v, %v, %v, want true, true, nil", ok, rehash, err)
+	}
+
+	weak := fastSettings(AlgorithmArgon2id)
+	hash, err = weak.hash("s3cret-Passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stronger := weak
+	stronger.Argon2Iterations = 2
+	if _, rehash, _ := stronger.verify("s3cret-Passphrase", hash); !rehash {
+		t.Error("verify() did not ask to rehash after the iterations were raised")
+	}
+	if _, rehash, _ := weak.verify("wrong-Passphrase", hash); rehash {
+		t.Error("verify() asked to rehash for a wrong password")
+	}
+}
+
+func TestBcryptRejectsLongPasswords(t *testing.T) {
+	settings := fastSettings(AlgorithmBcrypt)
+	if _, err := settings.hash(strings.Repeat("a", 73)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("hash() error = %v, want ErrPasswordTooLong", err)
+	}
+	long := strings.Repeat("a", 100)
+	hash, err := fastSettings(AlgorithmArgon2id).hash(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := settings.verify(long[:72], hash); ok {
+		t.Error("argon2id hash matched a truncated password")
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	settings := fastSettings(AlgorithmArgon2id)
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$md5$abc$def",
+		"$argon2id$v=19$m=64,t=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$!!$aGFzaA",
+		"$scrypt$ln=99,r=8,p=1$c2FsdA$aGFzaA",
+	} {
+		if ok, _, err := settings.verify("password", encoded); ok || err == nil {
+			t.Errorf("verify(%q) = %v, %v, want an error", encoded, ok, err)
+		}
+	}
+}
+
+func TestSetupValidatesSettings(t *testing.T) {
+	previous := hashOptions
+	t.Cleanup(func() { hashOptions = previous })
+
+	invalid := fastSettings("md5")
+	if err := invalid.Setup(); err == nil {
+		t.Error("Setup() accepted an unsupported algorithm")
+	}
+	invalid = fastSettings(AlgorithmBcrypt)
+	invalid.BcryptCost = 40
+	if err := invalid.Setup(); err == nil {
+		t.Error("Setup() accepted an invalid bcrypt cost")
+	}
+	valid := fastSettings(AlgorithmScrypt)
+	if err := valid.Setup(); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if hash, err := Hash("s3cret-Passphrase"); err != nil || !strings.HasPrefix(hash, "$scrypt$") {
+		t.Errorf("Hash() = %q, %v, want a scrypt hash", hash, err)
+	}
+}
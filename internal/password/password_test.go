@@ -0,0 +1,149 @@
+package password
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func reasons(violations []Violation) []string {
+	var list []string
+	for _, violation := range violations {
+		list = append(list, violation.Reason)
+	}
+	return list
+}
+
+func hasReason(violations []Violation, reason string) bool {
+	for _, violation := range violations {
+		if violation.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIsCommon(t *testing.T) {
+	for _, password := range []string{"password", "PASSWORD", "p@ssw0rd", "qwerty", "123456"} {
+		if !IsCommon(password) {
+			t.Errorf("IsCommon(%q) = false, want true", password)
+		}
+	}
+	if IsCommon("correct-horse-battery-staple") {
+		t.Error("IsCommon() = true for an uncommon password")
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		password string
+		min      int
+		max      int
+	}{
+		{password: "password", min: 0, max: 0},
+		{password: "P@ssw0rd", min: 0, max: 1},
+		{password: "qwertyuiop", min: 0, max: 1},
+		{password: "abcdefgh", min: 0, max: 1},
+		{password: "aaaaaaaaaaaa", min: 0, max: 1},
+		{password: "1990", min: 0, max: 0},
+		{password: "Tr0ub4dour&3x", min: 3, max: 4},
+		{password: "correct horse battery staple", min: 4, max: 4},
+		{password: "vK9#qL2!xZ7$", min: 4, max: 4},
+	}
+	for _, tt := range tests {
+		if score := Score(tt.password); score < tt.min || score > tt.max {
+			t.Errorf("Score(%q) = %d, want between %d and %d", tt.password, score, tt.min, tt.max)
+		}
+	}
+}
+
+func TestScoreUserInputs(t *testing.T) {
+	password := "Montgomery1987"
+	if without, with := Score(password), Score(password, "Montgomery Burns"); with >= without {
+		t.Errorf("Score() with the user's name = %d, want less than %d", with, without)
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		MinLength:        8,
+		MaxLength:        64,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		CheckBlocklist:   true,
+		MinScore:         2,
+	}
+
+	if violations := policy.Check("vK9#qL2!xZ7$", Context{Name: "Jane Doe", Email: "jane@example.com"}); violations != nil {
+		t.Errorf("Check() = %v, want no violations", reasons(violations))
+	}
+
+	violations := policy.Check("abc", Context{})
+	for _, reason := range []string{ReasonTooShort, ReasonMissingUpper, ReasonMissingDigit, ReasonMissingSymbol, ReasonTooWeak} {
+		if !hasReason(violations, reason) {
+			t.Errorf("Check() = %v, want %s", reasons(violations), reason)
+		}
+	}
+	if hasReason(violations, ReasonMissingLower) {
+		t.Errorf("Check() = %v, did not want %s", reasons(violations), ReasonMissingLower)
+	}
+	if violations[0].Params["min_length"] != "8" {
+		t.Errorf("min_length = %q, want 8", violations[0].Params["min_length"])
+	}
+
+	if violations := policy.Check(strings.Repeat("aB3$", 17), Context{}); !hasReason(violations, ReasonTooLong) {
+		t.Errorf("Check() = %v, want %s", reasons(violations), ReasonTooLong)
+	}
+	if violations := policy.Check("P@ssw0rd", Context{}); !hasReason(violations, ReasonCommon) {
+		t.Errorf("Check() = %v, want %s", reasons(violations), ReasonCommon)
+	}
+}
+
+func TestPolicyCheckLengthInRunes(t *testing.T) {
+	policy := &Policy{MinLength: 8, MaxLength: 8}
+	if violations := policy.Check("ğüşöçıİé", Context{}); violations != nil {
+		t.Errorf("Check() = %v, want no violations", reasons(violations))
+	}
+}
+
+func TestPolicyCheckContext(t *testing.T) {
+	policy := &Policy{MinLength: 8}
+	ctx := Context{Name: "Jane Montgomery", Email: "j.smithers@example.com"}
+
+	if violations := policy.Check("xMontgomery!7", ctx); !hasReason(violations, ReasonContainsName) {
+		t.Errorf("Check() = %v, want %s", reasons(violations), ReasonContainsName)
+	}
+	if violations := policy.Check("x$mith3rs-42", ctx); !hasReason(violations, ReasonContainsEmail) {
+		t.Errorf("Check() = %v, want %s", reasons(violations), ReasonContainsEmail)
+	}
+	if violations := policy.Check("vK9#qL2!xZ7$", ctx); violations != nil {
+		t.Errorf("Check() = %v, want no violations", reasons(violations))
+	}
+}
+
+func TestNilPolicyUsesDefaults(t *testing.T) {
+	var policy *Policy
+	if violations := policy.Check("password", Context{}); !hasReason(violations, ReasonCommon) {
+		t.Errorf("Check() = %v, want %s", reasons(violations), ReasonCommon)
+	}
+}
+
+func TestPolicyCheckMinAge(t *testing.T) {
+	now := time.Now()
+	policy := &Policy{MinAge: 24 * time.Hour}
+	changedAt := now.Add(-time.Hour)
+
+	violation := policy.CheckMinAge(&changedAt, now)
+	if violation == nil || violation.Reason != ReasonMinAge || violation.Params["retry_after"] != "82800" {
+		t.Errorf("CheckMinAge() = %+v, want %s with retry_after 82800", violation, ReasonMinAge)
+	}
+	changedAt = now.Add(-25 * time.Hour)
+	if violation := policy.CheckMinAge(&changedAt, now); violation != nil {
+		t.Errorf("CheckMinAge() = %+v, want nil once the minimum age passed", violation)
+	}
+	if violation := policy.CheckMinAge(nil, now); violation != nil {
+		t.Errorf("CheckMinAge() = %+v, want nil for a password never changed", violation)
+	}
+}
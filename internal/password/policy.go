@@ -0,0 +1,201 @@
+package password
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ReasonTooShort      = "password_too_short"
+	ReasonTooLong       = "password_too_long"
+	ReasonMissingUpper  = "password_missing_uppercase"
+	ReasonMissingLower  = "password_missing_lowercase"
+	ReasonMissingDigit  = "password_missing_digit"
+	ReasonMissingSymbol = "password_missing_symbol"
+	ReasonCommon        = "password_common"
+	ReasonContainsName  = "password_contains_name"
+	ReasonContainsEmail = "password_contains_email"
+	ReasonTooWeak       = "password_too_weak"
+	ReasonReused        = "password_reused"
+	ReasonMinAge        = "password_min_age"
+)
+
+const (
+	DefaultMinLength = 8
+	DefaultMaxLength = 64
+	DefaultMinScore  = 2
+
+	maxScore             = 4
+	minContextWordLength = 3
+)
+
+// Policy is the set of rules a new password has to follow
+type Policy struct {
+	MinLength        int
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	CheckBlocklist   bool
+	// MinScore is the minimum strength from 0 to 4, see Score. 0 disables the check
+	MinScore int
+	// Breaches screens passwords against a breach corpus when set
+	Breaches BreachStore
+	// HistoryDepth is how many of the last passwords, the current one included, cannot be used again. 0 disables it
+	HistoryDepth int
+	// MinAge is how long a password must be kept before the user can change it again. Resets are not limited
+	MinAge time.Duration
+}
+
+// Context is what is known about the user the password is for
+type Context struct {
+	Name  string
+	Email string
+}
+
+// Violation is a rule the password breaks, with the parameters a client needs to render a message
+type Violation struct {
+	Reason string
+	Params map[string]string
+}
+
+// DefaultPolicy returns the policy used when nothing is configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinLength:      DefaultMinLength,
+		MaxLength:      DefaultMaxLength,
+		CheckBlocklist: true,
+		MinScore:       DefaultMinScore,
+	}
+}
+
+// Check returns every rule the password breaks, or nil when it is acceptable
+func (p *Policy) Check(password string, ctx Context) []Violation {
+	if p == nil {
+		p = DefaultPolicy()
+	}
+	var violations []Violation
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, Violation{Reason: ReasonTooShort, Params: map[string]string{"min_length": strconv.Itoa(p.MinLength)}})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{Reason: ReasonTooLong, Params: map[string]string{"max_length": strconv.Itoa(p.MaxLength)}})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, Violation{Reason: ReasonMissingUpper})
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, Violation{Reason: ReasonMissingLower})
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, Violation{Reason: ReasonMissingDigit})
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, Violation{Reason: ReasonMissingSymbol})
+	}
+
+	if p.CheckBlocklist && IsCommon(password) {
+		violations = append(violations, Violation{Reason: ReasonCommon})
+	}
+	if containsAny(password, nameWords(ctx.Name)) {
+		violations = append(violations, Violation{Reason: ReasonContainsName})
+	}
+	if containsAny(password, emailWords(ctx.Email)) {
+		violations = append(violations, Violation{Reason: ReasonContainsEmail})
+	}
+
+	if p.MinScore > 0 {
+		minScore := min(p.MinScore, maxScore)
+		if score := Score(password, ctx.Name, ctx.Email); score < minScore {
+			violations = append(violations, Violation{Reason: ReasonTooWeak, Params: map[string]string{
+				"min_score": strconv.Itoa(minScore),
+				"score":     strconv.Itoa(score),
+			}})
+		}
+	}
+	return violations
+}
+
+// CheckBreached returns a violation when the password appears in the breach corpus. Without a store it never does
+func (p *Policy) CheckBreached(ctx context.Context, password string) (*Violation, error) {
+	if p == nil || p.Breaches == nil {
+		return nil, nil
+	}
+	count, err := BreachCount(ctx, p.Breaches, password)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return &Violation{Reason: ReasonBreached, Params: map[string]string{"count": strconv.Itoa(count)}}, nil
+}
+
+// CheckMinAge returns a violation when the password was changed less than MinAge ago
+func (p *Policy) CheckMinAge(changedAt *time.Time, now time.Time) *Violation {
+	if p == nil || p.MinAge <= 0 || changedAt == nil {
+		return nil
+	}
+	if wait := changedAt.Add(p.MinAge).Sub(now); wait > 0 {
+		return &Violation{Reason: ReasonMinAge, Params: map[string]string{
+			"min_age":     p.MinAge.String(),
+			"retry_after": strconv.Itoa(int(math.Ceil(wait.Seconds()))),
+		}}
+	}
+	return nil
+}
+
+// nameWords returns the parts of the name that are long enough to be meaningful in a password
+func nameWords(name string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) >= minContextWordLength {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// emailWords returns the local part of the email and its words, e.g. "john.doe" and "john", "doe"
+func emailWords(email string) []string {
+	local, _, found := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !found || local == "" {
+		return nil
+	}
+	words := nameWords(local)
+	if utf8.RuneCountInString(local) >= minContextWordLength && (len(words) != 1 || words[0] != local) {
+		words = append(words, local)
+	}
+	return words
+}
+
+func containsAny(password string, words []string) bool {
+	lower := strings.ToLower(password)
+	unleet := leetReplacer.Replace(lower)
+	for _, word := range words {
+		if strings.Contains(lower, word) || strings.Contains(unleet, word) {
+			return true
+		}
+	}
+	return false
+}
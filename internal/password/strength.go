@@ -0,0 +1,233 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Score rates the password from 0 (too guessable) to 4 (very unguessable) like zxcvbn does. The password is split
+// into the cheapest sequence of patterns an attacker would try: common passwords, the user's own data, keyboard walks,
+// alphabetical or numerical sequences, repeated characters and years. The remaining characters are brute forced. The
+// score follows from the estimated number of guesses.
+func Score(password string, userInputs ...string) int {
+	guesses := EstimateGuesses(password, userInputs...)
+	switch {
+	case guesses < 1e3:
+		return 0
+	case guesses < 1e6:
+		return 1
+	case guesses < 1e8:
+		return 2
+	case guesses < 1e10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// EstimateGuesses returns the estimated number of guesses needed to find the password
+func EstimateGuesses(password string, userInputs ...string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 1
+	}
+	dictionary := userDictionary(userInputs)
+	cardinality := float64(bruteForceCardinality(runes))
+
+	// best[i] is the fewest guesses for the first i characters
+	best := make([]float64, len(runes)+1)
+	best[0] = 1
+	for end := 1; end <= len(runes); end++ {
+		best[end] = best[end-1] * cardinality
+		for start := 0; start < end; start++ {
+			if guesses := patternGuesses(runes[start:end], dictionary); guesses > 0 {
+				best[end] = math.Min(best[end], best[start]*guesses)
+			}
+		}
+	}
+	return best[len(runes)]
+}
+
+// patternGuesses returns the guesses needed for the part when it forms a known pattern, or 0
+func patternGuesses(part []rune, dictionary map[string]int) float64 {
+	if len(part) < 3 {
+		return 0
+	}
+	var guesses float64
+	candidate := func(value float64) {
+		if value > 0 && (guesses == 0 || value < guesses) {
+			guesses = value
+		}
+	}
+	candidate(dictionaryGuesses(part, dictionary))
+	candidate(repeatGuesses(part))
+	candidate(sequenceGuesses(part))
+	candidate(keyboardGuesses(part))
+	candidate(yearGuesses(part))
+	return guesses
+}
+
+func dictionaryGuesses(part []rune, dictionary map[string]int) float64 {
+	if len(part) < 4 {
+		return 0
+	}
+	word := string(part)
+	lower := strings.ToLower(word)
+	variations := 1.0
+	if lower != word {
+		variations *= 2
+	}
+	rank, ok := dictionary[lower]
+	if !ok {
+		rank, ok = blocklist[lower]
+	}
+	if !ok {
+		unleet := leetReplacer.Replace(lower)
+		if unleet == lower {
+			return 0
+		}
+		variations *= 2
+		if rank, ok = dictionary[unleet]; !ok {
+			if rank, ok = blocklist[unleet]; !ok {
+				return 0
+			}
+		}
+	}
+	return float64(rank) * variations
+}
+
+func repeatGuesses(part []rune) float64 {
+	for _, r := range part[1:] {
+		if r != part[0] {
+			return 0
+		}
+	}
+	return float64(bruteForceCardinality(part[:1])) * float64(len(part))
+}
+
+// sequenceGuesses matches runs such as "abcd", "7654" or "xyz"
+func sequenceGuesses(part []rune) float64 {
+	delta := part[1] - part[0]
+	if delta != 1 && delta != -1 {
+		return 0
+	}
+	for i := 2; i < len(part); i++ {
+		if part[i]-part[i-1] != delta {
+			return 0
+		}
+	}
+	var base float64
+	switch first := unicode.ToLower(part[0]); {
+	case first == 'a' || first == 'z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if delta < 0 {
+		base *= 2
+	}
+	return base * float64(len(part))
+}
+
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm", "qazwsxedcrfvtgbyhnujmikolp"}
+
+// keyboardGuesses matches walks along a row of the keyboard such as "qwerty" or "lkjh"
+func keyboardGuesses(part []rune) float64 {
+	if len(part) < 4 {
+		return 0
+	}
+	walk := strings.ToLower(string(part))
+	reversed := []rune(walk)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	for _, row := range keyboardRows {
+		if strings.Contains(row, walk) {
+			return 40 * float64(len(part))
+		}
+		if strings.Contains(row, string(reversed)) {
+			return 80 * float64(len(part))
+		}
+	}
+	return 0
+}
+
+func yearGuesses(part []rune) float64 {
+	if len(part) != 4 {
+		return 0
+	}
+	year := 0
+	for _, r := range part {
+		if !unicode.IsDigit(r) {
+			return 0
+		}
+		year = year*10 + int(r-'0')
+	}
+	if year >= 1900 && year <= 2099 {
+		return 200
+	}
+	return 0
+}
+
+func bruteForceCardinality(runes []rune) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range runes {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	cardinality := 0
+	if lower {
+		cardinality += 26
+	}
+	if upper {
+		cardinality += 26
+	}
+	if digit {
+		cardinality += 10
+	}
+	if symbol {
+		cardinality += 33
+	}
+	return cardinality
+}
+
+// userDictionary ranks the user's own data, such as name parts and the email, as the first guesses
+func userDictionary(userInputs []string) map[string]int {
+	dictionary := make(map[string]int)
+	for _, input := range userInputs {
+		for _, word := range inputWords(input) {
+			if _, ok := dictionary[word]; !ok {
+				dictionary[word] = len(dictionary) + 1
+			}
+		}
+	}
+	return dictionary
+}
+
+// inputWords splits names and emails into the lowercased words a password could be built from
+func inputWords(input string) []string {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "" {
+		return nil
+	}
+	words := []string{input}
+	for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 3 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
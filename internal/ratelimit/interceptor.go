@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/cristalhq/jwt/v4"
+	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/clientip"
+	"github.com/usercoredev/usercore/internal/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryAfterHeader carries the number of seconds to wait after a rejected call. The gateway returns it as the
+// Retry-After header of the 429 response.
+const RetryAfterHeader = "retry-after"
+
+// Interceptor rejects the calls that exceed a policy with ResourceExhausted. Calls are counted in redis when the cache
+// is enabled, and in memory when it is disabled or redis fails. Only the policies keyed by one of keys are checked, all
+// of them without keys, so the IP and client policies can reject calls before they are authenticated and audited. It
+// must run after the client interceptor, and after the authentication interceptor for the ByUser policies.
+func Interceptor(settings Settings, primary, fallback Limiter, log *slog.Logger, keys ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !settings.Enabled {
+			return handler(ctx, req)
+		}
+		var clientID string
+		if ctxClient, ok := ctx.Value(client.Key).(*client.Item); ok && ctxClient != nil {
+			clientID = ctxClient.ID
+		}
+		now := time.Now()
+		for _, policy := range policiesFor(settings.Policies, info.FullMethod, clientID) {
+			if len(keys) > 0 && !slices.Contains(keys, policy.By) {
+				continue
+			}
+			subject := subjectOf(ctx, policy.By, clientID)
+			if subject == "" {
+				continue
+			}
+			key := policy.Method + "|" + policy.By + ":" + subject
+			allowed, wait, err := allow(ctx, primary, fallback, log, key, policy, now)
+			if err != nil {
+				log.ErrorContext(ctx, "rate limit check failed", "error", err)
+				continue
+			}
+			if !allowed {
+				seconds := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+				_ = grpc.SetHeader(ctx, metadata.Pairs(RetryAfterHeader, seconds))
+				log.WarnContext(ctx, "rate limit exceeded", "method", info.FullMethod, "by", policy.By, "limit", policy.Limit, "retry_after", seconds)
+				return nil, status.Errorf(codes.ResourceExhausted, responses.RateLimited)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+func allow(ctx context.Context, primary, fallback Limiter, log *slog.Logger, key string, policy Policy, now time.Time) (bool, time.Duration, error) {
+	if primary != nil {
+		allowed, wait, err := primary.Allow(ctx, key, policy.Limit, time.Duration(policy.Window), now)
+		if err == nil {
+			return allowed, wait, nil
+		}
+		log.WarnContext(ctx, "rate limit store failed, using in-memory fallback", "error", err)
+	}
+	return fallback.Allow(ctx, key, policy.Limit, time.Duration(policy.Window), now)
+}
+
+func subjectOf(ctx context.Context, by, clientID string) string {
+	switch by {
+	case ByIP:
+		return clientip.FromContext(ctx)
+	case ByClient:
+		return clientID
+	case ByUser:
+		if claims, ok := ctx.Value(token.Claims).(jwt.RegisteredClaims); ok {
+			return claims.ID
+		}
+	}
+	return ""
+}
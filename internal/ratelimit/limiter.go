@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter counts calls in a sliding window. Allow records the call when it is allowed and otherwise returns how long
+// the caller has to wait.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error)
+}
+
+// slidingWindowScript keeps the timestamps of the calls in a sorted set. It drops the calls that left the window, and
+// either records the call or returns the time until the oldest call leaves the window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if redis.call('ZCARD', key) < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+return tonumber(oldest[2]) + window - now
+`)
+
+type redisLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisLimiter(client *redis.Client) Limiter {
+	return &redisLimiter{client: client}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error) {
+	member := strconv.FormatInt(now.UnixNano(), 10)
+	wait, err := slidingWindowScript.Run(ctx, l.client, []string{"ratelimit:" + key},
+		now.UnixMilli(), window.Milliseconds(), limit, member).Int64()
+	if err != nil {
+		return false, 0, err
+	}
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+// memoryLimiter is used when the cache is disabled or redis fails. Its counts are per process.
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// sweepAt is when the buckets without calls in their window are dropped next
+	sweepAt time.Time
+}
+
+type bucket struct {
+	calls  []time.Time
+	window time.Duration
+}
+
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{buckets: make(map[string]*bucket)}
+}
+
+func (l *memoryLimiter) Allow(_ context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.After(l.sweepAt) {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{}
+		l.buckets[key] = b
+	}
+	b.window = window
+	b.calls = inWindow(b.calls, now, window)
+	if len(b.calls) >= limit {
+		return false, b.calls[0].Add(window).Sub(now), nil
+	}
+	b.calls = append(b.calls, now)
+	return true, 0, nil
+}
+
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if len(b.calls) == 0 || now.Sub(b.calls[len(b.calls)-1]) >= b.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweepAt = now.Add(time.Minute)
+}
+
+func inWindow(calls []time.Time, now time.Time, window time.Duration) []time.Time {
+	for i, call := range calls {
+		if now.Sub(call) < window {
+			return calls[i:]
+		}
+	}
+	return calls[:0]
+}
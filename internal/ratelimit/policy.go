@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	ByIP     = "ip"
+	ByClient = "client"
+	ByUser   = "user"
+)
+
+// Policy limits the calls of one caller to Limit per Window. Method is a full gRPC method name, a service followed by
+// "/*" or "*" for every method. A policy with a ClientID only applies to that client and replaces the policies without
+// a client for the same method and key.
+type Policy struct {
+	Method   string   `json:"method"`
+	By       string   `json:"by"`
+	ClientID string   `json:"client_id,omitempty"`
+	Limit    int      `json:"limit"`
+	Window   Duration `json:"window"`
+}
+
+// Duration reads durations such as "1m" from JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+type Settings struct {
+	Enabled        bool
+	PolicyFilePath string
+	Policies       []Policy
+}
+
+// DefaultPolicies protect the unauthenticated endpoints when no policy file is configured
+var DefaultPolicies = []Policy{
+	{Method: "/v1.AuthenticationService/*", By: ByIP, Limit: 30, Window: Duration(time.Minute)},
+	{Method: "/usercore.v1.EmailChangeService/*", By: ByIP, Limit: 30, Window: Duration(time.Minute)},
+	{Method: "/usercore.v1.SignInConfirmationService/*", By: ByIP, Limit: 30, Window: Duration(time.Minute)},
+	{Method: "*", By: ByClient, Limit: 6000, Window: Duration(time.Minute)},
+	{Method: "*", By: ByUser, Limit: 600, Window: Duration(time.Minute)},
+}
+
+// LoadPolicies reads the policies from the policy file, or uses DefaultPolicies when there is none
+func (s *Settings) LoadPolicies() error {
+	if s.PolicyFilePath == "" {
+		s.Policies = DefaultPolicies
+		return nil
+	}
+	file, err := os.Open(s.PolicyFilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	var policies []Policy
+	if err = json.Unmarshal(content, &policies); err != nil {
+		return err
+	}
+	for i, policy := range policies {
+		if err = policy.validate(); err != nil {
+			return fmt.Errorf("rate limit policy %d: %w", i, err)
+		}
+	}
+	s.Policies = policies
+	return nil
+}
+
+func (p *Policy) validate() error {
+	switch p.By {
+	case ByIP, ByClient, ByUser:
+	default:
+		return fmt.Errorf("unknown key %q", p.By)
+	}
+	if p.Method == "" {
+		return fmt.Errorf("method is required")
+	}
+	if p.Limit <= 0 || p.Window <= 0 {
+		return fmt.Errorf("limit and window must be positive")
+	}
+	return nil
+}
+
+func (p *Policy) matches(method string) bool {
+	if p.Method == "*" || p.Method == method {
+		return true
+	}
+	if service, found := strings.CutSuffix(p.Method, "/*"); found {
+		return strings.HasPrefix(method, service+"/")
+	}
+	return false
+}
+
+// policiesFor returns the policies that apply to the method called by the client
+func policiesFor(policies []Policy, method, clientID string) []Policy {
+	overridden := make(map[string]bool)
+	for _, policy := range policies {
+		if policy.ClientID != "" && policy.ClientID == clientID && policy.matches(method) {
+			overridden[policy.Method+"|"+policy.By] = true
+		}
+	}
+	var applied []Policy
+	for _, policy := range policies {
+		if !policy.matches(method) {
+			continue
+		}
+		if policy.ClientID == "" && overridden[policy.Method+"|"+policy.By] {
+			continue
+		}
+		if policy.ClientID != "" && policy.ClientID != clientID {
+			continue
+		}
+		applied = append(applied, policy)
+	}
+	return applied
+}
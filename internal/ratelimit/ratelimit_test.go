@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/usercoredev/usercore/internal/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPoliciesFor(t *testing.T) {
+	policies := []Policy{
+		{Method: "/v1.AuthenticationService/*", By: ByIP, Limit: 10, Window: Duration(time.Minute)},
+		{Method: "/v1.AuthenticationService/SignIn", By: ByIP, Limit: 5, Window: Duration(time.Minute)},
+		{Method: "*", By: ByClient, Limit: 100, Window: Duration(time.Minute)},
+		{Method: "*", By: ByClient, ClientID: "partner", Limit: 1000, Window: Duration(time.Minute)},
+	}
+
+	applied := policiesFor(policies, "/v1.AuthenticationService/SignIn", "web")
+	if len(applied) != 3 {
+		t.Errorf("Expected the service, method and client policies, got %+v", applied)
+	}
+	applied = policiesFor(policies, "/v1.UserService/GetUser", "partner")
+	if len(applied) != 1 || applied[0].Limit != 1000 {
+		t.Errorf("Expected the client specific policy to replace the generic one, got %+v", applied)
+	}
+	applied = policiesFor(policies, "/v1.AuthenticationServiceX/SignIn", "web")
+	if len(applied) != 1 || applied[0].By != ByClient {
+		t.Errorf("Expected the service wildcard to match whole service names only, got %+v", applied)
+	}
+}
+
+func TestMemoryLimiterSlidingWindow(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, _ := limiter.Allow(ctx, "key", 3, time.Minute, now.Add(time.Duration(i)*time.Second)); !allowed {
+			t.Fatalf("Expected call %d to be allowed", i+1)
+		}
+	}
+	allowed, wait, _ := limiter.Allow(ctx, "key", 3, time.Minute, now.Add(10*time.Second))
+	if allowed || wait != 50*time.Second {
+		t.Errorf("Expected the 4th call to wait 50s, got allowed=%v wait=%v", allowed, wait)
+	}
+	if allowed, _, _ = limiter.Allow(ctx, "other", 3, time.Minute, now.Add(10*time.Second)); !allowed {
+		t.Errorf("Expected other keys to be counted separately")
+	}
+	if allowed, _, _ = limiter.Allow(ctx, "key", 3, time.Minute, now.Add(time.Minute)); !allowed {
+		t.Errorf("Expected a call once the oldest one left the window")
+	}
+}
+
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestInterceptorRejectsWithRetryAfter(t *testing.T) {
+	settings := Settings{Enabled: true, Policies: []Policy{{Method: "*", By: ByIP, Limit: 1, Window: Duration(time.Minute)}}}
+	interceptor := Interceptor(settings, nil, NewMemoryLimiter(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/v1.AuthenticationService/SignIn"}
+
+	addr, _ := net.ResolveTCPAddr("tcp", "203.0.113.7:5000")
+	stream := &headerStream{}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	ctx = context.WithValue(ctx, client.Key, &client.Item{ID: "web"})
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected the first call to pass, got %v", err)
+	}
+	_, err := interceptor(ctx, nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if values := stream.header.Get(RetryAfterHeader); len(values) != 1 || values[0] != "60" {
+		t.Errorf("Expected a retry-after of 60 seconds, got %v", values)
+	}
+}
+
+func TestInterceptorChecksOnlyTheGivenKeys(t *testing.T) {
+	settings := Settings{Enabled: true, Policies: []Policy{
+		{Method: "*", By: ByIP, Limit: 1, Window: Duration(time.Minute)},
+		{Method: "*", By: ByClient, Limit: 1, Window: Duration(time.Minute)},
+	}}
+	interceptor := Interceptor(settings, nil, NewMemoryLimiter(), slog.New(slog.NewTextHandler(io.Discard, nil)), ByUser)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/v1.AuthenticationService/SignIn"}
+
+	addr, _ := net.ResolveTCPAddr("tcp", "203.0.113.7:5000")
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	ctx = context.WithValue(ctx, client.Key, &client.Item{ID: "web"})
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(ctx, nil, info, handler); err != nil {
+			t.Fatalf("Expected the ip and client policies not to be checked, got %v", err)
+		}
+	}
+}
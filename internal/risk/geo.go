@@ -0,0 +1,101 @@
+package risk
+
+import (
+	"errors"
+	"github.com/oschwald/maxminddb-golang"
+	"math"
+	"net"
+)
+
+// Location is what the geolocation databases know about an IP address, fields they do not know are empty
+type Location struct {
+	Country        string
+	ASN            uint
+	Organization   string
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+}
+
+// Locator finds the location of IP addresses
+type Locator interface {
+	Locate(ip net.IP) (Location, error)
+}
+
+// record reads the fields shared by the MaxMind City, Country and ASN databases
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// MaxMind locates addresses with local MaxMind-format (.mmdb) databases, like GeoLite2-City and GeoLite2-ASN. The
+// answers of all databases are merged.
+type MaxMind struct {
+	readers []*maxminddb.Reader
+}
+
+// OpenMaxMind opens the database files
+func OpenMaxMind(paths ...string) (*MaxMind, error) {
+	m := &MaxMind{}
+	for _, path := range paths {
+		reader, err := maxminddb.Open(path)
+		if err != nil {
+			_ = m.Close()
+			return nil, err
+		}
+		m.readers = append(m.readers, reader)
+	}
+	return m, nil
+}
+
+func (m *MaxMind) Locate(ip net.IP) (Location, error) {
+	var location Location
+	for _, reader := range m.readers {
+		var found record
+		if err := reader.Lookup(ip, &found); err != nil {
+			return location, err
+		}
+		if found.Country.ISOCode != "" {
+			location.Country = found.Country.ISOCode
+		}
+		if found.AutonomousSystemNumber != 0 {
+			location.ASN = found.AutonomousSystemNumber
+			location.Organization = found.AutonomousSystemOrganization
+		}
+		if found.Location.Latitude != nil && found.Location.Longitude != nil {
+			location.Latitude = *found.Location.Latitude
+			location.Longitude = *found.Location.Longitude
+			location.HasCoordinates = true
+		}
+	}
+	return location, nil
+}
+
+// Close releases the database files
+func (m *MaxMind) Close() error {
+	var errs []error
+	for _, reader := range m.readers {
+		errs = append(errs, reader.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// earthRadius is the mean radius of the earth in kilometers
+const earthRadius = 6371.0
+
+// distance returns the great-circle distance between two coordinates in kilometers
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,155 @@
+package risk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mmdb encodes values in the MaxMind DB data format
+type mmdb struct {
+	bytes.Buffer
+}
+
+// control writes the type and the size of a value, sizes from 29 to 284 take an extra byte
+func (m *mmdb) control(kind byte, size int) {
+	extra := -1
+	if size >= 29 {
+		size, extra = 29, size-29
+	}
+	if kind > 7 {
+		m.WriteByte(byte(size))
+		m.WriteByte(kind - 7)
+	} else {
+		m.WriteByte(kind<<5 | byte(size))
+	}
+	if extra >= 0 {
+		m.WriteByte(byte(extra))
+	}
+}
+
+func (m *mmdb) string(value string) {
+	m.control(2, len(value))
+	m.WriteString(value)
+}
+
+func (m *mmdb) double(value float64) {
+	m.control(3, 8)
+	_ = binary.Write(m, binary.BigEndian, math.Float64bits(value))
+}
+
+func (m *mmdb) uint(kind byte, value uint64, size int) {
+	m.control(kind, size)
+	for i := size - 1; i >= 0; i-- {
+		m.WriteByte(byte(value >> (8 * i)))
+	}
+}
+
+// writeTestDatabase writes an IPv4 database that knows only the 81.0.0.0/8 network
+func writeTestDatabase(t *testing.T) string {
+	t.Helper()
+	const nodeCount = 8
+	prefix := byte(81)
+
+	var data mmdb
+	data.control(7, 4)
+	data.string("country")
+	data.control(7, 1)
+	data.string("iso_code")
+	data.string("GB")
+	data.string("location")
+	data.control(7, 2)
+	data.string("latitude")
+	data.double(51.5)
+	data.string("longitude")
+	data.double(-0.12)
+	data.string("autonomous_system_number")
+	data.uint(6, 2856, 4)
+	data.string("autonomous_system_organization")
+	data.string("BT")
+
+	var file bytes.Buffer
+	for depth := 0; depth < nodeCount; depth++ {
+		next := uint32(depth + 1)
+		if depth == nodeCount-1 {
+			next = nodeCount + 16
+		}
+		records := [2]uint32{nodeCount, nodeCount}
+		records[prefix>>(7-depth)&1] = next
+		for _, record := range records {
+			file.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	file.Write(make([]byte, 16))
+	file.Write(data.Bytes())
+
+	var metadata mmdb
+	metadata.control(7, 8)
+	metadata.string("node_count")
+	metadata.uint(6, nodeCount, 4)
+	metadata.string("record_size")
+	metadata.uint(5, 24, 2)
+	metadata.string("ip_version")
+	metadata.uint(5, 4, 2)
+	metadata.string("database_type")
+	metadata.string("Usercore-Test")
+	metadata.string("languages")
+	metadata.control(11, 1)
+	metadata.string("en")
+	metadata.string("binary_format_major_version")
+	metadata.uint(5, 2, 2)
+	metadata.string("binary_format_minor_version")
+	metadata.uint(5, 0, 2)
+	metadata.string("build_epoch")
+	metadata.uint(9, 1700000000, 8)
+	file.WriteString("\xAB\xCD\xEFMaxMind.com")
+	file.Write(metadata.Bytes())
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, file.Bytes(), 0o600); err != nil {
+		t.Fatalf("Failed to write test database: %v", err)
+	}
+	return path
+}
+
+func TestMaxMindLocate(t *testing.T) {
+	locator, err := OpenMaxMind(writeTestDatabase(t))
+	if err != nil {
+		t.Fatalf("OpenMaxMind failed: %v", err)
+	}
+	defer locator.Close()
+
+	location, err := locator.Locate(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatalf("Locate failed: %v", err)
+	}
+	expected := Location{Country: "GB", ASN: 2856, Organization: "BT", Latitude: 51.5, Longitude: -0.12, HasCoordinates: true}
+	if location != expected {
+		t.Errorf("Locate() = %+v, want %+v", location, expected)
+	}
+
+	unknown, err := locator.Locate(net.ParseIP("203.0.113.7"))
+	if err != nil {
+		t.Fatalf("Locate failed: %v", err)
+	}
+	if unknown != (Location{}) {
+		t.Errorf("Expected an unknown address to have no location, got %+v", unknown)
+	}
+}
+
+func TestOpenMaxMindMissingFile(t *testing.T) {
+	if _, err := OpenMaxMind(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Expected a missing database to fail")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// London to Sydney
+	if km := distance(51.5, -0.12, -33.87, 151.21); km < 16900 || km > 17100 {
+		t.Errorf("Expected about 17000 km, got %.0f", km)
+	}
+}
@@ -0,0 +1,277 @@
+package risk
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/database"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// Actions a decision can take
+const (
+	ActionAllow                    = "allow"
+	ActionRequireMFA               = "require_mfa"
+	ActionRequireEmailConfirmation = "require_email_confirmation"
+	ActionBlock                    = "block"
+)
+
+// Kinds of the checked requests
+const (
+	KindSignIn  = "sign_in"
+	KindRefresh = "refresh"
+)
+
+// Reasons add to the score of a request
+const (
+	ReasonNewIP            = "new_ip"
+	ReasonNewASN           = "new_asn"
+	ReasonNewCountry       = "new_country"
+	ReasonNewDevice        = "new_device"
+	ReasonImpossibleTravel = "impossible_travel"
+)
+
+// weights are the points each reason adds to the score, the score is capped at 100
+var weights = map[string]int{
+	ReasonNewIP:            10,
+	ReasonNewASN:           20,
+	ReasonNewCountry:       35,
+	ReasonNewDevice:        25,
+	ReasonImpossibleTravel: 60,
+}
+
+// minTravelDistance is the distance in kilometers below which moves are put down to the inaccuracy of geolocation
+const minTravelDistance = 500
+
+// Settings configures the risk engine
+type Settings struct {
+	Enabled bool
+	// GeoIPDatabases are the MaxMind-format database files used for the country, ASN and coordinates of addresses.
+	// Without them only the IP address and the device are compared.
+	GeoIPDatabases []string
+	// MaxTravelSpeed in km/h is the fastest a user is believed to move between two requests
+	MaxTravelSpeed float64
+	// History is how many trusted decisions of the user new requests are compared against
+	History int
+	// Policy applies to the clients without a risk policy of their own
+	Policy client.RiskPolicy
+}
+
+// Engine scores sign-ins and token refreshes against the history of the user and decides what to do about them
+type Engine struct {
+	settings Settings
+	locator  Locator
+	logger   *slog.Logger
+}
+
+// Attempt is a sign-in or a token refresh to check
+type Attempt struct {
+	Kind        string
+	UserID      uuid.UUID
+	Client      *client.Item
+	SessionID   uint64
+	IP          string
+	Fingerprint string
+	Time        time.Time
+}
+
+// Decision is the outcome of the checks, ID is the recorded decision
+type Decision struct {
+	ID      uint64
+	Score   int
+	Reasons []string
+	Action  string
+}
+
+// New opens the geolocation databases, it returns nil when the engine is disabled
+func (s Settings) New(logger *slog.Logger) (*Engine, error) {
+	if !s.Enabled {
+		return nil, nil
+	}
+	var paths []string
+	for _, path := range s.GeoIPDatabases {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	var locator Locator
+	if len(paths) > 0 {
+		maxMind, err := OpenMaxMind(paths...)
+		if err != nil {
+			return nil, err
+		}
+		locator = maxMind
+	}
+	return &Engine{settings: s, locator: locator, logger: logger}, nil
+}
+
+// Evaluate scores the attempt, applies the policy of its client and records the decision. A nil engine allows
+// everything.
+func (e *Engine) Evaluate(ctx context.Context, attempt Attempt) (*Decision, error) {
+	if e == nil {
+		return &Decision{Action: ActionAllow}, nil
+	}
+	if attempt.Time.IsZero() {
+		attempt.Time = time.Now()
+	}
+	location := e.locate(ctx, attempt.IP)
+	score, reasons, err := e.score(attempt, location)
+	if err != nil {
+		return nil, err
+	}
+	decision := &Decision{Score: score, Reasons: reasons, Action: e.action(attempt.Client, score)}
+
+	record := database.RiskDecision{
+		UINTBaseModel: database.UINTBaseModel{CreatedAt: attempt.Time},
+		UserID:        attempt.UserID,
+		SessionID:     attempt.SessionID,
+		Kind:          attempt.Kind,
+		IP:            attempt.IP,
+		Country:       location.Country,
+		ASN:           location.ASN,
+		Latitude:      location.Latitude,
+		Longitude:     location.Longitude,
+		Fingerprint:   attempt.Fingerprint,
+		Score:         score,
+		Action:        decision.Action,
+		Trusted:       decision.Action == ActionAllow,
+	}
+	if attempt.Client != nil {
+		record.ClientID = attempt.Client.ID
+	}
+	record.SetReasons(reasons)
+	if err = database.CreateRiskDecision(&record); err != nil {
+		return nil, err
+	}
+	decision.ID = record.ID
+	e.logger.InfoContext(ctx, "risk decision",
+		"user_id", attempt.UserID.String(),
+		"kind", attempt.Kind,
+		"ip", attempt.IP,
+		"country", location.Country,
+		"asn", location.ASN,
+		"score", score,
+		"reasons", reasons,
+		"action", decision.Action,
+	)
+	return decision, nil
+}
+
+func (e *Engine) locate(ctx context.Context, address string) Location {
+	ip := net.ParseIP(address)
+	if e.locator == nil || ip == nil {
+		return Location{}
+	}
+	location, err := e.locator.Locate(ip)
+	if err != nil {
+		e.logger.WarnContext(ctx, "failed to locate address", "ip", address, "error", err)
+		return Location{}
+	}
+	return location
+}
+
+// score compares the attempt with the sessions, the trusted decisions and the sign-in devices of the user. A user
+// without any history, signing in for the first time, scores 0.
+func (e *Engine) score(attempt Attempt, location Location) (int, []string, error) {
+	decisions, err := database.GetTrustedRiskDecisions(attempt.UserID, e.settings.History)
+	if err != nil {
+		return 0, nil, err
+	}
+	sessions, err := database.GetSessionsByUserId(attempt.UserID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ips := map[string]bool{}
+	countries := map[string]bool{}
+	asns := map[uint]bool{}
+	for _, session := range sessions {
+		ips[session.IP] = true
+		ips[session.LastSeenIP] = true
+	}
+	for _, decision := range decisions {
+		ips[decision.IP] = true
+		if decision.Country != "" {
+			countries[decision.Country] = true
+		}
+		if decision.ASN != 0 {
+			asns[decision.ASN] = true
+		}
+	}
+	delete(ips, "")
+
+	var reasons []string
+	if attempt.IP != "" && len(ips) > 0 && !ips[attempt.IP] {
+		reasons = append(reasons, ReasonNewIP)
+	}
+	if location.ASN != 0 && len(asns) > 0 && !asns[location.ASN] {
+		reasons = append(reasons, ReasonNewASN)
+	}
+	if location.Country != "" && len(countries) > 0 && !countries[location.Country] {
+		reasons = append(reasons, ReasonNewCountry)
+	}
+	if attempt.Fingerprint != "" {
+		known, recorded, err := database.SignInFingerprintKnown(attempt.UserID, attempt.Fingerprint)
+		if err != nil {
+			return 0, nil, err
+		}
+		if recorded && !known {
+			reasons = append(reasons, ReasonNewDevice)
+		}
+	}
+	if location.HasCoordinates && e.impossibleTravel(attempt.Time, location, decisions) {
+		reasons = append(reasons, ReasonImpossibleTravel)
+	}
+
+	score := 0
+	for _, reason := range reasons {
+		score += weights[reason]
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, reasons, nil
+}
+
+// impossibleTravel reports whether the user would have moved faster than MaxTravelSpeed since the last located
+// decision
+func (e *Engine) impossibleTravel(at time.Time, location Location, decisions []database.RiskDecision) bool {
+	if e.settings.MaxTravelSpeed <= 0 {
+		return false
+	}
+	for _, decision := range decisions {
+		if decision.Latitude == 0 && decision.Longitude == 0 {
+			continue
+		}
+		km := distance(decision.Latitude, decision.Longitude, location.Latitude, location.Longitude)
+		if km < minTravelDistance {
+			return false
+		}
+		hours := at.Sub(decision.CreatedAt).Hours()
+		return hours <= 0 || km/hours > e.settings.MaxTravelSpeed
+	}
+	return false
+}
+
+// action applies the policy of the client to the score, the strictest action whose score is reached wins
+func (e *Engine) action(item *client.Item, score int) string {
+	policy := e.settings.Policy
+	if item != nil && item.Risk != nil {
+		policy = *item.Risk
+	}
+	reached := func(threshold int) bool {
+		return threshold > 0 && score >= threshold
+	}
+	switch {
+	case reached(policy.Block):
+		return ActionBlock
+	case reached(policy.RequireMFA):
+		return ActionRequireMFA
+	case reached(policy.RequireEmailConfirmation):
+		return ActionRequireEmailConfirmation
+	}
+	return ActionAllow
+}
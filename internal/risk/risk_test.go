@@ -0,0 +1,161 @@
+package risk
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// staticLocator locates the addresses it was given
+type staticLocator map[string]Location
+
+func (l staticLocator) Locate(ip net.IP) (Location, error) {
+	return l[ip.String()], nil
+}
+
+var (
+	london = Location{Country: "GB", ASN: 2856, Latitude: 51.5, Longitude: -0.12, HasCoordinates: true}
+	leeds  = Location{Country: "GB", ASN: 5089, Latitude: 53.8, Longitude: -1.55, HasCoordinates: true}
+	sydney = Location{Country: "AU", ASN: 1221, Latitude: -33.87, Longitude: 151.21, HasCoordinates: true}
+)
+
+func testEngine(policy client.RiskPolicy) *Engine {
+	return &Engine{
+		settings: Settings{Enabled: true, MaxTravelSpeed: 1000, History: 50, Policy: policy},
+		locator: staticLocator{
+			"81.2.69.142":  london,
+			"81.2.69.143":  london,
+			"86.1.1.1":     leeds,
+			"1.120.0.1":    sydney,
+			"203.0.113.10": {},
+		},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func createUser(t *testing.T) uuid.UUID {
+	t.Helper()
+	user := dbtest.CreateUser(t, "jane@example.com")
+	if _, err := database.RecordSignInFingerprint(user.ID, "laptop", time.Now()); err != nil {
+		t.Fatalf("Failed to record fingerprint: %v", err)
+	}
+	return user.ID
+}
+
+func TestEvaluateScoresAgainstHistory(t *testing.T) {
+	dbtest.Setup(t)
+	userID := createUser(t)
+	engine := testEngine(client.RiskPolicy{RequireEmailConfirmation: 30, Block: 90})
+	ctx := context.Background()
+	start := time.Now().Add(-48 * time.Hour)
+
+	first, err := engine.Evaluate(ctx, Attempt{Kind: KindSignIn, UserID: userID, IP: "81.2.69.142", Fingerprint: "laptop", Time: start})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if first.Score != 0 || first.Action != ActionAllow {
+		t.Errorf("Expected the first sign-in to be allowed, got %+v", first)
+	}
+
+	tests := []struct {
+		name    string
+		attempt Attempt
+		reasons []string
+		action  string
+	}{
+		{
+			"known address and device",
+			Attempt{IP: "81.2.69.142", Fingerprint: "laptop", Time: start.Add(time.Hour)},
+			nil, ActionAllow,
+		},
+		{
+			"new address in the same network",
+			Attempt{IP: "81.2.69.143", Fingerprint: "laptop", Time: start.Add(2 * time.Hour)},
+			[]string{ReasonNewIP}, ActionAllow,
+		},
+		{
+			"new provider and device",
+			Attempt{IP: "86.1.1.1", Fingerprint: "phone", Time: start.Add(150 * time.Minute)},
+			[]string{ReasonNewIP, ReasonNewASN, ReasonNewDevice}, ActionRequireEmailConfirmation,
+		},
+		{
+			"impossible travel",
+			Attempt{IP: "1.120.0.1", Fingerprint: "laptop", Time: start.Add(3 * time.Hour)},
+			[]string{ReasonNewIP, ReasonNewASN, ReasonNewCountry, ReasonImpossibleTravel}, ActionBlock,
+		},
+		{
+			"address without location",
+			Attempt{IP: "203.0.113.10", Fingerprint: "laptop", Time: start.Add(4 * time.Hour)},
+			[]string{ReasonNewIP}, ActionAllow,
+		},
+	}
+	for _, test := range tests {
+		test.attempt.Kind = KindSignIn
+		test.attempt.UserID = userID
+		decision, err := engine.Evaluate(ctx, test.attempt)
+		if err != nil {
+			t.Fatalf("%s: Evaluate failed: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(decision.Reasons, test.reasons) || decision.Action != test.action {
+			t.Errorf("%s: got reasons %v and action %s, want %v and %s", test.name, decision.Reasons, decision.Action, test.reasons, test.action)
+		}
+	}
+
+	decisions, err := database.GetRiskDecisionsByUserId(userID)
+	if err != nil {
+		t.Fatalf("GetRiskDecisionsByUserId failed: %v", err)
+	}
+	if len(decisions) != len(tests)+1 {
+		t.Errorf("Expected every decision to be recorded, got %d", len(decisions))
+	}
+	trusted, err := database.GetTrustedRiskDecisions(userID, 0)
+	if err != nil {
+		t.Fatalf("GetTrustedRiskDecisions failed: %v", err)
+	}
+	if len(trusted) != 4 || trusted[0].IP != "203.0.113.10" {
+		t.Errorf("Expected only the allowed decisions to be trusted, got %d", len(trusted))
+	}
+}
+
+func TestEvaluateUsesClientPolicy(t *testing.T) {
+	dbtest.Setup(t)
+	userID := createUser(t)
+	engine := testEngine(client.RiskPolicy{RequireEmailConfirmation: 20})
+	ctx := context.Background()
+	if _, err := engine.Evaluate(ctx, Attempt{Kind: KindSignIn, UserID: userID, IP: "81.2.69.142", Fingerprint: "laptop"}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	strict := &client.Item{ID: "strict", Risk: &client.RiskPolicy{RequireMFA: 20, Block: 30}}
+	decision, err := engine.Evaluate(ctx, Attempt{Kind: KindSignIn, UserID: userID, Client: strict, IP: "81.2.69.142", Fingerprint: "phone"})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if decision.Score != 25 || decision.Action != ActionRequireMFA {
+		t.Errorf("Expected the client policy to require MFA, got %+v", decision)
+	}
+
+	decision, err = engine.Evaluate(ctx, Attempt{Kind: KindSignIn, UserID: userID, Client: &client.Item{ID: "default"}, IP: "81.2.69.142", Fingerprint: "phone"})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if decision.Action != ActionRequireEmailConfirmation {
+		t.Errorf("Expected the default policy to require an email confirmation, got %+v", decision)
+	}
+}
+
+func TestNilEngineAllows(t *testing.T) {
+	var engine *Engine
+	decision, err := engine.Evaluate(context.Background(), Attempt{Kind: KindSignIn})
+	if err != nil || decision.Action != ActionAllow {
+		t.Errorf("Expected a disabled engine to allow, got %+v (%v)", decision, err)
+	}
+}
@@ -0,0 +1,180 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"time"
+)
+
+// Names of the built-in jobs
+const (
+	JobSessionCleanup          = "session_cleanup"
+	JobPasswordResetCleanup    = "password_reset_cleanup"
+	JobVerificationCodeCleanup = "verification_code_cleanup"
+	JobAccountPurge            = "account_purge"
+	JobRunHistoryCleanup       = "job_run_cleanup"
+	JobRiskDecisionCleanup     = "risk_decision_cleanup"
+	JobLoginAttemptCleanup     = "login_attempt_cleanup"
+	JobNotificationCleanup     = "notification_cleanup"
+)
+
+// batchSize is how many rows the jobs that delete in batches remove at once
+const batchSize = 500
+
+// SessionCleanup removes the sessions whose refresh token expired, and the signed out ones
+func SessionCleanup(interval time.Duration) Job {
+	return Job{
+		Name:     JobSessionCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			total := 0
+			for ctx.Err() == nil {
+				deleted, err := database.DeleteExpiredSessions(time.Now(), batchSize)
+				total += deleted
+				if err != nil {
+					return fmt.Sprintf("%d sessions deleted", total), err
+				}
+				if deleted < batchSize {
+					break
+				}
+			}
+			return fmt.Sprintf("%d sessions deleted", total), nil
+		},
+	}
+}
+
+// PasswordResetCleanup removes the expired password resets, used or not
+func PasswordResetCleanup(interval time.Duration) Job {
+	return Job{
+		Name:     JobPasswordResetCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			deleted, err := database.DeleteStalePasswordResets(time.Now())
+			return fmt.Sprintf("%d password resets deleted", deleted), err
+		},
+	}
+}
+
+// VerificationCodeCleanup removes the expired email changes and sign-in confirmations, and the email verification codes
+// older than codeTTL
+func VerificationCodeCleanup(interval, codeTTL time.Duration) Job {
+	return Job{
+		Name:     JobVerificationCodeCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			now := time.Now()
+			changes, err := database.DeleteStaleEmailChanges(now)
+			if err != nil {
+				return "", err
+			}
+			challenges, err := database.DeleteStaleSignInChallenges(now)
+			if err != nil {
+				return "", err
+			}
+			var codes int64
+			if codeTTL > 0 {
+				if codes, err = database.ClearEmailVerifyCodes(now.Add(-codeTTL)); err != nil {
+					return "", err
+				}
+			}
+			return fmt.Sprintf("%d email changes, %d sign-in challenges and %d verification codes deleted", changes, challenges, codes), nil
+		},
+	}
+}
+
+// AccountPurge permanently removes the users whose deletion grace period is over
+func AccountPurge(interval, gracePeriod time.Duration) Job {
+	return Job{
+		Name:     JobAccountPurge,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			total := 0
+			for ctx.Err() == nil {
+				purged, err := database.PurgeDeletedUsers(time.Now().Add(-gracePeriod), 100)
+				total += purged
+				if err != nil {
+					return fmt.Sprintf("%d users purged", total), err
+				}
+				if purged < 100 {
+					break
+				}
+			}
+			return fmt.Sprintf("%d users purged", total), nil
+		},
+	}
+}
+
+// RunHistoryCleanup removes the recorded job runs older than retention
+func RunHistoryCleanup(interval, retention time.Duration) Job {
+	return Job{
+		Name:     JobRunHistoryCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			deleted, err := database.DeleteJobRuns(time.Now().Add(-retention))
+			return fmt.Sprintf("%d job runs deleted", deleted), err
+		},
+	}
+}
+
+// RiskDecisionCleanup removes the risk decisions older than retention. They are the history new sign-ins are compared
+// against, so a longer retention remembers the usual locations and devices of users for longer.
+func RiskDecisionCleanup(interval, retention time.Duration) Job {
+	return Job{
+		Name:     JobRiskDecisionCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			total := 0
+			before := time.Now().Add(-retention)
+			for ctx.Err() == nil {
+				deleted, err := database.DeleteRiskDecisions(before, batchSize)
+				total += deleted
+				if err != nil {
+					return fmt.Sprintf("%d risk decisions deleted", total), err
+				}
+				if deleted < batchSize {
+					break
+				}
+			}
+			return fmt.Sprintf("%d risk decisions deleted", total), nil
+		},
+	}
+}
+
+// LoginAttemptCleanup removes the failed sign-in counters whose last failure is older than retention and that are not
+// locked
+func LoginAttemptCleanup(interval, retention time.Duration) Job {
+	return Job{
+		Name:     JobLoginAttemptCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			now := time.Now()
+			deleted, err := database.DeleteStaleLoginAttempts(now.Add(-retention), now)
+			return fmt.Sprintf("%d login attempts deleted", deleted), err
+		},
+	}
+}
+
+// NotificationCleanup removes the security notifications queued before retention, the ones given up on included. Their
+// data holds addresses and IPs, so they are not kept for longer than it takes to send or inspect them.
+func NotificationCleanup(interval, retention time.Duration) Job {
+	return Job{
+		Name:     JobNotificationCleanup,
+		Interval: interval,
+		Run: func(ctx context.Context) (string, error) {
+			total := 0
+			before := time.Now().Add(-retention)
+			for ctx.Err() == nil {
+				deleted, err := database.DeleteNotifications(before, batchSize)
+				total += deleted
+				if err != nil {
+					return fmt.Sprintf("%d notifications deleted", total), err
+				}
+				if deleted < batchSize {
+					break
+				}
+			}
+			return fmt.Sprintf("%d notifications deleted", total), nil
+		},
+	}
+}
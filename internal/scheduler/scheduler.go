@@ -0,0 +1,263 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"gorm.io/gorm"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// LeaseName is the lease the replicas compete for, its holder runs the jobs
+const LeaseName = "scheduler"
+
+// ErrLeaseLost cancels a running job once another replica took over the lease
+var ErrLeaseLost = errors.New("scheduler lease lost")
+
+type Settings struct {
+	Enabled bool
+	// PollInterval is how often the lease is renewed and the jobs are checked
+	PollInterval time.Duration
+	// LeaseTTL is how long a replica that stopped renewing the lease keeps it before another one takes over
+	LeaseTTL time.Duration
+}
+
+// Job is run every Interval by the replica holding the lease. Run returns a short summary of what it did.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Scheduler runs periodic jobs on a single replica at a time, elected through a lease in the database. The runs are
+// recorded, so a replica taking over continues the schedule.
+type Scheduler struct {
+	settings Settings
+	jobs     []Job
+	holder   string
+	logger   *slog.Logger
+	// now returns the time of the database, the lease and the schedule don't depend on the clock of the replica
+	now func() (time.Time, error)
+}
+
+func New(settings Settings, logger *slog.Logger) *Scheduler {
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = 15 * time.Second
+	}
+	if settings.LeaseTTL <= settings.PollInterval {
+		settings.LeaseTTL = 4 * settings.PollInterval
+	}
+	return &Scheduler{
+		settings: settings,
+		holder:   holderName(),
+		logger:   logger,
+		now:      database.Now,
+	}
+}
+
+// holderName identifies this replica in the lease and the recorded runs
+func holderName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "usercore"
+	}
+	buffer := make([]byte, 4)
+	_, _ = rand.Read(buffer)
+	return fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(buffer))
+}
+
+// Register adds a job, jobs without an interval are turned off
+func (s *Scheduler) Register(job Job) {
+	if job.Interval <= 0 {
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// Jobs returns the registered jobs sorted by name
+func (s *Scheduler) Jobs() []Job {
+	jobs := make([]Job, len(s.jobs))
+	copy(jobs, s.jobs)
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Holder returns the name this replica holds the lease with
+func (s *Scheduler) Holder() string {
+	return s.holder
+}
+
+// Run competes for the lease and runs the due jobs while holding it, until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.settings.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Tick(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "scheduler failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			if err := database.ReleaseJobLease(LeaseName, s.holder); err != nil {
+				s.logger.Error("failed to release scheduler lease", "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick takes or renews the lease and runs the jobs that are due. Replicas without the lease do nothing.
+func (s *Scheduler) Tick(ctx context.Context) error {
+	for _, job := range s.Jobs() {
+		leader, err := s.acquireLease()
+		if err != nil {
+			return err
+		}
+		if !leader {
+			return nil
+		}
+		due, err := s.due(job)
+		if err != nil {
+			return err
+		}
+		if !due {
+			continue
+		}
+		if err = s.run(ctx, job); err != nil {
+			if errors.Is(err, ErrLeaseLost) {
+				s.logger.WarnContext(ctx, "scheduler lease lost while a job was running", "job", job.Name)
+				return nil
+			}
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) acquireLease() (bool, error) {
+	now, err := s.now()
+	if err != nil {
+		return false, err
+	}
+	return database.AcquireJobLease(LeaseName, s.holder, now, s.settings.LeaseTTL)
+}
+
+// due reports whether the interval of the job passed since its last run started, on any replica
+func (s *Scheduler) due(job Job) (bool, error) {
+	last, err := database.GetLatestJobRun(job.Name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	now, err := s.now()
+	if err != nil {
+		return false, err
+	}
+	return !now.Before(last.StartedAt.Add(job.Interval)), nil
+}
+
+// run records the start of the job before running it, so a replica taking over doesn't start it again, and then how
+// it went. The lease is renewed while the job runs and the job is cancelled with ErrLeaseLost once it is lost. A failing
+// job is recorded and does not stop the other jobs.
+func (s *Scheduler) run(ctx context.Context, job Job) error {
+	startedAt, err := s.now()
+	if err != nil {
+		return err
+	}
+	run := database.JobRun{
+		Job:       job.Name,
+		Holder:    s.holder,
+		StartedAt: startedAt,
+		Outcome:   database.JobOutcomeRunning,
+	}
+	if err = database.CreateJobRun(&run); err != nil {
+		return err
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	renewing := make(chan struct{})
+	go func() {
+		defer close(renewing)
+		s.keepLease(jobCtx, cancel)
+	}()
+	began := time.Now()
+	result, err := s.safeRun(jobCtx, job)
+	elapsed := time.Since(began)
+	lost := errors.Is(context.Cause(jobCtx), ErrLeaseLost)
+	cancel(nil)
+	<-renewing
+
+	// the duration is measured locally, so the run doesn't depend on the database to be recorded
+	finishedAt := startedAt.Add(elapsed)
+	run.FinishedAt = &finishedAt
+	run.Duration = elapsed.Milliseconds()
+	run.Outcome = database.JobOutcomeSuccess
+	run.Result = result
+	if lost && (err == nil || errors.Is(err, context.Canceled)) {
+		err = ErrLeaseLost
+	}
+	if err != nil {
+		run.Outcome = database.JobOutcomeFailure
+		run.Error = err.Error()
+		s.logger.ErrorContext(ctx, "scheduled job failed", "job", job.Name, "error", err)
+	} else {
+		s.logger.InfoContext(ctx, "scheduled job finished", "job", job.Name, "duration_ms", run.Duration, "result", result)
+	}
+	if err := database.UpdateJobRun(&run); err != nil {
+		return err
+	}
+	if lost {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// keepLease renews the lease every poll interval until ctx is done, and cancels the job when another replica holds the
+// lease or it could not be renewed for longer than its TTL
+func (s *Scheduler) keepLease(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(s.settings.PollInterval)
+	defer ticker.Stop()
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		leader, err := s.acquireLease()
+		switch {
+		case err == nil && leader:
+			renewedAt = time.Now()
+		case err == nil:
+			cancel(ErrLeaseLost)
+			return
+		case time.Since(renewedAt) >= s.settings.LeaseTTL:
+			s.logger.ErrorContext(ctx, "failed to renew scheduler lease", "error", err)
+			cancel(ErrLeaseLost)
+			return
+		default:
+			s.logger.WarnContext(ctx, "failed to renew scheduler lease, retrying", "error", err)
+		}
+	}
+}
+
+// safeRun turns a panicking job into a failed run
+func (s *Scheduler) safeRun(ctx context.Context, job Job) (result string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return job.Run(ctx)
+}
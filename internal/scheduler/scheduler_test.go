@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func testScheduler(holder string, clock *time.Time) *Scheduler {
+	s := New(Settings{Enabled: true, PollInterval: time.Second, LeaseTTL: time.Minute}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.holder = holder
+	s.now = func() (time.Time, error) { return *clock, nil }
+	return s
+}
+
+func TestSchedulerRunsOnLeaderOnly(t *testing.T) {
+	dbtest.Setup(t)
+	clock := time.Now()
+	runs := map[string]int{}
+	job := func(holder string) Job {
+		return Job{Name: "count", Interval: time.Hour, Run: func(context.Context) (string, error) {
+			runs[holder]++
+			return "counted", nil
+		}}
+	}
+	first := testScheduler("first", &clock)
+	first.Register(job("first"))
+	second := testScheduler("second", &clock)
+	second.Register(job("second"))
+	ctx := context.Background()
+
+	for _, s := range []*Scheduler{first, second, first, second} {
+		if err := s.Tick(ctx); err != nil {
+			t.Fatalf("Tick failed: %v", err)
+		}
+	}
+	if runs["first"] != 1 || runs["second"] != 0 {
+		t.Fatalf("Expected only the leader to run the job once, got %v", runs)
+	}
+
+	// the leader stops renewing its lease, the other replica takes over and keeps the schedule
+	clock = clock.Add(2 * time.Minute)
+	if err := second.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if runs["second"] != 0 {
+		t.Error("Expected the job not to run before its interval passed")
+	}
+	lease, err := database.GetJobLease(LeaseName)
+	if err != nil || lease.Holder != "second" {
+		t.Fatalf("Expected the second replica to hold the lease, got %+v (%v)", lease, err)
+	}
+	clock = clock.Add(time.Hour)
+	if err = second.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = first.Tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if runs["first"] != 1 || runs["second"] != 1 {
+		t.Errorf("Expected the new leader to run the job, got %v", runs)
+	}
+
+	last, err := database.GetLatestJobRun("count")
+	if err != nil {
+		t.Fatalf("GetLatestJobRun failed: %v", err)
+	}
+	if last.Holder != "second" || last.Outcome != database.JobOutcomeSuccess || last.Result != "counted" {
+		t.Errorf("Unexpected recorded run %+v", last)
+	}
+}
+
+func TestSchedulerRecordsFailures(t *testing.T) {
+	dbtest.Setup(t)
+	clock := time.Now()
+	s := testScheduler("only", &clock)
+	ran := false
+	s.Register(Job{Name: "broken", Interval: time.Hour, Run: func(context.Context) (string, error) {
+		return "", errors.New("boom")
+	}})
+	s.Register(Job{Name: "panics", Interval: time.Hour, Run: func(context.Context) (string, error) {
+		panic("oops")
+	}})
+	s.Register(Job{Name: "works", Interval: time.Hour, Run: func(context.Context) (string, error) {
+		ran = true
+		return "", nil
+	}})
+	s.Register(Job{Name: "off", Run: func(context.Context) (string, error) {
+		t.Error("Expected a job without interval not to run")
+		return "", nil
+	}})
+
+	if err := s.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	if !ran {
+		t.Error("Expected failing jobs not to stop the other jobs")
+	}
+	if len(s.Jobs()) != 3 {
+		t.Errorf("Expected 3 registered jobs, got %d", len(s.Jobs()))
+	}
+	for _, name := range []string{"broken", "panics"} {
+		run, err := database.GetLatestJobRun(name)
+		if err != nil || run.Outcome != database.JobOutcomeFailure || run.Error == "" {
+			t.Errorf("Expected the failure of %s to be recorded, got %+v (%v)", name, run, err)
+		}
+	}
+}
+
+func TestSchedulerCancelsJobWhenLeaseIsLost(t *testing.T) {
+	dbtest.Setup(t)
+	clock := time.Now()
+	s := New(Settings{Enabled: true, PollInterval: 10 * time.Millisecond, LeaseTTL: time.Minute}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.holder = "first"
+	s.now = func() (time.Time, error) { return clock, nil }
+	s.Register(Job{Name: "slow", Interval: time.Hour, Run: func(ctx context.Context) (string, error) {
+		run, err := database.GetLatestJobRun("slow")
+		if err != nil || run.Outcome != database.JobOutcomeRunning || run.FinishedAt != nil {
+			t.Errorf("Expected the run to be recorded before the job starts, got %+v (%v)", run, err)
+		}
+		// another replica takes over, as if this one had stopped renewing the lease
+		database.DB.Model(&database.JobLease{}).Where("name = ?", LeaseName).Update("holder", "second")
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "", errors.New("not cancelled")
+		}
+	}})
+
+	if err := s.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick failed: %v", err)
+	}
+	run, err := database.GetLatestJobRun("slow")
+	if err != nil {
+		t.Fatalf("GetLatestJobRun failed: %v", err)
+	}
+	if run.Outcome != database.JobOutcomeFailure || run.Error != ErrLeaseLost.Error() || run.FinishedAt == nil {
+		t.Errorf("Expected the job to be cancelled because the lease was lost, got %+v", run)
+	}
+	lease, _ := database.GetJobLease(LeaseName)
+	if lease == nil || lease.Holder != "second" {
+		t.Errorf("Expected the other replica to keep the lease, got %+v", lease)
+	}
+}
+
+func TestCleanupJobs(t *testing.T) {
+	dbtest.Setup(t)
+	user := dbtest.CreateUser(t, "jane@example.com")
+	past := time.Now().Add(-time.Hour)
+	database.DB.Create(&database.Session{UserID: user.ID, RefreshToken: "expired", ExpiresAt: past})
+	database.DB.Create(&database.Session{UserID: user.ID, RefreshToken: "active", ExpiresAt: time.Now().Add(time.Hour)})
+	if _, err := database.CreatePasswordReset(user.ID, "abc123", -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	sentAt := time.Now().Add(-48 * time.Hour)
+	database.DB.Model(user).Updates(map[string]interface{}{"email_verify_code": "123456", "email_verify_sent_at": sentAt})
+	old := database.RiskDecision{UserID: user.ID, Kind: "sign_in", Action: "allow"}
+	old.CreatedAt = sentAt
+	database.DB.Create(&old)
+	database.DB.Create(&database.RiskDecision{UserID: user.ID, Kind: "sign_in", Action: "allow"})
+	lockedUntil := time.Now().Add(time.Hour)
+	database.DB.Create(&database.LoginAttempt{Identifier: "stale", Failures: 2, LastFailureAt: sentAt})
+	database.DB.Create(&database.LoginAttempt{Identifier: "locked", Failures: 5, LastFailureAt: sentAt, LockedUntil: &lockedUntil})
+	database.DB.Create(&database.LoginAttempt{Identifier: "recent", Failures: 1, LastFailureAt: time.Now()})
+	dead := database.Notification{UserID: user.ID, Event: "password_changed", Status: database.DeliveryStatusDead}
+	dead.CreatedAt = sentAt
+	database.DB.Create(&dead)
+	database.DB.Create(&database.Notification{UserID: user.ID, Event: "password_changed", Status: database.DeliveryStatusPending})
+
+	ctx := context.Background()
+	for _, job := range []Job{SessionCleanup(time.Hour), PasswordResetCleanup(time.Hour), VerificationCodeCleanup(time.Hour, 24*time.Hour),
+		RiskDecisionCleanup(time.Hour, 24*time.Hour), LoginAttemptCleanup(time.Hour, 24*time.Hour), NotificationCleanup(time.Hour, 24*time.Hour)} {
+		if _, err := job.Run(ctx); err != nil {
+			t.Fatalf("%s failed: %v", job.Name, err)
+		}
+	}
+
+	sessions, _ := database.GetSessionsByUserId(user.ID)
+	if len(sessions) != 1 || sessions[0].RefreshToken != "active" {
+		t.Errorf("Expected only the active session to be left, got %d", len(sessions))
+	}
+	var resets int64
+	database.DB.Unscoped().Model(&database.PasswordReset{}).Count(&resets)
+	if resets != 0 {
+		t.Errorf("Expected the expired password reset to be deleted, got %d", resets)
+	}
+	stored, _ := database.GetUserByID(user.ID, false)
+	if stored.EmailVerifyCode != "" {
+		t.Error("Expected the old verification code to be cleared")
+	}
+	decisions, _ := database.GetRiskDecisionsByUserId(user.ID)
+	if len(decisions) != 1 || decisions[0].ID == old.ID {
+		t.Errorf("Expected only the recent risk decision to be left, got %d", len(decisions))
+	}
+	var attempts []database.LoginAttempt
+	database.DB.Order("identifier asc").Find(&attempts)
+	if len(attempts) != 2 || attempts[0].Identifier != "locked" || attempts[1].Identifier != "recent" {
+		t.Errorf("Expected the locked and the recent login attempts to be left, got %+v", attempts)
+	}
+	var notifications []database.Notification
+	database.DB.Find(&notifications)
+	if len(notifications) != 1 || notifications[0].ID == dead.ID {
+		t.Errorf("Expected only the recent notification to be left, got %d", len(notifications))
+	}
+}
@@ -0,0 +1,210 @@
+package sessionpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reasons a session ends before its refresh token expires
+const (
+	ReasonIdleTimeout = "idle_timeout"
+	ReasonMaxLifetime = "max_lifetime"
+)
+
+// Policy limits the sessions a user can have at the same time and how long they last. A zero limit turns it off.
+type Policy struct {
+	// MaxPerUser limits the sessions of a user across all clients
+	MaxPerUser int `json:"max_per_user,omitempty"`
+	// MaxPerClient limits the sessions of a user on the client of the new session
+	MaxPerClient int `json:"max_per_client,omitempty"`
+	// MaxPerDeviceType limits the sessions of a user per device type (desktop, mobile, tablet, bot), across all clients
+	MaxPerDeviceType map[string]int `json:"max_per_device_type,omitempty"`
+	// SingleSessionPerClient ends the other sessions of the user on the client when a new one starts
+	SingleSessionPerClient bool `json:"single_session_per_client,omitempty"`
+	// IdleTimeout ends a session that was not refreshed for that long
+	IdleTimeout Duration `json:"idle_timeout,omitempty"`
+	// MaxLifetime ends a session that long after the sign-in, however often it is refreshed
+	MaxLifetime Duration `json:"max_lifetime,omitempty"`
+}
+
+// Duration reads durations such as "30m" from JSON
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Settings configures the policy of the clients without a session policy of their own
+type Settings struct {
+	MaxPerUser   int
+	MaxPerClient int
+	// MaxPerDeviceType is a comma separated list of device types with their limit, like "mobile=2,desktop=3"
+	MaxPerDeviceType       string
+	SingleSessionPerClient bool
+	IdleTimeout            time.Duration
+	MaxLifetime            time.Duration
+}
+
+var defaultPolicy Policy
+
+// Setup validates the settings and makes them the default policy
+func (s Settings) Setup() error {
+	policy := Policy{
+		MaxPerUser:             s.MaxPerUser,
+		MaxPerClient:           s.MaxPerClient,
+		SingleSessionPerClient: s.SingleSessionPerClient,
+		IdleTimeout:            Duration(s.IdleTimeout),
+		MaxLifetime:            Duration(s.MaxLifetime),
+	}
+	deviceTypes, err := ParseDeviceTypeLimits(s.MaxPerDeviceType)
+	if err != nil {
+		return err
+	}
+	policy.MaxPerDeviceType = deviceTypes
+	if err = policy.Validate(); err != nil {
+		return err
+	}
+	defaultPolicy = policy
+	return nil
+}
+
+// ParseDeviceTypeLimits reads a comma separated list of device types with their limit, like "mobile=2,desktop=3"
+func ParseDeviceTypeLimits(value string) (map[string]int, error) {
+	limits := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		deviceType, limit, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid device type session limit %q", entry)
+		}
+		max, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, fmt.Errorf("invalid device type session limit %q", entry)
+		}
+		limits[strings.ToLower(strings.TrimSpace(deviceType))] = max
+	}
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	return limits, nil
+}
+
+// Validate rejects negative limits
+func (p Policy) Validate() error {
+	if p.MaxPerUser < 0 || p.MaxPerClient < 0 || p.IdleTimeout < 0 || p.MaxLifetime < 0 {
+		return fmt.Errorf("session limits cannot be negative")
+	}
+	for deviceType, limit := range p.MaxPerDeviceType {
+		if limit < 0 {
+			return fmt.Errorf("session limit of device type %q cannot be negative", deviceType)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the policy of a client, or the default policy when the client has none
+func Resolve(policy *Policy) Policy {
+	if policy == nil {
+		return defaultPolicy
+	}
+	return *policy
+}
+
+// Expired returns why a session signed in at signedInAt and last used at lastUsedAt has ended at now, or an empty
+// reason while it lasts
+func (p Policy) Expired(signedInAt, lastUsedAt, now time.Time) string {
+	if p.MaxLifetime > 0 && !now.Before(signedInAt.Add(time.Duration(p.MaxLifetime))) {
+		return ReasonMaxLifetime
+	}
+	if p.IdleTimeout > 0 && !now.Before(lastUsedAt.Add(time.Duration(p.IdleTimeout))) {
+		return ReasonIdleTimeout
+	}
+	return ""
+}
+
+// ExpiresAt caps the expiry of a refresh token at the end of the lifetime of its session
+func (p Policy) ExpiresAt(signedInAt, expiresAt time.Time) time.Time {
+	if p.MaxLifetime > 0 {
+		if end := signedInAt.Add(time.Duration(p.MaxLifetime)); end.Before(expiresAt) {
+			return end
+		}
+	}
+	return expiresAt
+}
+
+// Session is what the policy needs to know about a session
+type Session struct {
+	ID         uint64
+	ClientID   string
+	DeviceType string
+	LastUsedAt time.Time
+}
+
+// Evict returns the IDs of the existing sessions to end so the new session fits the policy. The least recently used
+// sessions are evicted first.
+func (p Policy) Evict(existing []Session, next Session) []uint64 {
+	sessions := make([]Session, len(existing))
+	copy(sessions, existing)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.Before(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	evicted := map[uint64]bool{}
+	var ids []uint64
+	// limit evicts the oldest sessions matching the filter until the new session fits in the limit
+	limit := func(max int, matches func(Session) bool) {
+		if max <= 0 {
+			return
+		}
+		count := 0
+		for _, session := range sessions {
+			if !evicted[session.ID] && matches(session) {
+				count++
+			}
+		}
+		for _, session := range sessions {
+			if count < max {
+				return
+			}
+			if !evicted[session.ID] && matches(session) {
+				evicted[session.ID] = true
+				ids = append(ids, session.ID)
+				count--
+			}
+		}
+	}
+
+	sameClient := func(session Session) bool { return session.ClientID == next.ClientID }
+	if p.SingleSessionPerClient {
+		limit(1, sameClient)
+	}
+	limit(p.MaxPerClient, sameClient)
+	if next.DeviceType != "" {
+		limit(p.MaxPerDeviceType[next.DeviceType], func(session Session) bool { return session.DeviceType == next.DeviceType })
+	}
+	limit(p.MaxPerUser, func(Session) bool { return true })
+	return ids
+}
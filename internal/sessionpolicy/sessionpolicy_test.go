@@ -0,0 +1,99 @@
+package sessionpolicy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvict(t *testing.T) {
+	now := time.Now()
+	existing := []Session{
+		{ID: 1, ClientID: "web", DeviceType: "desktop", LastUsedAt: now.Add(-time.Hour)},
+		{ID: 2, ClientID: "web", DeviceType: "mobile", LastUsedAt: now.Add(-3 * time.Hour)},
+		{ID: 3, ClientID: "app", DeviceType: "mobile", LastUsedAt: now.Add(-2 * time.Hour)},
+		{ID: 4, ClientID: "app", DeviceType: "mobile", LastUsedAt: now.Add(-4 * time.Hour)},
+	}
+	tests := []struct {
+		name    string
+		policy  Policy
+		next    Session
+		evicted []uint64
+	}{
+		{"no limits", Policy{}, Session{ClientID: "web"}, nil},
+		{"under the user limit", Policy{MaxPerUser: 5}, Session{ClientID: "web"}, nil},
+		{"user limit", Policy{MaxPerUser: 3}, Session{ClientID: "web"}, []uint64{4, 2}},
+		{"client limit", Policy{MaxPerClient: 2}, Session{ClientID: "web"}, []uint64{2}},
+		{"device type limit", Policy{MaxPerDeviceType: map[string]int{"mobile": 2}}, Session{ClientID: "web", DeviceType: "mobile"}, []uint64{4, 2}},
+		{"other device type", Policy{MaxPerDeviceType: map[string]int{"mobile": 1}}, Session{ClientID: "web", DeviceType: "desktop"}, nil},
+		{"single session per client", Policy{SingleSessionPerClient: true}, Session{ClientID: "app"}, []uint64{4, 3}},
+		{"combined limits", Policy{MaxPerClient: 2, MaxPerUser: 3}, Session{ClientID: "app"}, []uint64{4, 2}},
+	}
+	for _, test := range tests {
+		evicted := test.policy.Evict(existing, test.next)
+		if !reflect.DeepEqual(evicted, test.evicted) {
+			t.Errorf("%s: Evict() = %v, want %v", test.name, evicted, test.evicted)
+		}
+	}
+}
+
+func TestParseDeviceTypeLimits(t *testing.T) {
+	limits, err := ParseDeviceTypeLimits(" Mobile=2, desktop = 3 ,")
+	if err != nil {
+		t.Fatalf("ParseDeviceTypeLimits failed: %v", err)
+	}
+	if !reflect.DeepEqual(limits, map[string]int{"mobile": 2, "desktop": 3}) {
+		t.Errorf("Unexpected limits %v", limits)
+	}
+	if limits, _ = ParseDeviceTypeLimits(""); limits != nil {
+		t.Errorf("Expected no limits, got %v", limits)
+	}
+	if _, err = ParseDeviceTypeLimits("mobile"); err == nil {
+		t.Error("Expected an entry without a limit to fail")
+	}
+	if err = (Settings{MaxPerUser: -1}).Setup(); err == nil {
+		t.Error("Expected a negative limit to fail")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	policy := Policy{IdleTimeout: Duration(30 * time.Minute), MaxLifetime: Duration(24 * time.Hour)}
+	signedIn := time.Now().Add(-12 * time.Hour)
+	tests := []struct {
+		name     string
+		lastUsed time.Time
+		now      time.Time
+		reason   string
+	}{
+		{"active", signedIn.Add(11 * time.Hour), signedIn.Add(11*time.Hour + 10*time.Minute), ""},
+		{"idle", signedIn.Add(11 * time.Hour), signedIn.Add(12 * time.Hour), ReasonIdleTimeout},
+		{"lifetime", signedIn.Add(24 * time.Hour), signedIn.Add(24*time.Hour + time.Minute), ReasonMaxLifetime},
+	}
+	for _, test := range tests {
+		if reason := policy.Expired(signedIn, test.lastUsed, test.now); reason != test.reason {
+			t.Errorf("%s: Expired() = %q, want %q", test.name, reason, test.reason)
+		}
+	}
+	if reason := (Policy{}).Expired(signedIn, signedIn, signedIn.Add(1000*time.Hour)); reason != "" {
+		t.Errorf("Expected a policy without lifetimes to keep sessions, got %q", reason)
+	}
+
+	expiresAt := policy.ExpiresAt(signedIn, signedIn.Add(48*time.Hour))
+	if !expiresAt.Equal(signedIn.Add(24 * time.Hour)) {
+		t.Errorf("Expected the refresh token to expire with the session, got %v", expiresAt)
+	}
+	if expiresAt = policy.ExpiresAt(signedIn, signedIn.Add(time.Hour)); !expiresAt.Equal(signedIn.Add(time.Hour)) {
+		t.Errorf("Expected an earlier expiry to be kept, got %v", expiresAt)
+	}
+}
+
+func TestPolicyJSON(t *testing.T) {
+	var policy Policy
+	if err := json.Unmarshal([]byte(`{"max_per_user":3,"idle_timeout":"30m"}`), &policy); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if policy.MaxPerUser != 3 || time.Duration(policy.IdleTimeout) != 30*time.Minute {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+}
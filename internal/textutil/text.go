@@ -1,15 +1,25 @@
 package textutil
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"math/big"
 )
 
+// RandomString returns a random alphanumeric string read from crypto/rand, it is used for one-time codes. It returns
+// an empty string when the system random source fails.
 func RandomString(length int) string {
+	if length <= 0 {
+		return ""
+	}
 	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	max := big.NewInt(int64(len(letters)))
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = byte(letters[rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(letters))])
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return ""
+		}
+		b[i] = byte(letters[n.Int64()])
 	}
 	return string(b)
 }
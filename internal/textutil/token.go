@@ -0,0 +1,15 @@
+package textutil
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// SecureToken returns a URL-safe token made of the given number of random bytes from crypto/rand
+func SecureToken(size int) (string, error) {
+	buffer := make([]byte, size)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
@@ -0,0 +1,17 @@
+package textutil
+
+import "testing"
+
+func TestSecureToken(t *testing.T) {
+	token1, err := SecureToken(32)
+	if err != nil {
+		t.Fatalf("SecureToken failed: %v", err)
+	}
+	token2, _ := SecureToken(32)
+	if len(token1) != 43 {
+		t.Errorf("Expected a 43 character token for 32 bytes, got %d", len(token1))
+	}
+	if token1 == token2 {
+		t.Errorf("Expected unique tokens")
+	}
+}
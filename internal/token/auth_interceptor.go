@@ -6,10 +6,13 @@ import (
 	"github.com/cristalhq/jwt/v4"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"github.com/usercoredev/usercore/app/responses"
+	"github.com/usercoredev/usercore/internal/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"log/slog"
+	"sync"
 	"time"
 )
 
@@ -33,6 +36,10 @@ func (s *Settings) AuthInterceptor() grpc.UnaryServerInterceptor {
 				if err != nil {
 					return nil, status.Errorf(codes.Unauthenticated, err.Error())
 				}
+				if err = s.checkSession(ctx, claims); err != nil {
+					return nil, err
+				}
+				logger.AddAttrs(ctx, slog.String("user_id", claims.ID))
 				ctx = context.WithValue(ctx, Claims, claims)
 			}
 		}
@@ -46,9 +53,131 @@ func (s *Settings) verify(receivedToken string) (jwt.RegisteredClaims, error) {
 	if err != nil {
 		return jwt.RegisteredClaims{}, errors.New(responses.TokenMalformed)
 	}
+	// signed tokens for other purposes, like password reset links, have their own audience
+	if !newClaims.IsForAudience(s.Audience) {
+		return jwt.RegisteredClaims{}, errors.New(responses.InvalidToken)
+	}
 	var isValid = newClaims.IsValidAt(time.Now())
 	if !isValid {
 		return jwt.RegisteredClaims{}, errors.New(responses.TokenExpired)
 	}
 	return newClaims, nil
 }
+
+// checkSession rejects the access tokens of revoked sessions and of sessions that expired under the session policy,
+// and records the use of the session at most every ActivityInterval. Tokens issued before they carried their session
+// are accepted until they expire.
+func (s *Settings) checkSession(ctx context.Context, claims jwt.RegisteredClaims) error {
+	sessionID, ok := SessionID(claims)
+	if s.LoadSession == nil || !ok {
+		return nil
+	}
+	session, ok := s.sessions.get(sessionID)
+	if !ok {
+		loaded, err := s.LoadSession(ctx, sessionID)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to check session of access token", "error", err)
+			return status.Errorf(codes.Internal, responses.ServerError)
+		}
+		if loaded == nil {
+			return status.Errorf(codes.Unauthenticated, responses.SessionRevoked)
+		}
+		session = *loaded
+		s.sessions.remember(sessionID, session)
+	}
+
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	if s.EndSession != nil {
+		if err := s.EndSession(ctx, sessionID, session, now); err != nil {
+			s.sessions.forget(sessionID)
+			return err
+		}
+	}
+	if s.TouchSession != nil && now.Sub(session.LastSeenAt) >= s.ActivityInterval {
+		if err := s.TouchSession(ctx, sessionID, now); err != nil {
+			// the call goes on, the next one records the use
+			slog.ErrorContext(ctx, "failed to record session activity", "error", err)
+			return nil
+		}
+		session.LastSeenAt = now
+		s.sessions.update(sessionID, session)
+	}
+	return nil
+}
+
+// sessionCache remembers the sessions found active for a while, so that a series of calls with the same access token
+// does not query the database every time. Revoked sessions are not cached.
+type sessionCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[uint64]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	session Session
+	expires time.Time
+}
+
+// sessionCacheSweep is the size from which the expired entries are dropped when a session is added
+const sessionCacheSweep = 10000
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, now: time.Now, entries: map[uint64]sessionCacheEntry{}}
+}
+
+// get returns the session when it was found active less than the TTL ago. A nil cache remembers nothing.
+func (c *sessionCache) get(sessionID uint64) (Session, bool) {
+	if c == nil {
+		return Session{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[sessionID]
+	if !ok || !c.now().Before(entry.expires) {
+		return Session{}, false
+	}
+	return entry.session, true
+}
+
+func (c *sessionCache) remember(sessionID uint64, session Session) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= sessionCacheSweep {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[sessionID] = sessionCacheEntry{session: session, expires: now.Add(c.ttl)}
+}
+
+// update replaces the cached session without extending how long it is trusted
+func (c *sessionCache) update(sessionID uint64, session Session) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[sessionID]; ok {
+		entry.session = session
+		c.entries[sessionID] = entry
+	}
+}
+
+func (c *sessionCache) forget(sessionID uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, sessionID)
+}
@@ -0,0 +1,116 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"github.com/cristalhq/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestCheckSessionCachesActiveSessions(t *testing.T) {
+	now := time.Now()
+	active := map[uint64]bool{1: true}
+	loads := 0
+	s := &Settings{
+		LoadSession: func(_ context.Context, sessionID uint64) (*Session, error) {
+			loads++
+			if !active[sessionID] {
+				return nil, nil
+			}
+			return &Session{CreatedAt: now, LastSeenAt: now}, nil
+		},
+		sessions: newSessionCache(time.Minute),
+	}
+	s.sessions.now = func() time.Time { return now }
+	ctx := context.Background()
+	claims := jwt.RegisteredClaims{Subject: "1"}
+
+	for i := 0; i < 3; i++ {
+		if err := s.checkSession(ctx, claims); err != nil {
+			t.Fatalf("Expected the session to be active, got %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected the active session to be loaded once, got %d", loads)
+	}
+
+	delete(active, 1)
+	now = now.Add(2 * time.Minute)
+	if err := s.checkSession(ctx, claims); err == nil {
+		t.Error("Expected the revoked session to be rejected once the cache expired")
+	}
+	if err := s.checkSession(ctx, claims); err == nil || loads != 3 {
+		t.Errorf("Expected a revoked session not to be cached, %d loads", loads)
+	}
+
+	s.sessions = nil
+	active[1] = true
+	_ = s.checkSession(ctx, claims)
+	_ = s.checkSession(ctx, claims)
+	if loads != 5 {
+		t.Errorf("Expected every call to load the session without a cache, got %d loads", loads)
+	}
+}
+
+func TestCheckSessionEnforcesPolicyAndRecordsActivity(t *testing.T) {
+	signedInAt := time.Now()
+	now := signedInAt
+	lastSeenAt := signedInAt
+	touches := 0
+	errIdle := errors.New("idle")
+	s := &Settings{
+		LoadSession: func(context.Context, uint64) (*Session, error) {
+			return &Session{CreatedAt: signedInAt, LastSeenAt: lastSeenAt}, nil
+		},
+		EndSession: func(_ context.Context, _ uint64, session Session, now time.Time) error {
+			if now.Sub(session.LastSeenAt) > 10*time.Minute {
+				return errIdle
+			}
+			return nil
+		},
+		TouchSession: func(_ context.Context, _ uint64, at time.Time) error {
+			touches++
+			lastSeenAt = at
+			return nil
+		},
+		ActivityInterval: time.Minute,
+		sessions:         newSessionCache(time.Hour),
+		now:              func() time.Time { return now },
+	}
+	s.sessions.now = s.now
+	ctx := context.Background()
+	claims := jwt.RegisteredClaims{Subject: "1"}
+
+	// calls within the activity interval are not recorded
+	for i := 0; i < 5; i++ {
+		now = now.Add(10 * time.Second)
+		if err := s.checkSession(ctx, claims); err != nil {
+			t.Fatalf("Expected the session to be active, got %v", err)
+		}
+	}
+	if touches != 0 {
+		t.Errorf("Expected no activity to be recorded within the interval, got %d", touches)
+	}
+	now = now.Add(time.Minute)
+	_ = s.checkSession(ctx, claims)
+	_ = s.checkSession(ctx, claims)
+	if touches != 1 || !lastSeenAt.Equal(now) {
+		t.Errorf("Expected the activity to be recorded once, got %d at %v", touches, lastSeenAt)
+	}
+
+	// a session in use does not idle out, even while its cached state is used
+	for i := 0; i < 4; i++ {
+		now = now.Add(5 * time.Minute)
+		if err := s.checkSession(ctx, claims); err != nil {
+			t.Fatalf("Expected a session in use to last, got %v", err)
+		}
+	}
+	now = now.Add(11 * time.Minute)
+	if err := s.checkSession(ctx, claims); !errors.Is(err, errIdle) {
+		t.Errorf("Expected an idle session to be ended, got %v", err)
+	}
+	if _, ok := s.sessions.get(1); ok {
+		t.Error("Expected an ended session to be dropped from the cache")
+	}
+}
@@ -1,14 +1,18 @@
 package token
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/cristalhq/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/app/responses"
 	"github.com/usercoredev/usercore/internal/cipher"
+	"strconv"
 	"time"
 )
 
@@ -39,6 +43,29 @@ type Settings struct {
 	PublicPrivateKey   PublicPrivateKey
 	Verifier           jwt.Verifier
 	Signer             jwt.Signer
+	// LoadSession returns the session of an access token, nil when it was revoked, so the tokens of revoked sessions are
+	// rejected before they expire. Nil skips the session checks
+	LoadSession func(ctx context.Context, sessionID uint64) (*Session, error)
+	// EndSession ends the session when it expired under the session policy at now and returns the error for the caller,
+	// nil while the session lasts. Nil skips the policy
+	EndSession func(ctx context.Context, sessionID uint64, session Session, now time.Time) error
+	// TouchSession records that the session was used at now, so that the idle timeout counts the calls with its access
+	// tokens. Nil skips it
+	TouchSession func(ctx context.Context, sessionID uint64, now time.Time) error
+	// ActivityInterval is how long after the last recorded use a call records it again, it bounds the writes per session
+	ActivityInterval time.Duration
+	// SessionCacheTTL is how long an active session is remembered before it is loaded again, the access tokens of a
+	// revoked session can be used for that long. 0 loads the session on every call
+	SessionCacheTTL time.Duration
+	sessions        *sessionCache
+	now             func() time.Time
+}
+
+// Session is what the auth interceptor knows of the session of an access token
+type Session struct {
+	CreatedAt time.Time
+	// LastSeenAt is the last recorded use of the session, its sign-in or refresh or a call with its access token
+	LastSeenAt time.Time
 }
 
 var options *Settings
@@ -56,6 +83,10 @@ func (s *Settings) Setup() {
 	}
 	s.Verifier = verifier
 	s.Signer = signer
+	if s.SessionCacheTTL > 0 {
+		s.sessions = newSessionCache(s.SessionCacheTTL)
+	}
+	s.now = time.Now
 	options = s
 }
 
@@ -79,10 +110,12 @@ func CreateRefreshToken(uuid uuid.UUID) (string, *time.Time) {
 	return hex.EncodeToString(refreshTokenString), &refreshTokenExpireTime
 }
 
-func CreateJWT(userId uuid.UUID) (string, error) {
+// CreateJWT creates the access token of a session. The ID of the token is the user and the subject is the session.
+func CreateJWT(userId uuid.UUID, sessionID uint64) (string, error) {
 	registeredClaims := &jwt.RegisteredClaims{
 		Issuer:    options.Issuer,
 		ID:        userId.String(),
+		Subject:   strconv.FormatUint(sessionID, 10),
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(options.AccessTokenExpire)),
 		NotBefore: jwt.NewNumericDate(time.Now()),
 		Audience:  jwt.Audience{options.Audience},
@@ -94,3 +127,45 @@ func CreateJWT(userId uuid.UUID) (string, error) {
 	}
 	return token.String(), nil
 }
+
+// SessionID returns the session of an access token, tokens issued before sessions were recorded in them have none
+func SessionID(claims jwt.RegisteredClaims) (uint64, bool) {
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateSignedToken signs a token for another purpose than authentication, like a password reset link. The purpose is
+// the audience of the token so that it is never accepted as an access token.
+func CreateSignedToken(purpose, subject, id string, expiresAt time.Time) (string, error) {
+	registeredClaims := &jwt.RegisteredClaims{
+		Issuer:    options.Issuer,
+		Subject:   subject,
+		ID:        id,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Audience:  jwt.Audience{purpose},
+	}
+	token, err := jwt.NewBuilder(options.Signer).Build(registeredClaims)
+	if err != nil {
+		return "", err
+	}
+	return token.String(), nil
+}
+
+// VerifySignedToken returns the claims of a token created with CreateSignedToken for the purpose
+func VerifySignedToken(receivedToken, purpose string) (jwt.RegisteredClaims, error) {
+	var claims jwt.RegisteredClaims
+	if err := jwt.ParseClaims([]byte(receivedToken), options.Verifier, &claims); err != nil {
+		return jwt.RegisteredClaims{}, errors.New(responses.TokenMalformed)
+	}
+	if !claims.IsForAudience(purpose) || !claims.IsIssuer(options.Issuer) {
+		return jwt.RegisteredClaims{}, errors.New(responses.InvalidToken)
+	}
+	if !claims.IsValidAt(time.Now()) {
+		return jwt.RegisteredClaims{}, errors.New(responses.TokenExpired)
+	}
+	return claims, nil
+}
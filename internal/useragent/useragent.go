@@ -0,0 +1,111 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Agent is what a user agent string tells about the browser, the operating system and the kind of device. Fields that
+// cannot be recognized are empty.
+type Agent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         string
+}
+
+type pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// browsers are checked in order, browsers built on Chrome and Safari mention them too so they come first
+var browsers = []pattern{
+	{"Edge", regexp.MustCompile(`(?:Edg|EdgA|EdgiOS|Edge)/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"gRPC", regexp.MustCompile(`^grpc-[\w-]+/([\d.]+)`)},
+	{"curl", regexp.MustCompile(`^curl/([\d.]+)`)},
+	{"OkHttp", regexp.MustCompile(`^okhttp/([\d.]+)`)},
+}
+
+var (
+	windowsPattern = regexp.MustCompile(`Windows NT ([\d.]+)`)
+	iosPattern     = regexp.MustCompile(`(?:iPhone|CPU) OS ([\d_]+)`)
+	macPattern     = regexp.MustCompile(`Mac OS X ([\d_.]+)`)
+	androidPattern = regexp.MustCompile(`Android ([\d.]+)`)
+	botPattern     = regexp.MustCompile(`(?i)bot|crawler|spider|slurp`)
+)
+
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+}
+
+// Parse reads the browser, the operating system and the device type from a user agent string
+func Parse(userAgent string) Agent {
+	var agent Agent
+	if userAgent == "" {
+		return agent
+	}
+	for _, browser := range browsers {
+		if match := browser.re.FindStringSubmatch(userAgent); match != nil {
+			agent.Browser, agent.BrowserVersion = browser.name, match[1]
+			break
+		}
+	}
+
+	switch {
+	case windowsPattern.MatchString(userAgent):
+		agent.OS = "Windows"
+		version := windowsPattern.FindStringSubmatch(userAgent)[1]
+		agent.OSVersion = windowsVersions[version]
+		if agent.OSVersion == "" {
+			agent.OSVersion = version
+		}
+	case strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "iPod"):
+		agent.OS = "iOS"
+		if match := iosPattern.FindStringSubmatch(userAgent); match != nil {
+			agent.OSVersion = strings.ReplaceAll(match[1], "_", ".")
+		}
+	case strings.Contains(userAgent, "Android"):
+		agent.OS = "Android"
+		if match := androidPattern.FindStringSubmatch(userAgent); match != nil {
+			agent.OSVersion = match[1]
+		}
+	case macPattern.MatchString(userAgent):
+		agent.OS = "macOS"
+		agent.OSVersion = strings.ReplaceAll(macPattern.FindStringSubmatch(userAgent)[1], "_", ".")
+	case strings.Contains(userAgent, "CrOS"):
+		agent.OS = "ChromeOS"
+	case strings.Contains(userAgent, "Linux"):
+		agent.OS = "Linux"
+	}
+
+	switch {
+	case botPattern.MatchString(userAgent):
+		agent.Device = DeviceBot
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") ||
+		(agent.OS == "Android" && !strings.Contains(userAgent, "Mobile")):
+		agent.Device = DeviceTablet
+	case strings.Contains(userAgent, "Mobi") || agent.OS == "iOS":
+		agent.Device = DeviceMobile
+	case agent.OS != "":
+		agent.Device = DeviceDesktop
+	}
+	return agent
+}
@@ -0,0 +1,54 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  Agent
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Agent{Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			Agent{Browser: "Edge", BrowserVersion: "124.0.2478.51", OS: "Windows", OSVersion: "10", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			Agent{Browser: "Safari", BrowserVersion: "17.4", OS: "macOS", OSVersion: "10.15.7", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			Agent{Browser: "Safari", BrowserVersion: "17.4", OS: "iOS", OSVersion: "17.4.1", Device: DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1",
+			Agent{Browser: "Chrome", BrowserVersion: "124.0.6367.88", OS: "iOS", OSVersion: "16.6", Device: DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/24.0 Chrome/117.0.0.0 Mobile Safari/537.36",
+			Agent{Browser: "Samsung Internet", BrowserVersion: "24.0", OS: "Android", OSVersion: "14", Device: DeviceMobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			Agent{Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Android", OSVersion: "13", Device: DeviceTablet},
+		},
+		{
+			"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+			Agent{Browser: "Firefox", BrowserVersion: "125.0", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Agent{Device: DeviceBot},
+		},
+		{"grpc-go/1.62.0", Agent{Browser: "gRPC", BrowserVersion: "1.62.0"}},
+		{"", Agent{}},
+	}
+	for _, test := range tests {
+		if agent := Parse(test.userAgent); agent != test.expected {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.userAgent, agent, test.expected)
+		}
+	}
+}
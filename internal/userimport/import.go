@@ -0,0 +1,219 @@
+package userimport
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/password"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"strings"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+const (
+	defaultBatchSize = 100
+	// maxLineSize bounds a single NDJSON line
+	maxLineSize = 1 << 20
+)
+
+type Options struct {
+	// Format is ndjson or csv. CSV files start with a header row naming the columns
+	Format string
+	// SkipRows is the number of rows an earlier run processed, the import resumes after them
+	SkipRows int
+	// Firebase holds the project parameters needed for firebase_scrypt hashes
+	Firebase  *password.FirebaseScryptParameters
+	BatchSize int
+	// MaxErrors bounds the row errors kept in the result, the counters stay exact. 0 keeps them all
+	MaxErrors int
+	// Checkpoint is called after each batch with the number of rows processed so far
+	Checkpoint func(rows int) error
+}
+
+// Result counts the rows of an import. Rows is what to pass as SkipRows to resume an interrupted import
+type Result struct {
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Skipped  int        `json:"skipped"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors,omitempty"`
+}
+
+func (r *Result) addError(rowErr RowError, maxErrors int) {
+	if rowErr.Reason == ReasonUserExists {
+		r.Skipped++
+	} else {
+		r.Failed++
+	}
+	if maxErrors == 0 || len(r.Errors) < maxErrors {
+		r.Errors = append(r.Errors, rowErr)
+	}
+}
+
+type pending struct {
+	row  int
+	user *database.User
+}
+
+// Import creates the users read from r. Rows that cannot be imported are reported in the result without stopping the
+// import; users whose email already exists are skipped, so an import can safely be run again. The error is only set
+// when the input cannot be read any further or the context is done, the result then covers the rows processed so far.
+func Import(ctx context.Context, r io.Reader, opts Options) (*Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Firebase != nil {
+		if err := opts.Firebase.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	next, err := newReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Rows: opts.SkipRows}
+	batch := make([]pending, 0, opts.BatchSize)
+	// Rows only moves forward: it counts every row read so far, the rows of the batch are written before it is reported
+	flush := func() error {
+		insertBatch(batch, result, opts.MaxErrors)
+		batch = batch[:0]
+		if opts.Checkpoint != nil {
+			return opts.Checkpoint(result.Rows)
+		}
+		return nil
+	}
+
+	for rowNumber := 1; ; rowNumber++ {
+		if err := ctx.Err(); err != nil {
+			return result, errors.Join(err, flush())
+		}
+		row, readErr := next()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		var rowErr *RowError
+		if readErr != nil && !errors.As(readErr, &rowErr) {
+			return result, errors.Join(fmt.Errorf("row %d: %w", rowNumber, readErr), flush())
+		}
+		if rowNumber <= opts.SkipRows {
+			continue
+		}
+		result.Rows = rowNumber
+		var user *database.User
+		if rowErr == nil {
+			user, rowErr = row.user(opts.Firebase)
+		}
+		if rowErr != nil {
+			rowErr.Row = rowNumber
+			rowErr.Email = row.Email
+			result.addError(*rowErr, opts.MaxErrors)
+			continue
+		}
+		batch = append(batch, pending{row: rowNumber, user: user})
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, flush()
+}
+
+// insertBatch creates the users of the batch in one transaction, and one by one when that fails, to find which rows
+// are at fault
+func insertBatch(batch []pending, result *Result, maxErrors int) {
+	if len(batch) == 0 {
+		return
+	}
+	users := make([]*database.User, len(batch))
+	for i, item := range batch {
+		users[i] = item.user
+	}
+	// Existing users are expected when an import runs again, the failures are reported per row instead of logged
+	db := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Silent)})
+	if err := db.Create(users).Error; err == nil {
+		result.Imported += len(batch)
+		return
+	}
+	for _, item := range batch {
+		err := db.Create(item.user).Error
+		switch {
+		case err == nil:
+			result.Imported++
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			result.addError(RowError{Row: item.row, Email: item.user.Email, Reason: ReasonUserExists, Message: "a user with this email already exists"}, maxErrors)
+		default:
+			result.addError(RowError{Row: item.row, Email: item.user.Email, Reason: ReasonServerError, Message: err.Error()}, maxErrors)
+		}
+	}
+}
+
+// newReader returns a function reading one row at a time. A *RowError means the row is malformed but the next one
+// can be read, any other error ends the import
+func newReader(r io.Reader, format string) (func() (Row, error), error) {
+	switch strings.ToLower(format) {
+	case FormatNDJSON, "jsonl", "":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return func() (Row, error) {
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+				var row Row
+				if err := json.Unmarshal([]byte(line), &row); err != nil {
+					return row, rowError(ReasonInvalidRow, "%v", err)
+				}
+				return row, nil
+			}
+			if err := scanner.Err(); err != nil {
+				return Row{}, err
+			}
+			return Row{}, io.EOF
+		}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the csv header: %w", err)
+		}
+		for i, column := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(column))
+		}
+		return func() (Row, error) {
+			record, err := reader.Read()
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					return Row{}, rowError(ReasonInvalidRow, "%v", err)
+				}
+				return Row{}, err
+			}
+			var row Row
+			if len(record) != len(header) {
+				return row, rowError(ReasonInvalidRow, "expected %d columns, got %d", len(header), len(record))
+			}
+			for i, value := range record {
+				if err := row.setField(header[i], strings.TrimSpace(value)); err != nil {
+					return row, rowError(ReasonInvalidRow, "%v", err)
+				}
+			}
+			return row, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
@@ -0,0 +1,171 @@
+package userimport
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"strings"
+	"testing"
+)
+
+func testRows(t *testing.T) string {
+	t.Helper()
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-Passw0rd"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbkdf2Key := pbkdf2.Key([]byte("pbkdf2-Passw0rd"), []byte("saltsalt"), 1000, 32, sha256.New)
+	shaSum := sha256.Sum256([]byte("pepper" + "sha-Passw0rd"))
+	return strings.Join([]string{
+		fmt.Sprintf(`{"email":"bcrypt@example.com","name":"Bcrypt","email_verified":true,"locale":"en","password_hash":%q,"hash_algorithm":"bcrypt"}`, bcryptHash),
+		fmt.Sprintf(`{"email":"pbkdf2@example.com","password_hash":%q,"password_salt":%q,"hash_algorithm":"pbkdf2_sha256","hash_iterations":1000}`,
+			base64.StdEncoding.EncodeToString(pbkdf2Key), base64.StdEncoding.EncodeToString([]byte("saltsalt"))),
+		"",
+		`{"email":"not-an-email","password_hash":"abc","hash_algorithm":"sha256"}`,
+		fmt.Sprintf(`{"email":"sha@example.com","password_hash":%q,"hash_encoding":"hex","password_salt":"pepper","hash_algorithm":"sha256"}`, hex.EncodeToString(shaSum[:])),
+		`{"email":"md5@example.com","password_hash":"abc","hash_algorithm":"md5"}`,
+		`{"email":`,
+		`{"email":"social@example.com","name":"Social"}`,
+	}, "\n")
+}
+
+func TestImportNDJSON(t *testing.T) {
+	dbtest.Setup(t)
+	result, err := Import(context.Background(), strings.NewReader(testRows(t)), Options{Format: FormatNDJSON, BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Rows != 7 || result.Imported != 4 || result.Failed != 3 || result.Skipped != 0 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	reasons := map[int]string{}
+	for _, rowErr := range result.Errors {
+		reasons[rowErr.Row] = rowErr.Reason
+	}
+	if reasons[3] != ReasonInvalidEmail || reasons[5] != ReasonUnsupportedAlgorithm || reasons[6] != ReasonInvalidRow {
+		t.Errorf("Unexpected row errors %+v", result.Errors)
+	}
+
+	for email, plain := range map[string]string{
+		"bcrypt@example.com": "bcrypt-Passw0rd",
+		"pbkdf2@example.com": "pbkdf2-Passw0rd",
+		"sha@example.com":    "sha-Passw0rd",
+	} {
+		user, err := database.GetUserByEmail(email)
+		if err != nil {
+			t.Fatalf("Expected %s to be imported: %v", email, err)
+		}
+		if user.ComparePassword("wrong") {
+			t.Errorf("Expected a wrong password to be rejected for %s", email)
+		}
+		if !user.ComparePassword(plain) {
+			t.Errorf("Expected the imported hash of %s to verify", email)
+		}
+		if !strings.HasPrefix(user.Password, "$argon2id$") {
+			t.Errorf("Expected the hash of %s to be upgraded, got %q", email, user.Password)
+		}
+	}
+	user, err := database.GetUserByEmail("bcrypt@example.com")
+	if err != nil || !user.EmailVerified || user.Profile == nil || user.Profile.Locale != "en" {
+		t.Errorf("Expected the profile fields to be imported, got %+v", user)
+	}
+	social, err := database.GetUserByEmail("social@example.com")
+	if err != nil || social.Password != "" || social.ComparePassword("") {
+		t.Errorf("Expected a user without a password, got %+v (%v)", social, err)
+	}
+}
+
+func TestImportResumeAndDuplicates(t *testing.T) {
+	dbtest.Setup(t)
+	var checkpoints []int
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options := Options{Format: FormatNDJSON, BatchSize: 1}
+	options.Checkpoint = func(rows int) error {
+		checkpoints = append(checkpoints, rows)
+		if rows >= 2 {
+			cancel()
+		}
+		return nil
+	}
+	result, err := Import(ctx, strings.NewReader(testRows(t)), options)
+	if err == nil || result.Rows != 2 || result.Imported != 2 {
+		t.Fatalf("Expected the import to stop after row 2, got %+v (%v)", result, err)
+	}
+	if checkpoints[0] != 1 || checkpoints[len(checkpoints)-1] != 2 {
+		t.Errorf("Unexpected checkpoints %v", checkpoints)
+	}
+
+	options.Checkpoint = nil
+	options.SkipRows = result.Rows
+	result, err = Import(context.Background(), strings.NewReader(testRows(t)), options)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Rows != 7 || result.Imported != 2 || result.Failed != 3 {
+		t.Errorf("Unexpected result after resuming %+v", result)
+	}
+
+	options.SkipRows = 0
+	result, err = Import(context.Background(), strings.NewReader(testRows(t)), options)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 4 {
+		t.Errorf("Expected existing users to be skipped, got %+v", result)
+	}
+}
+
+func TestImportRowsOnlyMoveForward(t *testing.T) {
+	dbtest.Setup(t)
+	input := `{"email": "first@example.com"}
+{"email": "not an email"}
+`
+	var checkpoints []int
+	result, err := Import(context.Background(), strings.NewReader(input), Options{
+		Format:    FormatNDJSON,
+		BatchSize: 10,
+		Checkpoint: func(rows int) error {
+			checkpoints = append(checkpoints, rows)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	// the invalid row is read after the pending batch, resuming from the batch would report it again
+	if result.Rows != 2 || len(checkpoints) != 1 || checkpoints[0] != 2 {
+		t.Errorf("Expected 2 rows and a checkpoint at 2, got %+v and %v", result, checkpoints)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	dbtest.Setup(t)
+	input := "email,name,email_verified,password_hash,password_salt,salt_encoding,salt_position,hash_algorithm\n" +
+		fmt.Sprintf("csv@example.com,CSV User,true,%s,pepper,utf8,suffix,sha1\n", base64.StdEncoding.EncodeToString(sha1Sum("s3cret"+"pepper"))) +
+		"short@example.com,Short\n" +
+		"verified@example.com,Verified,maybe,,,,,\n"
+	result, err := Import(context.Background(), strings.NewReader(input), Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 1 || result.Failed != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	user, err := database.GetUserByEmail("csv@example.com")
+	if err != nil || user.Name != "CSV User" || !user.EmailVerified || !user.ComparePassword("s3cret") {
+		t.Errorf("Expected the csv user to be imported with its hash, got %+v (%v)", user, err)
+	}
+}
+
+func sha1Sum(value string) []byte {
+	sum := sha1.Sum([]byte(value))
+	return sum[:]
+}
@@ -0,0 +1,244 @@
+package userimport
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/password"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hash algorithms accepted in the hash_algorithm field
+const (
+	HashBcrypt         = "bcrypt"
+	HashFirebaseScrypt = "firebase_scrypt"
+	HashPBKDF2SHA256   = "pbkdf2_sha256"
+	HashSHA1           = "sha1"
+	HashSHA256         = "sha256"
+	HashSHA512         = "sha512"
+	// HashPHC takes password_hash as a PHC string usercore understands, e.g. $pbkdf2-sha256$i=...,l=32$salt$hash
+	HashPHC = "phc"
+)
+
+// Row reasons reported for rows that were not imported
+const (
+	ReasonInvalidRow           = "invalid_row"
+	ReasonInvalidEmail         = "invalid_email"
+	ReasonUnsupportedAlgorithm = "unsupported_algorithm"
+	ReasonInvalidHash          = "invalid_hash"
+	ReasonUserExists           = "user_exists"
+	ReasonServerError          = "server_error"
+)
+
+// Row is a user to import. NDJSON lines use the json names, CSV files the same names in their header row
+type Row struct {
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	EmailVerified bool   `json:"email_verified"`
+	Picture       string `json:"picture"`
+	Gender        string `json:"gender"`
+	Birthdate     string `json:"birthdate"`
+	Locale        string `json:"locale"`
+	Timezone      string `json:"timezone"`
+
+	PasswordHash  string `json:"password_hash"`
+	PasswordSalt  string `json:"password_salt"`
+	HashAlgorithm string `json:"hash_algorithm"`
+	// HashIterations is the iteration count of pbkdf2_sha256
+	HashIterations int `json:"hash_iterations"`
+	// HashEncoding of password_hash is base64 (default) or hex
+	HashEncoding string `json:"hash_encoding"`
+	// SaltEncoding of password_salt is base64 (default for firebase_scrypt and pbkdf2_sha256), hex or utf8 (default
+	// for the sha variants)
+	SaltEncoding string `json:"salt_encoding"`
+	// SaltPosition places the salt before (prefix, default) or after (suffix) the password for the sha variants
+	SaltPosition string `json:"salt_position"`
+}
+
+// RowError is why a row was not imported
+type RowError struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email,omitempty"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Reason, e.Message)
+}
+
+func rowError(reason, format string, args ...any) *RowError {
+	return &RowError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// user converts the row to the user to create, with its hash stored as it is and tagged with its algorithm
+func (r *Row) user(firebase *password.FirebaseScryptParameters) (*database.User, *RowError) {
+	email := strings.TrimSpace(r.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, rowError(ReasonInvalidEmail, "invalid email %q", email)
+	}
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	hash, rowErr := r.hash(firebase)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	user := &database.User{
+		Name:          name,
+		Email:         email,
+		EmailVerified: r.EmailVerified,
+		Password:      hash,
+	}
+	profile := database.Profile{
+		Picture:  r.Picture,
+		Gender:   r.Gender,
+		Locale:   r.Locale,
+		Timezone: r.Timezone,
+	}
+	if r.Birthdate != "" {
+		birthdate, err := time.Parse(time.DateOnly, r.Birthdate)
+		if err != nil {
+			return nil, rowError(ReasonInvalidRow, "birthdate must be YYYY-MM-DD")
+		}
+		profile.Birthdate = &birthdate
+	}
+	if profile != (database.Profile{}) {
+		user.Profile = &profile
+	}
+	return user, nil
+}
+
+func (r *Row) hash(firebase *password.FirebaseScryptParameters) (string, *RowError) {
+	algorithm := strings.ToLower(strings.TrimSpace(r.HashAlgorithm))
+	if r.PasswordHash == "" {
+		if algorithm != "" {
+			return "", rowError(ReasonInvalidHash, "password_hash is required with hash_algorithm")
+		}
+		// Users of social providers have no password and sign in with the provider or reset it
+		return "", nil
+	}
+	if algorithm == HashPHC || algorithm == HashBcrypt {
+		if err := password.ValidateHash(r.PasswordHash); err != nil {
+			return "", rowError(ReasonInvalidHash, "%v", err)
+		}
+		return r.PasswordHash, nil
+	}
+
+	hash, err := decode(r.PasswordHash, r.HashEncoding, "base64")
+	if err != nil {
+		return "", rowError(ReasonInvalidHash, "password_hash: %v", err)
+	}
+	switch algorithm {
+	case HashFirebaseScrypt:
+		if firebase == nil {
+			return "", rowError(ReasonUnsupportedAlgorithm, "firebase scrypt parameters are not configured")
+		}
+		salt, err := decode(r.PasswordSalt, r.SaltEncoding, "base64")
+		if err != nil {
+			return "", rowError(ReasonInvalidHash, "password_salt: %v", err)
+		}
+		return password.FirebaseScryptHash(*firebase, salt, hash), nil
+	case HashPBKDF2SHA256:
+		if r.HashIterations < 1 {
+			return "", rowError(ReasonInvalidHash, "hash_iterations is required")
+		}
+		salt, err := decode(r.PasswordSalt, r.SaltEncoding, "base64")
+		if err != nil {
+			return "", rowError(ReasonInvalidHash, "password_salt: %v", err)
+		}
+		return password.PBKDF2SHA256Hash(r.HashIterations, salt, hash), nil
+	case HashSHA1, HashSHA256, HashSHA512:
+		salt, err := decode(r.PasswordSalt, r.SaltEncoding, "utf8")
+		if err != nil {
+			return "", rowError(ReasonInvalidHash, "password_salt: %v", err)
+		}
+		position := r.SaltPosition
+		if position == "" {
+			position = password.SaltPrefix
+		}
+		encoded, err := password.SaltedSHAHash("salted-"+algorithm, position, salt, hash)
+		if err != nil {
+			return "", rowError(ReasonInvalidHash, "%v", err)
+		}
+		return encoded, nil
+	default:
+		return "", rowError(ReasonUnsupportedAlgorithm, "unsupported hash_algorithm %q", r.HashAlgorithm)
+	}
+}
+
+func decode(value, encoding, fallback string) ([]byte, error) {
+	if encoding == "" {
+		encoding = fallback
+	}
+	switch encoding {
+	case "base64":
+		value = strings.TrimRight(value, "=")
+		if strings.ContainsAny(value, "-_") {
+			return base64.RawURLEncoding.DecodeString(value)
+		}
+		return base64.RawStdEncoding.DecodeString(value)
+	case "hex":
+		return hex.DecodeString(value)
+	case "utf8":
+		return []byte(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// setField sets a row field from a CSV column
+func (r *Row) setField(column, value string) error {
+	switch column {
+	case "email":
+		r.Email = value
+	case "name":
+		r.Name = value
+	case "email_verified":
+		if value == "" {
+			return nil
+		}
+		verified, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("email_verified: %w", err)
+		}
+		r.EmailVerified = verified
+	case "picture":
+		r.Picture = value
+	case "gender":
+		r.Gender = value
+	case "birthdate":
+		r.Birthdate = value
+	case "locale":
+		r.Locale = value
+	case "timezone":
+		r.Timezone = value
+	case "password_hash":
+		r.PasswordHash = value
+	case "password_salt":
+		r.PasswordSalt = value
+	case "hash_algorithm":
+		r.HashAlgorithm = value
+	case "hash_iterations":
+		if value == "" {
+			return nil
+		}
+		iterations, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("hash_iterations: %w", err)
+		}
+		r.HashIterations = iterations
+	case "hash_encoding":
+		r.HashEncoding = value
+	case "salt_encoding":
+		r.SaltEncoding = value
+	case "salt_position":
+		r.SaltPosition = value
+	}
+	return nil
+}
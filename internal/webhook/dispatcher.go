@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/database"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Settings struct {
+	Enabled        bool
+	PollInterval   time.Duration
+	Timeout        time.Duration
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	BatchSize      int
+}
+
+// Payload is the JSON body posted to webhook endpoints
+type Payload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher fans outbox events out to the webhooks of the clients and delivers them with exponential backoff.
+// Deliveries that exhaust their attempts are dead-lettered until they are redelivered.
+type Dispatcher struct {
+	settings   Settings
+	clients    *client.Settings
+	httpClient *http.Client
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+func NewDispatcher(settings Settings, clients *client.Settings, logger *slog.Logger) *Dispatcher {
+	if settings.PollInterval <= 0 {
+		settings.PollInterval = 5 * time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = 8
+	}
+	if settings.InitialBackoff <= 0 {
+		settings.InitialBackoff = 30 * time.Second
+	}
+	if settings.MaxBackoff <= 0 {
+		settings.MaxBackoff = time.Hour
+	}
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = 100
+	}
+	return &Dispatcher{
+		settings:   settings,
+		clients:    clients,
+		httpClient: &http.Client{Timeout: settings.Timeout},
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Run dispatches pending events until the context is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.settings.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.DispatchOnce(ctx); err != nil {
+			d.logger.ErrorContext(ctx, "webhook dispatch failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce fans out new outbox events and sends every delivery that is due
+func (d *Dispatcher) DispatchOnce(ctx context.Context) error {
+	if err := d.fanOut(); err != nil {
+		return err
+	}
+	now := d.now()
+	deliveries, err := database.GetDueWebhookDeliveries(now, d.settings.BatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delivery := &deliveries[i]
+		locked, err := delivery.Lock(now, now.Add(2*d.settings.Timeout))
+		if err != nil {
+			return err
+		}
+		if !locked {
+			continue
+		}
+		d.deliver(ctx, delivery)
+		if err = delivery.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) fanOut() error {
+	events, err := database.GetUnprocessedOutboxEvents(d.settings.BatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		event := &events[i]
+		var deliveries []database.WebhookDelivery
+		for _, item := range d.clients.Clients {
+			for _, endpoint := range item.Webhooks {
+				if !endpoint.Subscribes(event.EventType) {
+					continue
+				}
+				deliveries = append(deliveries, database.WebhookDelivery{
+					OutboxEventID: event.ID,
+					ClientID:      item.ID,
+					URL:           endpoint.URL,
+					Status:        database.DeliveryStatusPending,
+					NextAttemptAt: d.now(),
+				})
+			}
+		}
+		if _, err = database.ProcessOutboxEvent(event, deliveries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *database.WebhookDelivery) {
+	delivery.Attempts++
+	statusCode, err := d.send(ctx, delivery)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := d.now()
+		delivery.Status = database.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.settings.MaxAttempts {
+		delivery.Status = database.DeliveryStatusDead
+		d.logger.WarnContext(ctx, "webhook delivery dead-lettered", "delivery_id", delivery.ID, "client_id", delivery.ClientID, "attempts", delivery.Attempts, "error", err)
+		return
+	}
+	delivery.NextAttemptAt = d.now().Add(d.backoff(delivery.Attempts))
+	d.logger.InfoContext(ctx, "webhook delivery failed, retrying", "delivery_id", delivery.ID, "client_id", delivery.ClientID, "attempts", delivery.Attempts, "error", err)
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *database.WebhookDelivery) (int, error) {
+	ctxClient := d.clients.GetClient(delivery.ClientID)
+	if ctxClient == nil {
+		return 0, fmt.Errorf("client %s no longer exists", delivery.ClientID)
+	}
+	endpoint := ctxClient.GetWebhook(delivery.URL)
+	if endpoint == nil {
+		return 0, fmt.Errorf("webhook %s is no longer configured", delivery.URL)
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        strconv.FormatUint(delivery.OutboxEventID, 10),
+		Type:      delivery.OutboxEvent.EventType,
+		CreatedAt: delivery.OutboxEvent.CreatedAt.UTC(),
+		Data:      json.RawMessage(delivery.OutboxEvent.Payload),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, delivery.OutboxEvent.EventType)
+	request.Header.Set(DeliveryHeader, strconv.FormatUint(delivery.ID, 10))
+	request.Header.Set(SignatureHeader, Sign(endpoint.Secret, d.now(), body))
+
+	response, err := d.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("endpoint responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt, up to the configured maximum
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.settings.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= d.settings.MaxBackoff {
+			return d.settings.MaxBackoff
+		}
+	}
+	return wait
+}
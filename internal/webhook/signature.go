@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Usercore-Signature"
+	EventHeader     = "X-Usercore-Event"
+	DeliveryHeader  = "X-Usercore-Delivery"
+)
+
+// Sign returns the signature header value for the body. The signed content is the timestamp and the body joined by a
+// dot, so a receiver can reject replayed deliveries by checking the timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", unix, computeMAC(secret, unix, body))
+}
+
+// Verify checks a signature header value against the body and rejects signatures older than the tolerance
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) bool {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || signature == "" {
+		return false
+	}
+	if tolerance > 0 && now.Sub(time.Unix(seconds, 0)).Abs() > tolerance {
+		return false
+	}
+	expected := computeMAC(secret, unix, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func computeMAC(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/usercoredev/usercore/internal/client"
+	"github.com/usercoredev/usercore/internal/database"
+	"github.com/usercoredev/usercore/internal/database/dbtest"
+	"github.com/usercoredev/usercore/internal/pagination"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	bodies   [][]byte
+	headers  []http.Header
+	received int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.received++
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	w.WriteHeader(r.status)
+}
+
+func newTestDispatcher(url string, settings Settings) (*Dispatcher, *time.Time) {
+	clients := &client.Settings{Clients: []client.Item{{
+		ID:   "client-1",
+		Name: "Billing",
+		Webhooks: []client.Webhook{{
+			URL:    url,
+			Secret: "webhook-secret",
+			Events: []string{database.EventUserCreated},
+		}},
+	}}}
+	dispatcher := NewDispatcher(settings, clients, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	return dispatcher, &now
+}
+
+func paginationOf(size int32) pagination.PageMetadata {
+	return pagination.PageMetadata{PageSize: size}
+}
+
+func enqueue(t *testing.T, eventType string) {
+	t.Helper()
+	if err := database.EnqueueOutboxEvent(database.DB, eventType, uuid.New(), map[string]string{"name": "Jane"}); err != nil {
+		t.Fatalf("EnqueueOutboxEvent failed: %v", err)
+	}
+}
+
+func TestDispatcherDeliversSignedWebhook(t *testing.T) {
+	dbtest.Setup(t)
+	target := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(target)
+	defer server.Close()
+
+	dispatcher, _ := newTestDispatcher(server.URL, Settings{})
+	enqueue(t, database.EventUserCreated)
+	enqueue(t, database.EventUserDeleted)
+
+	if err := dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("DispatchOnce failed: %v", err)
+	}
+
+	if target.received != 1 {
+		t.Fatalf("Expected only the subscribed event to be delivered, got %d requests", target.received)
+	}
+	header := target.headers[0]
+	if header.Get(EventHeader) != database.EventUserCreated {
+		t.Errorf("Expected event header %s, got %s", database.EventUserCreated, header.Get(EventHeader))
+	}
+	if !Verify("webhook-secret", header.Get(SignatureHeader), target.bodies[0], 5*time.Minute, time.Now()) {
+		t.Errorf("Expected a valid signature")
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(target.bodies[0], &payload); err != nil {
+		t.Fatalf("Expected a JSON payload: %v", err)
+	}
+	if payload.Type != database.EventUserCreated || string(payload.Data) != `{"name":"Jane"}` {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+
+	deliveries, _, err := database.GetWebhookDeliveries(database.WebhookDeliveryFilter{}, paginationOf(10))
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != database.DeliveryStatusDelivered {
+		t.Errorf("Expected one delivered delivery, got %+v (%v)", deliveries, err)
+	}
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	dbtest.Setup(t)
+	target := &receiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(target)
+	defer server.Close()
+
+	dispatcher, now := newTestDispatcher(server.URL, Settings{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour})
+	enqueue(t, database.EventUserCreated)
+
+	if err := dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("DispatchOnce failed: %v", err)
+	}
+	// The retry is not due yet
+	if err := dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("DispatchOnce failed: %v", err)
+	}
+	if target.received != 1 {
+		t.Fatalf("Expected a single attempt before the backoff elapses, got %d", target.received)
+	}
+
+	for i := 0; i < 2; i++ {
+		*now = now.Add(time.Hour)
+		if err := dispatcher.DispatchOnce(context.Background()); err != nil {
+			t.Fatalf("DispatchOnce failed: %v", err)
+		}
+	}
+	if target.received != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", target.received)
+	}
+
+	delivery, err := database.GetWebhookDeliveryById(1)
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveryById failed: %v", err)
+	}
+	if delivery.Status != database.DeliveryStatusDead || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a dead delivery with status 500, got %s %d", delivery.Status, delivery.LastStatusCode)
+	}
+
+	target.status = http.StatusOK
+	if err = delivery.Redeliver(); err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	*now = time.Now().Add(time.Second)
+	if err = dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("DispatchOnce failed: %v", err)
+	}
+	delivery, _ = database.GetWebhookDeliveryById(1)
+	if delivery.Status != database.DeliveryStatusDelivered || delivery.Attempts != 1 {
+		t.Errorf("Expected the redelivery to succeed on its first attempt, got %s after %d", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(Settings{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}, &client.Settings{}, slog.Default())
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, wait := range expected {
+		if got := dispatcher.backoff(i + 1); got != wait {
+			t.Errorf("backoff(%d): expected %v, got %v", i+1, wait, got)
+		}
+	}
+}
+
+func TestVerifyRejectsTamperedBody(t *testing.T) {
+	now := time.Now()
+	header := Sign("secret", now, []byte(`{"a":1}`))
+	if !Verify("secret", header, []byte(`{"a":1}`), time.Minute, now) {
+		t.Errorf("Expected signature to verify")
+	}
+	if Verify("secret", header, []byte(`{"a":2}`), time.Minute, now) {
+		t.Errorf("Expected tampered body to be rejected")
+	}
+	if Verify("other", header, []byte(`{"a":1}`), time.Minute, now) {
+		t.Errorf("Expected wrong secret to be rejected")
+	}
+	if Verify("secret", header, []byte(`{"a":1}`), time.Minute, now.Add(time.Hour)) {
+		t.Errorf("Expected stale signature to be rejected")
+	}
+}
@@ -1,14 +1,48 @@
 package main
 
 import (
+	"fmt"
 	usercore "github.com/usercoredev/usercore/app"
+	"github.com/usercoredev/usercore/internal/config"
+	"os"
 )
 
 func main() {
-	usercoreApp := usercore.Create()
+	file, args := config.FileFromArgs(os.Args[1:])
+	cfg, err := config.Load(file)
+	// config and help run with an invalid configuration, so it can be inspected and fixed
+	if err == nil && (len(args) == 0 || (args[0] != "config" && args[0] != "help")) {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	usercoreApp := usercore.Create(cfg)
+	if len(args) > 0 {
+		if err = usercoreApp.RunCommand(args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	usercoreApp.ConfigureLogger()
+	usercoreApp.ConfigureMailer()
+	usercoreApp.ConfigureNotifications()
+	usercoreApp.ConfigureRisk()
+	usercoreApp.ConfigureClientIP()
+	usercoreApp.ConfigureDates()
 	usercoreApp.ConfigureToken()
+	usercoreApp.ConfigureSessionPolicy()
+	usercoreApp.ConfigurePasswordHashing()
 	usercoreApp.ConnectToDatabase()
 	usercoreApp.SetupCache()
+	usercoreApp.StartScheduler()
+	usercoreApp.StartDataExportWorker()
+	usercoreApp.StartNotificationWorker()
 	usercoreApp.LoadClients()
+	usercoreApp.LoadRateLimits()
+	usercoreApp.LoadBreachedPasswords()
+	usercoreApp.StartWebhookDispatcher()
 	usercoreApp.StartServer()
 }